@@ -0,0 +1,166 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package attachment
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	ctlreg "github.com/k14s/imgpkg/pkg/imgpkg/registry"
+)
+
+// emptyConfigMediaType is the config media type of artifacts without a config
+// (https://github.com/opencontainers/image-spec/blob/main/manifest.md#guidance-for-an-empty-descriptor)
+const emptyConfigMediaType = "application/vnd.oci.empty.v1+json"
+
+var emptyConfig = []byte("{}")
+
+type artifactDescriptor struct {
+	MediaType   types.MediaType   `json:"mediaType"`
+	Size        int64             `json:"size"`
+	Digest      regv1.Hash        `json:"digest"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type artifactManifest struct {
+	SchemaVersion int64                `json:"schemaVersion"`
+	MediaType     types.MediaType      `json:"mediaType"`
+	ArtifactType  string               `json:"artifactType"`
+	Config        artifactDescriptor   `json:"config"`
+	Layers        []artifactDescriptor `json:"layers"`
+	Subject       artifactDescriptor   `json:"subject"`
+}
+
+// artifactImage is an OCI image manifest with an artifact type and a subject
+// (not supported by go-containerregistry manifests), whose layers are attached files
+type artifactImage struct {
+	artifactType string
+	raw          []byte
+	files        []fileLayer
+}
+
+var _ regv1.Image = &artifactImage{}
+
+func newArtifactImage(artifactType string, subject regv1.Descriptor, files []fileLayer) (*artifactImage, error) {
+	manifest := artifactManifest{
+		SchemaVersion: 2,
+		MediaType:     types.OCIManifestSchema1,
+		ArtifactType:  artifactType,
+		Config: artifactDescriptor{
+			MediaType: emptyConfigMediaType,
+			Size:      int64(len(emptyConfig)),
+			Digest:    sha256Hash(emptyConfig),
+		},
+		Layers: []artifactDescriptor{},
+		Subject: artifactDescriptor{
+			MediaType: subject.MediaType,
+			Size:      subject.Size,
+			Digest:    subject.Digest,
+		},
+	}
+
+	for _, file := range files {
+		manifest.Layers = append(manifest.Layers, artifactDescriptor{
+			MediaType:   file.mediaType,
+			Size:        int64(len(file.contents)),
+			Digest:      sha256Hash(file.contents),
+			Annotations: map[string]string{TitleAnnotation: file.name},
+		})
+	}
+
+	raw, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	return &artifactImage{artifactType: artifactType, raw: raw, files: files}, nil
+}
+
+func (i *artifactImage) referrerDescriptor() (ctlreg.ReferrerDescriptor, error) {
+	digest, err := i.Digest()
+	if err != nil {
+		return ctlreg.ReferrerDescriptor{}, err
+	}
+
+	return ctlreg.ReferrerDescriptor{
+		MediaType:    types.OCIManifestSchema1,
+		Size:         int64(len(i.raw)),
+		Digest:       digest,
+		ArtifactType: i.artifactType,
+	}, nil
+}
+
+func (i *artifactImage) Layers() ([]regv1.Layer, error) {
+	var layers []regv1.Layer
+	for _, file := range i.files {
+		layers = append(layers, file)
+	}
+	return layers, nil
+}
+
+func (i *artifactImage) MediaType() (types.MediaType, error) { return types.OCIManifestSchema1, nil }
+
+func (i *artifactImage) Size() (int64, error) { return int64(len(i.raw)), nil }
+
+func (i *artifactImage) ConfigName() (regv1.Hash, error) { return sha256Hash(emptyConfig), nil }
+
+func (i *artifactImage) ConfigFile() (*regv1.ConfigFile, error) { return &regv1.ConfigFile{}, nil }
+
+func (i *artifactImage) RawConfigFile() ([]byte, error) { return emptyConfig, nil }
+
+func (i *artifactImage) Digest() (regv1.Hash, error) { return sha256Hash(i.raw), nil }
+
+func (i *artifactImage) Manifest() (*regv1.Manifest, error) {
+	return regv1.ParseManifest(bytes.NewReader(i.raw))
+}
+
+func (i *artifactImage) RawManifest() ([]byte, error) { return i.raw, nil }
+
+func (i *artifactImage) LayerByDigest(digest regv1.Hash) (regv1.Layer, error) {
+	for _, file := range i.files {
+		if sha256Hash(file.contents) == digest {
+			return file, nil
+		}
+	}
+	return nil, fmt.Errorf("Expected layer '%s' to be present in attachment", digest)
+}
+
+func (i *artifactImage) LayerByDiffID(diffID regv1.Hash) (regv1.Layer, error) {
+	return i.LayerByDigest(diffID)
+}
+
+// fileLayer stores an attached file as is (without compression)
+type fileLayer struct {
+	name      string
+	contents  []byte
+	mediaType types.MediaType
+}
+
+func (l fileLayer) Digest() (regv1.Hash, error) { return sha256Hash(l.contents), nil }
+
+func (l fileLayer) DiffID() (regv1.Hash, error) { return l.Digest() }
+
+func (l fileLayer) Compressed() (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(l.contents)), nil
+}
+
+func (l fileLayer) Uncompressed() (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(l.contents)), nil
+}
+
+func (l fileLayer) Size() (int64, error) { return int64(len(l.contents)), nil }
+
+func (l fileLayer) MediaType() (types.MediaType, error) { return l.mediaType, nil }
+
+func sha256Hash(contents []byte) regv1.Hash {
+	sum := sha256.Sum256(contents)
+	return regv1.Hash{Algorithm: "sha256", Hex: hex.EncodeToString(sum[:])}
+}
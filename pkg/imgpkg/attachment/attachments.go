@@ -0,0 +1,245 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package attachment
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	regremote "github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	ctlreg "github.com/k14s/imgpkg/pkg/imgpkg/registry"
+)
+
+// TitleAnnotation holds the file name of an attached file
+// (https://github.com/opencontainers/image-spec/blob/main/annotations.md)
+const TitleAnnotation = "org.opencontainers.image.title"
+
+type Registry interface {
+	Get(regname.Reference) (*regremote.Descriptor, error)
+	Image(regname.Reference) (regv1.Image, error)
+	WriteImage(regname.Reference, regv1.Image) error
+	WriteIndex(regname.Reference, regv1.ImageIndex) error
+	Referrers(regname.Digest) ([]ctlreg.ReferrerDescriptor, error)
+	ReferrersAPISupported(regname.Repository) (bool, error)
+}
+
+// Attachments are OCI artifacts that refer to an image or a bundle via their subject field,
+// stored in the repository of the image they are attached to
+type Attachments struct {
+	registry Registry
+}
+
+func NewAttachments(registry Registry) *Attachments {
+	return &Attachments{registry: registry}
+}
+
+// Attach pushes an artifact containing provided files, one layer per file, whose subject is the image.
+// When the registry does not support the referrers API, the artifact is also listed by the referrers fallback index.
+func (a Attachments) Attach(imageRef regname.Reference, artifactType string, paths []string) (regname.Digest, error) {
+	subject, err := a.registry.Get(imageRef)
+	if err != nil {
+		return regname.Digest{}, fmt.Errorf("Fetching '%s': %s", imageRef.Name(), err)
+	}
+
+	var files []fileLayer
+	names := map[string]struct{}{}
+	for _, path := range paths {
+		name := filepath.Base(path)
+		if _, found := names[name]; found {
+			return regname.Digest{}, fmt.Errorf("Expected attached files to have different names, but found '%s' multiple times", name)
+		}
+		names[name] = struct{}{}
+
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return regname.Digest{}, fmt.Errorf("Reading attachment file: %s", err)
+		}
+		files = append(files, fileLayer{name: name, contents: contents, mediaType: types.MediaType(artifactType)})
+	}
+
+	artifact, err := newArtifactImage(artifactType, subject.Descriptor, files)
+	if err != nil {
+		return regname.Digest{}, err
+	}
+
+	artifactDigest, err := artifact.Digest()
+	if err != nil {
+		return regname.Digest{}, err
+	}
+
+	repo := imageRef.Context()
+	artifactRef := repo.Digest(artifactDigest.String())
+
+	err = a.registry.WriteImage(artifactRef, artifact)
+	if err != nil {
+		return regname.Digest{}, fmt.Errorf("Writing attachment '%s': %s", artifactRef.Name(), err)
+	}
+
+	err = a.addToFallbackIndex(repo.Digest(subject.Digest.String()), artifact)
+	if err != nil {
+		return regname.Digest{}, fmt.Errorf("Writing referrers fallback index of '%s': %s", subject.Digest, err)
+	}
+
+	return artifactRef, nil
+}
+
+// List returns attachments of the image, only including ones of provided artifact type if not empty
+func (a Attachments) List(imageRef regname.Reference, artifactType string) (regname.Digest, []ctlreg.ReferrerDescriptor, error) {
+	subject, err := a.registry.Get(imageRef)
+	if err != nil {
+		return regname.Digest{}, nil, fmt.Errorf("Fetching '%s': %s", imageRef.Name(), err)
+	}
+
+	subjectRef := imageRef.Context().Digest(subject.Digest.String())
+
+	referrers, err := a.registry.Referrers(subjectRef)
+	if err != nil {
+		return regname.Digest{}, nil, err
+	}
+
+	var attachments []ctlreg.ReferrerDescriptor
+	for _, referrer := range referrers {
+		if artifactType == "" || referrer.ArtifactType == artifactType {
+			attachments = append(attachments, referrer)
+		}
+	}
+
+	return subjectRef, attachments, nil
+}
+
+// Pull writes files of the attachments into the output directory, returning paths of written files.
+// Layers without a file name are written using their digest as the file name (e.g. sha256-<hex>).
+func (a Attachments) Pull(imageRef regname.Reference, artifactType string, outputPath string) ([]string, error) {
+	_, attachments, err := a.List(imageRef, artifactType)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(outputPath, 0700)
+	if err != nil {
+		return nil, fmt.Errorf("Creating output directory: %s", err)
+	}
+
+	written := map[string]regv1.Hash{}
+	var paths []string
+
+	for _, attachment := range attachments {
+		if attachment.MediaType != types.OCIManifestSchema1 {
+			continue
+		}
+
+		attachmentRef := imageRef.Context().Digest(attachment.Digest.String())
+
+		img, err := a.registry.Image(attachmentRef)
+		if err != nil {
+			return nil, fmt.Errorf("Fetching attachment '%s': %s", attachmentRef.Name(), err)
+		}
+
+		manifest, err := img.Manifest()
+		if err != nil {
+			return nil, fmt.Errorf("Reading attachment '%s': %s", attachmentRef.Name(), err)
+		}
+
+		for _, layerDesc := range manifest.Layers {
+			fileName, err := attachedFileName(layerDesc)
+			if err != nil {
+				return nil, fmt.Errorf("Reading attachment '%s': %s", attachmentRef.Name(), err)
+			}
+
+			if otherAttachment, found := written[fileName]; found {
+				return nil, fmt.Errorf("Expected attachments '%s' and '%s' to not both contain file '%s' (hint: Use --artifact-type to select attachments)",
+					otherAttachment, attachment.Digest, fileName)
+			}
+			written[fileName] = attachment.Digest
+
+			path := filepath.Join(outputPath, fileName)
+
+			err = a.writeLayer(img, layerDesc.Digest, path)
+			if err != nil {
+				return nil, fmt.Errorf("Writing file '%s' of attachment '%s': %s", fileName, attachmentRef.Name(), err)
+			}
+			paths = append(paths, path)
+		}
+	}
+
+	return paths, nil
+}
+
+func (a Attachments) addToFallbackIndex(subjectRef regname.Digest, artifact *artifactImage) error {
+	supported, err := a.registry.ReferrersAPISupported(subjectRef.Context())
+	if err != nil {
+		return err
+	}
+	if supported {
+		return nil
+	}
+
+	referrers, err := a.registry.Referrers(subjectRef)
+	if err != nil {
+		return err
+	}
+
+	desc, err := artifact.referrerDescriptor()
+	if err != nil {
+		return err
+	}
+
+	for _, referrer := range referrers {
+		if referrer.Digest == desc.Digest {
+			return nil
+		}
+	}
+
+	index, err := ctlreg.NewReferrersIndex(append(referrers, desc))
+	if err != nil {
+		return err
+	}
+
+	subjectDigest, err := regv1.NewHash(subjectRef.DigestStr())
+	if err != nil {
+		return err
+	}
+
+	return a.registry.WriteIndex(subjectRef.Context().Tag(ctlreg.ReferrersFallbackTag(subjectDigest)), index)
+}
+
+func (a Attachments) writeLayer(img regv1.Image, digest regv1.Hash, path string) error {
+	layer, err := img.LayerByDigest(digest)
+	if err != nil {
+		return err
+	}
+
+	// Files are stored as is, hence compressed contents are the file contents
+	rc, err := layer.Compressed()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	contents, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, contents, 0600)
+}
+
+func attachedFileName(layerDesc regv1.Descriptor) (string, error) {
+	fileName, found := layerDesc.Annotations[TitleAnnotation]
+	if !found {
+		return strings.ReplaceAll(layerDesc.Digest.String(), ":", "-"), nil
+	}
+
+	if fileName != filepath.Base(fileName) || fileName == "." || fileName == ".." || fileName == string(filepath.Separator) {
+		return "", fmt.Errorf("Expected file name '%s' to not contain a directory", fileName)
+	}
+
+	return fileName, nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package attachment
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/k14s/imgpkg/test/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttach(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	img := fakeRegistry.WithRandomImage("library/image")
+	reg := fakeRegistry.Build()
+
+	imageRef, err := regname.NewDigest(img.RefDigest)
+	require.NoError(t, err)
+
+	t.Run("rejects files with the same name", func(t *testing.T) {
+		filesDir := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(filesDir, "a"), 0700))
+		require.NoError(t, os.MkdirAll(filepath.Join(filesDir, "b"), 0700))
+		for _, path := range []string{"a/sbom.json", "b/sbom.json"} {
+			require.NoError(t, ioutil.WriteFile(filepath.Join(filesDir, path), []byte("{}"), 0600))
+		}
+
+		_, err := NewAttachments(reg).Attach(imageRef, "application/spdx+json",
+			[]string{filepath.Join(filesDir, "a", "sbom.json"), filepath.Join(filesDir, "b", "sbom.json")})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Expected attached files to have different names, but found 'sbom.json' multiple times")
+
+		_, attachments, err := NewAttachments(reg).List(imageRef, "")
+		require.NoError(t, err)
+		assert.Empty(t, attachments)
+	})
+}
+
+func TestPull(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	img := fakeRegistry.WithRandomImage("library/image")
+	reg := fakeRegistry.Build()
+
+	imageRef, err := regname.NewDigest(img.RefDigest)
+	require.NoError(t, err)
+
+	t.Run("rejects file names that contain a directory", func(t *testing.T) {
+		subject, err := reg.Get(imageRef)
+		require.NoError(t, err)
+
+		// Attach does not produce such file names, but other tools may
+		artifact, err := newArtifactImage("text/plain", subject.Descriptor, []fileLayer{
+			{name: "../outside.txt", contents: []byte("outside"), mediaType: types.MediaType("text/plain")},
+		})
+		require.NoError(t, err)
+
+		artifactDigest, err := artifact.Digest()
+		require.NoError(t, err)
+		require.NoError(t, reg.WriteImage(imageRef.Context().Digest(artifactDigest.String()), artifact))
+
+		attachments := NewAttachments(reg)
+		require.NoError(t, attachments.addToFallbackIndex(imageRef, artifact))
+
+		parentDir := t.TempDir()
+		_, err = attachments.Pull(imageRef, "", filepath.Join(parentDir, "output"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Expected file name '../outside.txt' to not contain a directory")
+
+		_, err = os.Stat(filepath.Join(parentDir, "outside.txt"))
+		assert.True(t, os.IsNotExist(err), "Expected file to not be written outside of the output directory")
+	})
+}
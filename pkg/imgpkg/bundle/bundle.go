@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	goui "github.com/cppforlife/go-cli-ui/ui"
@@ -36,11 +37,11 @@ type Bundle struct {
 }
 
 func NewBundle(ref string, imagesMetadata ctlimg.ImagesMetadata) *Bundle {
-	return NewBundleWithReader(ref, imagesMetadata, &singleLayerReader{})
+	return NewBundleWithReader(ref, imagesMetadata, &layersReader{})
 }
 
 func NewBundleFromPlainImage(plainImg *plainimg.PlainImage, imagesMetadata ctlimg.ImagesMetadata) *Bundle {
-	return &Bundle{plainImg: plainImg, imgRetriever: imagesMetadata, imagesLockReader: &singleLayerReader{}, imagesRef: map[string]ImageRef{}}
+	return &Bundle{plainImg: plainImg, imgRetriever: imagesMetadata, imagesLockReader: &layersReader{}, imagesRef: map[string]ImageRef{}}
 }
 
 func NewBundleWithReader(ref string, imagesMetadata ctlimg.ImagesMetadata, imagesLockReader ImagesLockReader) *Bundle {
@@ -71,6 +72,12 @@ func (o *Bundle) imageRef(imageDigest string) (ImageRef, bool) {
 	return ref, found
 }
 
+// References returns true when the image is one of the images of the bundle
+func (o *Bundle) References(imageDigestRef string) bool {
+	_, found := o.imageRef(imageDigestRef)
+	return found
+}
+
 func (o *Bundle) imageRefs() []ImageRef {
 	var imgsRef []ImageRef
 	for _, ref := range o.imagesRef {
@@ -79,34 +86,63 @@ func (o *Bundle) imageRefs() []ImageRef {
 	return imgsRef
 }
 
-func (o *Bundle) NoteCopy(processedImages *imageset.ProcessedImages, reg ImagesMetadataWriter, logger util.LoggerWithLevels) error {
+// NoteCopy writes the locations image of the copied bundle.
+// Images of the bundle that were not copied have to be in excludedImages (digest references),
+// they are recorded as remaining at their original location.
+func (o *Bundle) NoteCopy(processedImages *imageset.ProcessedImages, excludedImages map[string]struct{}, reg ImagesMetadataWriter, logger util.LoggerWithLevels) error {
 	locationsCfg := ImageLocationsConfig{
 		APIVersion: LocationAPIVersion,
 		Kind:       ImageLocationsKind,
 	}
 	var bundleProcessedImage imageset.ProcessedImage
 	for _, image := range processedImages.All() {
-		ref, found := o.imageRef(image.UnprocessedImageRef.DigestRef)
-		if found {
-			locationsCfg.Images = append(locationsCfg.Images, ImageLocation{
-				Image:    ref.Image,
-				IsBundle: *ref.IsBundle,
-			})
-		}
 		if image.UnprocessedImageRef.DigestRef == o.DigestRef() {
 			bundleProcessedImage = image
 		}
 	}
 
-	if len(locationsCfg.Images) != len(o.imagesRef) {
-		panic(fmt.Sprintf("Expected: %d images to be written to Location OCI. Actual: %d were written", len(o.imagesRef), len(locationsCfg.Images)))
-	}
-
 	destinationRef, err := regname.NewDigest(bundleProcessedImage.DigestRef)
 	if err != nil {
 		panic(fmt.Sprintf("Internal inconsistency: '%s' have to be a digest", bundleProcessedImage.DigestRef))
 	}
 
+	copiedImages := map[string]struct{}{}
+	for _, image := range processedImages.All() {
+		ref, found := o.imageRef(image.UnprocessedImageRef.DigestRef)
+		if found {
+			copiedImages[ref.Image] = struct{}{}
+			location := ImageLocation{
+				Image:    ref.Image,
+				IsBundle: *ref.IsBundle,
+			}
+			relocatedDigest, err := relocatedDigest(image)
+			if err != nil {
+				return err
+			}
+			location.RelocatedDigest = relocatedDigest
+			relocatedRepo, err := relocatedRepo(image, destinationRef)
+			if err != nil {
+				return err
+			}
+			location.RelocatedRepo = relocatedRepo
+			locationsCfg.Images = append(locationsCfg.Images, location)
+		}
+	}
+
+	// Images excluded from the copy are recorded so that they are not expected in the bundle repository
+	var skippedImages []ImageLocation
+	for _, ref := range o.imagesRef {
+		if _, found := copiedImages[ref.Image]; found {
+			continue
+		}
+		if !isExcluded(ref, excludedImages) {
+			return fmt.Errorf("Expected image '%s' to be copied with the bundle, but it was not", ref.Image)
+		}
+		skippedImages = append(skippedImages, ImageLocation{Image: ref.Image, IsBundle: *ref.IsBundle, Skipped: true})
+	}
+	sort.Slice(skippedImages, func(i, j int) bool { return skippedImages[i].Image < skippedImages[j].Image })
+	locationsCfg.Images = append(locationsCfg.Images, skippedImages...)
+
 	logger.Debugf("creating Locations OCI Image\n")
 	// Using NewNoopUI because we do not want to have output from this push
 	err = NewLocations(logger).Save(reg, destinationRef, locationsCfg, goui.NewNoopUI())
@@ -116,6 +152,43 @@ func (o *Bundle) NoteCopy(processedImages *imageset.ProcessedImages, reg ImagesM
 	return nil
 }
 
+func isExcluded(ref ImageRef, excludedImages map[string]struct{}) bool {
+	for _, location := range ref.Locations() {
+		if _, found := excludedImages[location]; found {
+			return true
+		}
+	}
+	return false
+}
+
+// relocatedRepo returns the repository the image was copied to when it is not the bundle repository
+func relocatedRepo(image imageset.ProcessedImage, bundleDestinationRef regname.Digest) (string, error) {
+	newRef, err := regname.NewDigest(image.DigestRef)
+	if err != nil {
+		return "", err
+	}
+	if newRef.Context().Name() == bundleDestinationRef.Context().Name() {
+		return "", nil
+	}
+	return newRef.Context().Name(), nil
+}
+
+// relocatedDigest returns the new digest of the image when copying changed it
+func relocatedDigest(image imageset.ProcessedImage) (string, error) {
+	origRef, err := regname.NewDigest(image.UnprocessedImageRef.DigestRef)
+	if err != nil {
+		return "", err
+	}
+	newRef, err := regname.NewDigest(image.DigestRef)
+	if err != nil {
+		return "", err
+	}
+	if origRef.DigestStr() == newRef.DigestStr() {
+		return "", nil
+	}
+	return newRef.DigestStr(), nil
+}
+
 func (o *Bundle) Pull(outputPath string, ui goui.UI, pullNestedBundles bool) error {
 	isRootBundleRelocated, err := o.pull(outputPath, ui, pullNestedBundles, "", map[string]bool{}, 0)
 	if err != nil {
@@ -173,6 +246,13 @@ func (o *Bundle) pull(baseOutputPath string, ui goui.UI, pullNestedBundles bool,
 		return false, err
 	}
 
+	if skippedImages := bundleImageRefs.SkippedImages(); len(skippedImages) > 0 {
+		ui.BeginLinef("\nImages not relocated with the bundle (referenced from their original location)\n")
+		for _, image := range skippedImages {
+			goui.NewIndentingUI(ui).BeginLinef("%s\n", image)
+		}
+	}
+
 	if pullNestedBundles {
 		for _, bundleImgRef := range bundleImageRefs.ImageRefs() {
 			if isBundle, alreadyProcessedImage := imagesProcessed[bundleImgRef.Image]; alreadyProcessedImage {
@@ -179,9 +179,12 @@ func (p *processedImages) CheckAndAddImage(ref string) bool {
 	return present
 }
 
-type singleLayerReader struct{}
+// layersReader finds the images lock file in the bundle image layers.
+// Layers are searched from the top most to the base layer, so that the
+// file found is the same one that would be extracted by a pull
+type layersReader struct{}
 
-func (o *singleLayerReader) Read(img regv1.Image) (lockconfig.ImagesLock, error) {
+func (o *layersReader) Read(img regv1.Image) (lockconfig.ImagesLock, error) {
 	conf := lockconfig.ImagesLock{}
 
 	layers, err := img.Layers()
@@ -189,50 +192,81 @@ func (o *singleLayerReader) Read(img regv1.Image) (lockconfig.ImagesLock, error)
 		return conf, err
 	}
 
-	if len(layers) != 1 {
-		return conf, fmt.Errorf("Expected bundle to only have a single layer, got %d", len(layers))
+	if len(layers) == 0 {
+		return conf, fmt.Errorf("Expected bundle to have at least one layer, got 0")
 	}
 
-	layer := layers[0]
+	for idx := len(layers) - 1; idx >= 0; idx-- {
+		bs, found, err := o.readLayer(layers[idx])
+		if err != nil {
+			return conf, err
+		}
+		if found {
+			return lockconfig.NewImagesLockFromBytes(bs)
+		}
+	}
 
+	return conf, fmt.Errorf("Expected to find .imgpkg/images.yml in bundle image")
+}
+
+// readLayer returns the content of .imgpkg/images.yml when found in the layer.
+// A whiteout of the file (or of one of its parents) means that the file
+// was removed so searching in lower layers is pointless
+func (o *layersReader) readLayer(layer regv1.Layer) ([]byte, bool, error) {
 	mediaType, err := layer.MediaType()
 	if err != nil {
-		return conf, err
+		return nil, false, err
 	}
 
-	if mediaType != types.DockerLayer {
-		return conf, fmt.Errorf("Expected layer to have docker layer media type, was %s", mediaType)
+	if mediaType != types.DockerLayer && mediaType != types.OCILayer && !ctlimg.IsZstdLayer(mediaType) {
+		return nil, false, fmt.Errorf("Expected layer to have docker or OCI layer media type, was %s", mediaType)
 	}
 
-	// here we know layer is .tgz so decompress and read tar headers
-	unzippedReader, err := layer.Uncompressed()
+	// here we know layer is a compressed tar so decompress and read tar headers
+	unzippedReader, err := ctlimg.UncompressedLayer(layer)
 	if err != nil {
-		return conf, fmt.Errorf("Could not read bundle image layer contents: %v", err)
+		return nil, false, fmt.Errorf("Could not read bundle image layer contents: %v", err)
 	}
 
+	defer unzippedReader.Close()
+
+	const (
+		whiteoutPrefix       = ".wh."
+		whiteoutOpaqueMarker = ".wh..wh..opq"
+	)
+
+	var whitedOut bool
+
 	tarReader := tar.NewReader(unzippedReader)
 	for {
 		header, err := tarReader.Next()
 		if err != nil {
 			if err == io.EOF {
-				return conf, fmt.Errorf("Expected to find .imgpkg/images.yml in bundle image")
+				if whitedOut {
+					return nil, false, fmt.Errorf("Expected to find .imgpkg/images.yml in bundle image, but it was removed by a whiteout")
+				}
+				return nil, false, nil
 			}
-			return conf, fmt.Errorf("reading tar: %v", err)
+			return nil, false, fmt.Errorf("reading tar: %v", err)
 		}
 
 		basename := filepath.Base(header.Name)
-		dirname := filepath.Dir(header.Name)
-		if dirname == ImgpkgDir && basename == ImagesLockFile {
-			break
-		}
-	}
+		dirname := filepath.Dir(filepath.Clean(header.Name))
 
-	bs, err := ioutil.ReadAll(tarReader)
-	if err != nil {
-		return conf, fmt.Errorf("Reading images.yml from layer: %s", err)
-	}
+		switch {
+		case dirname == ImgpkgDir && basename == ImagesLockFile:
+			bs, err := ioutil.ReadAll(tarReader)
+			if err != nil {
+				return nil, false, fmt.Errorf("Reading images.yml from layer: %s", err)
+			}
+			return bs, true, nil
 
-	return lockconfig.NewImagesLockFromBytes(bs)
+		case dirname == ImgpkgDir && (basename == whiteoutPrefix+ImagesLockFile || basename == whiteoutOpaqueMarker),
+			dirname == "." && basename == whiteoutPrefix+ImgpkgDir:
+			// Entries of the same layer are not affected by its whiteouts
+			whitedOut = true
+		}
+	}
 }
 
 type LocationsConfig struct {
@@ -25,6 +25,7 @@ const (
 type Contents struct {
 	paths         []string
 	excludedPaths []string
+	layerOpts     ctlimg.LayerOpts
 }
 
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . ImagesMetadataWriter
@@ -34,7 +35,11 @@ type ImagesMetadataWriter interface {
 }
 
 func NewContents(paths []string, excludedPaths []string) Contents {
-	return Contents{paths: paths, excludedPaths: excludedPaths}
+	return NewContentsWithLayers(paths, excludedPaths, ctlimg.LayerOpts{})
+}
+
+func NewContentsWithLayers(paths []string, excludedPaths []string, layerOpts ctlimg.LayerOpts) Contents {
+	return Contents{paths: paths, excludedPaths: excludedPaths, layerOpts: layerOpts}
 }
 
 func (b Contents) Push(uploadRef regname.Tag, registry ImagesMetadataWriter, ui ui.UI) (string, error) {
@@ -44,7 +49,33 @@ func (b Contents) Push(uploadRef regname.Tag, registry ImagesMetadataWriter, ui
 	}
 
 	labels := map[string]string{BundleConfigLabel: "true"}
-	return plainimage.NewContents(b.paths, b.excludedPaths).Push(uploadRef, labels, registry, ui)
+	return plainimage.NewContentsWithLayers(b.paths, b.excludedPaths, b.imgLayerOpts()).Push(uploadRef, labels, registry, ui)
+}
+
+// Build creates the bundle image that Push would upload.
+// Caller is responsible for removing the image once it is no longer needed.
+func (b Contents) Build(ui ui.UI) (*ctlimg.FileImage, error) {
+	err := b.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	labels := map[string]string{BundleConfigLabel: "true"}
+	return plainimage.NewContentsWithLayers(b.paths, b.excludedPaths, b.imgLayerOpts()).Build(labels, ui)
+}
+
+// imgLayerOpts makes sure that, when content is split into multiple layers,
+// the .imgpkg directory always ends up in a layer of its own
+func (b Contents) imgLayerOpts() ctlimg.LayerOpts {
+	opts := b.layerOpts
+	if opts.Selector == nil {
+		return opts
+	}
+	if _, isSingleLayer := opts.Selector.(ctlimg.SingleLayer); isSingleLayer {
+		return opts
+	}
+	opts.Selector = imgpkgDirLayerSelector{opts.Selector}
+	return opts
 }
 
 func (b Contents) PresentsAsBundle() (bool, error) {
@@ -109,15 +140,9 @@ func (b *Contents) findImgpkgDirs() ([]string, error) {
 }
 
 func (b Contents) validateImgpkgDirs(imgpkgDirs []string) error {
-	if len(imgpkgDirs) != 1 {
-		imgpkgPath := filepath.Join(ImgpkgDir, ImagesLockFile)
-
-		msg := fmt.Sprintf("This directory is not a bundle. It it is missing %s", imgpkgPath)
-		if len(imgpkgDirs) > 0 {
-			msg = fmt.Sprintf("This directory constains multiple bundle definitions. Only a single instance of %s can be provided and instead these were provided %s", imgpkgPath, strings.Join(imgpkgDirs, ", "))
-		}
-
-		return bundleValidationError{msg}
+	err := validateImgpkgDirCount(imgpkgDirs)
+	if err != nil {
+		return err
 	}
 
 	// make sure it is a child of one input dir
@@ -146,6 +171,34 @@ func (b Contents) validateImgpkgDirs(imgpkgDirs []string) error {
 	return bundleValidationError{msg}
 }
 
+func validateImgpkgDirCount(imgpkgDirs []string) error {
+	if len(imgpkgDirs) != 1 {
+		imgpkgPath := filepath.Join(ImgpkgDir, ImagesLockFile)
+
+		msg := fmt.Sprintf("This directory is not a bundle. It it is missing %s", imgpkgPath)
+		if len(imgpkgDirs) > 0 {
+			msg = fmt.Sprintf("This directory constains multiple bundle definitions. Only a single instance of %s can be provided and instead these were provided %s", imgpkgPath, strings.Join(imgpkgDirs, ", "))
+		}
+
+		return bundleValidationError{msg}
+	}
+	return nil
+}
+
+type imgpkgDirLayerSelector struct {
+	delegate ctlimg.LayerSelector
+}
+
+var _ ctlimg.LayerSelector = imgpkgDirLayerSelector{}
+
+func (s imgpkgDirLayerSelector) LayerKey(fileFlagIdx int, relPath string, isDir bool) string {
+	cleanPath := filepath.ToSlash(filepath.Clean(relPath))
+	if cleanPath == ImgpkgDir || strings.HasPrefix(cleanPath, ImgpkgDir+"/") {
+		return ImgpkgDir
+	}
+	return s.delegate.LayerKey(fileFlagIdx, relPath, isDir)
+}
+
 type bundleValidationError struct {
 	msg string
 }
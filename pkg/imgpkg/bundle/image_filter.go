@@ -0,0 +1,165 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/k14s/imgpkg/pkg/imgpkg/lockconfig"
+)
+
+// ImageFilter selects which images lock entries are copied.
+// Zero value includes every image.
+type ImageFilter struct {
+	includeAnnotations []annotationSelector
+	excludeAnnotations []annotationSelector
+	excludeImages      []*regexp.Regexp
+}
+
+type annotationSelector struct {
+	key      string
+	value    string
+	anyValue bool
+}
+
+// NewImageFilter builds a filter from annotation selectors (format: key=value or key)
+// and regular expressions matched against image references.
+// Images are included when they match any of the include selectors (if provided)
+// and none of the exclude selectors or patterns.
+func NewImageFilter(includeAnnotations, excludeAnnotations, excludeImages []string) (ImageFilter, error) {
+	var filter ImageFilter
+	var err error
+
+	filter.includeAnnotations, err = newAnnotationSelectors(includeAnnotations)
+	if err != nil {
+		return ImageFilter{}, err
+	}
+
+	filter.excludeAnnotations, err = newAnnotationSelectors(excludeAnnotations)
+	if err != nil {
+		return ImageFilter{}, err
+	}
+
+	for _, pattern := range excludeImages {
+		exp, err := regexp.Compile(pattern)
+		if err != nil {
+			return ImageFilter{}, fmt.Errorf("Parsing image pattern '%s': %s", pattern, err)
+		}
+		filter.excludeImages = append(filter.excludeImages, exp)
+	}
+
+	return filter, nil
+}
+
+func newAnnotationSelectors(selectors []string) ([]annotationSelector, error) {
+	var result []annotationSelector
+	for _, selector := range selectors {
+		pieces := strings.SplitN(selector, "=", 2)
+		if pieces[0] == "" {
+			return nil, fmt.Errorf("Expected annotation selector '%s' to have a key (format: key=value or key)", selector)
+		}
+		if len(pieces) == 1 {
+			result = append(result, annotationSelector{key: pieces[0], anyValue: true})
+		} else {
+			result = append(result, annotationSelector{key: pieces[0], value: pieces[1]})
+		}
+	}
+	return result, nil
+}
+
+func (f ImageFilter) Includes(imageRef lockconfig.ImageRef) bool {
+	if len(f.includeAnnotations) > 0 && !f.matchesAny(f.includeAnnotations, imageRef.Annotations) {
+		return false
+	}
+
+	if f.matchesAny(f.excludeAnnotations, imageRef.Annotations) {
+		return false
+	}
+
+	for _, exp := range f.excludeImages {
+		if exp.MatchString(imageRef.Image) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (f ImageFilter) matchesAny(selectors []annotationSelector, annotations map[string]string) bool {
+	for _, selector := range selectors {
+		value, found := annotations[selector.key]
+		if found && (selector.anyValue || value == selector.value) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterBundleImages applies the filter to images of the root bundle and of its nested bundles.
+// Images of an excluded nested bundle are excluded as well, unless an included bundle also references them.
+// Returns included images, included bundles (starting with the root bundle) and excluded images.
+func (f ImageFilter) FilterBundleImages(root *Bundle, allBundles []*Bundle, allImageRefs ImageRefs) ([]ImageRef, []*Bundle, []ImageRef, error) {
+	bundlesByDigest := map[string]*Bundle{}
+	for _, bundle := range allBundles {
+		bundleRef, err := regname.NewDigest(bundle.DigestRef())
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		bundlesByDigest[bundleRef.DigestStr()] = bundle
+	}
+
+	includedImages := map[string]struct{}{}
+	visitedBundles := map[*Bundle]struct{}{}
+
+	bundlesToVisit := []*Bundle{root}
+	for len(bundlesToVisit) > 0 {
+		bundle := bundlesToVisit[0]
+		bundlesToVisit = bundlesToVisit[1:]
+
+		if _, visited := visitedBundles[bundle]; visited {
+			continue
+		}
+		visitedBundles[bundle] = struct{}{}
+
+		for _, imgRef := range bundle.imageRefs() {
+			if !f.Includes(imgRef.ImageRef) {
+				continue
+			}
+			includedImages[imgRef.Image] = struct{}{}
+
+			if imgRef.IsBundle == nil || !*imgRef.IsBundle {
+				continue
+			}
+			nestedBundleRef, err := regname.NewDigest(imgRef.Image)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			if nestedBundle, found := bundlesByDigest[nestedBundleRef.DigestStr()]; found {
+				bundlesToVisit = append(bundlesToVisit, nestedBundle)
+			}
+		}
+	}
+
+	// Keep bundles in the order they were found
+	includedBundles := []*Bundle{root}
+	for _, bundle := range allBundles {
+		if _, visited := visitedBundles[bundle]; visited && bundle != root {
+			includedBundles = append(includedBundles, bundle)
+		}
+	}
+
+	var included, excluded []ImageRef
+	for _, imgRef := range allImageRefs.ImageRefs() {
+		if _, found := includedImages[imgRef.Image]; found {
+			included = append(included, imgRef)
+		} else {
+			excluded = append(excluded, imgRef)
+		}
+	}
+
+	return included, includedBundles, excluded, nil
+}
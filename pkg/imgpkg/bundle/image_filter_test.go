@@ -0,0 +1,71 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle_test
+
+import (
+	"testing"
+
+	"github.com/k14s/imgpkg/pkg/imgpkg/bundle"
+	"github.com/k14s/imgpkg/pkg/imgpkg/lockconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageFilter(t *testing.T) {
+	linuxImage := lockconfig.ImageRef{Image: "registry.io/app@sha256:1", Annotations: map[string]string{"os": "linux", "optional": ""}}
+	windowsImage := lockconfig.ImageRef{Image: "registry.io/app-windows@sha256:2", Annotations: map[string]string{"os": "windows"}}
+	plainImage := lockconfig.ImageRef{Image: "registry.io/other@sha256:3"}
+
+	testCases := []struct {
+		name               string
+		includeAnnotations []string
+		excludeAnnotations []string
+		excludeImages      []string
+		expectedIncluded   []lockconfig.ImageRef
+	}{
+		{
+			name:             "includes everything by default",
+			expectedIncluded: []lockconfig.ImageRef{linuxImage, windowsImage, plainImage},
+		},
+		{
+			name:               "includes images matching any include annotation",
+			includeAnnotations: []string{"os=linux", "os=windows"},
+			expectedIncluded:   []lockconfig.ImageRef{linuxImage, windowsImage},
+		},
+		{
+			name:               "excludes images with annotation key regardless of value",
+			excludeAnnotations: []string{"optional"},
+			expectedIncluded:   []lockconfig.ImageRef{windowsImage, plainImage},
+		},
+		{
+			name:             "excludes images matching pattern",
+			excludeImages:    []string{"-windows@"},
+			expectedIncluded: []lockconfig.ImageRef{linuxImage, plainImage},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			filter, err := bundle.NewImageFilter(tc.includeAnnotations, tc.excludeAnnotations, tc.excludeImages)
+			require.NoError(t, err)
+
+			var included []lockconfig.ImageRef
+			for _, imageRef := range []lockconfig.ImageRef{linuxImage, windowsImage, plainImage} {
+				if filter.Includes(imageRef) {
+					included = append(included, imageRef)
+				}
+			}
+			assert.Equal(t, tc.expectedIncluded, included)
+		})
+	}
+
+	t.Run("errors on invalid selectors", func(t *testing.T) {
+		_, err := bundle.NewImageFilter([]string{"=linux"}, nil, nil)
+		require.EqualError(t, err, "Expected annotation selector '=linux' to have a key (format: key=value or key)")
+
+		_, err = bundle.NewImageFilter(nil, nil, []string{"("})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Parsing image pattern '('")
+	})
+}
@@ -25,6 +25,12 @@ type ImageLocationsConfig struct {
 type ImageLocation struct {
 	Image    string `json:"image"`    // This generated yaml, but due to lib we need to use `json`
 	IsBundle bool   `json:"isBundle"` // This generated yaml, but due to lib we need to use `json`
+	// RelocatedDigest is set when the image was given a new digest during copy (e.g. layers were recompressed)
+	RelocatedDigest string `json:"relocatedDigest,omitempty"` // This generated yaml, but due to lib we need to use `json`
+	// Skipped is set when the image was excluded from the copy, hence it remains in its original location
+	Skipped bool `json:"skipped,omitempty"` // This generated yaml, but due to lib we need to use `json`
+	// RelocatedRepo is set when the image was copied to a repository other than the bundle repository
+	RelocatedRepo string `json:"relocatedRepo,omitempty"` // This generated yaml, but due to lib we need to use `json`
 }
 
 func NewLocationConfigFromPath(path string) (ImageLocationsConfig, error) {
@@ -75,8 +75,67 @@ func (i *ImageRefs) LocalizeToRepo(relativeToRepo string) {
 	defer i.refsLock.Unlock()
 
 	for j, imgRef := range i.refs {
-		i.refs[j].AddLocation(replaceImageRepo(imgRef.Image, relativeToRepo))
+		if i.isSkipped(imgRef.Image) {
+			continue
+		}
+		i.refs[j].AddLocation(replaceImageRepo(i.relocatedImage(imgRef.Image), i.relocatedRepo(imgRef.Image, relativeToRepo)))
+	}
+}
+
+// SkippedImages returns images that were excluded when the bundle was copied,
+// hence were not relocated to the bundle repository
+func (i *ImageRefs) SkippedImages() []string {
+	var skipped []string
+	for _, imgRef := range i.ImageRefs() {
+		if i.isSkipped(imgRef.Image) {
+			skipped = append(skipped, imgRef.Image)
+		}
+	}
+	return skipped
+}
+
+func (i *ImageRefs) isSkipped(image string) bool {
+	if i.imageLocationsConfig == nil {
+		return false
+	}
+
+	for _, imgLoc := range i.imageLocationsConfig.Images {
+		if imgLoc.Image == image {
+			return imgLoc.Skipped
+		}
+	}
+
+	return false
+}
+
+// relocatedImage returns image reference with the digest it was given when it was copied
+func (i *ImageRefs) relocatedImage(image string) string {
+	if i.imageLocationsConfig == nil {
+		return image
 	}
+
+	for _, imgLoc := range i.imageLocationsConfig.Images {
+		if imgLoc.Image == image && imgLoc.RelocatedDigest != "" {
+			return strings.Split(image, "@")[0] + "@" + imgLoc.RelocatedDigest
+		}
+	}
+
+	return image
+}
+
+// relocatedRepo returns repository the image was copied to, which defaults to the bundle repository
+func (i *ImageRefs) relocatedRepo(image string, relativeToRepo string) string {
+	if i.imageLocationsConfig == nil {
+		return relativeToRepo
+	}
+
+	for _, imgLoc := range i.imageLocationsConfig.Images {
+		if imgLoc.Image == image && imgLoc.RelocatedRepo != "" {
+			return imgLoc.RelocatedRepo
+		}
+	}
+
+	return relativeToRepo
 }
 
 func (i *ImageRefs) UpdateRelativeToRepo(imgRetriever ctlimg.ImagesMetadata, relativeToRepo string) (bool, error) {
@@ -59,7 +59,7 @@ func NewLocationsWithReader(reader LocationImageReader, logger util.LoggerWithLe
 
 func (r LocationsConfigs) Fetch(registry image.ImagesMetadata, bundleRef name.Digest) (ImageLocationsConfig, error) {
 	r.logger.Tracef("fetching Locations OCI Images for bundle: %s\n", bundleRef)
-	locRef, err := r.locationsRefFromBundleRef(bundleRef)
+	locRef, err := r.LocationsRef(bundleRef)
 	if err != nil {
 		return ImageLocationsConfig{}, fmt.Errorf("calculating locations image tag: %s", err)
 	}
@@ -87,7 +87,7 @@ func (r LocationsConfigs) Fetch(registry image.ImagesMetadata, bundleRef name.Di
 func (r LocationsConfigs) Save(reg ImagesMetadataWriter, bundleRef name.Digest, config ImageLocationsConfig, ui ui.UI) error {
 	r.logger.Tracef("saving Locations OCI Image for bundle: %s\n", bundleRef.Name())
 
-	locRef, err := r.locationsRefFromBundleRef(bundleRef)
+	locRef, err := r.LocationsRef(bundleRef)
 	if err != nil {
 		return fmt.Errorf("calculating locations image tag: %s", err)
 	}
@@ -112,7 +112,8 @@ func (r LocationsConfigs) Save(reg ImagesMetadataWriter, bundleRef name.Digest,
 	return nil
 }
 
-func (r LocationsConfigs) locationsRefFromBundleRef(bundleRef name.Digest) (name.Tag, error) {
+// LocationsRef returns tag of the locations image that is created next to the bundle
+func (r LocationsConfigs) LocationsRef(bundleRef name.Digest) (name.Tag, error) {
 	hash, err := regv1.NewHash(bundleRef.DigestStr())
 	if err != nil {
 		return name.Tag{}, err
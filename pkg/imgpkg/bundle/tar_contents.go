@@ -0,0 +1,74 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	ctlimg "github.com/k14s/imgpkg/pkg/imgpkg/image"
+	"github.com/k14s/imgpkg/pkg/imgpkg/plainimage"
+)
+
+// TarContents uses an existing tar stream as the single layer of the bundle.
+// Tar entries are validated with the same rules as Contents directories.
+type TarContents struct {
+	stream    io.Reader
+	layerOpts ctlimg.LayerOpts
+}
+
+func NewTarContents(stream io.Reader, layerOpts ctlimg.LayerOpts) TarContents {
+	return TarContents{stream: stream, layerOpts: layerOpts}
+}
+
+func (b TarContents) Push(uploadRef regname.Tag, registry ImagesMetadataWriter, ui ui.UI) (string, error) {
+	labels := map[string]string{BundleConfigLabel: "true"}
+	return plainimage.NewTarContentsWithValidation(b.stream, b.layerOpts, validateTarImgpkgDirs).Push(uploadRef, labels, registry, ui)
+}
+
+// Build creates the bundle image that Push would upload.
+// Caller is responsible for removing the image once it is no longer needed.
+func (b TarContents) Build(ui ui.UI) (*ctlimg.FileImage, error) {
+	labels := map[string]string{BundleConfigLabel: "true"}
+	return plainimage.NewTarContentsWithValidation(b.stream, b.layerOpts, validateTarImgpkgDirs).Build(labels, ui)
+}
+
+// TarPathsPresentAsBundle checks if tar entry paths (see ctlimg.TarStreamImage.EntryPaths)
+// make up a valid bundle
+func TarPathsPresentAsBundle(paths []string) bool {
+	return validateTarImgpkgDirs(paths) == nil
+}
+
+func validateTarImgpkgDirs(paths []string) error {
+	var imgpkgDirs []string
+	pathsSet := map[string]struct{}{}
+
+	for _, entryPath := range paths {
+		pathsSet[entryPath] = struct{}{}
+		if path.Base(entryPath) == ImgpkgDir {
+			imgpkgDirs = append(imgpkgDirs, entryPath)
+		}
+	}
+
+	err := validateImgpkgDirCount(imgpkgDirs)
+	if err != nil {
+		return err
+	}
+
+	if imgpkgDirs[0] != ImgpkgDir {
+		msg := fmt.Sprintf("Expected '%s' directory, to be a direct child of the tar root; was %s", ImgpkgDir, imgpkgDirs[0])
+		return bundleValidationError{msg}
+	}
+
+	imgpkgPath := path.Join(ImgpkgDir, ImagesLockFile)
+	if _, found := pathsSet[imgpkgPath]; !found {
+		msg := "The bundle expected .imgpkg/images.yml to exist, but it wasn't found in the tar"
+		return bundleValidationError{msg}
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package bundle
+
+import (
+	"fmt"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/k14s/imgpkg/pkg/imgpkg/imageset"
+	plainimg "github.com/k14s/imgpkg/pkg/imgpkg/plainimage"
+	"github.com/k14s/imgpkg/pkg/imgpkg/util"
+)
+
+// NoteTarImport writes locations images of every bundle imported from a tarball,
+// so that images placed in repositories other than the bundle repository can be found.
+// Images are matched by digest since the tarball may have been created from a relocated bundle;
+// images of a bundle that are not part of the tarball are recorded as remaining at their original location.
+func NoteTarImport(processedImages *imageset.ProcessedImages, reg ImagesMetadataWriter, logger util.LoggerWithLevels) error {
+	// Same image may be found in the tarball under multiple references
+	imagesByDigest := map[string]imageset.ProcessedImage{}
+	bundleDigests := map[string]struct{}{}
+	var bundles []*Bundle
+
+	for _, image := range processedImages.All() {
+		sourceRef, err := regname.NewDigest(image.UnprocessedImageRef.DigestRef)
+		if err != nil {
+			return err
+		}
+		imagesByDigest[sourceRef.DigestStr()] = image
+
+		if image.Image == nil {
+			continue
+		}
+
+		bundle := NewBundleFromPlainImage(plainimg.NewFetchedPlainImageWithTag(
+			image.UnprocessedImageRef.DigestRef, image.UnprocessedImageRef.Tag, image.Image, nil), reg)
+
+		isBundle, err := bundle.IsBundle()
+		if err != nil {
+			return err
+		}
+		if isBundle {
+			bundles = append(bundles, bundle)
+			bundleDigests[sourceRef.DigestStr()] = struct{}{}
+		}
+	}
+
+	for _, bundle := range bundles {
+		excludedImages, err := bundle.addTarImportImageRefs(imagesByDigest, bundleDigests)
+		if err != nil {
+			return fmt.Errorf("Reading images of bundle %s: %s", bundle.DigestRef(), err)
+		}
+
+		err = bundle.NoteCopy(processedImages, excludedImages, reg, logger)
+		if err != nil {
+			return fmt.Errorf("Creating copy information for bundle %s: %s", bundle.DigestRef(), err)
+		}
+	}
+
+	return nil
+}
+
+// addTarImportImageRefs records images of the bundle, located where they were found in the tarball.
+// Returns images that are not part of the tarball.
+func (o *Bundle) addTarImportImageRefs(imagesByDigest map[string]imageset.ProcessedImage,
+	bundleDigests map[string]struct{}) (map[string]struct{}, error) {
+
+	img, err := o.plainImg.Fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	imagesLock, err := o.imagesLockReader.Read(img)
+	if err != nil {
+		return nil, err
+	}
+
+	excludedImages := map[string]struct{}{}
+
+	for _, lockImgRef := range imagesLock.Images {
+		digestRef, err := regname.NewDigest(lockImgRef.Image)
+		if err != nil {
+			return nil, err
+		}
+
+		imgRef := ImageRef{ImageRef: lockImgRef.DeepCopy()}
+		_, isBundle := bundleDigests[digestRef.DigestStr()]
+		imgRef.IsBundle = &isBundle
+
+		foundImage, found := imagesByDigest[digestRef.DigestStr()]
+		if found {
+			imgRef.AddLocation(foundImage.UnprocessedImageRef.DigestRef)
+		} else {
+			excludedImages[lockImgRef.Image] = struct{}{}
+		}
+
+		o.addImageRefs(imgRef)
+	}
+
+	return excludedImages, nil
+}
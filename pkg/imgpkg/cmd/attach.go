@@ -0,0 +1,107 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/k14s/imgpkg/pkg/imgpkg/attachment"
+	"github.com/k14s/imgpkg/pkg/imgpkg/bundle"
+	"github.com/k14s/imgpkg/pkg/imgpkg/plainimage"
+	"github.com/k14s/imgpkg/pkg/imgpkg/registry"
+	"github.com/spf13/cobra"
+)
+
+type AttachOptions struct {
+	ui ui.UI
+
+	ImageFlags    ImageFlags
+	BundleFlags   BundleFlags
+	RegistryFlags RegistryFlags
+
+	ArtifactType string
+	Files        []string
+}
+
+func NewAttachOptions(ui ui.UI) *AttachOptions {
+	return &AttachOptions{ui: ui}
+}
+
+func NewAttachCmd(o *AttachOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "attach",
+		Short: "Attach files to a bundle or image",
+		Long: `Attach files to a bundle or image by pushing an OCI artifact that refers to it via its subject field.
+Attachments are copied along with the bundle or image by 'copy --referrers'.`,
+		RunE: func(_ *cobra.Command, _ []string) error { return o.Run() },
+		Example: `
+  # Attach an SBOM to bundle repo/app1-bundle
+  imgpkg attach -b repo/app1-bundle --artifact-type application/spdx+json -f sbom.json
+
+  # Attach release notes and a scan report to image repo/app1-image
+  imgpkg attach -i repo/app1-image --artifact-type application/vnd.example.release+json -f notes.md -f scan.json`,
+	}
+	o.ImageFlags.Set(cmd)
+	o.BundleFlags.Set(cmd)
+	o.RegistryFlags.Set(cmd)
+	cmd.Flags().StringVar(&o.ArtifactType, "artifact-type", "", "Artifact type of the attachment (example: application/spdx+json)")
+	cmd.Flags().StringSliceVarP(&o.Files, "file", "f", nil, "Set file to attach (format: /tmp/foo) (can be specified multiple times)")
+	return cmd
+}
+
+func (o *AttachOptions) Run() error {
+	if o.ArtifactType == "" {
+		return fmt.Errorf("Expected --artifact-type to be non-empty")
+	}
+	if len(o.Files) == 0 {
+		return fmt.Errorf("Expected at least one file to attach")
+	}
+
+	reg, err := registry.NewRegistry(o.RegistryFlags.AsRegistryOpts())
+	if err != nil {
+		return fmt.Errorf("Unable to create a registry with the options %v: %v", o.RegistryFlags.AsRegistryOpts(), err)
+	}
+
+	subjectRef, err := attachmentSubjectRef(o.ImageFlags, o.BundleFlags, reg)
+	if err != nil {
+		return err
+	}
+
+	attachmentRef, err := attachment.NewAttachments(reg).Attach(subjectRef, o.ArtifactType, o.Files)
+	if err != nil {
+		return err
+	}
+
+	o.ui.BeginLinef("Attached '%s' to '%s'", attachmentRef.Name(), subjectRef.Name())
+
+	return nil
+}
+
+// attachmentSubjectRef returns reference of the bundle or image that attachments refer to
+func attachmentSubjectRef(imageFlags ImageFlags, bundleFlags BundleFlags, reg registry.Registry) (regname.Reference, error) {
+	switch {
+	case imageFlags.Image != "" && bundleFlags.Bundle != "":
+		return nil, fmt.Errorf("Expected only one of image or bundle")
+
+	case bundleFlags.Bundle != "":
+		plainImg := plainimage.NewPlainImage(bundleFlags.Bundle, reg)
+		ok, err := bundle.NewBundleFromPlainImage(plainImg, reg).IsBundle()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("Expected bundle image but found plain image (hint: Did you use -i instead of -b?)")
+		}
+		return regname.NewDigest(plainImg.DigestRef())
+
+	case imageFlags.Image != "":
+		// Image may be an image index, hence it is not fetched as a plain image
+		return regname.ParseReference(imageFlags.Image, regname.WeakValidation)
+
+	default:
+		return nil, fmt.Errorf("Expected either image or bundle")
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	goui "github.com/cppforlife/go-cli-ui/ui"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/k14s/imgpkg/pkg/imgpkg/attachment"
+	"github.com/k14s/imgpkg/pkg/imgpkg/imageset"
+	"github.com/k14s/imgpkg/pkg/imgpkg/referrers"
+	"github.com/k14s/imgpkg/pkg/imgpkg/util"
+	"github.com/k14s/imgpkg/test/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttachToBundle(t *testing.T) {
+	confUI := goui.NewConfUI(goui.NewNoopLogger())
+	defer confUI.Flush()
+
+	filesDir := t.TempDir()
+	sbomPath := filepath.Join(filesDir, "sbom.json")
+	require.NoError(t, ioutil.WriteFile(sbomPath, []byte(`{"spdxVersion":"SPDX-2.2"}`), 0600))
+	notesPath := filepath.Join(filesDir, "notes.md")
+	require.NoError(t, ioutil.WriteFile(notesPath, []byte("# Release notes"), 0600))
+
+	attachAndPull := func(t *testing.T, fakeRegistry *helpers.FakeTestRegistryBuilder, bundleRef string) {
+		attachOpts := AttachOptions{
+			ui:           confUI,
+			BundleFlags:  BundleFlags{Bundle: bundleRef},
+			ArtifactType: "application/spdx+json",
+			Files:        []string{sbomPath},
+		}
+		require.NoError(t, attachOpts.Run())
+
+		attachOpts.ArtifactType = "text/markdown"
+		attachOpts.Files = []string{notesPath}
+		require.NoError(t, attachOpts.Run())
+
+		listOpts := AttachmentsListOptions{ui: confUI, BundleFlags: BundleFlags{Bundle: bundleRef}}
+		require.NoError(t, listOpts.Run())
+
+		outputPath := filepath.Join(t.TempDir(), "sboms")
+		pullOpts := AttachmentsPullOptions{
+			ui:           confUI,
+			BundleFlags:  BundleFlags{Bundle: bundleRef},
+			ArtifactType: "application/spdx+json",
+			OutputPath:   outputPath,
+		}
+		require.NoError(t, pullOpts.Run())
+
+		files, err := ioutil.ReadDir(outputPath)
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+		assert.Equal(t, "sbom.json", files[0].Name())
+
+		contents, err := ioutil.ReadFile(filepath.Join(outputPath, "sbom.json"))
+		require.NoError(t, err)
+		assert.Equal(t, `{"spdxVersion":"SPDX-2.2"}`, string(contents))
+	}
+
+	t.Run("lists attachments using fallback index in a registry without referrers API", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+		bundleInfo := fakeRegistry.WithBundleFromPath("library/bundle", "test_assets/bundle").
+			WithEveryImageFromPath("test_assets/image_with_config", map[string]string{})
+		reg := fakeRegistry.Build()
+
+		attachAndPull(t, fakeRegistry, bundleInfo.RefDigest)
+
+		repo := strings.Split(bundleInfo.RefDigest, "@")[0]
+		_, err := remote.Head(mustParseReference(t, repo+":"+strings.Replace(bundleInfo.Digest, ":", "-", 1)))
+		require.NoError(t, err)
+
+		subjectRef, attachments, err := attachment.NewAttachments(reg).List(mustParseReference(t, bundleInfo.RefDigest), "")
+		require.NoError(t, err)
+		assert.Equal(t, bundleInfo.RefDigest, subjectRef.Name())
+		require.Len(t, attachments, 2)
+		assert.Equal(t, "application/spdx+json", attachments[0].ArtifactType)
+		assert.Equal(t, "text/markdown", attachments[1].ArtifactType)
+	})
+
+	t.Run("lists attachments using referrers API when registry supports it", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+		bundleInfo := fakeRegistry.WithBundleFromPath("library/bundle", "test_assets/bundle").
+			WithEveryImageFromPath("test_assets/image_with_config", map[string]string{})
+		fakeRegistry.WithReferrersAPI()
+		fakeRegistry.Build()
+
+		attachAndPull(t, fakeRegistry, bundleInfo.RefDigest)
+
+		repo := strings.Split(bundleInfo.RefDigest, "@")[0]
+		_, err := remote.Head(mustParseReference(t, repo+":"+strings.Replace(bundleInfo.Digest, ":", "-", 1)))
+		require.Error(t, err)
+	})
+
+	t.Run("attachments are copied with the bundle when copying referrers", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+		bundleInfo := fakeRegistry.WithBundleFromPath("library/bundle", "test_assets/bundle").
+			WithEveryImageFromPath("test_assets/image_with_config", map[string]string{})
+		reg := fakeRegistry.Build()
+
+		attachOpts := AttachOptions{
+			ui:           confUI,
+			BundleFlags:  BundleFlags{Bundle: bundleInfo.RefDigest},
+			ArtifactType: "application/spdx+json",
+			Files:        []string{sbomPath},
+		}
+		require.NoError(t, attachOpts.Run())
+
+		subject := subject
+		subject.BundleFlags.Bundle = bundleInfo.RefDigest
+		subject.registry = reg
+		subject.referrersRetriever = referrers.NewReferrers(reg, 1, subject.logger)
+
+		destRepo := fakeRegistry.ReferenceOnTestServer("library/bundle-copy")
+		_, err := subject.CopyToRepo(destRepo)
+		require.NoError(t, err)
+
+		outputPath := t.TempDir()
+		pullOpts := AttachmentsPullOptions{
+			ui:          confUI,
+			BundleFlags: BundleFlags{Bundle: destRepo + "@" + bundleInfo.Digest},
+			OutputPath:  outputPath,
+		}
+		require.NoError(t, pullOpts.Run())
+
+		contents, err := ioutil.ReadFile(filepath.Join(outputPath, "sbom.json"))
+		require.NoError(t, err)
+		assert.Equal(t, `{"spdxVersion":"SPDX-2.2"}`, string(contents))
+	})
+
+	t.Run("attachments are copied with the bundle through a tarball", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+		bundleInfo := fakeRegistry.WithBundleFromPath("library/bundle", "test_assets/bundle").
+			WithEveryImageFromPath("test_assets/image_with_config", map[string]string{})
+		reg := fakeRegistry.Build()
+
+		attachOpts := AttachOptions{
+			ui:           confUI,
+			BundleFlags:  BundleFlags{Bundle: bundleInfo.RefDigest},
+			ArtifactType: "application/spdx+json",
+			Files:        []string{sbomPath},
+		}
+		require.NoError(t, attachOpts.Run())
+
+		subject := subject
+		subject.BundleFlags.Bundle = bundleInfo.RefDigest
+		subject.registry = reg
+		subject.referrersRetriever = referrers.NewReferrers(reg, 1, subject.logger)
+
+		tarPath := filepath.Join(t.TempDir(), "bundle.tar")
+		require.NoError(t, subject.CopyToTar(tarPath))
+
+		prefixedLogger := util.NewLogger(stdOut).NewPrefixedWriter("test | ")
+		destRepo := fakeRegistry.ReferenceOnTestServer("library/bundle-from-tar")
+		tarImageSet := imageset.NewTarImageSet(imageset.NewImageSet(1, prefixedLogger), 1, prefixedLogger)
+		_, err := tarImageSet.Import(tarPath, mustParseRepository(t, destRepo), reg)
+		require.NoError(t, err)
+
+		outputPath := t.TempDir()
+		pullOpts := AttachmentsPullOptions{
+			ui:          confUI,
+			BundleFlags: BundleFlags{Bundle: destRepo + "@" + bundleInfo.Digest},
+			OutputPath:  outputPath,
+		}
+		require.NoError(t, pullOpts.Run())
+
+		contents, err := ioutil.ReadFile(filepath.Join(outputPath, "sbom.json"))
+		require.NoError(t, err)
+		assert.Equal(t, `{"spdxVersion":"SPDX-2.2"}`, string(contents))
+	})
+
+	t.Run("rejects attaching to a plain image using bundle flag", func(t *testing.T) {
+		fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+		defer fakeRegistry.CleanUp()
+		img := fakeRegistry.WithRandomImage("library/image")
+		fakeRegistry.Build()
+
+		attachOpts := AttachOptions{
+			ui:           confUI,
+			BundleFlags:  BundleFlags{Bundle: img.RefDigest},
+			ArtifactType: "application/spdx+json",
+			Files:        []string{sbomPath},
+		}
+		err := attachOpts.Run()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Expected bundle image but found plain image")
+	})
+}
@@ -0,0 +1,16 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func NewAttachmentsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "attachments",
+		Short: "Attachments",
+	}
+	return cmd
+}
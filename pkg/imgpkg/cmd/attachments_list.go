@@ -0,0 +1,90 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	uitable "github.com/cppforlife/go-cli-ui/ui/table"
+	"github.com/k14s/imgpkg/pkg/imgpkg/attachment"
+	"github.com/k14s/imgpkg/pkg/imgpkg/registry"
+	"github.com/spf13/cobra"
+)
+
+type AttachmentsListOptions struct {
+	ui ui.UI
+
+	ImageFlags    ImageFlags
+	BundleFlags   BundleFlags
+	RegistryFlags RegistryFlags
+	ArtifactType  string
+}
+
+func NewAttachmentsListOptions(ui ui.UI) *AttachmentsListOptions {
+	return &AttachmentsListOptions{ui: ui}
+}
+
+func NewAttachmentsListCmd(o *AttachmentsListOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List attachments of a bundle or image",
+		RunE:    func(_ *cobra.Command, _ []string) error { return o.Run() },
+		Example: `
+  # List attachments of bundle repo/app1-bundle
+  imgpkg attachments ls -b repo/app1-bundle
+
+  # List SBOMs attached to image repo/app1-image
+  imgpkg attachments ls -i repo/app1-image --artifact-type application/spdx+json`,
+	}
+	o.ImageFlags.Set(cmd)
+	o.BundleFlags.Set(cmd)
+	o.RegistryFlags.Set(cmd)
+	cmd.Flags().StringVar(&o.ArtifactType, "artifact-type", "", "Only list attachments of artifact type (example: application/spdx+json)")
+	return cmd
+}
+
+func (o *AttachmentsListOptions) Run() error {
+	reg, err := registry.NewRegistry(o.RegistryFlags.AsRegistryOpts())
+	if err != nil {
+		return fmt.Errorf("Unable to create a registry with the options %v: %v", o.RegistryFlags.AsRegistryOpts(), err)
+	}
+
+	subjectRef, err := attachmentSubjectRef(o.ImageFlags, o.BundleFlags, reg)
+	if err != nil {
+		return err
+	}
+
+	digestRef, attachments, err := attachment.NewAttachments(reg).List(subjectRef, o.ArtifactType)
+	if err != nil {
+		return err
+	}
+
+	table := uitable.Table{
+		Title:   fmt.Sprintf("Attachments of '%s'", digestRef.Name()),
+		Content: "attachments",
+
+		Header: []uitable.Header{
+			uitable.NewHeader("Digest"),
+			uitable.NewHeader("Artifact type"),
+		},
+
+		SortBy: []uitable.ColumnSort{
+			{Column: 1, Asc: true},
+			{Column: 0, Asc: true},
+		},
+	}
+
+	for _, attached := range attachments {
+		table.Rows = append(table.Rows, []uitable.Value{
+			uitable.NewValueString(attached.Digest.String()),
+			uitable.NewValueString(attached.ArtifactType),
+		})
+	}
+
+	o.ui.PrintTable(table)
+
+	return nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	"github.com/k14s/imgpkg/pkg/imgpkg/attachment"
+	"github.com/k14s/imgpkg/pkg/imgpkg/registry"
+	"github.com/spf13/cobra"
+)
+
+type AttachmentsPullOptions struct {
+	ui ui.UI
+
+	ImageFlags    ImageFlags
+	BundleFlags   BundleFlags
+	RegistryFlags RegistryFlags
+	ArtifactType  string
+	OutputPath    string
+}
+
+func NewAttachmentsPullOptions(ui ui.UI) *AttachmentsPullOptions {
+	return &AttachmentsPullOptions{ui: ui}
+}
+
+func NewAttachmentsPullCmd(o *AttachmentsPullOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pull",
+		Short: "Pull files attached to a bundle or image",
+		RunE:  func(_ *cobra.Command, _ []string) error { return o.Run() },
+		Example: `
+  # Pull SBOMs attached to bundle repo/app1-bundle into /tmp/app1-sboms
+  imgpkg attachments pull -b repo/app1-bundle --artifact-type application/spdx+json -o /tmp/app1-sboms`,
+	}
+	o.ImageFlags.Set(cmd)
+	o.BundleFlags.Set(cmd)
+	o.RegistryFlags.Set(cmd)
+	cmd.Flags().StringVar(&o.ArtifactType, "artifact-type", "", "Only pull attachments of artifact type (example: application/spdx+json)")
+	cmd.Flags().StringVarP(&o.OutputPath, "output", "o", "", "Output directory path")
+	cmd.MarkFlagRequired("output")
+	return cmd
+}
+
+func (o *AttachmentsPullOptions) Run() error {
+	if o.OutputPath == "" {
+		return fmt.Errorf("Expected --output to be none empty")
+	}
+
+	reg, err := registry.NewRegistry(o.RegistryFlags.AsRegistryOpts())
+	if err != nil {
+		return fmt.Errorf("Unable to create a registry with the options %v: %v", o.RegistryFlags.AsRegistryOpts(), err)
+	}
+
+	subjectRef, err := attachmentSubjectRef(o.ImageFlags, o.BundleFlags, reg)
+	if err != nil {
+		return err
+	}
+
+	paths, err := attachment.NewAttachments(reg).Pull(subjectRef, o.ArtifactType, o.OutputPath)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		o.ui.BeginLinef("Wrote '%s'\n", path)
+	}
+	o.ui.BeginLinef("Pulled %d attached files of '%s'", len(paths), subjectRef.Name())
+
+	return nil
+}
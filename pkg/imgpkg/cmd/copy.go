@@ -5,15 +5,22 @@ package cmd
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/cppforlife/go-cli-ui/ui"
 	regname "github.com/google/go-containerregistry/pkg/name"
 	regv1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/types"
 	"github.com/k14s/imgpkg/pkg/imgpkg/bundle"
+	ctlimg "github.com/k14s/imgpkg/pkg/imgpkg/image"
+	"github.com/k14s/imgpkg/pkg/imgpkg/imagedesc"
 	ctlimgset "github.com/k14s/imgpkg/pkg/imgpkg/imageset"
 	"github.com/k14s/imgpkg/pkg/imgpkg/lockconfig"
 	"github.com/k14s/imgpkg/pkg/imgpkg/plainimage"
+	"github.com/k14s/imgpkg/pkg/imgpkg/referrers"
 	"github.com/k14s/imgpkg/pkg/imgpkg/registry"
 	"github.com/k14s/imgpkg/pkg/imgpkg/signature"
 	"github.com/k14s/imgpkg/pkg/imgpkg/util"
@@ -22,22 +29,47 @@ import (
 
 const rootBundleLabelKey string = "dev.carvel.imgpkg.copy.root-bundle"
 
+// copyFailedAnnotation marks images of an images lock output that failed to be copied (they keep their original location)
+const copyFailedAnnotation = "imgpkg.carvel.dev/copy-failed"
+
+// cosignArtifactAnnotation marks images of an images lock output that are cosign signatures, attestations or SBOMs
+const cosignArtifactAnnotation = "imgpkg.carvel.dev/cosign-artifact"
+
 type CopyOptions struct {
-	ImageFlags      ImageFlags
-	BundleFlags     BundleFlags
-	LockInputFlags  LockInputFlags
-	LockOutputFlags LockOutputFlags
-	TarFlags        TarFlags
-	RegistryFlags   RegistryFlags
-	SignatureFlags  SignatureFlags
-
-	RepoDst                 string
+	ui ui.UI
+
+	ImageFlags       ImageFlags
+	BundleFlags      BundleFlags
+	LockInputFlags   LockInputFlags
+	LockOutputFlags  LockOutputFlags
+	TarFlags         TarFlags
+	RegistryFlags    RegistryFlags
+	SignatureFlags   SignatureFlags
+	VerifyFlags      VerifyFlags
+	PolicyFlags      PolicyFlags
+	ImageFilterFlags ImageFilterFlags
+
+	RepoDsts                []string
+	RegistryPrefixDst       string
+	StripSourceHost         bool
 	Concurrency             int
 	IncludeNonDistributable bool
+	Recompress              string
+	LockOutputFormat        string
+	Platforms               []string
+	Force                   bool
+	DryRun                  bool
+	ReportOutput            string
+	DstTagStrategy          string
+	DstTagTemplate          string
+	MountFromRepos          []string
+	Checkpoint              string
+	ContinueOnError         bool
+	Referrers               bool
 }
 
-func NewCopyOptions() *CopyOptions {
-	return &CopyOptions{}
+func NewCopyOptions(ui ui.UI) *CopyOptions {
+	return &CopyOptions{ui: ui}
 }
 
 func NewCopyCmd(o *CopyOptions) *cobra.Command {
@@ -52,8 +84,54 @@ func NewCopyCmd(o *CopyOptions) *cobra.Command {
     # Copy bundle dkalinin/app1-bundle to another registry (or repository)
     imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle
 
+    # Show images that would be copied without copying them (add --json for JSON output)
+    imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle --dry-run
+
+    # Copy bundle dkalinin/app1-bundle to multiple registries, fetching its images only once
+    # (lock output is written per destination, e.g. relocated-eu-registry-app1-bundle.yml)
+    imgpkg copy -b dkalinin/app1-bundle --to-repo eu-registry/app1-bundle --to-repo us-registry/app1-bundle --lock-output relocated.yml
+
+    # Copy bundle dkalinin/app1-bundle keeping source repositories (e.g. internal-registry/mirror/index.docker.io/dkalinin/app1-bundle)
+    imgpkg copy -b dkalinin/app1-bundle --to-registry-prefix internal-registry/mirror
+
     # Copy image dkalinin/app1-image to another registry (or repository)
-    imgpkg copy -i dkalinin/app1-image --to-repo internal-registry/app1-image`,
+    imgpkg copy -i dkalinin/app1-image --to-repo internal-registry/app1-image
+
+    # Copy images pinned by kbld lock file and write relocated digests as kbld overrides
+    imgpkg copy --lock kbld.lock.yml --to-repo internal-registry/app1 --lock-output relocated.kbld.lock.yml
+
+    # Copy bundle dkalinin/app1-bundle to another registry without images annotated as windows images
+    imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle --exclude-annotation os=windows
+
+    # Copy bundle dkalinin/app1-bundle to local tarball only including linux/amd64 images of multi-platform images
+    imgpkg copy -b dkalinin/app1-bundle --to-tar /Volumes/app1-bundle.tar --platform linux/amd64
+
+    # Copy bundle dkalinin/app1-bundle to another registry tagging images with their original tags and short digests
+    imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle --dst-tag-strategy template --dst-tag-template '{{if .Tag}}{{.Tag}}-{{end}}{{printf "%.12s" .Hex}}'
+
+    # Copy bundle dkalinin/app1-bundle recording progress, so that rerunning an interrupted copy skips images already copied
+    imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle --checkpoint app1-bundle-copy.json
+
+    # Copy every image of bundle dkalinin/app1-bundle that can be copied, reporting the ones that failed at the end
+    imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle --continue-on-error --lock-output relocated.yml
+
+    # Copy image dkalinin/app1-image with its cosign signatures, attestations and SBOMs (and signatures of these)
+    imgpkg copy -i dkalinin/app1-image --to-repo internal-registry/app1-image --cosign-signatures --cosign-attestations --cosign-sboms
+
+    # Copy bundle dkalinin/app1-bundle only if it and its images are signed with the cosign key
+    imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle --verify-key cosign.pub
+
+    # Copy bundle dkalinin/app1-bundle only if its images satisfy the policy (e.g. come from allowed registries)
+    imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle --policy policy.yml
+
+    # Copy image dkalinin/app1-image with manifests referring to it (e.g. notation signatures, SBOMs attached by oras)
+    imgpkg copy -i dkalinin/app1-image --to-repo internal-registry/app1-image --referrers
+
+    # Copy bundle dkalinin/app1-bundle from a tarball mounting layers already present in other repositories of the registry
+    imgpkg copy --tar /Volumes/app1-bundle.tar --to-repo internal-registry/app1-bundle --mount-from internal-registry/app0-bundle,internal-registry/base-images
+
+    # Copy bundle dkalinin/app1-bundle to another registry converting gzip layers to zstd
+    imgpkg copy -b dkalinin/app1-bundle --to-repo internal-registry/app1-bundle --recompress zstd`,
 	}
 
 	o.ImageFlags.SetCopy(cmd)
@@ -63,10 +141,42 @@ func NewCopyCmd(o *CopyOptions) *cobra.Command {
 	o.TarFlags.Set(cmd)
 	o.RegistryFlags.Set(cmd)
 	o.SignatureFlags.Set(cmd)
-	cmd.Flags().StringVar(&o.RepoDst, "to-repo", "", "Location to upload assets")
+	o.VerifyFlags.Set(cmd)
+	o.PolicyFlags.Set(cmd)
+	o.ImageFilterFlags.Set(cmd)
+	cmd.Flags().StringArrayVar(&o.RepoDsts, "to-repo", nil, "Location to upload assets (can be specified multiple times)")
+	cmd.Flags().StringVar(&o.RegistryPrefixDst, "to-registry-prefix", "",
+		"Location under which every image is uploaded to a repository matching its source (<prefix>/<source-host>/<source-repo>)")
+	cmd.Flags().BoolVar(&o.StripSourceHost, "strip-source-host", false,
+		"Do not include source registry hosts in repositories created under --to-registry-prefix")
 	cmd.Flags().IntVar(&o.Concurrency, "concurrency", 5, "Concurrency")
 	cmd.Flags().BoolVar(&o.IncludeNonDistributable, "include-non-distributable-layers", false,
 		"Include non-distributable layers when copying an image/bundle")
+	cmd.Flags().StringVar(&o.LockOutputFormat, "lock-output-format", "",
+		fmt.Sprintf("Format of images lock output (%s, %s) (default %s if --lock is a kbld lock file, otherwise %s)", imgpkgLockFormat, kbldLockFormat, kbldLockFormat, imgpkgLockFormat))
+	cmd.Flags().StringVar(&o.Recompress, "recompress", "",
+		fmt.Sprintf("Convert gzip layers to another compression while copying to a repository (%s); changes image digests", ctlimg.ZstdCompression))
+	cmd.Flags().StringVar(&o.ReportOutput, "report-output", "",
+		"Location to write a JSON report of copied images (sources, destinations, tags and sizes; duration is reported for the whole copy only)")
+	cmd.Flags().BoolVar(&o.DryRun, "dry-run", false,
+		"Print images, sizes and destinations that would be copied without copying anything")
+	cmd.Flags().BoolVar(&o.Force, "force", false,
+		"Copy images to a repository even when they are already present in it")
+	cmd.Flags().StringSliceVar(&o.Platforms, "platform", nil,
+		"Only copy images for given platform from multi-platform images (format: os/arch[/variant]) (can be specified multiple times); changes image index digests")
+	cmd.Flags().StringVar(&o.DstTagStrategy, "dst-tag-strategy", ctlimgset.ImgpkgDigestTagStrategy,
+		fmt.Sprintf("Tags used to upload images to a repository (%s: sha256-<hex>.imgpkg, %s: digest only, %s: source tag when present, %s: --dst-tag-template)",
+			ctlimgset.ImgpkgDigestTagStrategy, ctlimgset.NoneTagStrategy, ctlimgset.OriginalTagStrategy, ctlimgset.TemplateTagStrategy))
+	cmd.Flags().StringVar(&o.DstTagTemplate, "dst-tag-template", "",
+		"Go template of tags used with --dst-tag-strategy template (available: .Repo, .Digest, .Algorithm, .Hex, .Tag)")
+	cmd.Flags().StringVar(&o.Checkpoint, "checkpoint", "",
+		"Location of a file recording copied images and written tarball layers; rerunning with the same file resumes an interrupted copy")
+	cmd.Flags().StringSliceVar(&o.MountFromRepos, "mount-from", nil,
+		"Repositories in the destination registry to mount blobs from instead of uploading them (format: repo1,repo2) (can be specified multiple times)")
+	cmd.Flags().BoolVar(&o.ContinueOnError, "continue-on-error", false,
+		"Copy every image that can be copied, then report images that failed (lock output is marked incomplete)")
+	cmd.Flags().BoolVar(&o.Referrers, "referrers", false,
+		"Copy manifests referring to copied images via their subject field (OCI referrers), including referrers of referrers")
 	return cmd
 }
 
@@ -75,7 +185,67 @@ func (c *CopyOptions) Run() error {
 		return fmt.Errorf("Expected either --lock, --bundle (-b), --image (-i), or --tar as a source")
 	}
 	if !c.hasOneDst() {
-		return fmt.Errorf("Expected either --to-tar or --to-repo")
+		return fmt.Errorf("Expected either --to-tar, --to-repo or --to-registry-prefix")
+	}
+	if c.Recompress != "" && !c.isRegistryDst() {
+		return fmt.Errorf("Expected --recompress to be used with --to-repo or --to-registry-prefix")
+	}
+	if c.Force && !c.isRegistryDst() {
+		return fmt.Errorf("Expected --force to be used with --to-repo or --to-registry-prefix")
+	}
+	if c.hasDstTagStrategy() && !c.isRegistryDst() {
+		return fmt.Errorf("Expected --dst-tag-strategy and --dst-tag-template to be used with --to-repo or --to-registry-prefix")
+	}
+	if len(c.MountFromRepos) > 0 && !c.isRegistryDst() {
+		return fmt.Errorf("Expected --mount-from to be used with --to-repo or --to-registry-prefix")
+	}
+	if c.ContinueOnError && !c.isRegistryDst() {
+		return fmt.Errorf("Expected --continue-on-error to be used with --to-repo or --to-registry-prefix")
+	}
+	if c.StripSourceHost && !c.isRegistryPrefixDst() {
+		return fmt.Errorf("Expected --strip-source-host to be used with --to-registry-prefix")
+	}
+	if c.DryRun && c.isTarSrc() {
+		return fmt.Errorf("Expected --dry-run to be used when copying from a registry")
+	}
+	if c.DryRun && c.LockOutputFlags.LockFilePath != "" {
+		return fmt.Errorf("Expected --lock-output to not be used with --dry-run since nothing is copied")
+	}
+	if c.DryRun && c.ReportOutput != "" {
+		return fmt.Errorf("Expected --report-output to not be used with --dry-run since nothing is copied")
+	}
+	if c.DryRun && c.Checkpoint != "" {
+		return fmt.Errorf("Expected --checkpoint to not be used with --dry-run since nothing is copied")
+	}
+	if c.DryRun && c.ContinueOnError {
+		return fmt.Errorf("Expected --continue-on-error to not be used with --dry-run since nothing is copied")
+	}
+	if len(c.Platforms) > 0 && c.isTarSrc() {
+		return fmt.Errorf("Expected --platform to be used when copying from a registry")
+	}
+	if err := c.VerifyFlags.Validate(); err != nil {
+		return err
+	}
+	if c.VerifyFlags.IsSet() && c.isTarSrc() {
+		return fmt.Errorf("Expected --verify-key to be used when copying from a registry")
+	}
+	if err := c.PolicyFlags.Validate(); err != nil {
+		return err
+	}
+	if c.PolicyFlags.IsSet() && c.isTarSrc() {
+		return fmt.Errorf("Expected --policy to be used when copying from a registry")
+	}
+	if c.Referrers && c.isTarSrc() {
+		return fmt.Errorf("Expected --referrers to be used when copying from a registry (referrers found while creating the tarball are copied from it)")
+	}
+	if c.Referrers && (c.Recompress != "" || len(c.Platforms) > 0) {
+		return fmt.Errorf("Expected --referrers to not be used with --recompress or --platform since referrers would refer to original image digests")
+	}
+	if c.ImageFilterFlags.IsSet() && (c.isTarSrc() || c.ImageFlags.Image != "") {
+		return fmt.Errorf("Expected --include-annotation, --exclude-annotation and --exclude-image to be used when copying a bundle or lock file from a registry")
+	}
+	if c.LockOutputFormat != "" && c.LockOutputFormat != imgpkgLockFormat && c.LockOutputFormat != kbldLockFormat {
+		return fmt.Errorf("Unknown --lock-output-format '%s' (known: %s, %s)", c.LockOutputFormat, imgpkgLockFormat, kbldLockFormat)
 	}
 
 	registryOpts := c.RegistryFlags.AsRegistryOpts()
@@ -90,8 +260,16 @@ func (c *CopyOptions) Run() error {
 	prefixedLogger := logger.NewPrefixedWriter("copy | ")
 	levelLogger := logger.NewLevelLogger(util.LogWarn, prefixedLogger)
 
-	imagesUploaderLogger := logger.NewProgressBar(levelLogger, "done uploading images", "Error uploading images")
-	regWithProgress := registry.NewRegistryWithProgress(reg, imagesUploaderLogger)
+	regWithProgress := registry.NewRegistryWithProgress(reg, func() util.ProgressLogger {
+		return logger.NewProgressBar(levelLogger, "done uploading images", "Error uploading images")
+	})
+
+	tagStrategy, err := c.newDestinationTagStrategy()
+	if err != nil {
+		return err
+	}
+
+	report := newCopyReportBuilder(c.IncludeNonDistributable, tagStrategy, levelLogger)
 
 	switch {
 	case c.isTarSrc():
@@ -99,44 +277,151 @@ func (c *CopyOptions) Run() error {
 			return fmt.Errorf("Cannot use tar source (--tar) with tar destination (--to-tar)")
 		}
 
-		importRepo, err := regname.NewRepository(c.RepoDst)
+		imageSet, err := c.newImageSet(prefixedLogger)
 		if err != nil {
-			return fmt.Errorf("Building import repository ref: %s", err)
+			return err
 		}
-
-		imageSet := ctlimgset.NewImageSet(c.Concurrency, prefixedLogger)
 		tarImageSet := ctlimgset.NewTarImageSet(imageSet, c.Concurrency, prefixedLogger)
 
-		processedImages, err := tarImageSet.Import(c.TarFlags.TarSrc, importRepo, regWithProgress)
+		if c.isRegistryPrefixDst() {
+			registryPrefix, err := c.newRegistryPrefix()
+			if err != nil {
+				return err
+			}
+
+			processedImages, err := tarImageSet.ImportToRegistryPrefix(c.TarFlags.TarSrc, registryPrefix, regWithProgress)
+			if err != nil {
+				return err
+			}
+
+			// Images are not in bundle repositories, hence bundles need locations images to find them
+			if len(processedImages.Failures()) == 0 {
+				err = bundle.NoteTarImport(processedImages, reg, levelLogger)
+				if err != nil {
+					return err
+				}
+			} else {
+				levelLogger.Warnf("locations of bundle images were not recorded since some images failed to be copied\n")
+			}
+
+			informUserToUseTheNonDistributableFlagWithDescriptors(levelLogger, c.IncludeNonDistributable, processedImagesMediaType(processedImages))
+
+			err = c.writeReportOutput(report, func() error {
+				err := report.AddTarBundles(c.TarFlags.TarSrc)
+				if err != nil {
+					return err
+				}
+				return report.AddProcessedImages(processedImages, reg)
+			})
+			if err != nil {
+				return err
+			}
+
+			err = c.writeLockOutput(processedImages, reg, c.LockOutputFlags.LockFilePath)
+			if err != nil {
+				return err
+			}
+
+			return c.reportFailures([]*ctlimgset.ProcessedImages{processedImages})
+		}
+
+		// Tarball is read for each destination since it is available locally
+		var allProcessedImages []*ctlimgset.ProcessedImages
+		for _, repoDst := range c.RepoDsts {
+			importRepo, err := regname.NewRepository(repoDst)
+			if err != nil {
+				return fmt.Errorf("Building import repository ref: %s", err)
+			}
+
+			processedImages, err := tarImageSet.Import(c.TarFlags.TarSrc, importRepo, regWithProgress)
+			if err != nil {
+				return err
+			}
+			allProcessedImages = append(allProcessedImages, processedImages)
+		}
+
+		informUserToUseTheNonDistributableFlagWithDescriptors(levelLogger, c.IncludeNonDistributable, processedImagesMediaType(allProcessedImages[0]))
+
+		err = c.writeReportOutput(report, func() error {
+			err := report.AddTarBundles(c.TarFlags.TarSrc)
+			if err != nil {
+				return err
+			}
+			return c.addProcessedImagesToReport(report, allProcessedImages, reg)
+		})
+		if err != nil {
+			return err
+		}
+
+		err = c.writeLockOutputs(allProcessedImages, reg)
 		if err != nil {
 			return err
 		}
 
-		informUserToUseTheNonDistributableFlagWithDescriptors(levelLogger, c.IncludeNonDistributable, processedImagesMediaType(processedImages))
-		return c.writeLockOutput(processedImages, reg)
+		return c.reportFailures(allProcessedImages)
 
 	case c.isRepoSrc():
-		imageSet := ctlimgset.NewImageSet(c.Concurrency, prefixedLogger)
+		imageSet, err := c.newImageSet(prefixedLogger)
+		if err != nil {
+			return err
+		}
 
 		var signatureRetriever SignatureRetriever
-		if c.SignatureFlags.CopyCosignSignatures {
-			signatureRetriever = signature.NewSignatures(signature.NewCosign(reg), c.Concurrency)
+		if artifacts := c.SignatureFlags.Artifacts(); len(artifacts) > 0 {
+			signatureRetriever = signature.NewSignatures(signature.NewCosign(reg), c.Concurrency).WithArtifacts(artifacts...)
 		} else {
 			signatureRetriever = signature.NewNoop()
 		}
 
+		var referrersRetriever SignatureRetriever
+		if c.Referrers {
+			referrersRetriever = referrers.NewReferrers(reg, c.Concurrency, levelLogger)
+		} else {
+			referrersRetriever = signature.NewNoop()
+		}
+
+		verifier, err := c.VerifyFlags.Verifier(reg, c.Concurrency)
+		if err != nil {
+			return err
+		}
+
+		var policyEvaluator PolicyEvaluator
+		if c.PolicyFlags.IsSet() {
+			policyEvaluator, err = c.PolicyFlags.Evaluator(reg, c.Concurrency)
+			if err != nil {
+				return err
+			}
+		}
+
+		imageFilter, err := c.ImageFilterFlags.ImageFilter()
+		if err != nil {
+			return err
+		}
+
 		repoSrc := CopyRepoSrc{
 			logger:                  levelLogger,
 			ImageFlags:              c.ImageFlags,
 			BundleFlags:             c.BundleFlags,
 			LockInputFlags:          c.LockInputFlags,
 			IncludeNonDistributable: c.IncludeNonDistributable,
+			ImageFilter:             imageFilter,
+			VerifyScope:             c.VerifyFlags.Scope,
+			PolicyMode:              c.PolicyFlags.Mode,
+			PolicyReportPath:        c.PolicyFlags.ReportPath,
 
 			registry:           regWithProgress,
 			imageSet:           imageSet,
 			tarImageSet:        ctlimgset.NewTarImageSet(imageSet, c.Concurrency, prefixedLogger),
 			Concurrency:        c.Concurrency,
 			signatureRetriever: signatureRetriever,
+			referrersRetriever: referrersRetriever,
+			verifier:           verifier,
+			policyEvaluator:    policyEvaluator,
+			reportBuilder:      report,
+		}
+
+		if c.DryRun {
+			return c.printPlan(repoSrc)
 		}
 
 		switch {
@@ -145,24 +430,232 @@ func (c *CopyOptions) Run() error {
 				return fmt.Errorf("cannot output lock file with tar destination")
 			}
 
-			return repoSrc.CopyToTar(c.TarFlags.TarDst)
+			err := repoSrc.CopyToTar(c.TarFlags.TarDst)
+			if err != nil {
+				return err
+			}
+
+			return c.writeReportOutput(report, func() error { return report.AddTarImages(c.TarFlags.TarDst) })
+
+		case c.isRegistryPrefixDst():
+			registryPrefix, err := c.newRegistryPrefix()
+			if err != nil {
+				return err
+			}
+
+			processedImages, err := repoSrc.CopyToRegistryPrefix(registryPrefix)
+			if err != nil {
+				return err
+			}
+
+			err = c.writeReportOutput(report, func() error { return report.AddProcessedImages(processedImages, reg) })
+			if err != nil {
+				return err
+			}
+
+			err = c.writeLockOutput(processedImages, reg, c.LockOutputFlags.LockFilePath)
+			if err != nil {
+				return err
+			}
+
+			return c.reportFailures([]*ctlimgset.ProcessedImages{processedImages})
 
 		case c.isRepoDst():
-			processedImages, err := repoSrc.CopyToRepo(c.RepoDst)
+			allProcessedImages, err := repoSrc.CopyToRepos(c.RepoDsts)
+			if err != nil {
+				return err
+			}
+
+			err = c.writeReportOutput(report, func() error { return c.addProcessedImagesToReport(report, allProcessedImages, reg) })
+			if err != nil {
+				return err
+			}
+
+			err = c.writeLockOutputs(allProcessedImages, reg)
 			if err != nil {
 				return err
 			}
-			return c.writeLockOutput(processedImages, reg)
+
+			return c.reportFailures(allProcessedImages)
 		}
 	}
 	panic("Unreachable")
 }
 
-func (c *CopyOptions) writeLockOutput(processedImages *ctlimgset.ProcessedImages, registry registry.Registry) error {
-	if c.LockOutputFlags.LockFilePath == "" {
+func (c *CopyOptions) writeReportOutput(report *copyReportBuilder, addImages func() error) error {
+	if c.ReportOutput == "" {
+		return nil
+	}
+
+	err := addImages()
+	if err != nil {
+		return fmt.Errorf("Building copy report: %s", err)
+	}
+
+	return report.Build().WriteToPath(c.ReportOutput)
+}
+
+func (c *CopyOptions) addProcessedImagesToReport(report *copyReportBuilder,
+	allProcessedImages []*ctlimgset.ProcessedImages, registry registry.Registry) error {
+
+	for _, processedImages := range allProcessedImages {
+		err := report.AddProcessedImages(processedImages, registry)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *CopyOptions) printPlan(repoSrc CopyRepoSrc) error {
+	var plans []CopyPlan
+
+	switch {
+	case c.isTarDst():
+		plan, err := repoSrc.PlanCopyToTar(c.TarFlags.TarDst)
+		if err != nil {
+			return err
+		}
+		plans = append(plans, plan)
+
+	case c.isRegistryPrefixDst():
+		registryPrefix, err := c.newRegistryPrefix()
+		if err != nil {
+			return err
+		}
+		plan, err := repoSrc.PlanCopyToRegistryPrefix(registryPrefix)
+		if err != nil {
+			return err
+		}
+		plans = append(plans, plan)
+
+	default:
+		for _, repoDst := range c.RepoDsts {
+			plan, err := repoSrc.PlanCopyToRepo(repoDst)
+			if err != nil {
+				return err
+			}
+			plans = append(plans, plan)
+		}
+	}
+
+	for _, plan := range plans {
+		plan.Print(c.ui)
+	}
+
+	if c.Recompress != "" {
+		c.ui.PrintLinef("Layers will be recompressed with %s, hence destination digests will differ from the ones shown", c.Recompress)
+	}
+
+	return nil
+}
+
+func (c *CopyOptions) newImageSet(logger ctlimgset.Logger) (ctlimgset.ImageSet, error) {
+	platformFilter, err := imagedesc.NewPlatformFilter(c.Platforms)
+	if err != nil {
+		return ctlimgset.ImageSet{}, err
+	}
+
+	tagStrategy, err := c.newDestinationTagStrategy()
+	if err != nil {
+		return ctlimgset.ImageSet{}, err
+	}
+
+	mountFromRepos, err := c.mountFromRepos()
+	if err != nil {
+		return ctlimgset.ImageSet{}, err
+	}
+
+	imageSet := ctlimgset.NewImageSet(c.Concurrency, logger)
+
+	if c.Recompress != "" {
+		compression, err := ctlimg.NewCompression(c.Recompress)
+		if err != nil {
+			return ctlimgset.ImageSet{}, err
+		}
+		if compression == ctlimg.GzipCompression {
+			return ctlimgset.ImageSet{}, fmt.Errorf("Expected --recompress to be %s since layers are only recompressed from %s", ctlimg.ZstdCompression, ctlimg.GzipCompression)
+		}
+		imageSet = ctlimgset.NewImageSetWithRecompression(c.Concurrency, logger, compression)
+	}
+
+	imageSet = imageSet.WithPlatformFilter(platformFilter).WithForce(c.Force).
+		WithDestinationTagStrategy(tagStrategy).WithMountFromRepos(mountFromRepos).
+		WithContinueOnError(c.ContinueOnError)
+
+	if c.Checkpoint != "" {
+		checkpoint, err := ctlimgset.NewCheckpoint(c.Checkpoint)
+		if err != nil {
+			return ctlimgset.ImageSet{}, err
+		}
+		imageSet = imageSet.WithCheckpoint(checkpoint)
+	}
+
+	return imageSet, nil
+}
+
+func (c *CopyOptions) mountFromRepos() ([]regname.Repository, error) {
+	var repos []regname.Repository
+	for _, repo := range c.MountFromRepos {
+		mountFromRepo, err := regname.NewRepository(repo)
+		if err != nil {
+			return nil, fmt.Errorf("Parsing --mount-from: %s", err)
+		}
+		repos = append(repos, mountFromRepo)
+	}
+	return repos, nil
+}
+
+func (c *CopyOptions) hasDstTagStrategy() bool {
+	return (c.DstTagStrategy != "" && c.DstTagStrategy != ctlimgset.ImgpkgDigestTagStrategy) || c.DstTagTemplate != ""
+}
+
+func (c *CopyOptions) newDestinationTagStrategy() (ctlimgset.DestinationTagStrategy, error) {
+	tagStrategy, err := ctlimgset.NewDestinationTagStrategy(c.DstTagStrategy, c.DstTagTemplate)
+	if err != nil {
+		return ctlimgset.DestinationTagStrategy{}, fmt.Errorf("Parsing --dst-tag-strategy: %s", err)
+	}
+	return tagStrategy, nil
+}
+
+// writeLockOutputs writes a lock output file for each destination repository
+func (c *CopyOptions) writeLockOutputs(allProcessedImages []*ctlimgset.ProcessedImages, registry registry.Registry) error {
+	if len(allProcessedImages) == 1 {
+		return c.writeLockOutput(allProcessedImages[0], registry, c.LockOutputFlags.LockFilePath)
+	}
+
+	for i, processedImages := range allProcessedImages {
+		err := c.writeLockOutput(processedImages, registry, lockOutputPathForRepo(c.LockOutputFlags.LockFilePath, c.RepoDsts[i]))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lockOutputPathForRepo includes the repository in the lock output file name
+// (e.g. relocated.yml becomes relocated-registry.io-app.yml for registry.io/app)
+func lockOutputPathForRepo(path string, repo string) string {
+	if path == "" {
+		return ""
+	}
+	ext := filepath.Ext(path)
+	repoName := strings.NewReplacer("/", "-", ":", "-").Replace(repo)
+	return strings.TrimSuffix(path, ext) + "-" + repoName + ext
+}
+
+func (c *CopyOptions) writeLockOutput(processedImages *ctlimgset.ProcessedImages, registry registry.Registry, path string) error {
+	if path == "" {
 		return nil
 	}
 
+	for _, failedImage := range processedImages.Failures() {
+		if _, ok := failedImage.Labels[rootBundleLabelKey]; ok {
+			c.ui.ErrorLinef("Warning: Skipped writing lock output '%s' since bundle %s failed to be copied", path, failedImage.UnprocessedImageRef.DigestRef)
+			return nil
+		}
+	}
+
 	processedImageRootBundle := c.findProcessedImageRootBundle(processedImages)
 
 	if processedImageRootBundle != nil {
@@ -175,8 +668,11 @@ func (c *CopyOptions) writeLockOutput(processedImages *ctlimgset.ProcessedImages
 		if !ok {
 			panic(fmt.Errorf("Internal inconsistency: '%s' should be a bundle but it is not", processedImageRootBundle.DigestRef))
 		}
+		if c.isKbldLockOutput() {
+			return fmt.Errorf("Expected --lock-output-format to be %s when copying a bundle, since kbld lock files only describe images", imgpkgLockFormat)
+		}
 
-		return c.writeBundleLockOutput(foundBundle)
+		return c.writeBundleLockOutput(foundBundle, processedImages, path)
 	}
 
 	// if the tarball was created with an older version (prior to assign a label to the root bundle) and it contains a bundle
@@ -186,7 +682,7 @@ func (c *CopyOptions) writeLockOutput(processedImages *ctlimgset.ProcessedImages
 		return err
 	}
 
-	return c.writeImagesLockOutput(processedImages)
+	return c.writeImagesLockOutput(processedImages, path)
 }
 
 func (c *CopyOptions) findProcessedImageRootBundle(processedImages *ctlimgset.ProcessedImages) *ctlimgset.ProcessedImage {
@@ -230,13 +726,32 @@ func (c *CopyOptions) isRepoSrc() bool {
 	return c.ImageFlags.Image != "" || c.BundleFlags.Bundle != "" || c.LockInputFlags.LockFilePath != ""
 }
 
-func (c *CopyOptions) isTarDst() bool  { return c.TarFlags.TarDst != "" }
-func (c *CopyOptions) isRepoDst() bool { return c.RepoDst != "" }
+func (c *CopyOptions) isTarDst() bool            { return c.TarFlags.TarDst != "" }
+func (c *CopyOptions) isRepoDst() bool           { return len(c.RepoDsts) > 0 }
+func (c *CopyOptions) isRegistryPrefixDst() bool { return c.RegistryPrefixDst != "" }
+
+// isRegistryDst returns true when images are copied to one or more repositories
+func (c *CopyOptions) isRegistryDst() bool { return c.isRepoDst() || c.isRegistryPrefixDst() }
 
 func (c *CopyOptions) hasOneDst() bool {
-	repoSet := c.isRepoDst()
-	tarSet := c.isTarDst()
-	return (repoSet || tarSet) && !(repoSet && tarSet)
+	var seen bool
+	for _, isSet := range []bool{c.isRepoDst(), c.isTarDst(), c.isRegistryPrefixDst()} {
+		if isSet {
+			if seen {
+				return false
+			}
+			seen = true
+		}
+	}
+	return seen
+}
+
+func (c *CopyOptions) newRegistryPrefix() (ctlimgset.RegistryPrefix, error) {
+	registryPrefix, err := ctlimgset.NewRegistryPrefix(c.RegistryPrefixDst, c.StripSourceHost)
+	if err != nil {
+		return ctlimgset.RegistryPrefix{}, fmt.Errorf("Parsing --to-registry-prefix: %s", err)
+	}
+	return registryPrefix, nil
 }
 
 func (c *CopyOptions) hasOneSrc() bool {
@@ -253,7 +768,7 @@ func (c *CopyOptions) hasOneSrc() bool {
 	return seen
 }
 
-func (c *CopyOptions) writeImagesLockOutput(processedImages *ctlimgset.ProcessedImages) error {
+func (c *CopyOptions) writeImagesLockOutput(processedImages *ctlimgset.ProcessedImages, path string) error {
 	imagesLock := lockconfig.ImagesLock{
 		LockVersion: lockconfig.LockVersion{
 			APIVersion: lockconfig.ImagesLockAPIVersion,
@@ -262,30 +777,189 @@ func (c *CopyOptions) writeImagesLockOutput(processedImages *ctlimgset.Processed
 	}
 
 	if c.LockInputFlags.LockFilePath != "" {
-		var err error
-		imagesLock, err = lockconfig.NewImagesLockFromPath(c.LockInputFlags.LockFilePath)
+		_, inputImagesLock, err := lockconfig.NewLockFromPath(c.LockInputFlags.LockFilePath)
 		if err != nil {
 			return err
 		}
+		if inputImagesLock == nil {
+			return fmt.Errorf("Expected '%s' to be an images lock file", c.LockInputFlags.LockFilePath)
+		}
+		imagesLock = *inputImagesLock
+		imageFilter, err := c.ImageFilterFlags.ImageFilter()
+		if err != nil {
+			return err
+		}
+
+		failedImages := map[string]ctlimgset.FailedImage{}
+		for _, failedImage := range processedImages.Failures() {
+			failedImages[failedImage.Key()] = failedImage
+		}
+
 		for i, image := range imagesLock.Images {
 			img, found := processedImages.FindByURL(ctlimgset.UnprocessedImageRef{DigestRef: image.Image})
 			if !found {
+				if !imageFilter.Includes(image) {
+					// Images excluded from the copy keep their original location
+					continue
+				}
+				if failedImage, failed := failedImages[ctlimgset.UnprocessedImageRef{DigestRef: image.Image}.Key()]; failed {
+					imagesLock.Images[i] = withCopyFailedAnnotation(image, failedImage)
+					continue
+				}
 				return fmt.Errorf("Expected image '%s' to have been copied but was not", image.Image)
 			}
 			imagesLock.Images[i].Image = img.DigestRef
 		}
+
+		// Cosign artifacts are not part of the lock input, yet they are relocated alongside its images
+		if !c.isKbldLockOutput() {
+			for _, img := range processedImages.All() {
+				if imageRef, found := cosignArtifactImageRef(img); found {
+					imagesLock.Images = append(imagesLock.Images, imageRef)
+				}
+			}
+		}
 	} else {
 		for _, img := range processedImages.All() {
-			imagesLock.Images = append(imagesLock.Images, lockconfig.ImageRef{
-				Image: img.DigestRef,
-			})
+			imageRef, found := cosignArtifactImageRef(img)
+			if !found {
+				imageRef = lockconfig.ImageRef{Image: img.DigestRef}
+			}
+			imagesLock.Images = append(imagesLock.Images, imageRef)
 		}
+		for _, failedImage := range processedImages.Failures() {
+			imagesLock.Images = append(imagesLock.Images, withCopyFailedAnnotation(
+				lockconfig.ImageRef{Image: failedImage.UnprocessedImageRef.DigestRef}, failedImage))
+		}
+	}
+
+	if c.isKbldLockOutput() {
+		return writeLockToPath(lockconfig.NewKbldLockFromImagesLock(imagesLock), processedImages, path)
 	}
 
-	return imagesLock.WriteToPath(c.LockOutputFlags.LockFilePath)
+	return writeLockToPath(imagesLock, processedImages, path)
+}
+
+func cosignArtifactImageRef(img ctlimgset.ProcessedImage) (lockconfig.ImageRef, bool) {
+	artifact, found := signature.ArtifactFromLabels(img.Labels)
+	if !found {
+		return lockconfig.ImageRef{}, false
+	}
+	return lockconfig.ImageRef{
+		Image:       img.DigestRef,
+		Annotations: map[string]string{cosignArtifactAnnotation: artifact.Name},
+	}, true
+}
+
+func withCopyFailedAnnotation(image lockconfig.ImageRef, failedImage ctlimgset.FailedImage) lockconfig.ImageRef {
+	image = image.DeepCopy()
+	image.Annotations[copyFailedAnnotation] = describeCopyFailure(failedImage)
+	return image
+}
+
+type lockOutput interface {
+	AsBytes() ([]byte, error)
+	WriteToPath(path string) error
+}
+
+// writeLockToPath marks lock output as incomplete with a comment listing images
+// that failed to be copied and bundles missing some of their images
+func writeLockToPath(lock lockOutput, processedImages *ctlimgset.ProcessedImages, path string) error {
+	failedImages := processedImages.Failures()
+	if len(failedImages) == 0 {
+		return lock.WriteToPath(path)
+	}
+
+	bs, err := lock.AsBytes()
+	if err != nil {
+		return err
+	}
+
+	header := fmt.Sprintf("# Incomplete: %d images failed to be copied\n", len(failedImages))
+	for _, failedImage := range failedImages {
+		header += fmt.Sprintf("# - %s (%s)\n", failedImage.UnprocessedImageRef.DigestRef, describeCopyFailure(failedImage))
+	}
+	for _, img := range processedImages.All() {
+		if len(img.MissingImages) > 0 {
+			header += fmt.Sprintf("# Incomplete bundle: %s (missing %d images)\n", img.DigestRef, len(img.MissingImages))
+		}
+	}
+
+	err = ioutil.WriteFile(path, append([]byte(header), bs...), 0600)
+	if err != nil {
+		return fmt.Errorf("Writing lock output: %s", err)
+	}
+
+	return nil
+}
+
+// reportFailures prints images that failed to be copied when copy continued on errors
+// and returns an error so that copy exits with a non-zero status
+func (c *CopyOptions) reportFailures(allProcessedImages []*ctlimgset.ProcessedImages) error {
+	var failedCount int
+
+	for i, processedImages := range allProcessedImages {
+		failedImages := processedImages.Failures()
+		if len(failedImages) == 0 {
+			continue
+		}
+		failedCount += len(failedImages)
+
+		destination := ""
+		if len(allProcessedImages) > 1 {
+			destination = fmt.Sprintf(" to %s", c.RepoDsts[i])
+		}
+
+		c.ui.PrintLinef("")
+		c.ui.PrintLinef("Failed to copy %d images%s:", len(failedImages), destination)
+		for _, failedImage := range failedImages {
+			c.ui.PrintLinef("  %s (%s): %s", failedImage.UnprocessedImageRef.DigestRef, describeCopyFailure(failedImage), failedImage.Err)
+		}
+
+		for _, img := range processedImages.All() {
+			if len(img.MissingImages) == 0 {
+				continue
+			}
+			c.ui.PrintLinef("Incomplete bundle %s (copied to %s) is missing images:", img.UnprocessedImageRef.DigestRef, img.DigestRef)
+			for _, missingImage := range img.MissingImages {
+				c.ui.PrintLinef("  %s", missingImage)
+			}
+		}
+	}
+
+	if failedCount > 0 {
+		return fmt.Errorf("Copying %d images failed", failedCount)
+	}
+	return nil
+}
+
+// describeCopyFailure includes status code and error codes returned by the registry when available
+func describeCopyFailure(failedImage ctlimgset.FailedImage) string {
+	statusCode := failedImage.StatusCode()
+	if statusCode == 0 {
+		return "no registry response"
+	}
+
+	desc := fmt.Sprintf("status %d", statusCode)
+	if codes := failedImage.ErrorCodes(); len(codes) > 0 {
+		desc += ", " + strings.Join(codes, ", ")
+	}
+	return desc
+}
+
+// isKbldLockOutput defaults to the format of the lock input so that kbld lock files stay kbld lock files
+func (c *CopyOptions) isKbldLockOutput() bool {
+	if c.LockOutputFormat != "" {
+		return c.LockOutputFormat == kbldLockFormat
+	}
+	if c.LockInputFlags.LockFilePath == "" {
+		return false
+	}
+	_, err := lockconfig.NewKbldLockFromPath(c.LockInputFlags.LockFilePath)
+	return err == nil
 }
 
-func (c *CopyOptions) writeBundleLockOutput(bundle *bundle.Bundle) error {
+func (c *CopyOptions) writeBundleLockOutput(bundle *bundle.Bundle, processedImages *ctlimgset.ProcessedImages, path string) error {
 	bundleLock := lockconfig.BundleLock{
 		LockVersion: lockconfig.LockVersion{
 			APIVersion: lockconfig.BundleLockAPIVersion,
@@ -297,7 +971,7 @@ func (c *CopyOptions) writeBundleLockOutput(bundle *bundle.Bundle) error {
 		},
 	}
 
-	return bundleLock.WriteToPath(c.LockOutputFlags.LockFilePath)
+	return writeLockToPath(bundleLock, processedImages, path)
 }
 
 func processedImagesMediaType(processedImages *ctlimgset.ProcessedImages) []string {
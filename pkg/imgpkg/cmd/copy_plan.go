@@ -0,0 +1,457 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	uitable "github.com/cppforlife/go-cli-ui/ui/table"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	ctlbundle "github.com/k14s/imgpkg/pkg/imgpkg/bundle"
+	"github.com/k14s/imgpkg/pkg/imgpkg/imagedesc"
+	ctlimgset "github.com/k14s/imgpkg/pkg/imgpkg/imageset"
+	"github.com/k14s/imgpkg/pkg/imgpkg/signature"
+	"github.com/k14s/imgpkg/pkg/imgpkg/util"
+)
+
+const (
+	copyPlanImageType      = "image"
+	copyPlanImageIndexType = "image index"
+	copyPlanBundleType     = "bundle"
+	copyPlanReferrerType   = "referrer"
+)
+
+// CopyPlan describes what a copy would write without copying anything
+type CopyPlan struct {
+	Images          []CopyPlanImage
+	LocationsImages []string
+
+	// Bytes is the size of unique manifests, configs and layers that would be copied
+	Bytes int64
+	// NonDistributableBytes is the size of unique non-distributable layers
+	NonDistributableBytes   int64
+	NonDistributableLayers  int
+	IncludeNonDistributable bool
+}
+
+type CopyPlanImage struct {
+	Source      string
+	Type        string
+	Destination string
+	Tags        []string
+
+	Bytes                  int64
+	NonDistributableLayers int
+	// AlreadyPresent is true when the image is skipped since it is present in the destination repository
+	AlreadyPresent bool
+}
+
+// PlanCopyToRepo collects descriptors of images that CopyToRepo would copy without fetching layers contents
+func (c CopyRepoSrc) PlanCopyToRepo(repo string) (CopyPlan, error) {
+	importRepo, err := regname.NewRepository(repo)
+	if err != nil {
+		return CopyPlan{}, fmt.Errorf("Building import repository ref: %s", err)
+	}
+
+	return c.planToRegistry(func(string) (regname.Repository, error) { return importRepo, nil })
+}
+
+// PlanCopyToRegistryPrefix collects descriptors of images that CopyToRegistryPrefix would copy without fetching layers contents
+func (c CopyRepoSrc) PlanCopyToRegistryPrefix(prefix ctlimgset.RegistryPrefix) (CopyPlan, error) {
+	return c.planToRegistry(prefix.ImportRepo)
+}
+
+func (c CopyRepoSrc) planToRegistry(importRepoFor func(string) (regname.Repository, error)) (CopyPlan, error) {
+	plan, err := c.plan(func(source string, digest regv1.Hash, tag string) (string, []string, error) {
+		importRepo, err := importRepoFor(source)
+		if err != nil {
+			return "", nil, err
+		}
+		tags, err := c.imageSet.DestinationTags(importRepo, digest, tag)
+		if err != nil {
+			return "", nil, err
+		}
+		return importRepo.Name() + "@" + digest.String(), tags, nil
+	}, func(source string, bundleDigest regv1.Hash) (string, error) {
+		importRepo, err := importRepoFor(source)
+		if err != nil {
+			return "", err
+		}
+		bundleRef, err := regname.NewDigest(importRepo.Name() + "@" + bundleDigest.String())
+		if err != nil {
+			return "", err
+		}
+		locationsRef, err := ctlbundle.NewLocations(c.logger).LocationsRef(bundleRef)
+		if err != nil {
+			return "", err
+		}
+		return locationsRef.Name(), nil
+	}, func(images []CopyPlanImage) (map[string]struct{}, error) {
+		return c.existingImages(images, importRepoFor)
+	})
+	if err != nil {
+		return CopyPlan{}, err
+	}
+
+	err = checkDistinctTags(plan)
+	if err != nil {
+		return CopyPlan{}, err
+	}
+	return plan, nil
+}
+
+// existingImages runs the same check as the copy to find images that are skipped
+// since they are already present in their destination repository
+func (c CopyRepoSrc) existingImages(images []CopyPlanImage,
+	importRepoFor func(string) (regname.Repository, error)) (map[string]struct{}, error) {
+
+	importRepos := map[string]regname.Repository{}
+	imagesByRepo := map[string]*ctlimgset.UnprocessedImageRefs{}
+
+	for _, img := range images {
+		importRepo, err := importRepoFor(img.Source)
+		if err != nil {
+			return nil, err
+		}
+		if _, found := imagesByRepo[importRepo.Name()]; !found {
+			importRepos[importRepo.Name()] = importRepo
+			imagesByRepo[importRepo.Name()] = ctlimgset.NewUnprocessedImageRefs()
+		}
+		imagesByRepo[importRepo.Name()].Add(ctlimgset.UnprocessedImageRef{DigestRef: img.Source})
+	}
+
+	existing := map[string]struct{}{}
+	for repoName, repoImages := range imagesByRepo {
+		existingImages, err := c.imageSet.ExistingImages(repoImages, importRepos[repoName], c.registry)
+		if err != nil {
+			return nil, err
+		}
+		for _, img := range existingImages {
+			existing[img.DigestRef] = struct{}{}
+		}
+	}
+	return existing, nil
+}
+
+// checkDistinctTags fails the plan when different images would be written with the same tag, as the copy would
+func checkDistinctTags(plan CopyPlan) error {
+	images := append([]CopyPlanImage{}, plan.Images...)
+	sort.SliceStable(images, func(i, j int) bool { return images[i].Source < images[j].Source })
+
+	tags := ctlimgset.NewDestinationTags()
+	for _, img := range images {
+		destRef, err := regname.NewDigest(img.Destination)
+		if err != nil {
+			return err
+		}
+		digest, err := regv1.NewHash(destRef.DigestStr())
+		if err != nil {
+			return err
+		}
+
+		err = tags.Claim(destRef.Context(), img.Tags, digest, img.Source)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PlanCopyToTar collects descriptors of images that CopyToTar would write without fetching layers contents
+func (c CopyRepoSrc) PlanCopyToTar(dstPath string) (CopyPlan, error) {
+	return c.plan(func(_ string, _ regv1.Hash, tag string) (string, []string, error) {
+		var tags []string
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+		return dstPath, tags, nil
+	}, nil, nil)
+}
+
+func (c CopyRepoSrc) plan(destination func(string, regv1.Hash, string) (string, []string, error),
+	locationsImage func(string, regv1.Hash) (string, error),
+	existingImages func([]CopyPlanImage) (map[string]struct{}, error)) (CopyPlan, error) {
+
+	images, err := c.getSourceImages()
+	if err != nil {
+		return CopyPlan{}, err
+	}
+
+	err = c.verifySourceImages(images.imageRefs, images.bundles)
+	if err != nil {
+		return CopyPlan{}, err
+	}
+
+	attachedImages, err := c.fetchAttachedImages(images.imageRefs)
+	if err != nil {
+		return CopyPlan{}, err
+	}
+
+	// Signatures, attestations and SBOMs are described by their kind of artifact
+	artifacts := map[string]signature.Artifact{}
+	for _, img := range attachedImages.All() {
+		if artifact, found := signature.ArtifactFromLabels(img.Labels); found {
+			artifacts[img.DigestRef] = artifact
+		}
+		images.imageRefs.Add(img)
+	}
+
+	err = c.evaluatePolicy(images.imageRefs, images.bundles, images.annotations)
+	if err != nil {
+		return CopyPlan{}, err
+	}
+
+	bundleRefs := map[string]struct{}{}
+	for _, bundle := range images.bundles {
+		bundleRefs[bundle.DigestRef()] = struct{}{}
+	}
+
+	ids, err := c.imageSet.Export(images.imageRefs, c.registry)
+	if err != nil {
+		return CopyPlan{}, err
+	}
+
+	plan := CopyPlan{IncludeNonDistributable: c.IncludeNonDistributable}
+	descs := ids.Descriptors()
+
+	for _, desc := range descs {
+		var planImage CopyPlanImage
+		var digestStr, tag string
+		var labels map[string]string
+
+		switch {
+		case desc.Image != nil:
+			planImage = CopyPlanImage{Source: desc.Image.Refs[0], Type: copyPlanImageType}
+			digestStr, tag, labels = desc.Image.Manifest.Digest, desc.Image.Tag, desc.Image.Labels
+
+		case desc.ImageIndex != nil:
+			planImage = CopyPlanImage{Source: desc.ImageIndex.Refs[0], Type: copyPlanImageIndexType}
+			digestStr, tag, labels = desc.ImageIndex.Digest, desc.ImageIndex.Tag, desc.ImageIndex.Labels
+
+		default:
+			panic("Unknown descriptor")
+		}
+
+		digest, err := regv1.NewHash(digestStr)
+		if err != nil {
+			return CopyPlan{}, err
+		}
+
+		_, isRootBundle := labels[rootBundleLabelKey]
+		_, isBundle := bundleRefs[planImage.Source]
+		artifact, isArtifact := artifacts[planImage.Source]
+		_, isReferrer := labels[ctlimgset.ReferrerLabelKey]
+
+		switch {
+		case isRootBundle || isBundle:
+			planImage.Type = copyPlanBundleType
+			if locationsImage != nil {
+				locationsRef, err := locationsImage(planImage.Source, digest)
+				if err != nil {
+					return CopyPlan{}, err
+				}
+				plan.LocationsImages = append(plan.LocationsImages, locationsRef)
+			}
+		case isArtifact:
+			planImage.Type = artifact.Name
+		case isReferrer:
+			planImage.Type = copyPlanReferrerType
+		}
+
+		planImage.Destination, planImage.Tags, err = destination(planImage.Source, digest, tag)
+		if err != nil {
+			return CopyPlan{}, err
+		}
+		plan.Images = append(plan.Images, planImage)
+	}
+
+	if existingImages != nil {
+		existing, err := existingImages(plan.Images)
+		if err != nil {
+			return CopyPlan{}, err
+		}
+		for idx := range plan.Images {
+			_, plan.Images[idx].AlreadyPresent = existing[plan.Images[idx].Source]
+		}
+	}
+
+	// Blobs of skipped images are not part of the total, unless they are shared with copied images
+	sizes := &blobSizes{seen: map[string]struct{}{}, includeNonDistributable: c.IncludeNonDistributable}
+	skippedSizes := &blobSizes{seen: map[string]struct{}{}, includeNonDistributable: c.IncludeNonDistributable}
+
+	for _, alreadyPresent := range []bool{false, true} {
+		for idx, desc := range descs {
+			planImage := &plan.Images[idx]
+			if planImage.AlreadyPresent != alreadyPresent {
+				continue
+			}
+
+			imageSizes := sizes
+			if alreadyPresent {
+				imageSizes = skippedSizes
+			}
+
+			switch {
+			case desc.Image != nil:
+				planImage.Bytes, planImage.NonDistributableLayers = imageSizes.image(*desc.Image)
+			case desc.ImageIndex != nil:
+				planImage.Bytes, planImage.NonDistributableLayers = imageSizes.imageIndex(*desc.ImageIndex)
+			}
+		}
+	}
+
+	plan.Bytes = sizes.bytes
+	plan.NonDistributableBytes = sizes.nonDistributableBytes
+	plan.NonDistributableLayers = sizes.nonDistributableLayers
+
+	return plan, nil
+}
+
+// blobSizes sums sizes of blobs while keeping track of blobs shared by multiple images
+type blobSizes struct {
+	seen                    map[string]struct{}
+	includeNonDistributable bool
+
+	bytes                  int64
+	nonDistributableBytes  int64
+	nonDistributableLayers int
+}
+
+func (s *blobSizes) imageIndex(desc imagedesc.ImageIndexDescriptor) (int64, int) {
+	size := s.blob(desc.Digest, int64(len(desc.Raw)))
+	var nonDistributableLayers int
+
+	for _, img := range desc.Images {
+		imgSize, imgNonDistributableLayers := s.image(img)
+		size += imgSize
+		nonDistributableLayers += imgNonDistributableLayers
+	}
+	for _, idx := range desc.Indexes {
+		idxSize, idxNonDistributableLayers := s.imageIndex(idx)
+		size += idxSize
+		nonDistributableLayers += idxNonDistributableLayers
+	}
+
+	return size, nonDistributableLayers
+}
+
+func (s *blobSizes) image(desc imagedesc.ImageDescriptor) (int64, int) {
+	size := s.blob(desc.Manifest.Digest, int64(len(desc.Manifest.Raw)))
+	size += s.blob(desc.Config.Digest, int64(len(desc.Config.Raw)))
+	var nonDistributableLayers int
+
+	for _, layer := range desc.Layers {
+		layerSize, distributable := s.layer(layer.Digest, layer.Size, types.MediaType(layer.MediaType))
+		size += layerSize
+		if !distributable {
+			nonDistributableLayers++
+		}
+	}
+
+	return size, nonDistributableLayers
+}
+
+// layer returns size of the layer that is copied, which is 0 for skipped non-distributable layers
+func (s *blobSizes) layer(digest string, size int64, mediaType types.MediaType) (int64, bool) {
+	if mediaType.IsDistributable() {
+		return s.blob(digest, size), true
+	}
+
+	if _, found := s.seen[digest]; !found {
+		s.nonDistributableLayers++
+		s.nonDistributableBytes += size
+	}
+	if s.includeNonDistributable {
+		return s.blob(digest, size), false
+	}
+	s.seen[digest] = struct{}{}
+	return 0, false
+}
+
+func (s *blobSizes) blob(digest string, size int64) int64 {
+	if _, found := s.seen[digest]; !found {
+		s.seen[digest] = struct{}{}
+		s.bytes += size
+	}
+	return size
+}
+
+func (p CopyPlan) Print(ui ui.UI) {
+	imagesTable := uitable.Table{
+		Title:   "Images",
+		Content: "images",
+
+		Header: []uitable.Header{
+			uitable.NewHeader("Source"),
+			uitable.NewHeader("Type"),
+			uitable.NewHeader("Destination"),
+			uitable.NewHeader("Tags"),
+			uitable.NewHeader("Size"),
+			uitable.NewHeader("Non-distributable layers"),
+			uitable.NewHeader("Action"),
+		},
+
+		SortBy: []uitable.ColumnSort{
+			{Column: 0, Asc: true},
+		},
+	}
+
+	for _, img := range p.Images {
+		action := "copy"
+		if img.AlreadyPresent {
+			action = "skip (already present)"
+		}
+
+		imagesTable.Rows = append(imagesTable.Rows, []uitable.Value{
+			uitable.NewValueString(img.Source),
+			uitable.NewValueString(img.Type),
+			uitable.NewValueString(img.Destination),
+			uitable.NewValueStrings(img.Tags),
+			uitable.NewValueString(util.FormatBytes(img.Bytes)),
+			uitable.NewValueInt(img.NonDistributableLayers),
+			uitable.NewValueString(action),
+		})
+	}
+
+	ui.PrintTable(imagesTable)
+
+	if len(p.LocationsImages) > 0 {
+		locationsTable := uitable.Table{
+			Title:   "Locations images",
+			Content: "locations images",
+
+			Header: []uitable.Header{
+				uitable.NewHeader("Destination"),
+			},
+
+			SortBy: []uitable.ColumnSort{
+				{Column: 0, Asc: true},
+			},
+		}
+
+		for _, locationsImage := range p.LocationsImages {
+			locationsTable.Rows = append(locationsTable.Rows, []uitable.Value{
+				uitable.NewValueString(locationsImage),
+			})
+		}
+
+		ui.PrintTable(locationsTable)
+	}
+
+	ui.PrintLinef("Total unique size: %s", util.FormatBytes(p.Bytes))
+
+	if p.NonDistributableLayers > 0 {
+		if p.IncludeNonDistributable {
+			ui.PrintLinef("Non-distributable layers: %d (%s), included in total",
+				p.NonDistributableLayers, util.FormatBytes(p.NonDistributableBytes))
+		} else {
+			ui.PrintLinef("Non-distributable layers: %d (%s), skipped (hint: use --include-non-distributable-layers to copy them)",
+				p.NonDistributableLayers, util.FormatBytes(p.NonDistributableBytes))
+		}
+	}
+}
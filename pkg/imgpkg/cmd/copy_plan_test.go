@@ -0,0 +1,132 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	goui "github.com/cppforlife/go-cli-ui/ui"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/k14s/imgpkg/pkg/imgpkg/lockconfig"
+	"github.com/k14s/imgpkg/test/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanCopyBundle(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	randomImage := fakeRegistry.WithRandomImage("library/image_with_config")
+	randomImageWithNonDistributableLayer := fakeRegistry.
+		WithRandomImage("library/image_with_non_dist_layer").WithNonDistributableLayer()
+
+	rootBundle := fakeRegistry.WithBundleFromPath("library/bundle", "test_assets/bundle_with_mult_images").
+		WithImageRefs([]lockconfig.ImageRef{
+			{Image: randomImage.RefDigest},
+			{Image: randomImageWithNonDistributableLayer.RefDigest},
+		})
+
+	subject := subject
+	subject.BundleFlags = BundleFlags{rootBundle.RefDigest}
+	subject.registry = fakeRegistry.Build()
+
+	destRepo := fakeRegistry.ReferenceOnTestServer("library/bundle-copy")
+
+	t.Run("describes images written to the repository", func(t *testing.T) {
+		plan, err := subject.PlanCopyToRepo(destRepo)
+		require.NoError(t, err)
+
+		planImages := map[string]CopyPlanImage{}
+		for _, img := range plan.Images {
+			planImages[img.Source] = img
+		}
+		require.Len(t, planImages, 3)
+
+		bundleImage := planImages[rootBundle.RefDigest]
+		assert.Equal(t, copyPlanBundleType, bundleImage.Type)
+		assert.Equal(t, destRepo+"@"+rootBundle.Digest, bundleImage.Destination)
+		assert.Contains(t, bundleImage.Tags, "sha256-"+rootBundle.Digest[len("sha256:"):]+".imgpkg")
+
+		assert.Equal(t, copyPlanImageType, planImages[randomImage.RefDigest].Type)
+		assert.Equal(t, 0, planImages[randomImage.RefDigest].NonDistributableLayers)
+		assert.Equal(t, 1, planImages[randomImageWithNonDistributableLayer.RefDigest].NonDistributableLayers)
+
+		assert.Equal(t, []string{destRepo + ":sha256-" + rootBundle.Digest[len("sha256:"):] + ".image-locations.imgpkg"}, plan.LocationsImages)
+
+		assert.Equal(t, 1, plan.NonDistributableLayers)
+		assert.NotZero(t, plan.NonDistributableBytes)
+
+		// Images do not share any blobs and non-distributable layers are not copied
+		var imagesBytes int64
+		for _, img := range plan.Images {
+			imagesBytes += img.Bytes
+		}
+		assert.Equal(t, imagesBytes, plan.Bytes)
+	})
+
+	t.Run("does not write anything to the repository", func(t *testing.T) {
+		bundleDigest, err := name.NewDigest(destRepo + "@" + rootBundle.Digest)
+		require.NoError(t, err)
+
+		_, err = subject.registry.Digest(bundleDigest)
+		require.Error(t, err)
+	})
+
+	t.Run("describes images written to the tarball", func(t *testing.T) {
+		tarPath := filepath.Join(os.TempDir(), "plan-bundle.tar")
+
+		plan, err := subject.PlanCopyToTar(tarPath)
+		require.NoError(t, err)
+
+		require.Len(t, plan.Images, 3)
+		for _, img := range plan.Images {
+			assert.Equal(t, tarPath, img.Destination)
+		}
+		assert.Empty(t, plan.LocationsImages)
+
+		_, err = os.Stat(tarPath)
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("prints plan as a table", func(t *testing.T) {
+		plan, err := subject.PlanCopyToRepo(destRepo)
+		require.NoError(t, err)
+
+		output := &bytes.Buffer{}
+		plan.Print(goui.NewWriterUI(output, output, nil))
+
+		assert.Contains(t, output.String(), "Images")
+		assert.Contains(t, output.String(), "Locations images")
+		assert.Contains(t, output.String(), "Total unique size:")
+		assert.Contains(t, output.String(), "Non-distributable layers: 1 (")
+	})
+
+	t.Run("describes images already present in the repository as skipped", func(t *testing.T) {
+		copyOpts := CopyOptions{
+			ImageFlags:  ImageFlags{Image: randomImage.RefDigest},
+			RepoDsts:    []string{destRepo},
+			Concurrency: 1,
+		}
+		require.NoError(t, copyOpts.Run())
+
+		plan, err := subject.PlanCopyToRepo(destRepo)
+		require.NoError(t, err)
+
+		var copiedBytes int64
+		for _, img := range plan.Images {
+			assert.Equal(t, img.Source == randomImage.RefDigest, img.AlreadyPresent, img.Source)
+			if !img.AlreadyPresent {
+				copiedBytes += img.Bytes
+			}
+		}
+		assert.Equal(t, copiedBytes, plan.Bytes)
+
+		output := &bytes.Buffer{}
+		plan.Print(goui.NewWriterUI(output, output, nil))
+		assert.Contains(t, output.String(), "skip (already present)")
+	})
+}
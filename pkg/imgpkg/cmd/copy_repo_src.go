@@ -5,6 +5,7 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
 	regname "github.com/google/go-containerregistry/pkg/name"
 	ctlbundle "github.com/k14s/imgpkg/pkg/imgpkg/bundle"
@@ -14,6 +15,7 @@ import (
 	"github.com/k14s/imgpkg/pkg/imgpkg/imagetar"
 	"github.com/k14s/imgpkg/pkg/imgpkg/lockconfig"
 	"github.com/k14s/imgpkg/pkg/imgpkg/plainimage"
+	"github.com/k14s/imgpkg/pkg/imgpkg/policy"
 	"github.com/k14s/imgpkg/pkg/imgpkg/util"
 )
 
@@ -26,30 +28,50 @@ type CopyRepoSrc struct {
 	BundleFlags             BundleFlags
 	LockInputFlags          LockInputFlags
 	IncludeNonDistributable bool
+	ImageFilter             ctlbundle.ImageFilter
+	VerifyScope             string
+	PolicyMode              string
+	PolicyReportPath        string
 	Concurrency             int
 	logger                  util.LoggerWithLevels
 	imageSet                ctlimgset.ImageSet
 	tarImageSet             ctlimgset.TarImageSet
 	registry                ctlimgset.ImagesReaderWriter
 	signatureRetriever      SignatureRetriever
+	referrersRetriever      SignatureRetriever
+	verifier                ImageVerifier
+	// policyEvaluator is nil when no policy was provided
+	policyEvaluator PolicyEvaluator
+	// reportBuilder records bundles found while collecting images for the copy report
+	reportBuilder *copyReportBuilder
 }
 
 func (c CopyRepoSrc) CopyToTar(dstPath string) error {
-	unprocessedImageRefs, _, err := c.getSourceImages()
+	images, err := c.getSourceImages()
 	if err != nil {
 		return err
 	}
 
-	signatures, err := c.signatureRetriever.Fetch(unprocessedImageRefs)
+	err = c.verifySourceImages(images.imageRefs, images.bundles)
 	if err != nil {
 		return err
 	}
 
-	for _, signature := range signatures.All() {
-		unprocessedImageRefs.Add(signature)
+	attachedImages, err := c.fetchAttachedImages(images.imageRefs)
+	if err != nil {
+		return err
 	}
 
-	ids, err := c.tarImageSet.Export(unprocessedImageRefs, dstPath, c.registry, imagetar.NewImageLayerWriterCheck(c.IncludeNonDistributable))
+	for _, img := range attachedImages.All() {
+		images.imageRefs.Add(img)
+	}
+
+	err = c.evaluatePolicy(images.imageRefs, images.bundles, images.annotations)
+	if err != nil {
+		return err
+	}
+
+	ids, err := c.tarImageSet.Export(images.imageRefs, dstPath, c.registry, imagetar.NewImageLayerWriterCheck(c.IncludeNonDistributable))
 	if err != nil {
 		return err
 	}
@@ -61,64 +83,280 @@ func (c CopyRepoSrc) CopyToTar(dstPath string) error {
 
 func (c CopyRepoSrc) CopyToRepo(repo string) (*ctlimgset.ProcessedImages, error) {
 	c.logger.Tracef("CopyToRepo(%s)\n", repo)
-	unprocessedImageRefs, bundles, err := c.getSourceImages()
+
+	importRepo, err := regname.NewRepository(repo)
+	if err != nil {
+		return nil, fmt.Errorf("Building import repository ref: %s", err)
+	}
+
+	processedImages, err := c.copyToRegistry(func(unprocessedImageRefs *ctlimgset.UnprocessedImageRefs) ([]*ctlimgset.ProcessedImages, []*imagedesc.ImageRefDescriptors, error) {
+		processedImages, ids, err := c.imageSet.Relocate(unprocessedImageRefs, importRepo, c.registry)
+		if err != nil {
+			return nil, nil, err
+		}
+		return []*ctlimgset.ProcessedImages{processedImages}, []*imagedesc.ImageRefDescriptors{ids}, nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	return processedImages[0], nil
+}
 
-	c.logger.Debugf("fetching signatures\n")
-	signatures, err := c.signatureRetriever.Fetch(unprocessedImageRefs)
+// CopyToRepos copies images to every repository while only fetching them once.
+// Returned processed images are in the same order as repositories.
+func (c CopyRepoSrc) CopyToRepos(repos []string) ([]*ctlimgset.ProcessedImages, error) {
+	c.logger.Tracef("CopyToRepos(%s)\n", strings.Join(repos, ", "))
+
+	var importRepos []regname.Repository
+	for _, repo := range repos {
+		importRepo, err := regname.NewRepository(repo)
+		if err != nil {
+			return nil, fmt.Errorf("Building import repository ref: %s", err)
+		}
+		importRepos = append(importRepos, importRepo)
+	}
+
+	return c.copyToRegistry(func(unprocessedImageRefs *ctlimgset.UnprocessedImageRefs) ([]*ctlimgset.ProcessedImages, []*imagedesc.ImageRefDescriptors, error) {
+		processedImages, ids, err := c.imageSet.RelocateToRepos(unprocessedImageRefs, importRepos, c.registry)
+		if err != nil {
+			return nil, nil, err
+		}
+		return processedImages, []*imagedesc.ImageRefDescriptors{ids}, nil
+	})
+}
+
+// CopyToRegistryPrefix copies every image to a repository under the prefix
+// that matches its source registry and repository
+func (c CopyRepoSrc) CopyToRegistryPrefix(prefix ctlimgset.RegistryPrefix) (*ctlimgset.ProcessedImages, error) {
+	c.logger.Tracef("CopyToRegistryPrefix(%s)\n", prefix)
+
+	processedImages, err := c.copyToRegistry(func(unprocessedImageRefs *ctlimgset.UnprocessedImageRefs) ([]*ctlimgset.ProcessedImages, []*imagedesc.ImageRefDescriptors, error) {
+		processedImages, allIds, err := c.imageSet.RelocateToRegistryPrefix(unprocessedImageRefs, prefix, c.registry)
+		if err != nil {
+			return nil, nil, err
+		}
+		return []*ctlimgset.ProcessedImages{processedImages}, allIds, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return processedImages[0], nil
+}
+
+func (c CopyRepoSrc) copyToRegistry(relocate func(*ctlimgset.UnprocessedImageRefs) ([]*ctlimgset.ProcessedImages, []*imagedesc.ImageRefDescriptors, error)) ([]*ctlimgset.ProcessedImages, error) {
+	images, err := c.getSourceImages()
 	if err != nil {
 		return nil, err
 	}
-	for _, signature := range signatures.All() {
-		unprocessedImageRefs.Add(signature)
+
+	err = c.verifySourceImages(images.imageRefs, images.bundles)
+	if err != nil {
+		return nil, err
 	}
 
-	importRepo, err := regname.NewRepository(repo)
+	attachedImages, err := c.fetchAttachedImages(images.imageRefs)
 	if err != nil {
-		return nil, fmt.Errorf("Building import repository ref: %s", err)
+		return nil, err
+	}
+	for _, img := range attachedImages.All() {
+		images.imageRefs.Add(img)
+	}
+
+	err = c.evaluatePolicy(images.imageRefs, images.bundles, images.annotations)
+	if err != nil {
+		return nil, err
 	}
 
 	c.logger.Debugf("copy the fetched images\n")
-	processedImages, ids, err := c.imageSet.Relocate(unprocessedImageRefs, importRepo, c.registry)
+	allProcessedImages, allIds, err := relocate(images.imageRefs)
 	if err != nil {
 		return nil, err
 	}
 
+	if c.reportBuilder != nil {
+		err = c.reportBuilder.AddBundles(images.bundles)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Each destination gets its own locations images
+	for _, processedImages := range allProcessedImages {
+		failedImages := processedImages.Failures()
+		incompleteBundles := c.incompleteBundles(images.bundles, failedImages)
+
+		for _, bundle := range images.bundles {
+			if failedToCopy(bundle.DigestRef(), failedImages) {
+				continue
+			}
+			if missingImages, incomplete := incompleteBundles[bundle.DigestRef()]; incomplete {
+				c.logger.Warnf("bundle %s is incomplete since %d of its images failed to be copied\n", bundle.DigestRef(), len(missingImages))
+				processedImages.MarkIncomplete(bundle.DigestRef(), missingImages)
+				continue
+			}
+			if err := bundle.NoteCopy(processedImages, images.excludedImages, c.registry, c.logger); err != nil {
+				return nil, fmt.Errorf("Creating copy information for bundle %s: %s", bundle.DigestRef(), err)
+			}
+		}
+	}
+
+	var mediaTypes []string
+	for _, ids := range allIds {
+		mediaTypes = append(mediaTypes, imageRefDescriptorsMediaTypes(ids)...)
+	}
+	informUserToUseTheNonDistributableFlagWithDescriptors(c.logger, c.IncludeNonDistributable, mediaTypes)
+
+	return allProcessedImages, nil
+}
+
+// verifySourceImages checks signatures of images to copy (or only of bundles, depending on verify scope)
+func (c CopyRepoSrc) verifySourceImages(unprocessedImageRefs *ctlimgset.UnprocessedImageRefs, bundles []*ctlbundle.Bundle) error {
+	var imageRefs []string
+
+	switch c.VerifyScope {
+	case verifyScopeBundles:
+		if len(bundles) == 0 {
+			return fmt.Errorf("Expected a bundle to be copied when verifying signatures of bundles only")
+		}
+		for _, bundle := range bundles {
+			imageRefs = append(imageRefs, bundle.DigestRef())
+		}
+	default:
+		for _, img := range unprocessedImageRefs.All() {
+			imageRefs = append(imageRefs, img.DigestRef)
+		}
+	}
+
+	c.logger.Debugf("verifying signatures of %d images\n", len(imageRefs))
+	return c.verifier.Verify(imageRefs)
+}
+
+// evaluatePolicy checks that images to copy (including attached images) satisfy the policy
+func (c CopyRepoSrc) evaluatePolicy(unprocessedImageRefs *ctlimgset.UnprocessedImageRefs,
+	bundles []*ctlbundle.Bundle, annotations map[string]map[string]string) error {
+
+	if c.policyEvaluator == nil {
+		return nil
+	}
+
+	bundleRefs := map[string]struct{}{}
 	for _, bundle := range bundles {
-		if err := bundle.NoteCopy(processedImages, c.registry, c.logger); err != nil {
-			return nil, fmt.Errorf("Creating copy information for bundle %s: %s", bundle.DigestRef(), err)
+		bundleRefs[bundle.DigestRef()] = struct{}{}
+	}
+
+	var images []policy.Image
+	for _, img := range unprocessedImageRefs.All() {
+		_, isBundle := bundleRefs[img.DigestRef]
+		images = append(images, policy.Image{DigestRef: img.DigestRef, Annotations: annotations[img.DigestRef], IsBundle: isBundle})
+	}
+
+	c.logger.Debugf("evaluating policy for %d images\n", len(images))
+	return enforcePolicy(c.policyEvaluator, c.PolicyMode, c.PolicyReportPath, images, c.logger.Warnf)
+}
+
+// fetchAttachedImages returns signatures of the images and their referrers,
+// including referrers of signatures
+func (c CopyRepoSrc) fetchAttachedImages(unprocessedImageRefs *ctlimgset.UnprocessedImageRefs) (*ctlimgset.UnprocessedImageRefs, error) {
+	c.logger.Debugf("fetching signatures\n")
+	attachedImages, err := c.signatureRetriever.Fetch(unprocessedImageRefs)
+	if err != nil {
+		return nil, err
+	}
+
+	subjects := ctlimgset.NewUnprocessedImageRefs()
+	for _, img := range unprocessedImageRefs.All() {
+		subjects.Add(img)
+	}
+	for _, img := range attachedImages.All() {
+		subjects.Add(img)
+	}
+
+	c.logger.Debugf("fetching referrers\n")
+	referrers, err := c.referrersRetriever.Fetch(subjects)
+	if err != nil {
+		return nil, err
+	}
+	for _, img := range referrers.All() {
+		attachedImages.Add(img)
+	}
+
+	return attachedImages, nil
+}
+
+// incompleteBundles returns images that failed to be copied for every bundle that references them.
+// Bundles that reference incomplete bundles (or bundles that failed to be copied) are incomplete as well.
+func (c CopyRepoSrc) incompleteBundles(bundles []*ctlbundle.Bundle, failedImages []ctlimgset.FailedImage) map[string][]string {
+	incompleteBundles := map[string][]string{}
+
+	var failedRefs []string
+	for _, failedImage := range failedImages {
+		failedRefs = append(failedRefs, failedImage.UnprocessedImageRef.DigestRef)
+	}
+
+	for found := true; found; {
+		found = false
+
+		for _, bundle := range bundles {
+			if _, incomplete := incompleteBundles[bundle.DigestRef()]; incomplete {
+				continue
+			}
+
+			var missingImages []string
+			for _, failedRef := range failedRefs {
+				if bundle.References(failedRef) {
+					missingImages = append(missingImages, failedRef)
+				}
+			}
+
+			if len(missingImages) > 0 {
+				incompleteBundles[bundle.DigestRef()] = missingImages
+				failedRefs = append(failedRefs, bundle.DigestRef())
+				found = true
+			}
 		}
 	}
-	informUserToUseTheNonDistributableFlagWithDescriptors(c.logger, c.IncludeNonDistributable, imageRefDescriptorsMediaTypes(ids))
 
-	return processedImages, nil
+	return incompleteBundles
 }
 
-func (c CopyRepoSrc) getSourceImages() (*ctlimgset.UnprocessedImageRefs, []*ctlbundle.Bundle, error) {
-	unprocessedImageRefs := ctlimgset.NewUnprocessedImageRefs()
+// sourceImages are images found in the copy source
+type sourceImages struct {
+	imageRefs *ctlimgset.UnprocessedImageRefs
+	// bundles are the bundles among images (root and nested bundles)
+	bundles []*ctlbundle.Bundle
+	// annotations are recorded by images locks for images (by digest reference)
+	annotations map[string]map[string]string
+	// excludedImages are images of bundles excluded by filters (by digest reference)
+	excludedImages map[string]struct{}
+}
+
+func newSourceImages() sourceImages {
+	return sourceImages{
+		imageRefs:      ctlimgset.NewUnprocessedImageRefs(),
+		annotations:    map[string]map[string]string{},
+		excludedImages: map[string]struct{}{},
+	}
+}
+
+func (c CopyRepoSrc) getSourceImages() (sourceImages, error) {
+	images := newSourceImages()
 
 	switch {
 	case c.LockInputFlags.LockFilePath != "":
 		bundleLock, imagesLock, err := lockconfig.NewLockFromPath(c.LockInputFlags.LockFilePath)
 		if err != nil {
-			return nil, nil, err
+			return sourceImages{}, err
 		}
 
 		switch {
 		case bundleLock != nil:
 			c.logger.Tracef("get images from BundleLock file\n")
-			_, bundles, imagesRef, err := c.getBundleImageRefs(bundleLock.Bundle.Image)
+			_, err := c.addBundleImages(bundleLock.Bundle.Image, &images)
 			if err != nil {
-				return nil, nil, err
-			}
-
-			for _, img := range imagesRef.ImageRefs() {
-				unprocessedImageRefs.Add(ctlimgset.UnprocessedImageRef{DigestRef: img.PrimaryLocation()})
+				return sourceImages{}, err
 			}
 
-			unprocessedImageRefs.Add(ctlimgset.UnprocessedImageRef{
+			images.imageRefs.Add(ctlimgset.UnprocessedImageRef{
 				DigestRef: bundleLock.Bundle.Image,
 				Tag:       bundleLock.Bundle.Tag,
 				Labels: map[string]string{
@@ -126,24 +364,30 @@ func (c CopyRepoSrc) getSourceImages() (*ctlimgset.UnprocessedImageRefs, []*ctlb
 				},
 			})
 
-			return unprocessedImageRefs, bundles, nil
+			return images, nil
 
 		case imagesLock != nil:
 			c.logger.Tracef("get images from ImagesLock file\n")
 			for _, img := range imagesLock.Images {
+				if !c.ImageFilter.Includes(img) {
+					c.logger.Logf("skipping image '%s' excluded by filters\n", img.Image)
+					continue
+				}
+
 				plainImg := plainimage.NewPlainImage(img.Image, c.registry)
 
 				ok, err := ctlbundle.NewBundleFromPlainImage(plainImg, c.registry).IsBundle()
 				if err != nil {
-					return nil, nil, err
+					return sourceImages{}, err
 				}
 				if ok {
-					return nil, nil, fmt.Errorf("Unable to copy bundles using an Images Lock file (hint: Create a bundle with these images)")
+					return sourceImages{}, fmt.Errorf("Unable to copy bundles using an Images Lock file (hint: Create a bundle with these images)")
 				}
 
-				unprocessedImageRefs.Add(ctlimgset.UnprocessedImageRef{DigestRef: plainImg.DigestRef()})
+				images.imageRefs.Add(ctlimgset.UnprocessedImageRef{DigestRef: plainImg.DigestRef()})
+				images.annotations[plainImg.DigestRef()] = img.Annotations
 			}
-			return unprocessedImageRefs, nil, nil
+			return images, nil
 
 		default:
 			panic("Unreachable")
@@ -155,27 +399,23 @@ func (c CopyRepoSrc) getSourceImages() (*ctlimgset.UnprocessedImageRefs, []*ctlb
 
 		ok, err := ctlbundle.NewBundleFromPlainImage(plainImg, c.registry).IsBundle()
 		if err != nil {
-			return nil, nil, err
+			return sourceImages{}, err
 		}
 		if ok {
-			return nil, nil, fmt.Errorf("Expected bundle flag when copying a bundle (hint: Use -b instead of -i for bundles)")
+			return sourceImages{}, fmt.Errorf("Expected bundle flag when copying a bundle (hint: Use -b instead of -i for bundles)")
 		}
 
-		unprocessedImageRefs.Add(ctlimgset.UnprocessedImageRef{DigestRef: plainImg.DigestRef(), Tag: plainImg.Tag()})
-		return unprocessedImageRefs, nil, nil
+		images.imageRefs.Add(ctlimgset.UnprocessedImageRef{DigestRef: plainImg.DigestRef(), Tag: plainImg.Tag()})
+		return images, nil
 
 	default:
 		c.logger.Tracef("copy bundle\n")
-		bundle, allBundles, imagesRef, err := c.getBundleImageRefs(c.BundleFlags.Bundle)
+		bundle, err := c.addBundleImages(c.BundleFlags.Bundle, &images)
 		if err != nil {
-			return nil, nil, err
-		}
-
-		for _, img := range imagesRef.ImageRefs() {
-			unprocessedImageRefs.Add(ctlimgset.UnprocessedImageRef{DigestRef: img.PrimaryLocation()})
+			return sourceImages{}, err
 		}
 
-		unprocessedImageRefs.Add(ctlimgset.UnprocessedImageRef{
+		images.imageRefs.Add(ctlimgset.UnprocessedImageRef{
 			DigestRef: bundle.DigestRef(),
 			Tag:       bundle.Tag(),
 			Labels: map[string]string{
@@ -183,25 +423,52 @@ func (c CopyRepoSrc) getSourceImages() (*ctlimgset.UnprocessedImageRefs, []*ctlb
 			}},
 		)
 
-		return unprocessedImageRefs, allBundles, nil
+		return images, nil
 	}
 }
 
-func (c CopyRepoSrc) getBundleImageRefs(bundleRef string) (*ctlbundle.Bundle, []*ctlbundle.Bundle, ctlbundle.ImageRefs, error) {
+// addBundleImages adds images of the bundle and of its nested bundles that are not excluded by filters.
+// Nested bundles excluded by filters are not copied, neither are images only they reference.
+func (c CopyRepoSrc) addBundleImages(bundleRef string, images *sourceImages) (*ctlbundle.Bundle, error) {
 	bundle := ctlbundle.NewBundle(bundleRef, c.registry)
 	isBundle, err := bundle.IsBundle()
 	if err != nil {
-		return nil, nil, ctlbundle.ImageRefs{}, err
+		return nil, err
 	}
 	if !isBundle {
-		return nil, nil, ctlbundle.ImageRefs{}, fmt.Errorf("Expected bundle image but found plain image (hint: Did you use -i instead of -b?)")
+		return nil, fmt.Errorf("Expected bundle image but found plain image (hint: Did you use -i instead of -b?)")
 	}
 
-	nestedBundles, imageRefs, err := bundle.AllImagesRefs(c.Concurrency, c.logger)
+	allBundles, imageRefs, err := bundle.AllImagesRefs(c.Concurrency, c.logger)
 	if err != nil {
-		return nil, nil, ctlbundle.ImageRefs{}, fmt.Errorf("Reading Images from Bundle: %s", err)
+		return nil, fmt.Errorf("Reading Images from Bundle: %s", err)
+	}
+
+	includedImages, includedBundles, excludedImages, err := c.ImageFilter.FilterBundleImages(bundle, allBundles, imageRefs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, img := range excludedImages {
+		c.logger.Logf("skipping image '%s' excluded by filters\n", img.Image)
+		images.excludedImages[img.PrimaryLocation()] = struct{}{}
+	}
+	for _, img := range includedImages {
+		images.imageRefs.Add(ctlimgset.UnprocessedImageRef{DigestRef: img.PrimaryLocation()})
+		images.annotations[img.PrimaryLocation()] = img.Annotations
+	}
+	images.bundles = includedBundles
+
+	return bundle, nil
+}
+
+func failedToCopy(digestRef string, failedImages []ctlimgset.FailedImage) bool {
+	for _, failedImage := range failedImages {
+		if failedImage.UnprocessedImageRef.DigestRef == digestRef {
+			return true
+		}
 	}
-	return bundle, nestedBundles, imageRefs, nil
+	return false
 }
 
 func imageRefDescriptorsMediaTypes(ids *imagedesc.ImageRefDescriptors) []string {
@@ -6,12 +6,18 @@ package cmd
 import (
 	"archive/tar"
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 
 	goui "github.com/cppforlife/go-cli-ui/ui"
@@ -25,10 +31,14 @@ import (
 	"github.com/k14s/imgpkg/pkg/imgpkg/imageset"
 	"github.com/k14s/imgpkg/pkg/imgpkg/imagetar"
 	"github.com/k14s/imgpkg/pkg/imgpkg/lockconfig"
+	"github.com/k14s/imgpkg/pkg/imgpkg/policy"
+	"github.com/k14s/imgpkg/pkg/imgpkg/referrers"
+	"github.com/k14s/imgpkg/pkg/imgpkg/signature"
 	"github.com/k14s/imgpkg/pkg/imgpkg/util"
 	"github.com/k14s/imgpkg/test/helpers"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/yaml"
 )
 
 var subject CopyRepoSrc
@@ -47,6 +57,8 @@ func TestMain(m *testing.M) {
 		tarImageSet:        imageset.NewTarImageSet(imageSet, 1, prefixedLogger),
 		Concurrency:        1,
 		signatureRetriever: &fakeSignatureRetriever{},
+		referrersRetriever: &fakeSignatureRetriever{},
+		verifier:           signature.NewNoop(),
 	}
 
 	os.Exit(m.Run())
@@ -957,3 +969,1787 @@ func downloadImagesLocation(t *testing.T, imgRef, location string) {
 	err = ctlimg.NewDirImage(filepath.Join(location), img, writerUI).AsDirectory()
 	require.NoError(t, err)
 }
+
+func TestToRepoBundleWithRecompression(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	randomImage := fakeRegistry.WithRandomImage("library/image_with_config")
+
+	nestedBundle := fakeRegistry.WithBundleFromPath("library/nested-bundle", "test_assets/bundle_with_mult_images").
+		WithImageRefs([]lockconfig.ImageRef{
+			{Image: randomImage.RefDigest},
+		})
+
+	rootBundle := fakeRegistry.WithBundleFromPath("library/bundle", "test_assets/bundle_with_mult_images").
+		WithImageRefs([]lockconfig.ImageRef{
+			{Image: nestedBundle.RefDigest},
+		})
+
+	logger := util.NewLogger(stdOut)
+	prefixedLogger := logger.NewPrefixedWriter("test | ")
+
+	subject := subject
+	subject.BundleFlags.Bundle = rootBundle.RefDigest
+	subject.imageSet = imageset.NewImageSetWithRecompression(1, prefixedLogger, ctlimg.ZstdCompression)
+	subject.registry = fakeRegistry.Build()
+
+	destRepo := fakeRegistry.ReferenceOnTestServer("library/bundle-copy")
+	processedImages, err := subject.CopyToRepo(destRepo)
+	require.NoError(t, err)
+	require.Len(t, processedImages.All(), 3)
+
+	relocatedRefs := map[string]string{}
+	for _, processedImage := range processedImages.All() {
+		relocatedRefs[processedImage.UnprocessedImageRef.DigestRef] = processedImage.DigestRef
+	}
+
+	t.Run("every layer is recompressed with zstd giving images new digests", func(t *testing.T) {
+		for origRef, relocatedRef := range relocatedRefs {
+			origDigest, err := name.NewDigest(origRef)
+			require.NoError(t, err)
+			newDigest, err := name.NewDigest(relocatedRef)
+			require.NoError(t, err)
+			assert.NotEqual(t, origDigest.DigestStr(), newDigest.DigestStr())
+
+			img, err := subject.registry.Image(newDigest)
+			require.NoError(t, err)
+			layers, err := img.Layers()
+			require.NoError(t, err)
+			for _, layer := range layers {
+				mediaType, err := layer.MediaType()
+				require.NoError(t, err)
+				assert.Equal(t, ctlimg.OCILayerZstd, mediaType)
+			}
+		}
+	})
+
+	t.Run("nested images are resolved to their new digests", func(t *testing.T) {
+		copiedBundle := bundle.NewBundle(relocatedRefs[rootBundle.RefDigest], subject.registry)
+		_, imageRefs, err := copiedBundle.AllImagesRefs(1, subject.logger)
+		require.NoError(t, err)
+
+		var locations []string
+		for _, imgRef := range imageRefs.ImageRefs() {
+			locations = append(locations, imgRef.PrimaryLocation())
+		}
+		assert.ElementsMatch(t, []string{
+			relocatedRefs[nestedBundle.RefDigest],
+			relocatedRefs[randomImage.RefDigest],
+		}, locations)
+	})
+
+	t.Run("bundle contents can be pulled", func(t *testing.T) {
+		outputDir, err := os.MkdirTemp("", "imgpkg-pull-recompressed")
+		require.NoError(t, err)
+		defer os.RemoveAll(outputDir)
+
+		copiedBundle := bundle.NewBundle(relocatedRefs[rootBundle.RefDigest], subject.registry)
+		require.NoError(t, copiedBundle.Pull(outputDir, goui.NewNoopUI(), true))
+
+		imagesLock, err := lockconfig.NewImagesLockFromPath(filepath.Join(outputDir, ".imgpkg", "images.yml"))
+		require.NoError(t, err)
+		require.Len(t, imagesLock.Images, 1)
+		assert.Equal(t, relocatedRefs[nestedBundle.RefDigest], imagesLock.Images[0].Image)
+	})
+}
+
+func TestToRepoImagesFromKbldLock(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	randomImage := fakeRegistry.WithRandomImage("library/app")
+	fakeRegistry.Build()
+
+	tempDir, err := os.MkdirTemp("", "imgpkg-kbld-lock")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+
+	kbldLockPath := filepath.Join(tempDir, "kbld.lock.yml")
+	require.NoError(t, ioutil.WriteFile(kbldLockPath, []byte(fmt.Sprintf(`---
+apiVersion: kbld.k14s.io/v1alpha1
+kind: Config
+minimumRequiredVersion: 0.30.0
+overrides:
+- image: app:v1
+  newImage: %s
+  preresolved: true
+searchRules:
+- keyMatcher:
+    name: sidecarImage
+`, randomImage.RefDigest)), 0600))
+
+	destRepo := fakeRegistry.ReferenceOnTestServer("library/app-copy")
+	relocatedImage := destRepo + "@" + randomImage.Digest
+
+	t.Run("writes relocated digests as kbld overrides", func(t *testing.T) {
+		lockOutputPath := filepath.Join(tempDir, "relocated.kbld.lock.yml")
+
+		copyOpts := CopyOptions{
+			LockInputFlags:  LockInputFlags{LockFilePath: kbldLockPath},
+			LockOutputFlags: LockOutputFlags{LockFilePath: lockOutputPath},
+			RepoDsts:        []string{destRepo},
+			Concurrency:     1,
+		}
+		require.NoError(t, copyOpts.Run())
+
+		kbldLock, err := lockconfig.NewKbldLockFromPath(lockOutputPath)
+		require.NoError(t, err)
+		assert.Equal(t, []lockconfig.KbldOverride{
+			{Image: "app:v1", NewImage: relocatedImage, Preresolved: true},
+		}, kbldLock.Overrides)
+	})
+
+	t.Run("writes images lock when requested", func(t *testing.T) {
+		lockOutputPath := filepath.Join(tempDir, "relocated.images.yml")
+
+		copyOpts := CopyOptions{
+			LockInputFlags:   LockInputFlags{LockFilePath: kbldLockPath},
+			LockOutputFlags:  LockOutputFlags{LockFilePath: lockOutputPath},
+			LockOutputFormat: "imgpkg",
+			RepoDsts:         []string{destRepo},
+			Concurrency:      1,
+		}
+		require.NoError(t, copyOpts.Run())
+
+		imagesLock, err := lockconfig.NewImagesLockFromPath(lockOutputPath)
+		require.NoError(t, err)
+		assert.Equal(t, []lockconfig.ImageRef{
+			{Image: relocatedImage, Annotations: map[string]string{lockconfig.KbldIDAnnotation: "app:v1"}},
+		}, imagesLock.Images)
+	})
+}
+
+func TestToRepoBundleWithImageFilters(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	linuxImage := fakeRegistry.WithRandomImage("library/linux-image")
+	windowsImage := fakeRegistry.WithRandomImage("library/windows-image")
+	gpuImage := fakeRegistry.WithRandomImage("library/gpu-image")
+
+	rootBundle := fakeRegistry.WithBundleFromPath("library/bundle", "test_assets/bundle_with_mult_images").
+		WithImageRefs([]lockconfig.ImageRef{
+			{Image: linuxImage.RefDigest, Annotations: map[string]string{"os": "linux"}},
+			{Image: windowsImage.RefDigest, Annotations: map[string]string{"os": "windows"}},
+			{Image: gpuImage.RefDigest, Annotations: map[string]string{"os": "linux"}},
+		})
+
+	imageFilter, err := bundle.NewImageFilter(nil, []string{"os=windows"}, []string{"gpu"})
+	require.NoError(t, err)
+
+	subject := subject
+	subject.BundleFlags.Bundle = rootBundle.RefDigest
+	subject.ImageFilter = imageFilter
+	subject.registry = fakeRegistry.Build()
+
+	destRepo := fakeRegistry.ReferenceOnTestServer("library/bundle-copy")
+	processedImages, err := subject.CopyToRepo(destRepo)
+	require.NoError(t, err)
+
+	t.Run("only copies images selected by filters", func(t *testing.T) {
+		var processedImageDigests []string
+		for _, processedImage := range processedImages.All() {
+			processedImageDigests = append(processedImageDigests, processedImage.DigestRef)
+		}
+		assert.ElementsMatch(t, []string{
+			destRepo + "@" + rootBundle.Digest,
+			destRepo + "@" + linuxImage.Digest,
+		}, processedImageDigests)
+	})
+
+	t.Run("records skipped images in the locations config", func(t *testing.T) {
+		bundleDigest, err := name.NewDigest(destRepo + "@" + rootBundle.Digest)
+		require.NoError(t, err)
+
+		cfg, err := bundle.NewLocations(subject.logger).Fetch(subject.registry, bundleDigest)
+		require.NoError(t, err)
+
+		assert.Equal(t, []bundle.ImageLocation{
+			{Image: linuxImage.RefDigest},
+			{Image: gpuImage.RefDigest, Skipped: true},
+			{Image: windowsImage.RefDigest, Skipped: true},
+		}, cfg.Images)
+	})
+
+	t.Run("pull references skipped images from their original location", func(t *testing.T) {
+		outputDir, err := os.MkdirTemp("", "imgpkg-pull-filtered")
+		require.NoError(t, err)
+		defer os.RemoveAll(outputDir)
+
+		output := &bytes.Buffer{}
+		copiedBundle := bundle.NewBundle(destRepo+"@"+rootBundle.Digest, subject.registry)
+		require.NoError(t, copiedBundle.Pull(outputDir, goui.NewWriterUI(output, output, nil), false))
+
+		imagesLock, err := lockconfig.NewImagesLockFromPath(filepath.Join(outputDir, ".imgpkg", "images.yml"))
+		require.NoError(t, err)
+
+		var images []string
+		for _, img := range imagesLock.Images {
+			images = append(images, img.Image)
+		}
+		assert.Equal(t, []string{destRepo + "@" + linuxImage.Digest, windowsImage.RefDigest, gpuImage.RefDigest}, images)
+
+		assert.Contains(t, output.String(), "Images not relocated with the bundle")
+		assert.Contains(t, output.String(), windowsImage.RefDigest)
+		assert.Contains(t, output.String(), gpuImage.RefDigest)
+	})
+}
+
+func TestToRepoBundleWithImageFiltersOnNestedBundles(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	linuxImage := fakeRegistry.WithRandomImage("library/linux-image")
+	windowsImage := fakeRegistry.WithRandomImage("library/windows-image")
+	gpuImage := fakeRegistry.WithRandomImage("library/gpu-image")
+
+	appBundle := fakeRegistry.WithBundleFromPath("library/app-bundle", "test_assets/bundle_with_mult_images").
+		WithImageRefs([]lockconfig.ImageRef{
+			{Image: linuxImage.RefDigest, Annotations: map[string]string{"os": "linux"}},
+			{Image: windowsImage.RefDigest, Annotations: map[string]string{"os": "windows"}},
+		})
+	gpuBundle := fakeRegistry.WithBundleFromPath("library/gpu-bundle", "test_assets/bundle_with_mult_images").
+		WithImageRefs([]lockconfig.ImageRef{
+			{Image: gpuImage.RefDigest},
+			{Image: linuxImage.RefDigest},
+		})
+	rootBundle := fakeRegistry.WithBundleFromPath("library/bundle", "test_assets/bundle_with_mult_images").
+		WithImageRefs([]lockconfig.ImageRef{
+			{Image: appBundle.RefDigest},
+			{Image: gpuBundle.RefDigest},
+		})
+
+	imageFilter, err := bundle.NewImageFilter(nil, []string{"os=windows"}, []string{"gpu-bundle"})
+	require.NoError(t, err)
+
+	subject := subject
+	subject.BundleFlags.Bundle = rootBundle.RefDigest
+	subject.ImageFilter = imageFilter
+	subject.registry = fakeRegistry.Build()
+
+	destRepo := fakeRegistry.ReferenceOnTestServer("library/bundle-copy")
+	processedImages, err := subject.CopyToRepo(destRepo)
+	require.NoError(t, err)
+
+	t.Run("does not copy excluded nested bundles nor images only they reference", func(t *testing.T) {
+		var processedImageDigests []string
+		for _, processedImage := range processedImages.All() {
+			processedImageDigests = append(processedImageDigests, processedImage.DigestRef)
+		}
+		assert.ElementsMatch(t, []string{
+			destRepo + "@" + rootBundle.Digest,
+			destRepo + "@" + appBundle.Digest,
+			destRepo + "@" + linuxImage.Digest,
+		}, processedImageDigests)
+	})
+
+	t.Run("records excluded images in the locations config of included bundles", func(t *testing.T) {
+		rootBundleDigest, err := name.NewDigest(destRepo + "@" + rootBundle.Digest)
+		require.NoError(t, err)
+
+		cfg, err := bundle.NewLocations(subject.logger).Fetch(subject.registry, rootBundleDigest)
+		require.NoError(t, err)
+
+		assert.ElementsMatch(t, []bundle.ImageLocation{
+			{Image: appBundle.RefDigest, IsBundle: true},
+			{Image: gpuBundle.RefDigest, IsBundle: true, Skipped: true},
+		}, cfg.Images)
+
+		appBundleDigest, err := name.NewDigest(destRepo + "@" + appBundle.Digest)
+		require.NoError(t, err)
+
+		cfg, err = bundle.NewLocations(subject.logger).Fetch(subject.registry, appBundleDigest)
+		require.NoError(t, err)
+
+		assert.ElementsMatch(t, []bundle.ImageLocation{
+			{Image: linuxImage.RefDigest},
+			{Image: windowsImage.RefDigest, Skipped: true},
+		}, cfg.Images)
+	})
+}
+
+func TestToRepoBundleWithPlatformFilter(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	multiPlatformImage := fakeRegistry.WithMultiPlatformImageIndex("library/multi-platform-image",
+		regv1.Platform{OS: "linux", Architecture: "amd64"},
+		regv1.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"},
+		regv1.Platform{OS: "windows", Architecture: "amd64"},
+	)
+
+	rootBundle := fakeRegistry.WithBundleFromPath("library/bundle", "test_assets/bundle_with_mult_images").
+		WithImageRefs([]lockconfig.ImageRef{
+			{Image: multiPlatformImage.RefDigest},
+		})
+
+	platformFilter, err := imagedesc.NewPlatformFilter([]string{"linux/amd64", "linux/arm64"})
+	require.NoError(t, err)
+
+	logger := util.NewLogger(stdOut)
+	prefixedLogger := logger.NewPrefixedWriter("test | ")
+
+	subject := subject
+	subject.BundleFlags.Bundle = rootBundle.RefDigest
+	subject.imageSet = imageset.NewImageSet(1, prefixedLogger).WithPlatformFilter(platformFilter)
+	subject.tarImageSet = imageset.NewTarImageSet(subject.imageSet, 1, prefixedLogger)
+	subject.registry = fakeRegistry.Build()
+
+	destRepo := fakeRegistry.ReferenceOnTestServer("library/bundle-copy")
+	processedImages, err := subject.CopyToRepo(destRepo)
+	require.NoError(t, err)
+
+	processedImage, found := processedImages.FindByURL(imageset.UnprocessedImageRef{DigestRef: multiPlatformImage.RefDigest})
+	require.True(t, found)
+
+	t.Run("image index only contains selected platforms and has a new digest", func(t *testing.T) {
+		relocatedDigest, err := name.NewDigest(processedImage.DigestRef)
+		require.NoError(t, err)
+		assert.NotEqual(t, multiPlatformImage.Digest, relocatedDigest.DigestStr())
+
+		index, err := subject.registry.Index(relocatedDigest)
+		require.NoError(t, err)
+		indexManifest, err := index.IndexManifest()
+		require.NoError(t, err)
+
+		var platforms []string
+		for _, manifest := range indexManifest.Manifests {
+			platforms = append(platforms, manifest.Platform.OS+"/"+manifest.Platform.Architecture)
+
+			_, err := subject.registry.Image(relocatedDigest.Context().Digest(manifest.Digest.String()))
+			require.NoError(t, err)
+		}
+		assert.Equal(t, []string{"linux/amd64", "linux/arm64"}, platforms)
+	})
+
+	t.Run("locations config maps original digest to the filtered image index", func(t *testing.T) {
+		bundleDigest, err := name.NewDigest(destRepo + "@" + rootBundle.Digest)
+		require.NoError(t, err)
+
+		cfg, err := bundle.NewLocations(subject.logger).Fetch(subject.registry, bundleDigest)
+		require.NoError(t, err)
+
+		relocatedDigest, err := name.NewDigest(processedImage.DigestRef)
+		require.NoError(t, err)
+		assert.Equal(t, []bundle.ImageLocation{
+			{Image: multiPlatformImage.RefDigest, RelocatedDigest: relocatedDigest.DigestStr()},
+		}, cfg.Images)
+	})
+
+	t.Run("tarball only contains selected platforms", func(t *testing.T) {
+		imageTarPath := filepath.Join(os.TempDir(), "bundle-platforms.tar")
+		defer os.Remove(imageTarPath)
+
+		require.NoError(t, subject.CopyToTar(imageTarPath))
+
+		imageOrIndexes, err := imagetar.NewTarReader(imageTarPath).Read()
+		require.NoError(t, err)
+
+		var foundIndex bool
+		for _, imageOrIndex := range imageOrIndexes {
+			if imageOrIndex.Index == nil {
+				continue
+			}
+			foundIndex = true
+			assert.Equal(t, multiPlatformImage.RefDigest, imageOrIndex.Ref())
+
+			indexManifest, err := (*imageOrIndex.Index).IndexManifest()
+			require.NoError(t, err)
+			assert.Len(t, indexManifest.Manifests, 2)
+		}
+		assert.True(t, foundIndex)
+	})
+
+	t.Run("fails when image index does not contain any selected platform", func(t *testing.T) {
+		platformFilter, err := imagedesc.NewPlatformFilter([]string{"linux/s390x"})
+		require.NoError(t, err)
+
+		subject := subject
+		subject.imageSet = imageset.NewImageSet(1, prefixedLogger).WithPlatformFilter(platformFilter)
+
+		_, err = subject.CopyToRepo(destRepo)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "to contain images for platforms linux/s390x")
+	})
+}
+
+func TestToRepoBundleSkipsImagesAlreadyInDestination(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	image1 := fakeRegistry.WithRandomImage("library/image1")
+	image2 := fakeRegistry.WithRandomImage("library/image2")
+
+	rootBundle := fakeRegistry.WithBundleFromPath("library/bundle", "test_assets/bundle_with_mult_images").
+		WithImageRefs([]lockconfig.ImageRef{
+			{Image: image1.RefDigest},
+			{Image: image2.RefDigest},
+		})
+
+	logger := util.NewLogger(stdOut)
+	prefixedLogger := logger.NewPrefixedWriter("test | ")
+
+	subject := subject
+	subject.imageSet = imageset.NewImageSet(1, prefixedLogger)
+	subject.registry = fakeRegistry.Build()
+
+	destRepo := fakeRegistry.ReferenceOnTestServer("library/bundle-copy")
+
+	subjectImage := subject
+	subjectImage.ImageFlags.Image = image1.RefDigest
+	_, err := subjectImage.CopyToRepo(destRepo)
+	require.NoError(t, err)
+
+	processedImagesDigests := func(processedImages *imageset.ProcessedImages) []string {
+		var digests []string
+		for _, processedImage := range processedImages.All() {
+			digests = append(digests, processedImage.DigestRef)
+		}
+		return digests
+	}
+
+	subject.BundleFlags.Bundle = rootBundle.RefDigest
+
+	t.Run("only copies images missing from the destination", func(t *testing.T) {
+		stdOut.Reset()
+
+		processedImages, err := subject.CopyToRepo(destRepo)
+		require.NoError(t, err)
+
+		assert.ElementsMatch(t, []string{
+			destRepo + "@" + rootBundle.Digest,
+			destRepo + "@" + image1.Digest,
+			destRepo + "@" + image2.Digest,
+		}, processedImagesDigests(processedImages))
+		assert.Contains(t, stdOut.String(), "exporting 2 images...")
+		assert.Contains(t, stdOut.String(), "copied 2 images")
+		assert.Contains(t, stdOut.String(), "skipped 1 images already present in "+destRepo)
+	})
+
+	t.Run("skips every image when run again", func(t *testing.T) {
+		stdOut.Reset()
+
+		processedImages, err := subject.CopyToRepo(destRepo)
+		require.NoError(t, err)
+
+		assert.Len(t, processedImages.All(), 3)
+		assert.Contains(t, stdOut.String(), "copied 0 images (0 B), skipped 3 images already present in "+destRepo)
+
+		copiedBundle := bundle.NewBundle(destRepo+"@"+rootBundle.Digest, subject.registry)
+		_, imageRefs, err := copiedBundle.AllImagesRefs(1, subject.logger)
+		require.NoError(t, err)
+
+		var locations []string
+		for _, imgRef := range imageRefs.ImageRefs() {
+			locations = append(locations, imgRef.PrimaryLocation())
+		}
+		assert.ElementsMatch(t, []string{destRepo + "@" + image1.Digest, destRepo + "@" + image2.Digest}, locations)
+	})
+
+	t.Run("copies every image when forced", func(t *testing.T) {
+		stdOut.Reset()
+
+		subject := subject
+		subject.imageSet = imageset.NewImageSet(1, prefixedLogger).WithForce(true)
+
+		processedImages, err := subject.CopyToRepo(destRepo)
+		require.NoError(t, err)
+
+		assert.Len(t, processedImages.All(), 3)
+		assert.Contains(t, stdOut.String(), "exporting 3 images...")
+		assert.NotContains(t, stdOut.String(), "already present")
+	})
+}
+
+func TestToRepoBundleWithDestinationTagStrategy(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	image1 := fakeRegistry.WithRandomImage("library/image1")
+
+	rootBundle := fakeRegistry.WithBundleFromPath("library/bundle", "test_assets/bundle_with_mult_images").
+		WithImageRefs([]lockconfig.ImageRef{
+			{Image: image1.RefDigest},
+		})
+
+	logger := util.NewLogger(stdOut)
+	prefixedLogger := logger.NewPrefixedWriter("test | ")
+
+	subject := subject
+	subject.BundleFlags.Bundle = fakeRegistry.ReferenceOnTestServer("library/bundle")
+	subject.registry = fakeRegistry.Build()
+
+	copyWithStrategy := func(t *testing.T, destRepo string, strategy string, tagTemplate string) {
+		tagStrategy, err := imageset.NewDestinationTagStrategy(strategy, tagTemplate)
+		require.NoError(t, err)
+
+		subject := subject
+		subject.imageSet = imageset.NewImageSet(1, prefixedLogger).WithDestinationTagStrategy(tagStrategy)
+
+		_, err = subject.CopyToRepo(destRepo)
+		require.NoError(t, err)
+	}
+
+	assertTag := func(t *testing.T, destRepo string, tag string, expectedDigest string) {
+		tagRef, err := name.NewTag(destRepo + ":" + tag)
+		require.NoError(t, err)
+
+		digest, err := subject.registry.Digest(tagRef)
+		require.NoError(t, err)
+		assert.Equal(t, expectedDigest, digest.String())
+	}
+
+	assertNoTag := func(t *testing.T, destRepo string, tag string) {
+		tagRef, err := name.NewTag(destRepo + ":" + tag)
+		require.NoError(t, err)
+
+		_, err = subject.registry.Digest(tagRef)
+		assert.Error(t, err)
+	}
+
+	imgpkgDigestTag := func(digest string) string {
+		return "sha256-" + digest[len("sha256:"):] + ".imgpkg"
+	}
+
+	t.Run("none uploads images by digest", func(t *testing.T) {
+		destRepo := fakeRegistry.ReferenceOnTestServer("library/bundle-copy-none")
+		copyWithStrategy(t, destRepo, imageset.NoneTagStrategy, "")
+
+		assertNoTag(t, destRepo, imgpkgDigestTag(image1.Digest))
+		assertNoTag(t, destRepo, imgpkgDigestTag(rootBundle.Digest))
+		assertTag(t, destRepo, "latest", rootBundle.Digest)
+
+		imgDigestRef, err := name.NewDigest(destRepo + "@" + image1.Digest)
+		require.NoError(t, err)
+		_, err = subject.registry.Digest(imgDigestRef)
+		require.NoError(t, err)
+	})
+
+	t.Run("original reuses source tags", func(t *testing.T) {
+		destRepo := fakeRegistry.ReferenceOnTestServer("library/bundle-copy-original")
+		copyWithStrategy(t, destRepo, imageset.OriginalTagStrategy, "")
+
+		assertTag(t, destRepo, "latest", rootBundle.Digest)
+		assertNoTag(t, destRepo, imgpkgDigestTag(rootBundle.Digest))
+		assertNoTag(t, destRepo, imgpkgDigestTag(image1.Digest))
+	})
+
+	t.Run("template builds tags from digest and source tag", func(t *testing.T) {
+		destRepo := fakeRegistry.ReferenceOnTestServer("library/bundle-copy-template")
+		copyWithStrategy(t, destRepo, imageset.TemplateTagStrategy, `{{if .Tag}}{{.Tag}}-{{end}}{{printf "%.12s" .Hex}}`)
+
+		assertTag(t, destRepo, "latest-"+rootBundle.Digest[len("sha256:"):][:12], rootBundle.Digest)
+		assertTag(t, destRepo, image1.Digest[len("sha256:"):][:12], image1.Digest)
+		assertNoTag(t, destRepo, imgpkgDigestTag(image1.Digest))
+	})
+
+	t.Run("different images cannot be written with the same tag", func(t *testing.T) {
+		tagStrategy, err := imageset.NewDestinationTagStrategy(imageset.TemplateTagStrategy, "latest")
+		require.NoError(t, err)
+
+		subject := subject
+		subject.imageSet = imageset.NewImageSet(1, prefixedLogger).WithDestinationTagStrategy(tagStrategy)
+
+		destRepo := fakeRegistry.ReferenceOnTestServer("library/bundle-copy-same-tag")
+
+		_, err = subject.PlanCopyToRepo(destRepo)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Expected images to be written with distinct tags")
+
+		_, err = subject.CopyToRepo(destRepo)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Expected images to be written with distinct tags")
+		assert.Contains(t, err.Error(), destRepo+":latest")
+
+		assertNoTag(t, destRepo, "latest")
+	})
+
+	t.Run("template has to produce valid tags", func(t *testing.T) {
+		tagStrategy, err := imageset.NewDestinationTagStrategy(imageset.TemplateTagStrategy, "{{.Repo}}")
+		require.NoError(t, err)
+
+		subject := subject
+		subject.imageSet = imageset.NewImageSet(1, prefixedLogger).WithDestinationTagStrategy(tagStrategy)
+
+		_, err = subject.CopyToRepo(fakeRegistry.ReferenceOnTestServer("library/bundle-copy-invalid"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Expected tag template to produce a valid tag")
+	})
+
+	t.Run("template is required by template strategy only", func(t *testing.T) {
+		_, err := imageset.NewDestinationTagStrategy(imageset.TemplateTagStrategy, "")
+		assert.Error(t, err)
+
+		_, err = imageset.NewDestinationTagStrategy(imageset.NoneTagStrategy, "{{.Hex}}")
+		assert.Error(t, err)
+
+		_, err = imageset.NewDestinationTagStrategy("unknown", "")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Unknown destination tag strategy 'unknown'")
+	})
+}
+
+func TestToRegistryPrefixBundleContainingANestedBundle(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	randomImage := fakeRegistry.WithRandomImage("library/image_with_config")
+	randomImage2 := fakeRegistry.WithRandomImage("other/image_with_config_2")
+
+	nestedBundle := fakeRegistry.WithBundleFromPath("library/nested-bundle", "test_assets/bundle_with_mult_images").
+		WithImageRefs([]lockconfig.ImageRef{
+			{Image: randomImage2.RefDigest},
+		})
+
+	rootBundle := fakeRegistry.WithBundleFromPath("library/bundle", "test_assets/bundle_with_mult_images").
+		WithImageRefs([]lockconfig.ImageRef{
+			{Image: randomImage.RefDigest},
+			{Image: nestedBundle.RefDigest},
+		})
+
+	subject := subject
+	subject.BundleFlags.Bundle = rootBundle.RefDigest
+	subject.registry = fakeRegistry.Build()
+
+	copiedImageLocations := func(t *testing.T, bundleRef string) map[string]string {
+		copiedBundle := bundle.NewBundle(bundleRef, subject.registry)
+		_, imageRefs, err := copiedBundle.AllImagesRefs(1, subject.logger)
+		require.NoError(t, err)
+
+		locations := map[string]string{}
+		for _, imgRef := range imageRefs.ImageRefs() {
+			locations[imgRef.Image] = imgRef.PrimaryLocation()
+		}
+		return locations
+	}
+
+	t.Run("copies images to repositories matching their source", func(t *testing.T) {
+		registryPrefix, err := imageset.NewRegistryPrefix(fakeRegistry.ReferenceOnTestServer("mirror"), false)
+		require.NoError(t, err)
+
+		prefix := fakeRegistry.ReferenceOnTestServer("mirror/" + strings.ReplaceAll(fakeRegistry.Host(), ":", "-"))
+
+		processedImages, err := subject.CopyToRegistryPrefix(registryPrefix)
+		require.NoError(t, err)
+
+		var processedImageDigests []string
+		for _, processedImage := range processedImages.All() {
+			processedImageDigests = append(processedImageDigests, processedImage.DigestRef)
+		}
+		assert.ElementsMatch(t, []string{
+			prefix + "/library/bundle@" + rootBundle.Digest,
+			prefix + "/library/nested-bundle@" + nestedBundle.Digest,
+			prefix + "/library/image_with_config@" + randomImage.Digest,
+			prefix + "/other/image_with_config_2@" + randomImage2.Digest,
+		}, processedImageDigests)
+
+		locations := copiedImageLocations(t, prefix+"/library/bundle@"+rootBundle.Digest)
+		assert.Equal(t, map[string]string{
+			randomImage.RefDigest:  prefix + "/library/image_with_config@" + randomImage.Digest,
+			nestedBundle.RefDigest: prefix + "/library/nested-bundle@" + nestedBundle.Digest,
+			randomImage2.RefDigest: prefix + "/other/image_with_config_2@" + randomImage2.Digest,
+		}, locations)
+	})
+
+	t.Run("strips source registry host when requested", func(t *testing.T) {
+		registryPrefix, err := imageset.NewRegistryPrefix(fakeRegistry.ReferenceOnTestServer("stripped-mirror"), true)
+		require.NoError(t, err)
+
+		prefix := fakeRegistry.ReferenceOnTestServer("stripped-mirror")
+
+		_, err = subject.CopyToRegistryPrefix(registryPrefix)
+		require.NoError(t, err)
+
+		locations := copiedImageLocations(t, prefix+"/library/bundle@"+rootBundle.Digest)
+		assert.Equal(t, prefix+"/other/image_with_config_2@"+randomImage2.Digest, locations[randomImage2.RefDigest])
+	})
+
+	t.Run("plans copy to repositories matching their source", func(t *testing.T) {
+		registryPrefix, err := imageset.NewRegistryPrefix(fakeRegistry.ReferenceOnTestServer("planned-mirror"), true)
+		require.NoError(t, err)
+
+		plan, err := subject.PlanCopyToRegistryPrefix(registryPrefix)
+		require.NoError(t, err)
+
+		prefix := fakeRegistry.ReferenceOnTestServer("planned-mirror")
+		var destinations []string
+		for _, img := range plan.Images {
+			destinations = append(destinations, img.Destination)
+		}
+		assert.Contains(t, destinations, prefix+"/other/image_with_config_2@"+randomImage2.Digest)
+		assert.Len(t, plan.LocationsImages, 2)
+	})
+}
+
+func TestToRegistryPrefixFromTarBundleContainingANestedBundle(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	randomImage := fakeRegistry.WithRandomImage("library/image_with_config")
+	randomImage2 := fakeRegistry.WithRandomImage("other/image_with_config_2")
+
+	nestedBundle := fakeRegistry.WithBundleFromPath("library/nested-bundle", "test_assets/bundle_with_mult_images").
+		WithImageRefs([]lockconfig.ImageRef{
+			{Image: randomImage2.RefDigest},
+		})
+	reg := fakeRegistry.Build()
+
+	confUI := goui.NewConfUI(goui.NewNoopLogger())
+	defer confUI.Flush()
+
+	bundleDir := t.TempDir()
+	require.NoError(t, createBundleDir(bundleDir, fmt.Sprintf(`---
+apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: ImagesLock
+images:
+- image: %s
+- image: %s
+`, randomImage.RefDigest, nestedBundle.RefDigest)))
+
+	bundleRef := fakeRegistry.ReferenceOnTestServer("library/bundle")
+	pushOpts := PushOptions{
+		ui:          confUI,
+		FileFlags:   FileFlags{Files: []string{bundleDir}},
+		BundleFlags: BundleFlags{Bundle: bundleRef},
+	}
+	require.NoError(t, pushOpts.Run())
+
+	bundleDigest, err := reg.Digest(mustParseReference(t, bundleRef))
+	require.NoError(t, err)
+
+	tarPath := filepath.Join(t.TempDir(), "bundle.tar")
+	copyToTarOpts := CopyOptions{
+		ui:          confUI,
+		BundleFlags: BundleFlags{Bundle: bundleRef + "@" + bundleDigest.String()},
+		TarFlags:    TarFlags{TarDst: tarPath},
+		Concurrency: 1,
+	}
+	require.NoError(t, copyToTarOpts.Run())
+
+	// Images can only be found through locations recorded when importing the tarball
+	fakeRegistry.RemoveImage("library/image_with_config@" + randomImage.Digest)
+	fakeRegistry.RemoveImage("other/image_with_config_2@" + randomImage2.Digest)
+	fakeRegistry.RemoveImage("library/nested-bundle@" + nestedBundle.Digest)
+
+	copyFromTarOpts := CopyOptions{
+		ui:                confUI,
+		TarFlags:          TarFlags{TarSrc: tarPath},
+		RegistryPrefixDst: fakeRegistry.ReferenceOnTestServer("mirror"),
+		Concurrency:       1,
+	}
+	require.NoError(t, copyFromTarOpts.Run())
+
+	prefix := fakeRegistry.ReferenceOnTestServer("mirror/" + strings.ReplaceAll(fakeRegistry.Host(), ":", "-"))
+	outputPath := filepath.Join(t.TempDir(), "bundle")
+
+	pullOpts := PullOptions{
+		ui:                   confUI,
+		BundleFlags:          BundleFlags{Bundle: prefix + "/library/bundle@" + bundleDigest.String()},
+		BundleRecursiveFlags: BundleRecursiveFlags{Recursive: true},
+		OutputPath:           outputPath,
+	}
+	require.NoError(t, pullOpts.Run())
+
+	imagesLock, err := lockconfig.NewImagesLockFromPath(filepath.Join(outputPath, ".imgpkg", "images.yml"))
+	require.NoError(t, err)
+	var images []string
+	for _, img := range imagesLock.Images {
+		images = append(images, img.Image)
+	}
+	assert.ElementsMatch(t, []string{
+		prefix + "/library/image_with_config@" + randomImage.Digest,
+		prefix + "/library/nested-bundle@" + nestedBundle.Digest,
+	}, images)
+
+	nestedImagesLock, err := lockconfig.NewImagesLockFromPath(filepath.Join(outputPath, ".imgpkg", "bundles",
+		strings.ReplaceAll(nestedBundle.Digest, ":", "-"), ".imgpkg", "images.yml"))
+	require.NoError(t, err)
+	require.Len(t, nestedImagesLock.Images, 1)
+	assert.Equal(t, prefix+"/other/image_with_config_2@"+randomImage2.Digest, nestedImagesLock.Images[0].Image)
+}
+
+func TestToReposBundle(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	randomImage := fakeRegistry.WithRandomImage("library/image_with_config")
+
+	rootBundle := fakeRegistry.WithBundleFromPath("library/bundle", "test_assets/bundle_with_mult_images").
+		WithImageRefs([]lockconfig.ImageRef{
+			{Image: randomImage.RefDigest},
+		})
+
+	// Destinations live in other registries so that blobs cannot be mounted from the source repository
+	destRegistry1 := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer destRegistry1.CleanUp()
+	destRegistry2 := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer destRegistry2.CleanUp()
+
+	subject := subject
+	subject.BundleFlags.Bundle = rootBundle.RefDigest
+	subject.registry = fakeRegistry.Build()
+
+	layers, err := randomImage.Image.Layers()
+	require.NoError(t, err)
+
+	blobRequests := map[string]int{}
+	blobRequestsLock := &sync.Mutex{}
+	fakeRegistry.WithCustomHandler(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Method == http.MethodGet && strings.Contains(request.URL.Path, "/blobs/") {
+			blobRequestsLock.Lock()
+			blobRequests[request.URL.Path[strings.LastIndex(request.URL.Path, "/")+1:]]++
+			blobRequestsLock.Unlock()
+		}
+	})
+
+	destRepos := []string{
+		destRegistry1.ReferenceOnTestServer("library/bundle-copy"),
+		destRegistry2.ReferenceOnTestServer("library/bundle-copy"),
+	}
+
+	allProcessedImages, err := subject.CopyToRepos(destRepos)
+	require.NoError(t, err)
+	require.Len(t, allProcessedImages, 2)
+
+	t.Run("copies every image to each repository", func(t *testing.T) {
+		for i, destRepo := range destRepos {
+			var processedImageDigests []string
+			for _, processedImage := range allProcessedImages[i].All() {
+				processedImageDigests = append(processedImageDigests, processedImage.DigestRef)
+			}
+			assert.ElementsMatch(t, []string{
+				destRepo + "@" + rootBundle.Digest,
+				destRepo + "@" + randomImage.Digest,
+			}, processedImageDigests)
+		}
+	})
+
+	t.Run("fetches each layer only once", func(t *testing.T) {
+		for _, layer := range layers {
+			digest, err := layer.Digest()
+			require.NoError(t, err)
+			assert.Equal(t, 1, blobRequests[digest.String()], "layer %s", digest)
+		}
+	})
+
+	t.Run("writes locations image to each repository", func(t *testing.T) {
+		for _, destRepo := range destRepos {
+			copiedBundle := bundle.NewBundle(destRepo+"@"+rootBundle.Digest, subject.registry)
+			_, imageRefs, err := copiedBundle.AllImagesRefs(1, subject.logger)
+			require.NoError(t, err)
+
+			require.Len(t, imageRefs.ImageRefs(), 1)
+			assert.Equal(t, destRepo+"@"+randomImage.Digest, imageRefs.ImageRefs()[0].PrimaryLocation())
+		}
+	})
+}
+
+func TestCopyToMultipleRepos(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	bundleInfo := fakeRegistry.WithBundleFromPath("library/bundle", "test_assets/bundle").
+		WithEveryImageFromPath("test_assets/image_with_config", map[string]string{})
+	fakeRegistry.Build()
+
+	destRepos := []string{
+		fakeRegistry.ReferenceOnTestServer("library/bundle-copy1"),
+		fakeRegistry.ReferenceOnTestServer("library/bundle-copy2"),
+		fakeRegistry.ReferenceOnTestServer("library/bundle-copy3"),
+	}
+	reportPath := filepath.Join(t.TempDir(), "report.json")
+
+	copyOpts := CopyOptions{
+		BundleFlags:  BundleFlags{Bundle: bundleInfo.RefDigest},
+		RepoDsts:     destRepos,
+		ReportOutput: reportPath,
+		Concurrency:  2,
+	}
+	require.NoError(t, copyOpts.Run())
+
+	t.Run("copies the bundle to each repository", func(t *testing.T) {
+		for _, destRepo := range destRepos {
+			_, err := remote.Head(mustParseReference(t, destRepo+"@"+bundleInfo.Digest))
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("reports bytes transferred to each repository", func(t *testing.T) {
+		bs, err := ioutil.ReadFile(reportPath)
+		require.NoError(t, err)
+
+		var report CopyReport
+		require.NoError(t, json.Unmarshal(bs, &report))
+
+		bundleBytes := map[string]int64{}
+		var imagesBytes int64
+		for _, img := range report.Images {
+			imagesBytes += img.Bytes
+			if img.Source == bundleInfo.RefDigest {
+				bundleBytes[img.Destination] = img.Bytes
+			}
+		}
+
+		require.Len(t, bundleBytes, len(destRepos))
+		for _, destRepo := range destRepos {
+			assert.NotZero(t, bundleBytes[destRepo+"@"+bundleInfo.Digest], "destination %s", destRepo)
+			assert.Equal(t, bundleBytes[destRepos[0]+"@"+bundleInfo.Digest], bundleBytes[destRepo+"@"+bundleInfo.Digest])
+		}
+		assert.Equal(t, imagesBytes, report.Totals.Bytes)
+	})
+}
+
+func TestLockOutputPathForRepo(t *testing.T) {
+	assert.Equal(t, "relocated-registry.io-5000-app.yml", lockOutputPathForRepo("relocated.yml", "registry.io:5000/app"))
+	assert.Equal(t, "dir/lock-registry.io-org-app", lockOutputPathForRepo("dir/lock", "registry.io/org/app"))
+	assert.Equal(t, "", lockOutputPathForRepo("", "registry.io/app"))
+}
+
+func TestToRepoImageMountingBlobsFromOtherRepository(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	randomImage := fakeRegistry.WithRandomImage("library/image")
+
+	// Destination lives in another registry so that blobs cannot be mounted from the source repository
+	destRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer destRegistry.CleanUp()
+	destRegistry.WithRepositoryScopedBlobs()
+	destRegistry.WithImage("library/base", randomImage.Image)
+	destRegistry.Build()
+
+	mountedBlobs := map[string]string{}
+	var uploadedBlobs []string
+	blobRequestsLock := &sync.Mutex{}
+	destRegistry.WithCustomHandler(func(writer http.ResponseWriter, request *http.Request) {
+		blobRequestsLock.Lock()
+		defer blobRequestsLock.Unlock()
+
+		if request.Method == http.MethodPost && request.URL.Query().Get("mount") != "" {
+			mountedBlobs[request.URL.Query().Get("mount")] = request.URL.Query().Get("from")
+		}
+		if request.Method == http.MethodPut && strings.Contains(request.URL.Path, "/blobs/uploads/") {
+			uploadedBlobs = append(uploadedBlobs, request.URL.Query().Get("digest"))
+		}
+	})
+
+	output := bytes.NewBufferString("")
+	imageSet := imageset.NewImageSet(1, util.NewLogger(output).NewPrefixedWriter("test | ")).
+		WithMountFromRepos([]name.Repository{mustParseRepository(t, destRegistry.ReferenceOnTestServer("library/base"))})
+
+	subject := subject
+	subject.ImageFlags.Image = randomImage.RefDigest
+	subject.imageSet = imageSet
+	subject.registry = fakeRegistry.Build()
+
+	destRepo := destRegistry.ReferenceOnTestServer("library/image-copy")
+	processedImages, err := subject.CopyToRepo(destRepo)
+	require.NoError(t, err)
+	require.Len(t, processedImages.All(), 1)
+	assert.Equal(t, destRepo+"@"+randomImage.Digest, processedImages.All()[0].DigestRef)
+
+	layers, err := randomImage.Image.Layers()
+	require.NoError(t, err)
+
+	var layersSize int64
+	for _, layer := range layers {
+		digest, err := layer.Digest()
+		require.NoError(t, err)
+		size, err := layer.Size()
+		require.NoError(t, err)
+		layersSize += size
+
+		assert.Equal(t, "library/base", mountedBlobs[digest.String()], "layer %s", digest)
+		assert.NotContains(t, uploadedBlobs, digest.String(), "layer %s", digest)
+	}
+
+	assert.Contains(t, output.String(), fmt.Sprintf("mounted %d blobs (%s) into %s from %s",
+		len(layers), util.FormatBytes(layersSize), destRepo, destRegistry.ReferenceOnTestServer("library/base")))
+
+	t.Run("copied image can be pulled from destination repository", func(t *testing.T) {
+		copiedImage, err := subject.registry.Image(mustParseReference(t, destRepo+"@"+randomImage.Digest))
+		require.NoError(t, err)
+		_, err = copiedImage.Layers()
+		require.NoError(t, err)
+		for _, layer := range layers {
+			digest, err := layer.Digest()
+			require.NoError(t, err)
+			copiedLayer, err := copiedImage.LayerByDigest(digest)
+			require.NoError(t, err)
+			rc, err := copiedLayer.Compressed()
+			require.NoError(t, err)
+			rc.Close()
+		}
+	})
+}
+
+func mustParseRepository(t *testing.T, repo string) name.Repository {
+	parsedRepo, err := name.NewRepository(repo)
+	require.NoError(t, err)
+	return parsedRepo
+}
+
+func mustParseReference(t *testing.T, ref string) name.Reference {
+	parsedRef, err := name.ParseReference(ref)
+	require.NoError(t, err)
+	return parsedRef
+}
+
+func TestToRepoBundleResumingFromCheckpoint(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	image1 := fakeRegistry.WithRandomImage("library/image1")
+	image2 := fakeRegistry.WithRandomImage("library/image2")
+
+	rootBundle := fakeRegistry.WithBundleFromPath("library/bundle", "test_assets/bundle_with_mult_images").
+		WithImageRefs([]lockconfig.ImageRef{
+			{Image: image1.RefDigest},
+			{Image: image2.RefDigest},
+		})
+
+	logger := util.NewLogger(stdOut)
+	prefixedLogger := logger.NewPrefixedWriter("test | ")
+	checkpointPath := filepath.Join(t.TempDir(), "checkpoint.json")
+	destRepo := fakeRegistry.ReferenceOnTestServer("library/bundle-copy")
+
+	subject := subject
+	subject.registry = fakeRegistry.Build()
+
+	// Images are forced to be copied so that they are not skipped for being present in the destination
+	copyWithCheckpoint := func(t *testing.T) *imageset.ProcessedImages {
+		checkpoint, err := imageset.NewCheckpoint(checkpointPath)
+		require.NoError(t, err)
+
+		subject := subject
+		subject.imageSet = imageset.NewImageSet(1, prefixedLogger).WithForce(true).WithCheckpoint(checkpoint)
+
+		processedImages, err := subject.CopyToRepo(destRepo)
+		require.NoError(t, err)
+		return processedImages
+	}
+
+	// Simulates a copy interrupted right after image1 was copied
+	subject.ImageFlags.Image = image1.RefDigest
+	copyWithCheckpoint(t)
+
+	image1Hash, err := regv1.NewHash(image1.Digest)
+	require.NoError(t, err)
+
+	image1Uploads := 0
+	fakeRegistry.WithCustomHandler(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Method == http.MethodPut && strings.Contains(request.URL.Path, "/manifests/") &&
+			strings.Contains(request.URL.Path, image1Hash.Hex) {
+			image1Uploads++
+		}
+	})
+
+	subject.ImageFlags.Image = ""
+	subject.BundleFlags.Bundle = rootBundle.RefDigest
+	processedImages := copyWithCheckpoint(t)
+
+	t.Run("returns every image including the ones copied before", func(t *testing.T) {
+		var processedImageDigests []string
+		for _, processedImage := range processedImages.All() {
+			processedImageDigests = append(processedImageDigests, processedImage.DigestRef)
+			if processedImage.DigestRef == destRepo+"@"+image1.Digest {
+				assert.True(t, processedImage.AlreadyPresent)
+			}
+		}
+		assert.ElementsMatch(t, []string{
+			destRepo + "@" + rootBundle.Digest,
+			destRepo + "@" + image1.Digest,
+			destRepo + "@" + image2.Digest,
+		}, processedImageDigests)
+	})
+
+	t.Run("does not upload images copied before", func(t *testing.T) {
+		assert.Equal(t, 0, image1Uploads)
+	})
+
+	t.Run("records every copied image", func(t *testing.T) {
+		checkpoint, err := imageset.NewCheckpoint(checkpointPath)
+		require.NoError(t, err)
+
+		importRepo, err := name.NewRepository(destRepo)
+		require.NoError(t, err)
+
+		for _, img := range []string{rootBundle.RefDigest, image1.RefDigest, image2.RefDigest} {
+			_, found := checkpoint.ImportedImage(imageset.UnprocessedImageRef{DigestRef: img}, importRepo)
+			assert.True(t, found, "image %s", img)
+		}
+	})
+}
+
+func TestToTarBundleResumingFromCheckpoint(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	image1 := fakeRegistry.WithRandomImage("library/image1")
+
+	logger := util.NewLogger(stdOut)
+	prefixedLogger := logger.NewPrefixedWriter("test | ")
+	tempDir := t.TempDir()
+	checkpointPath := filepath.Join(tempDir, "checkpoint.json")
+	tarPath := filepath.Join(tempDir, "bundle.tar")
+
+	subject := subject
+	subject.ImageFlags.Image = image1.RefDigest
+	subject.registry = fakeRegistry.Build()
+
+	copyWithCheckpoint := func(t *testing.T) {
+		checkpoint, err := imageset.NewCheckpoint(checkpointPath)
+		require.NoError(t, err)
+
+		subject := subject
+		subject.imageSet = imageset.NewImageSet(1, prefixedLogger).WithCheckpoint(checkpoint)
+		subject.tarImageSet = imageset.NewTarImageSet(subject.imageSet, 1, prefixedLogger)
+
+		require.NoError(t, subject.CopyToTar(tarPath))
+	}
+
+	copyWithCheckpoint(t)
+
+	// Simulates a copy interrupted while writing the last layer
+	var state map[string]interface{}
+	checkpointBytes, err := ioutil.ReadFile(checkpointPath)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(checkpointBytes, &state))
+
+	tarball := state["tarball"].(map[string]interface{})
+	layers := tarball["layers"].([]interface{})
+	require.NotEmpty(t, layers)
+	lastLayer := layers[len(layers)-1].(map[string]interface{})
+	lastLayerOffset := int64(lastLayer["offset"].(float64))
+	lastLayerDigest := strings.Replace(strings.TrimSuffix(lastLayer["name"].(string), ".tar.gz"), "-", ":", 1)
+
+	tarball["layers"] = layers[:len(layers)-1]
+	tarball["end"] = lastLayerOffset
+	checkpointBytes, err = json.Marshal(state)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(checkpointPath, checkpointBytes, 0600))
+	require.NoError(t, os.Truncate(tarPath, lastLayerOffset+700))
+
+	image1Layers, err := image1.Image.Layers()
+	require.NoError(t, err)
+
+	layerDigests := map[string]struct{}{}
+	for _, layer := range image1Layers {
+		digest, err := layer.Digest()
+		require.NoError(t, err)
+		layerDigests[digest.String()] = struct{}{}
+	}
+
+	var layerRequests []string
+	fakeRegistry.WithCustomHandler(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Method == http.MethodGet && strings.Contains(request.URL.Path, "/blobs/") {
+			digest := request.URL.Path[strings.LastIndex(request.URL.Path, "/")+1:]
+			if _, found := layerDigests[digest]; found {
+				layerRequests = append(layerRequests, digest)
+			}
+		}
+	})
+
+	copyWithCheckpoint(t)
+
+	t.Run("only fetches layers that were not written before", func(t *testing.T) {
+		assert.Equal(t, []string{lastLayerDigest}, layerRequests)
+	})
+
+	t.Run("tar contains every layer", func(t *testing.T) {
+		imgOrIndexes, err := imagetar.NewTarReader(tarPath).Read()
+		require.NoError(t, err)
+
+		for _, imgOrIndex := range imgOrIndexes {
+			imgLayers, err := (*imgOrIndex.Image).Layers()
+			require.NoError(t, err)
+
+			for _, layer := range imgLayers {
+				digest, err := layer.Digest()
+				require.NoError(t, err)
+
+				rc, err := layer.Compressed()
+				require.NoError(t, err)
+				actualDigest, _, err := regv1.SHA256(rc)
+				rc.Close()
+				require.NoError(t, err)
+				assert.Equal(t, digest, actualDigest)
+			}
+		}
+	})
+}
+
+func TestToRepoBundleContinuingOnError(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	image1 := fakeRegistry.WithRandomImage("library/image1")
+	image2 := fakeRegistry.WithRandomImage("library/image2")
+
+	rootBundle := fakeRegistry.WithBundleFromPath("library/bundle", "test_assets/bundle_with_mult_images").
+		WithImageRefs([]lockconfig.ImageRef{
+			{Image: image1.RefDigest},
+			{Image: image2.RefDigest},
+		})
+
+	logger := util.NewLogger(stdOut)
+	prefixedLogger := logger.NewPrefixedWriter("test | ")
+	destRepo := fakeRegistry.ReferenceOnTestServer("library/bundle-copy")
+
+	subject := subject
+	subject.BundleFlags.Bundle = rootBundle.RefDigest
+	subject.registry = fakeRegistry.Build()
+
+	image2Hash, err := regv1.NewHash(image2.Digest)
+	require.NoError(t, err)
+	fakeRegistry.WithManifestUploadError(image2Hash.Hex, http.StatusForbidden, "DENIED")
+	defer fakeRegistry.ResetHandler()
+
+	subject.imageSet = imageset.NewImageSet(1, prefixedLogger).WithContinueOnError(true)
+
+	processedImages, err := subject.CopyToRepo(destRepo)
+	require.NoError(t, err)
+
+	t.Run("copies every other image", func(t *testing.T) {
+		var processedImageDigests []string
+		for _, processedImage := range processedImages.All() {
+			processedImageDigests = append(processedImageDigests, processedImage.DigestRef)
+		}
+		assert.ElementsMatch(t, []string{
+			destRepo + "@" + rootBundle.Digest,
+			destRepo + "@" + image1.Digest,
+		}, processedImageDigests)
+	})
+
+	t.Run("records failed image with registry error", func(t *testing.T) {
+		failedImages := processedImages.Failures()
+		require.Len(t, failedImages, 1)
+		assert.Equal(t, image2.RefDigest, failedImages[0].UnprocessedImageRef.DigestRef)
+		assert.Equal(t, http.StatusForbidden, failedImages[0].StatusCode())
+		assert.Equal(t, []string{"DENIED"}, failedImages[0].ErrorCodes())
+	})
+
+	t.Run("flags bundle as incomplete", func(t *testing.T) {
+		for _, processedImage := range processedImages.All() {
+			if processedImage.UnprocessedImageRef.DigestRef == rootBundle.RefDigest {
+				assert.Equal(t, []string{image2.RefDigest}, processedImage.MissingImages)
+			} else {
+				assert.Empty(t, processedImage.MissingImages)
+			}
+		}
+	})
+
+	t.Run("does not write locations of incomplete bundle", func(t *testing.T) {
+		locationsRef := fmt.Sprintf("%s:%s.image-locations.imgpkg", destRepo, strings.ReplaceAll(rootBundle.Digest, ":", "-"))
+		_, err := remote.Head(mustParseReference(t, locationsRef))
+		assert.Error(t, err)
+	})
+
+	t.Run("copy command writes incomplete lock output and fails", func(t *testing.T) {
+		tempDir := t.TempDir()
+		imagesLockPath := filepath.Join(tempDir, "images.yml")
+		lockOutputPath := filepath.Join(tempDir, "relocated.yml")
+
+		imagesLock := lockconfig.NewEmptyImagesLock()
+		imagesLock.Images = []lockconfig.ImageRef{{Image: image1.RefDigest}, {Image: image2.RefDigest}}
+		require.NoError(t, imagesLock.WriteToPath(imagesLockPath))
+
+		copyOpts := CopyOptions{
+			ui:              goui.NewNoopUI(),
+			LockInputFlags:  LockInputFlags{LockFilePath: imagesLockPath},
+			LockOutputFlags: LockOutputFlags{LockFilePath: lockOutputPath},
+			RepoDsts:        []string{fakeRegistry.ReferenceOnTestServer("library/images-copy")},
+			Concurrency:     1,
+			ContinueOnError: true,
+		}
+		err := copyOpts.Run()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Copying 1 images failed")
+
+		lockOutput, err := ioutil.ReadFile(lockOutputPath)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(string(lockOutput), "# Incomplete: 1 images failed to be copied\n"))
+
+		relocatedLock, err := lockconfig.NewImagesLockFromPath(lockOutputPath)
+		require.NoError(t, err)
+		assert.Equal(t, []lockconfig.ImageRef{
+			{Image: fakeRegistry.ReferenceOnTestServer("library/images-copy") + "@" + image1.Digest},
+			{Image: image2.RefDigest, Annotations: map[string]string{copyFailedAnnotation: "status 403, DENIED"}},
+		}, relocatedLock.Images)
+	})
+}
+
+func TestToRepoImageContinuingOnTagError(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	image := fakeRegistry.WithRandomImage("library/tagged-image")
+
+	logger := util.NewLogger(stdOut)
+	prefixedLogger := logger.NewPrefixedWriter("test | ")
+	destRepo := fakeRegistry.ReferenceOnTestServer("library/tagged-image-copy")
+
+	subject := subject
+	subject.ImageFlags.Image = fakeRegistry.ReferenceOnTestServer("library/tagged-image:latest")
+	subject.registry = fakeRegistry.Build()
+
+	// Image manifest is uploaded, but writing its original tag fails
+	fakeRegistry.WithManifestUploadError("/library/tagged-image-copy/manifests/latest", http.StatusForbidden, "DENIED")
+	defer fakeRegistry.ResetHandler()
+
+	subject.imageSet = imageset.NewImageSet(1, prefixedLogger).WithContinueOnError(true)
+
+	processedImages, err := subject.CopyToRepo(destRepo)
+	require.NoError(t, err)
+
+	failedImages := processedImages.Failures()
+	require.Len(t, failedImages, 1)
+	assert.Equal(t, image.RefDigest, failedImages[0].UnprocessedImageRef.DigestRef)
+	assert.Contains(t, failedImages[0].Err.Error(), "Importing image as")
+	assert.Equal(t, http.StatusForbidden, failedImages[0].StatusCode())
+	assert.Equal(t, []string{"DENIED"}, failedImages[0].ErrorCodes())
+}
+
+func TestToRepoImageWithCosignArtifacts(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	image1 := fakeRegistry.WithRandomImage("library/image1")
+
+	image1Hash, err := regv1.NewHash(image1.Digest)
+	require.NoError(t, err)
+	attestationTag := fmt.Sprintf("sha256-%s.att", image1Hash.Hex)
+	sbomTag := fmt.Sprintf("sha256-%s.sbom", image1Hash.Hex)
+	attestation := fakeRegistry.WithRandomImage("library/image1:" + attestationTag)
+	sbom := fakeRegistry.WithRandomImage("library/image1:" + sbomTag)
+
+	attestationHash, err := regv1.NewHash(attestation.Digest)
+	require.NoError(t, err)
+	attestationSignatureTag := fmt.Sprintf("sha256-%s.sig", attestationHash.Hex)
+	attestationSignature := fakeRegistry.WithRandomImage("library/image1:" + attestationSignatureTag)
+
+	reg := fakeRegistry.Build()
+
+	logger := util.NewLogger(stdOut)
+	prefixedLogger := logger.NewPrefixedWriter("test | ")
+
+	subject := subject
+	subject.ImageFlags.Image = image1.RefDigest
+	subject.registry = reg
+	subject.signatureRetriever = signature.NewSignatures(signature.NewCosign(reg), 1).
+		WithArtifacts(signature.SignatureArtifact, signature.AttestationArtifact, signature.SBOMArtifact)
+
+	expectedArtifacts := map[string]string{
+		attestation.Digest:          signature.AttestationLabelKey,
+		sbom.Digest:                 signature.SBOMLabelKey,
+		attestationSignature.Digest: signature.SignatureLabelKey,
+	}
+
+	assertArtifactsCopied := func(t *testing.T, destRepo string, processedImages *imageset.ProcessedImages) {
+		require.Len(t, processedImages.All(), 4)
+
+		for _, processedImage := range processedImages.All() {
+			digest := mustParseReference(t, processedImage.DigestRef).(name.Digest).DigestStr()
+			if digest == image1.Digest {
+				assert.Empty(t, processedImage.Labels)
+				continue
+			}
+			labelKey, found := expectedArtifacts[digest]
+			require.True(t, found, "unexpected image %s", processedImage.DigestRef)
+			assert.Equal(t, map[string]string{labelKey: ""}, processedImage.Labels)
+		}
+
+		for tag, digest := range map[string]string{
+			attestationTag:          attestation.Digest,
+			sbomTag:                 sbom.Digest,
+			attestationSignatureTag: attestationSignature.Digest,
+		} {
+			desc, err := remote.Head(mustParseReference(t, destRepo+":"+tag))
+			require.NoError(t, err)
+			assert.Equal(t, digest, desc.Digest.String())
+		}
+	}
+
+	t.Run("copies artifacts and signatures of artifacts to a repository", func(t *testing.T) {
+		destRepo := fakeRegistry.ReferenceOnTestServer("library/image1-copy")
+
+		processedImages, err := subject.CopyToRepo(destRepo)
+		require.NoError(t, err)
+
+		assertArtifactsCopied(t, destRepo, processedImages)
+
+		builder := newCopyReportBuilder(false, imageset.DestinationTagStrategy{}, subject.logger)
+		require.NoError(t, builder.AddProcessedImages(processedImages, subject.registry))
+		reportTypes := map[string]string{}
+		for _, img := range builder.Build().Images {
+			reportTypes[img.Source] = img.Type
+		}
+		assert.Equal(t, map[string]string{
+			image1.RefDigest:               copyPlanImageType,
+			attestation.RefDigest:          "attestation",
+			sbom.RefDigest:                 "sbom",
+			attestationSignature.RefDigest: "signature",
+		}, reportTypes)
+
+		lockOutputPath := filepath.Join(t.TempDir(), "relocated.yml")
+		require.NoError(t, (&CopyOptions{}).writeImagesLockOutput(processedImages, lockOutputPath))
+		imagesLock, err := lockconfig.NewImagesLockFromPath(lockOutputPath)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []lockconfig.ImageRef{
+			{Image: destRepo + "@" + image1.Digest},
+			{Image: destRepo + "@" + attestation.Digest, Annotations: map[string]string{cosignArtifactAnnotation: "attestation"}},
+			{Image: destRepo + "@" + sbom.Digest, Annotations: map[string]string{cosignArtifactAnnotation: "sbom"}},
+			{Image: destRepo + "@" + attestationSignature.Digest, Annotations: map[string]string{cosignArtifactAnnotation: "signature"}},
+		}, imagesLock.Images)
+	})
+
+	t.Run("copies artifacts through a tarball", func(t *testing.T) {
+		tarPath := filepath.Join(t.TempDir(), "image.tar")
+		require.NoError(t, subject.CopyToTar(tarPath))
+
+		// Labels are persisted in the tarball since the registry the images come from is not available when importing it
+		tarItems, err := imagetar.NewTarReader(tarPath).Read()
+		require.NoError(t, err)
+		tarLabels := map[string]map[string]string{}
+		for _, item := range tarItems {
+			tarLabels[mustParseReference(t, item.Ref()).(name.Digest).DigestStr()] = item.Labels
+		}
+		assert.Equal(t, map[string]string{signature.SignatureLabelKey: ""}, tarLabels[attestationSignature.Digest])
+
+		destRepo := fakeRegistry.ReferenceOnTestServer("library/image1-from-tar")
+		tarImageSet := imageset.NewTarImageSet(imageset.NewImageSet(1, prefixedLogger), 1, prefixedLogger)
+		processedImages, err := tarImageSet.Import(tarPath, mustParseRepository(t, destRepo), reg)
+		require.NoError(t, err)
+
+		assertArtifactsCopied(t, destRepo, processedImages)
+
+		builder := newCopyReportBuilder(false, imageset.DestinationTagStrategy{}, subject.logger)
+		require.NoError(t, builder.AddProcessedImages(processedImages, subject.registry))
+		for _, img := range builder.Build().Images {
+			if mustParseReference(t, img.Source).(name.Digest).DigestStr() == attestationSignature.Digest {
+				assert.Equal(t, "signature", img.Type)
+			}
+		}
+
+		lockOutputPath := filepath.Join(t.TempDir(), "relocated.yml")
+		require.NoError(t, (&CopyOptions{}).writeImagesLockOutput(processedImages, lockOutputPath))
+		imagesLock, err := lockconfig.NewImagesLockFromPath(lockOutputPath)
+		require.NoError(t, err)
+		assert.Contains(t, imagesLock.Images, lockconfig.ImageRef{
+			Image:       destRepo + "@" + attestationSignature.Digest,
+			Annotations: map[string]string{cosignArtifactAnnotation: "signature"},
+		})
+	})
+}
+
+func TestToRepoImageWithReferrers(t *testing.T) {
+	logger := &helpers.Logger{LogLevel: helpers.LogDebug}
+	srcRegistry := helpers.NewFakeRegistry(t, logger)
+	defer srcRegistry.CleanUp()
+	dstRegistry := helpers.NewFakeRegistry(t, logger)
+	defer dstRegistry.CleanUp()
+
+	image1 := srcRegistry.WithRandomImage("library/image1")
+	sbom := srcRegistry.WithReferrer(image1, "application/spdx+json")
+	sbomSignature := srcRegistry.WithReferrer(sbom, "application/vnd.cncf.notary.signature")
+	srcRegistry.WithReferrersAPI()
+
+	reg := srcRegistry.Build()
+
+	prefixedLogger := util.NewLogger(stdOut).NewPrefixedWriter("test | ")
+
+	subject := subject
+	subject.ImageFlags.Image = image1.RefDigest
+	subject.registry = reg
+	subject.referrersRetriever = referrers.NewReferrers(reg, 1, subject.logger)
+
+	expectedReferrers := map[string]struct {
+		subject      string
+		artifactType string
+	}{
+		sbom.Digest:          {image1.Digest, "application/spdx+json"},
+		sbomSignature.Digest: {sbom.Digest, "application/vnd.cncf.notary.signature"},
+	}
+
+	assertReferrersCopied := func(t *testing.T, destRepo string, processedImages *imageset.ProcessedImages) {
+		require.Len(t, processedImages.All(), 3)
+
+		for _, processedImage := range processedImages.All() {
+			digest := mustParseReference(t, processedImage.DigestRef).(name.Digest).DigestStr()
+			if digest == image1.Digest {
+				assert.Empty(t, processedImage.Labels)
+				continue
+			}
+			expected, found := expectedReferrers[digest]
+			require.True(t, found, "unexpected image %s", processedImage.DigestRef)
+			assert.Equal(t, map[string]string{imageset.ReferrerLabelKey: expected.subject}, processedImage.Labels)
+		}
+
+		for digest, expected := range expectedReferrers {
+			descriptors, err := reg.Referrers(mustParseReference(t, destRepo+"@"+expected.subject).(name.Digest))
+			require.NoError(t, err)
+			require.Len(t, descriptors, 1)
+			assert.Equal(t, digest, descriptors[0].Digest.String())
+			assert.Equal(t, expected.artifactType, descriptors[0].ArtifactType)
+		}
+	}
+
+	assertFallbackIndexes := func(t *testing.T, destRepo string, exist bool) {
+		for _, subjectDigest := range []string{image1.Digest, sbom.Digest} {
+			fallbackTag := strings.Replace(subjectDigest, ":", "-", 1)
+			_, err := remote.Head(mustParseReference(t, destRepo+":"+fallbackTag))
+			if exist {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		}
+	}
+
+	t.Run("copies referrers and writes fallback indexes to a registry without referrers API", func(t *testing.T) {
+		destRepo := dstRegistry.ReferenceOnTestServer("library/image1-copy")
+
+		processedImages, err := subject.CopyToRepo(destRepo)
+		require.NoError(t, err)
+
+		assertReferrersCopied(t, destRepo, processedImages)
+		assertFallbackIndexes(t, destRepo, true)
+
+		builder := newCopyReportBuilder(false, imageset.DestinationTagStrategy{}, subject.logger)
+		require.NoError(t, builder.AddProcessedImages(processedImages, subject.registry))
+		reportTypes := map[string]string{}
+		for _, img := range builder.Build().Images {
+			reportTypes[img.Source] = img.Type
+		}
+		assert.Equal(t, map[string]string{
+			image1.RefDigest:        copyPlanImageType,
+			sbom.RefDigest:          copyPlanReferrerType,
+			sbomSignature.RefDigest: copyPlanReferrerType,
+		}, reportTypes)
+
+		t.Run("finds referrers using fallback indexes", func(t *testing.T) {
+			subject := subject
+			subject.ImageFlags.Image = destRepo + "@" + image1.Digest
+
+			destRepo := dstRegistry.ReferenceOnTestServer("library/image1-copy-again")
+
+			processedImages, err := subject.CopyToRepo(destRepo)
+			require.NoError(t, err)
+
+			assertReferrersCopied(t, destRepo, processedImages)
+		})
+	})
+
+	t.Run("copies referrers through a tarball", func(t *testing.T) {
+		tarPath := filepath.Join(t.TempDir(), "image.tar")
+		require.NoError(t, subject.CopyToTar(tarPath))
+
+		destRepo := dstRegistry.ReferenceOnTestServer("library/image1-from-tar")
+		tarImageSet := imageset.NewTarImageSet(imageset.NewImageSet(1, prefixedLogger), 1, prefixedLogger)
+		processedImages, err := tarImageSet.Import(tarPath, mustParseRepository(t, destRepo), reg)
+		require.NoError(t, err)
+
+		assertReferrersCopied(t, destRepo, processedImages)
+		assertFallbackIndexes(t, destRepo, true)
+	})
+
+	t.Run("does not write fallback indexes to a registry with referrers API", func(t *testing.T) {
+		destRepo := srcRegistry.ReferenceOnTestServer("library/image1-copy")
+
+		processedImages, err := subject.CopyToRepo(destRepo)
+		require.NoError(t, err)
+
+		assertReferrersCopied(t, destRepo, processedImages)
+		assertFallbackIndexes(t, destRepo, false)
+	})
+}
+
+func TestToRepoBundleVerifyingSignatures(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	bundleInfo := fakeRegistry.WithBundleFromPath("library/bundle", "test_assets/bundle").
+		WithEveryImageFromPath("test_assets/image_with_config", map[string]string{})
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	fakeRegistry.WithCosignSignature(bundleInfo.RefDigest, bundleInfo.Digest, key)
+
+	reg := fakeRegistry.Build()
+
+	keyPath := filepath.Join(t.TempDir(), "cosign.pub")
+	helpers.WriteCosignPublicKey(t, keyPath, key.Public())
+
+	verifier, err := (&VerifyFlags{KeyPaths: []string{keyPath}}).Verifier(reg, 1)
+	require.NoError(t, err)
+
+	subject := subject
+	subject.BundleFlags = BundleFlags{bundleInfo.RefDigest}
+	subject.registry = reg
+	subject.verifier = verifier
+
+	t.Run("copies the bundle when only signatures of bundles are verified", func(t *testing.T) {
+		subject := subject
+		subject.VerifyScope = verifyScopeBundles
+
+		destRepo := fakeRegistry.ReferenceOnTestServer("library/bundle-copy")
+		_, err := subject.CopyToRepo(destRepo)
+		require.NoError(t, err)
+
+		_, err = remote.Head(mustParseReference(t, destRepo+"@"+bundleInfo.Digest))
+		require.NoError(t, err)
+	})
+
+	t.Run("does not copy anything when images of the bundle are not signed", func(t *testing.T) {
+		destRepo := fakeRegistry.ReferenceOnTestServer("library/bundle-unverified-copy")
+		_, err := subject.CopyToRepo(destRepo)
+		require.Error(t, err)
+
+		assert.Regexp(t, `Expected images to be signed with one of the verification keys, but \d+ images could not be verified`, err.Error())
+		assert.Contains(t, err.Error(), "no signature found")
+		assert.NotContains(t, err.Error(), bundleInfo.RefDigest+":")
+
+		_, err = remote.Head(mustParseReference(t, destRepo+"@"+bundleInfo.Digest))
+		require.Error(t, err)
+	})
+}
+
+func TestToRepoBundleWithPolicy(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	bundleInfo := fakeRegistry.WithBundleFromPath("library/bundle", "test_assets/bundle").
+		WithEveryImageFromPath("test_assets/image_with_config", map[string]string{})
+	signedBundleInfo := fakeRegistry.WithBundleFromPath("library/signed-bundle", "test_assets/bundle").
+		WithEveryImageFromPath("test_assets/image_with_config", map[string]string{})
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	fakeRegistry.WithCosignSignature(signedBundleInfo.RefDigest, signedBundleInfo.Digest, key)
+
+	reg := fakeRegistry.Build()
+
+	strictPolicy := policy.Policy{
+		AllowedRegistries:       []string{"gcr.io"},
+		RequireBundleSignatures: true,
+		MaxTotalSizeBytes:       1,
+	}
+
+	subject := subject
+	subject.registry = reg
+
+	t.Run("does not copy anything when bundle violates the policy", func(t *testing.T) {
+		subject := subject
+		subject.BundleFlags = BundleFlags{bundleInfo.RefDigest}
+		subject.policyEvaluator = policy.NewEvaluator(strictPolicy, reg, signature.NewCosign(reg), 1)
+
+		destRepo := fakeRegistry.ReferenceOnTestServer("library/bundle-violating-copy")
+		_, err := subject.CopyToRepo(destRepo)
+		require.Error(t, err)
+
+		assert.Contains(t, err.Error(), "Expected images to satisfy policy, but found")
+		assert.Contains(t, err.Error(), fmt.Sprintf("[allowedRegistries] %s: registry '%s' is not allowed", bundleInfo.RefDigest, fakeRegistry.Host()))
+		assert.Contains(t, err.Error(), fmt.Sprintf("[requireBundleSignatures] %s: bundle is not signed", bundleInfo.RefDigest))
+		assert.Contains(t, err.Error(), "[maxTotalSizeBytes] total size of images is")
+
+		_, err = remote.Head(mustParseReference(t, destRepo+"@"+bundleInfo.Digest))
+		require.Error(t, err)
+	})
+
+	t.Run("copies the bundle when violations are only reported", func(t *testing.T) {
+		subject := subject
+		subject.BundleFlags = BundleFlags{bundleInfo.RefDigest}
+		subject.policyEvaluator = policy.NewEvaluator(strictPolicy, reg, signature.NewCosign(reg), 1)
+		subject.PolicyMode = policyModeWarn
+
+		destRepo := fakeRegistry.ReferenceOnTestServer("library/bundle-warned-copy")
+		_, err := subject.CopyToRepo(destRepo)
+		require.NoError(t, err)
+
+		_, err = remote.Head(mustParseReference(t, destRepo+"@"+bundleInfo.Digest))
+		require.NoError(t, err)
+	})
+
+	t.Run("copies a signed bundle satisfying the policy", func(t *testing.T) {
+		subject := subject
+		subject.BundleFlags = BundleFlags{signedBundleInfo.RefDigest}
+		subject.policyEvaluator = policy.NewEvaluator(policy.Policy{
+			AllowedRegistries:       []string{fakeRegistry.Host()},
+			RequireBundleSignatures: true,
+		}, reg, signature.NewCosign(reg), 1)
+
+		destRepo := fakeRegistry.ReferenceOnTestServer("library/signed-bundle-copy")
+		_, err := subject.CopyToRepo(destRepo)
+		require.NoError(t, err)
+
+		_, err = remote.Head(mustParseReference(t, destRepo+"@"+signedBundleInfo.Digest))
+		require.NoError(t, err)
+	})
+}
+
+func TestToRepoImageWithPolicy(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	img := fakeRegistry.WithImageFromPath("library/image", "test_assets/image_with_config", map[string]string{}).
+		WithNonDistributableLayer()
+	fakeRegistry.Build()
+
+	tempDir := t.TempDir()
+
+	imagesLockPath := filepath.Join(tempDir, "images.yml")
+	require.NoError(t, ioutil.WriteFile(imagesLockPath, []byte(fmt.Sprintf(`---
+apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: ImagesLock
+images:
+- image: %s
+  annotations:
+    kbld.carvel.dev/id: %s
+`, img.RefDigest, fakeRegistry.ReferenceOnTestServer("library/image:latest"))), 0600))
+
+	policyPath := filepath.Join(tempDir, "policy.yml")
+	require.NoError(t, ioutil.WriteFile(policyPath, []byte(`---
+apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: Policy
+rejectMutableTags: true
+mutableTags: [latest]
+forbidNonDistributableLayers: true
+`), 0600))
+
+	t.Run("does not copy images violating the policy file", func(t *testing.T) {
+		destRepo := fakeRegistry.ReferenceOnTestServer("library/image-copy")
+		reportPath := filepath.Join(tempDir, "policy-report.yml")
+
+		copyOpts := CopyOptions{
+			LockInputFlags: LockInputFlags{LockFilePath: imagesLockPath},
+			PolicyFlags:    PolicyFlags{Path: policyPath, Mode: policyModeEnforce, ReportPath: reportPath},
+			RepoDsts:       []string{destRepo},
+			Concurrency:    1,
+		}
+		err := copyOpts.Run()
+		require.Error(t, err)
+
+		assert.Contains(t, err.Error(), "Expected images to satisfy policy, but found 2 violations")
+		assert.Contains(t, err.Error(), fmt.Sprintf("[forbidNonDistributableLayers] %s: contains non-distributable layers", img.RefDigest))
+		assert.Contains(t, err.Error(), fmt.Sprintf("[rejectMutableTags] %s: referenced by mutable tag", img.RefDigest))
+
+		_, err = remote.Head(mustParseReference(t, destRepo+"@"+img.Digest))
+		require.Error(t, err)
+
+		bs, err := ioutil.ReadFile(reportPath)
+		require.NoError(t, err)
+		var report policy.Report
+		require.NoError(t, yaml.Unmarshal(bs, &report))
+		assert.Equal(t, policy.ReportKind, report.Kind)
+		assert.Equal(t, policyModeEnforce, report.Mode)
+		require.Len(t, report.Violations, 2)
+		assert.Equal(t, policy.NonDistributableLayersRule, report.Violations[0].Rule)
+		assert.Equal(t, img.RefDigest, report.Violations[0].Image)
+		assert.Equal(t, policy.RejectMutableTagsRule, report.Violations[1].Rule)
+	})
+
+	t.Run("rejects policy report without policy", func(t *testing.T) {
+		copyOpts := CopyOptions{
+			LockInputFlags: LockInputFlags{LockFilePath: imagesLockPath},
+			PolicyFlags:    PolicyFlags{ReportPath: filepath.Join(tempDir, "report.yml")},
+			RepoDsts:       []string{fakeRegistry.ReferenceOnTestServer("library/image-copy")},
+			Concurrency:    1,
+		}
+		err := copyOpts.Run()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Expected --policy-report to be used with --policy")
+	})
+
+	t.Run("rejects unknown policy mode", func(t *testing.T) {
+		copyOpts := CopyOptions{
+			LockInputFlags: LockInputFlags{LockFilePath: imagesLockPath},
+			PolicyFlags:    PolicyFlags{Path: policyPath, Mode: "audit"},
+			RepoDsts:       []string{fakeRegistry.ReferenceOnTestServer("library/image-copy")},
+			Concurrency:    1,
+		}
+		err := copyOpts.Run()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Unknown --policy-mode 'audit'")
+	})
+}
@@ -0,0 +1,403 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	ctlbundle "github.com/k14s/imgpkg/pkg/imgpkg/bundle"
+	ctlimg "github.com/k14s/imgpkg/pkg/imgpkg/image"
+	"github.com/k14s/imgpkg/pkg/imgpkg/imagedesc"
+	ctlimgset "github.com/k14s/imgpkg/pkg/imgpkg/imageset"
+	"github.com/k14s/imgpkg/pkg/imgpkg/imagetar"
+	"github.com/k14s/imgpkg/pkg/imgpkg/signature"
+	"github.com/k14s/imgpkg/pkg/imgpkg/util"
+)
+
+const copyReportLocationsType = "locations"
+
+// CopyReport describes images written by a copy
+type CopyReport struct {
+	Images   []CopyReportImage `json:"images"`
+	Totals   CopyReportTotals  `json:"totals"`
+	Warnings []string          `json:"warnings"`
+}
+
+type CopyReportImage struct {
+	// Source is empty for images created during copy (e.g. locations images)
+	Source      string   `json:"source,omitempty"`
+	Destination string   `json:"destination"`
+	Tags        []string `json:"tags"`
+	Type        string   `json:"type"`
+	// Bytes is the size of manifests, configs and layers of the image (0 when image was already present)
+	Bytes          int64 `json:"bytes"`
+	AlreadyPresent bool  `json:"alreadyPresent,omitempty"`
+	// DurationSeconds is the time until the image was written to the repository (see imageset.ProcessedImage.Duration).
+	// It is not set for images that were already present or written to a tarball.
+	DurationSeconds float64 `json:"durationSeconds,omitempty"`
+}
+
+type CopyReportTotals struct {
+	Images int `json:"images"`
+	// Bytes is the size of manifests, configs and layers that were transferred, counted once per destination
+	Bytes int64 `json:"bytes"`
+	// DurationSeconds covers the whole copy
+	DurationSeconds float64 `json:"durationSeconds"`
+}
+
+// copyReportBuilder collects copied images into a CopyReport
+type copyReportBuilder struct {
+	startedAt time.Time
+	// sizes are kept for each destination (repository or tarball) since blobs are transferred to each of them
+	sizes                   map[string]*blobSizes
+	includeNonDistributable bool
+	report                  CopyReport
+	// bundleDigests identifies bundles from what was collected during the copy,
+	// so that configs of copied images do not need to be fetched again
+	bundleDigests map[string]struct{}
+	// tagStrategy describes tags written for images copied to a repository
+	tagStrategy ctlimgset.DestinationTagStrategy
+	logger      util.LoggerWithLevels
+}
+
+func newCopyReportBuilder(includeNonDistributable bool, tagStrategy ctlimgset.DestinationTagStrategy, logger util.LoggerWithLevels) *copyReportBuilder {
+	return &copyReportBuilder{
+		startedAt:               time.Now(),
+		tagStrategy:             tagStrategy,
+		logger:                  logger,
+		sizes:                   map[string]*blobSizes{},
+		includeNonDistributable: includeNonDistributable,
+		report:                  CopyReport{Images: []CopyReportImage{}, Warnings: []string{}},
+		bundleDigests:           map[string]struct{}{},
+	}
+}
+
+// AddBundles records bundles found while collecting images to copy from a registry
+func (b *copyReportBuilder) AddBundles(bundles []*ctlbundle.Bundle) error {
+	for _, bundle := range bundles {
+		bundleRef, err := regname.NewDigest(bundle.DigestRef())
+		if err != nil {
+			return err
+		}
+		b.bundleDigests[bundleRef.DigestStr()] = struct{}{}
+	}
+	return nil
+}
+
+// AddTarBundles records bundles found in the tarball images are copied from
+func (b *copyReportBuilder) AddTarBundles(tarPath string) error {
+	imgOrIndexes, err := imagetar.NewTarReader(tarPath).Read()
+	if err != nil {
+		return err
+	}
+	return b.addTarBundles(imgOrIndexes)
+}
+
+// addTarBundles reads configs from descriptors of the tarball, hence nothing is fetched from a registry
+func (b *copyReportBuilder) addTarBundles(imgOrIndexes []imagedesc.ImageOrIndex) error {
+	for _, item := range imgOrIndexes {
+		if item.Image == nil {
+			continue
+		}
+
+		cfg, err := (*item.Image).ConfigFile()
+		if err != nil {
+			return fmt.Errorf("Reading config of image %s: %s", item.Ref(), err)
+		}
+		if _, found := cfg.Config.Labels[ctlbundle.BundleConfigLabel]; !found {
+			continue
+		}
+
+		digest, err := (*item.Image).Digest()
+		if err != nil {
+			return err
+		}
+		b.bundleDigests[digest.String()] = struct{}{}
+	}
+	return nil
+}
+
+// AddProcessedImages records images copied to a repository,
+// including locations images that were created for copied bundles
+func (b *copyReportBuilder) AddProcessedImages(processedImages *ctlimgset.ProcessedImages, registry ctlimg.ImagesMetadata) error {
+	for _, item := range processedImages.All() {
+		destRef, err := regname.NewDigest(item.DigestRef)
+		if err != nil {
+			return err
+		}
+
+		digest, err := regv1.NewHash(destRef.DigestStr())
+		if err != nil {
+			return err
+		}
+
+		tags, err := b.tagStrategy.Tags(destRef.Context(), digest, item.UnprocessedImageRef.Tag)
+		if err != nil {
+			return err
+		}
+
+		reportImage := CopyReportImage{
+			Source:          item.UnprocessedImageRef.DigestRef,
+			Destination:     item.DigestRef,
+			Tags:            tags,
+			AlreadyPresent:  item.AlreadyPresent,
+			DurationSeconds: item.Duration.Seconds(),
+		}
+
+		err = b.addImage(reportImage, destRef.Context().Name(), item.Labels, item.Image, item.ImageIndex)
+		if err != nil {
+			return err
+		}
+
+		if b.report.Images[len(b.report.Images)-1].Type == copyPlanBundleType {
+			err = b.addLocationsImage(destRef, registry)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// AddTarImages records images written to a tarball
+func (b *copyReportBuilder) AddTarImages(tarPath string) error {
+	imgOrIndexes, err := imagetar.NewTarReader(tarPath).Read()
+	if err != nil {
+		return err
+	}
+
+	err = b.addTarBundles(imgOrIndexes)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range imgOrIndexes {
+		tags := []string{}
+		if item.Tag() != "" {
+			tags = append(tags, item.Tag())
+		}
+
+		reportImage := CopyReportImage{Source: item.Ref(), Destination: tarPath, Tags: tags}
+
+		var img regv1.Image
+		var idx regv1.ImageIndex
+		switch {
+		case item.Image != nil:
+			img = *item.Image
+		case item.Index != nil:
+			idx = *item.Index
+		}
+
+		err = b.addImage(reportImage, tarPath, item.Labels, img, idx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *copyReportBuilder) addImage(reportImage CopyReportImage, destination string, labels map[string]string, img regv1.Image, idx regv1.ImageIndex) error {
+	sizes := b.destinationSizes(destination)
+	if reportImage.AlreadyPresent {
+		// Blobs of images that were already present were not transferred
+		sizes = &blobSizes{seen: map[string]struct{}{}, includeNonDistributable: b.includeNonDistributable}
+	}
+
+	var err error
+	switch {
+	case img != nil:
+		reportImage.Type = copyPlanImageType
+		reportImage.Bytes, err = sizes.regImage(img)
+		if err == nil {
+			var digest regv1.Hash
+			digest, err = img.Digest()
+			if _, isBundle := b.bundleDigests[digest.String()]; isBundle {
+				reportImage.Type = copyPlanBundleType
+			}
+		}
+	case idx != nil:
+		reportImage.Type = copyPlanImageIndexType
+		reportImage.Bytes, err = sizes.regImageIndex(idx)
+	default:
+		panic("Unknown item")
+	}
+	if err != nil {
+		return fmt.Errorf("Describing image %s: %s", reportImage.Source, err)
+	}
+
+	if _, found := labels[rootBundleLabelKey]; found {
+		reportImage.Type = copyPlanBundleType
+	}
+	if artifact, found := signature.ArtifactFromLabels(labels); found {
+		reportImage.Type = artifact.Name
+	}
+	if _, found := labels[ctlimgset.ReferrerLabelKey]; found {
+		reportImage.Type = copyPlanReferrerType
+	}
+	if reportImage.AlreadyPresent {
+		reportImage.Bytes = 0
+	}
+
+	b.report.Images = append(b.report.Images, reportImage)
+	return nil
+}
+
+func (b *copyReportBuilder) addLocationsImage(bundleRef regname.Digest, registry ctlimg.ImagesMetadata) error {
+	locationsRef, err := ctlbundle.NewLocations(b.logger).LocationsRef(bundleRef)
+	if err != nil {
+		return err
+	}
+
+	// Locations images are only created for bundles copied from a registry
+	digest, err := registry.Digest(locationsRef)
+	if err != nil {
+		if transportErr, ok := err.(*transport.Error); ok && transportErr.StatusCode == http.StatusNotFound {
+			return nil
+		}
+		return fmt.Errorf("Fetching locations image '%s': %s", locationsRef.Name(), err)
+	}
+
+	locationsImg, err := registry.Image(locationsRef.Digest(digest.String()))
+	if err != nil {
+		return err
+	}
+
+	size, err := b.destinationSizes(locationsRef.Context().Name()).regImage(locationsImg)
+	if err != nil {
+		return err
+	}
+
+	b.report.Images = append(b.report.Images, CopyReportImage{
+		Destination: locationsRef.Context().Digest(digest.String()).Name(),
+		Tags:        []string{locationsRef.TagStr()},
+		Type:        copyReportLocationsType,
+		Bytes:       size,
+	})
+	return nil
+}
+
+// destinationSizes returns sizes of blobs transferred to the destination
+func (b *copyReportBuilder) destinationSizes(destination string) *blobSizes {
+	sizes, found := b.sizes[destination]
+	if !found {
+		sizes = &blobSizes{seen: map[string]struct{}{}, includeNonDistributable: b.includeNonDistributable}
+		b.sizes[destination] = sizes
+	}
+	return sizes
+}
+
+func (b *copyReportBuilder) Build() CopyReport {
+	var bytes, nonDistributableBytes int64
+	var nonDistributableLayers int
+
+	for _, sizes := range b.sizes {
+		bytes += sizes.bytes
+		nonDistributableBytes += sizes.nonDistributableBytes
+		nonDistributableLayers += sizes.nonDistributableLayers
+	}
+
+	report := b.report
+	report.Totals = CopyReportTotals{
+		Images:          len(report.Images),
+		Bytes:           bytes,
+		DurationSeconds: time.Since(b.startedAt).Seconds(),
+	}
+
+	if nonDistributableLayers > 0 && !b.includeNonDistributable {
+		report.Warnings = append(report.Warnings, fmt.Sprintf(
+			"Skipped %d non-distributable layers (%s); use --include-non-distributable-layers to copy them",
+			nonDistributableLayers, util.FormatBytes(nonDistributableBytes)))
+	}
+
+	return report
+}
+
+func (r CopyReport) WriteToPath(path string) error {
+	bs, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Marshaling copy report: %s", err)
+	}
+
+	err = ioutil.WriteFile(path, append(bs, '\n'), 0600)
+	if err != nil {
+		return fmt.Errorf("Writing copy report: %s", err)
+	}
+
+	return nil
+}
+
+func (s *blobSizes) regImageIndex(idx regv1.ImageIndex) (int64, error) {
+	digest, err := idx.Digest()
+	if err != nil {
+		return 0, err
+	}
+	rawManifest, err := idx.RawManifest()
+	if err != nil {
+		return 0, err
+	}
+	indexManifest, err := idx.IndexManifest()
+	if err != nil {
+		return 0, err
+	}
+
+	size := s.blob(digest.String(), int64(len(rawManifest)))
+
+	for _, manDesc := range indexManifest.Manifests {
+		var childSize int64
+		if manDesc.MediaType.IsIndex() {
+			childIdx, err := idx.ImageIndex(manDesc.Digest)
+			if err != nil {
+				return 0, err
+			}
+			childSize, err = s.regImageIndex(childIdx)
+			if err != nil {
+				return 0, err
+			}
+		} else {
+			childImg, err := idx.Image(manDesc.Digest)
+			if err != nil {
+				return 0, err
+			}
+			childSize, err = s.regImage(childImg)
+			if err != nil {
+				return 0, err
+			}
+		}
+		size += childSize
+	}
+
+	return size, nil
+}
+
+func (s *blobSizes) regImage(img regv1.Image) (int64, error) {
+	digest, err := img.Digest()
+	if err != nil {
+		return 0, err
+	}
+	rawManifest, err := img.RawManifest()
+	if err != nil {
+		return 0, err
+	}
+	manifest, err := img.Manifest()
+	if err != nil {
+		return 0, err
+	}
+
+	size := s.blob(digest.String(), int64(len(rawManifest)))
+	size += s.blob(manifest.Config.Digest.String(), manifest.Config.Size)
+
+	for _, layer := range manifest.Layers {
+		layerSize, _ := s.layer(layer.Digest.String(), layer.Size, layer.MediaType)
+		size += layerSize
+	}
+
+	return size, nil
+}
@@ -0,0 +1,183 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/k14s/imgpkg/pkg/imgpkg/image/imagefakes"
+	"github.com/k14s/imgpkg/pkg/imgpkg/imageset"
+	"github.com/k14s/imgpkg/pkg/imgpkg/lockconfig"
+	"github.com/k14s/imgpkg/test/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyReport(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	randomImage := fakeRegistry.WithRandomImage("library/image_with_config")
+	randomImageWithNonDistributableLayer := fakeRegistry.
+		WithRandomImage("library/image_with_non_dist_layer").WithNonDistributableLayer()
+
+	rootBundle := fakeRegistry.WithBundleFromPath("library/bundle", "test_assets/bundle_with_mult_images").
+		WithImageRefs([]lockconfig.ImageRef{
+			{Image: randomImage.RefDigest},
+			{Image: randomImageWithNonDistributableLayer.RefDigest},
+		})
+
+	subject := subject
+	subject.BundleFlags = BundleFlags{rootBundle.RefDigest}
+	subject.registry = fakeRegistry.Build()
+
+	destRepo := fakeRegistry.ReferenceOnTestServer("library/bundle-copy")
+
+	reportImagesBySource := func(report CopyReport) map[string]CopyReportImage {
+		result := map[string]CopyReportImage{}
+		for _, img := range report.Images {
+			result[img.Source] = img
+		}
+		return result
+	}
+
+	t.Run("repository to repository", func(t *testing.T) {
+		builder := newCopyReportBuilder(false, imageset.DestinationTagStrategy{}, subject.logger)
+		subject := subject
+		subject.reportBuilder = builder
+
+		processedImages, err := subject.CopyToRepo(destRepo)
+		require.NoError(t, err)
+
+		require.NoError(t, builder.AddProcessedImages(processedImages, subject.registry))
+		report := builder.Build()
+
+		require.Len(t, report.Images, 4)
+		assert.Equal(t, 4, report.Totals.Images)
+
+		reportImages := reportImagesBySource(report)
+
+		bundleImage := reportImages[rootBundle.RefDigest]
+		assert.Equal(t, copyPlanBundleType, bundleImage.Type)
+		assert.Equal(t, destRepo+"@"+rootBundle.Digest, bundleImage.Destination)
+		assert.Equal(t, []string{"sha256-" + rootBundle.Digest[len("sha256:"):] + ".imgpkg"}, bundleImage.Tags)
+
+		assert.Equal(t, copyPlanImageType, reportImages[randomImage.RefDigest].Type)
+		assert.Equal(t, destRepo+"@"+randomImage.Digest, reportImages[randomImage.RefDigest].Destination)
+
+		locationsImage := reportImages[""]
+		assert.Equal(t, copyReportLocationsType, locationsImage.Type)
+		assert.Equal(t, []string{"sha256-" + rootBundle.Digest[len("sha256:"):] + ".image-locations.imgpkg"}, locationsImage.Tags)
+
+		var imagesBytes int64
+		for _, img := range report.Images {
+			assert.NotZero(t, img.Bytes)
+			imagesBytes += img.Bytes
+			if img.Source != "" {
+				assert.NotZero(t, img.DurationSeconds, img.Source)
+			}
+		}
+		assert.Equal(t, imagesBytes, report.Totals.Bytes)
+
+		require.Len(t, report.Warnings, 1)
+		assert.Contains(t, report.Warnings[0], "Skipped 1 non-distributable layers")
+	})
+
+	t.Run("images already present in the repository are reported without bytes", func(t *testing.T) {
+		builder := newCopyReportBuilder(false, imageset.DestinationTagStrategy{}, subject.logger)
+		subject := subject
+		subject.reportBuilder = builder
+
+		processedImages, err := subject.CopyToRepo(destRepo)
+		require.NoError(t, err)
+
+		require.NoError(t, builder.AddProcessedImages(processedImages, subject.registry))
+		report := builder.Build()
+
+		for source, img := range reportImagesBySource(report) {
+			if source == "" {
+				continue
+			}
+			assert.True(t, img.AlreadyPresent)
+			assert.Zero(t, img.Bytes)
+			assert.Zero(t, img.DurationSeconds)
+		}
+	})
+
+	t.Run("repository to tarball", func(t *testing.T) {
+		tarPath := filepath.Join(os.TempDir(), "report-bundle.tar")
+		defer os.Remove(tarPath)
+
+		require.NoError(t, subject.CopyToTar(tarPath))
+
+		builder := newCopyReportBuilder(false, imageset.DestinationTagStrategy{}, subject.logger)
+		require.NoError(t, builder.AddTarImages(tarPath))
+		report := builder.Build()
+
+		require.Len(t, report.Images, 3)
+		for _, img := range report.Images {
+			assert.Equal(t, tarPath, img.Destination)
+		}
+		assert.Equal(t, copyPlanBundleType, reportImagesBySource(report)[rootBundle.RefDigest].Type)
+
+		reportPath := filepath.Join(os.TempDir(), "report.json")
+		defer os.Remove(reportPath)
+		require.NoError(t, report.WriteToPath(reportPath))
+
+		bs, err := ioutil.ReadFile(reportPath)
+		require.NoError(t, err)
+
+		var readReport CopyReport
+		require.NoError(t, json.Unmarshal(bs, &readReport))
+		assert.Equal(t, report, readReport)
+	})
+
+	t.Run("tarball to repository", func(t *testing.T) {
+		tarPath := filepath.Join(os.TempDir(), "report-bundle.tar")
+		defer os.Remove(tarPath)
+
+		require.NoError(t, subject.CopyToTar(tarPath))
+
+		tarDestRepo := fakeRegistry.ReferenceOnTestServer("library/bundle-from-tar")
+		importRepo, err := name.NewRepository(tarDestRepo)
+		require.NoError(t, err)
+
+		processedImages, err := subject.tarImageSet.Import(tarPath, importRepo, subject.registry)
+		require.NoError(t, err)
+
+		builder := newCopyReportBuilder(false, imageset.DestinationTagStrategy{}, subject.logger)
+		require.NoError(t, builder.AddTarBundles(tarPath))
+		require.NoError(t, builder.AddProcessedImages(processedImages, subject.registry))
+		report := builder.Build()
+
+		require.Len(t, report.Images, 3)
+		reportImages := reportImagesBySource(report)
+		assert.Equal(t, copyPlanBundleType, reportImages[rootBundle.RefDigest].Type)
+		assert.Equal(t, tarDestRepo+"@"+rootBundle.Digest, reportImages[rootBundle.RefDigest].Destination)
+	})
+
+	t.Run("locations image lookup errors other than not found are returned", func(t *testing.T) {
+		bundleRef, err := name.NewDigest(destRepo + "@" + rootBundle.Digest)
+		require.NoError(t, err)
+
+		fakeImagesMetadata := &imagefakes.FakeImagesMetadata{}
+		builder := newCopyReportBuilder(false, imageset.DestinationTagStrategy{}, subject.logger)
+
+		fakeImagesMetadata.DigestReturns(regv1.Hash{}, &transport.Error{StatusCode: http.StatusNotFound})
+		require.NoError(t, builder.addLocationsImage(bundleRef, fakeImagesMetadata))
+		assert.Empty(t, builder.Build().Images)
+
+		fakeImagesMetadata.DigestReturns(regv1.Hash{}, &transport.Error{StatusCode: http.StatusInternalServerError})
+		err = builder.addLocationsImage(bundleRef, fakeImagesMetadata)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Fetching locations image")
+	})
+}
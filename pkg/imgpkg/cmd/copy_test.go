@@ -6,15 +6,17 @@ package cmd
 import (
 	"strings"
 	"testing"
+
+	"github.com/cppforlife/go-cli-ui/ui"
 )
 
 func TestMultiDest(t *testing.T) {
-	err := (&CopyOptions{RepoDst: "foo", TarFlags: TarFlags{TarDst: "bar", TarSrc: "foo"}}).Run()
+	err := (&CopyOptions{RepoDsts: []string{"foo"}, TarFlags: TarFlags{TarDst: "bar", TarSrc: "foo"}}).Run()
 	if err == nil {
 		t.Fatalf("Expected Run() to err")
 	}
 
-	if !strings.Contains(err.Error(), "Expected either --to-tar or --to-repo") {
+	if !strings.Contains(err.Error(), "Expected either --to-tar, --to-repo or --to-registry-prefix") {
 		t.Fatalf("Expected error message related to destinations, got: %s", err)
 	}
 }
@@ -25,7 +27,7 @@ func TestNoDest(t *testing.T) {
 		t.Fatalf("Expected Run() to err")
 	}
 
-	if !strings.Contains(err.Error(), "Expected either --to-tar or --to-repo") {
+	if !strings.Contains(err.Error(), "Expected either --to-tar, --to-repo or --to-registry-prefix") {
 		t.Fatalf("Expected error message related to destinations, got: %s", err)
 	}
 
@@ -65,3 +67,29 @@ func TestTarSrcWithTarDst(t *testing.T) {
 		t.Fatalf("Expected error message related to destinations, got: %s", err)
 	}
 }
+
+func TestRecompressWithTarDst(t *testing.T) {
+	err := (&CopyOptions{ImageFlags: ImageFlags{Image: "foo"}, TarFlags: TarFlags{TarDst: "bar"}, Recompress: "zstd"}).Run()
+	if err == nil {
+		t.Fatalf("Expected Run() to err")
+	}
+
+	if !strings.Contains(err.Error(), "Expected --recompress to be used with --to-repo") {
+		t.Fatalf("Expected error message related to recompression, got: %s", err)
+	}
+}
+
+func TestExcludeImageFlagKeepsRegexpsWithCommas(t *testing.T) {
+	confUI := ui.NewConfUI(ui.NewNoopLogger())
+	defer confUI.Flush()
+
+	copyOpts := NewCopyOptions(confUI)
+	err := NewCopyCmd(copyOpts).ParseFlags([]string{"--exclude-image", "app{1,3}", "--exclude-image", "gpu"})
+	if err != nil {
+		t.Fatalf("Expected flags to be parsed, got: %s", err)
+	}
+
+	if len(copyOpts.ImageFilterFlags.ExcludeImages) != 2 || copyOpts.ImageFilterFlags.ExcludeImages[0] != "app{1,3}" {
+		t.Fatalf("Expected --exclude-image values to be kept as is, got: %v", copyOpts.ImageFilterFlags.ExcludeImages)
+	}
+}
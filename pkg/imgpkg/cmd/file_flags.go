@@ -11,10 +11,13 @@ type FileFlags struct {
 	Files []string
 
 	ExcludedFilePaths []string
+
+	FromTar string
 }
 
 func (f *FileFlags) Set(cmd *cobra.Command) {
-	cmd.Flags().StringSliceVarP(&f.Files, "file", "f", nil, "Set file (format: /tmp/foo) (can be specified multiple times)")
+	cmd.Flags().StringSliceVarP(&f.Files, "file", "f", nil, "Set file (format: /tmp/foo) (can be specified multiple times) (use '-' to read a tar stream from stdin)")
+	cmd.Flags().StringVar(&f.FromTar, "from-tar", "", "Use an existing tar file, as is, as the only layer instead of files (format: /tmp/foo.tar)")
 
 	cmd.Flags().StringSliceVar(&f.ExcludedFilePaths, "file-exclude-defaults", []string{".git"}, "Excluded file paths by default (can be specified multiple times)")
 	cmd.Flags().MarkDeprecated("file-exclude-defaults", "use '--file-exclusion' instead")
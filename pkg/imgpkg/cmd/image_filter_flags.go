@@ -0,0 +1,32 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	ctlbundle "github.com/k14s/imgpkg/pkg/imgpkg/bundle"
+	"github.com/spf13/cobra"
+)
+
+type ImageFilterFlags struct {
+	IncludeAnnotations []string
+	ExcludeAnnotations []string
+	ExcludeImages      []string
+}
+
+func (f *ImageFilterFlags) Set(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&f.IncludeAnnotations, "include-annotation", nil,
+		"Only copy images whose images lock annotations match (format: key=value or key) (can be specified multiple times)")
+	cmd.Flags().StringSliceVar(&f.ExcludeAnnotations, "exclude-annotation", nil,
+		"Do not copy images whose images lock annotations match (format: key=value or key) (can be specified multiple times)")
+	cmd.Flags().StringArrayVar(&f.ExcludeImages, "exclude-image", nil,
+		"Do not copy images whose reference matches regular expression (format: 'windows|gpu') (can be specified multiple times)")
+}
+
+func (f ImageFilterFlags) IsSet() bool {
+	return len(f.IncludeAnnotations) > 0 || len(f.ExcludeAnnotations) > 0 || len(f.ExcludeImages) > 0
+}
+
+func (f ImageFilterFlags) ImageFilter() (ctlbundle.ImageFilter, error) {
+	return ctlbundle.NewImageFilter(f.IncludeAnnotations, f.ExcludeAnnotations, f.ExcludeImages)
+}
@@ -46,12 +46,25 @@ func NewImgpkgCmd(o *ImgpkgOptions) *cobra.Command {
 	cmd.AddCommand(NewPushCmd(NewPushOptions(o.ui)))
 	cmd.AddCommand(NewPullCmd(NewPullOptions(o.ui)))
 	cmd.AddCommand(NewVersionCmd(NewVersionOptions(o.ui)))
-	cmd.AddCommand(NewCopyCmd(NewCopyOptions()))
+	cmd.AddCommand(NewCopyCmd(NewCopyOptions(o.ui)))
+
+	cmd.AddCommand(NewAttachCmd(NewAttachOptions(o.ui)))
+
+	attachmentsCmd := NewAttachmentsCmd()
+	attachmentsCmd.AddCommand(NewAttachmentsListCmd(NewAttachmentsListOptions(o.ui)))
+	attachmentsCmd.AddCommand(NewAttachmentsPullCmd(NewAttachmentsPullOptions(o.ui)))
+	cmd.AddCommand(attachmentsCmd)
 
 	tagCmd := NewTagCmd()
 	tagCmd.AddCommand(NewTagListCmd(NewTagListOptions(o.ui)))
 	cmd.AddCommand(tagCmd)
 
+	lockCmd := NewLockCmd()
+	lockCmd.AddCommand(NewLockGenerateCmd(NewLockGenerateOptions(o.ui)))
+	lockCmd.AddCommand(NewLockUpdateCmd(NewLockUpdateOptions(o.ui)))
+	lockCmd.AddCommand(NewLockConvertCmd(NewLockConvertOptions(o.ui)))
+	cmd.AddCommand(lockCmd)
+
 	// Last one runs first
 	cobrautil.VisitCommands(cmd, cobrautil.ReconfigureCmdWithSubcmd)
 	cobrautil.VisitCommands(cmd, cobrautil.DisallowExtraArgs)
@@ -0,0 +1,65 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+
+	ctlimg "github.com/k14s/imgpkg/pkg/imgpkg/image"
+	"github.com/spf13/cobra"
+)
+
+type LayerFlags struct {
+	LayerPerFile bool
+	LayerPerDir  bool
+
+	Compression      string
+	CompressionLevel int
+}
+
+func (l *LayerFlags) Set(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&l.LayerPerFile, "layer-per-file", false, "Place contents of each provided file path (-f) in its own layer")
+	cmd.Flags().BoolVar(&l.LayerPerDir, "layer-per-dir", false, "Place each top level directory in its own layer (top level files share a layer)")
+	cmd.Flags().StringVar(&l.Compression, "compression", string(ctlimg.GzipCompression),
+		fmt.Sprintf("Layer compression (%s, %s)", ctlimg.GzipCompression, ctlimg.ZstdCompression))
+	cmd.Flags().IntVar(&l.CompressionLevel, "compression-level", 0,
+		"Layer compression level, gzip: 1 (fastest) to 9 (smallest), zstd: 1 to 22 (default fastest gzip level or zstd level 3)")
+}
+
+func (l LayerFlags) LayerOpts() (ctlimg.LayerOpts, error) {
+	compression := ctlimg.GzipCompression
+	if l.Compression != "" {
+		var err error
+		compression, err = ctlimg.NewCompression(l.Compression)
+		if err != nil {
+			return ctlimg.LayerOpts{}, err
+		}
+	}
+
+	// Zero value falls back to the default level
+	minLevel, maxLevel := compression.LevelRange()
+	if l.CompressionLevel != 0 && (l.CompressionLevel < minLevel || l.CompressionLevel > maxLevel) {
+		return ctlimg.LayerOpts{}, fmt.Errorf("Expected --compression-level to be between %d and %d for %s compression", minLevel, maxLevel, compression)
+	}
+
+	selector, err := l.layerSelector()
+	if err != nil {
+		return ctlimg.LayerOpts{}, err
+	}
+
+	return ctlimg.LayerOpts{Selector: selector, Compression: compression, CompressionLevel: l.CompressionLevel}, nil
+}
+
+func (l LayerFlags) layerSelector() (ctlimg.LayerSelector, error) {
+	switch {
+	case l.LayerPerFile && l.LayerPerDir:
+		return nil, fmt.Errorf("Expected only one of --layer-per-file or --layer-per-dir")
+	case l.LayerPerFile:
+		return ctlimg.LayerPerFilePath{}, nil
+	case l.LayerPerDir:
+		return ctlimg.LayerPerDir{}, nil
+	default:
+		return ctlimg.SingleLayer{}, nil
+	}
+}
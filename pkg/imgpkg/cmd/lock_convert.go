@@ -0,0 +1,116 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	"github.com/k14s/imgpkg/pkg/imgpkg/lockconfig"
+	"github.com/spf13/cobra"
+)
+
+const (
+	imgpkgLockFormat = "imgpkg"
+	kbldLockFormat   = "kbld"
+)
+
+type LockConvertOptions struct {
+	ui ui.UI
+
+	LockFilePath string
+	OutputPath   string
+	Format       string
+}
+
+func NewLockConvertOptions(ui ui.UI) *LockConvertOptions {
+	return &LockConvertOptions{ui: ui}
+}
+
+func NewLockConvertCmd(o *LockConvertOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "convert",
+		Short: "Convert between images lock and kbld lock files",
+		RunE:  func(_ *cobra.Command, _ []string) error { return o.Run() },
+		Example: `
+  # Convert kbld lock file into bundle images lock file
+  imgpkg lock convert --lock kbld.lock.yml --output config/.imgpkg/images.yml
+
+  # Convert images lock file into kbld lock file
+  imgpkg lock convert --lock config/.imgpkg/images.yml --output kbld.lock.yml`,
+	}
+	cmd.Flags().StringVar(&o.LockFilePath, "lock", "", "Images lock or kbld lock file to convert")
+	cmd.Flags().StringVarP(&o.OutputPath, "output", "o", "", "Location to write the converted lock file (prints to stdout if not set)")
+	cmd.Flags().StringVar(&o.Format, "format", "",
+		fmt.Sprintf("Format to convert to (%s, %s) (default is the format --lock is not in)", imgpkgLockFormat, kbldLockFormat))
+	cmd.MarkFlagRequired("lock")
+	return cmd
+}
+
+type lockFile interface {
+	AsBytes() ([]byte, error)
+	WriteToPath(string) error
+}
+
+func (o *LockConvertOptions) Run() error {
+	if o.LockFilePath == "" {
+		return fmt.Errorf("Expected --lock to be provided")
+	}
+
+	var isKbldLock bool
+	var imagesLock lockconfig.ImagesLock
+
+	kbldLock, err := lockconfig.NewKbldLockFromPath(o.LockFilePath)
+	if err == nil {
+		isKbldLock = true
+		imagesLock = kbldLock.AsImagesLock()
+	} else {
+		bundleLock, inputImagesLock, err := lockconfig.NewLockFromPath(o.LockFilePath)
+		if err != nil {
+			return err
+		}
+		if bundleLock != nil {
+			return fmt.Errorf("Expected '%s' to be an images lock or kbld lock file, but was a bundle lock file", o.LockFilePath)
+		}
+		imagesLock = *inputImagesLock
+	}
+
+	format := o.Format
+	switch {
+	case format == "" && isKbldLock:
+		format = imgpkgLockFormat
+	case format == "":
+		format = kbldLockFormat
+	}
+
+	var result lockFile
+
+	switch format {
+	case imgpkgLockFormat:
+		result = imagesLock
+	case kbldLockFormat:
+		result = lockconfig.NewKbldLockFromImagesLock(imagesLock)
+	default:
+		return fmt.Errorf("Unknown --format '%s' (known: %s, %s)", o.Format, imgpkgLockFormat, kbldLockFormat)
+	}
+
+	if o.OutputPath == "" {
+		bs, err := result.AsBytes()
+		if err != nil {
+			return err
+		}
+
+		o.ui.PrintBlock(bs)
+		return nil
+	}
+
+	err = result.WriteToPath(o.OutputPath)
+	if err != nil {
+		return err
+	}
+
+	o.ui.BeginLinef("Wrote %s lock to '%s'", format, o.OutputPath)
+
+	return nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	goui "github.com/cppforlife/go-cli-ui/ui"
+	"github.com/k14s/imgpkg/pkg/imgpkg/lockconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockConvert(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "imgpkg-lock-convert")
+	require.NoError(t, err)
+	defer Cleanup(tempDir)
+
+	confUI := goui.NewConfUI(goui.NewNoopLogger())
+	defer confUI.Flush()
+
+	image := "my.registry.io/app@sha256:a7e8c1d3a2cbbc4f9d5b1cbdd0fdd1e0c7c1a4df0a1a4f7f7c8b1e0b0c8d1a2b"
+
+	imagesLockPath := filepath.Join(tempDir, "images.yml")
+	imagesLock := lockconfig.NewEmptyImagesLock()
+	imagesLock.AddImageRef(lockconfig.ImageRef{Image: image, Annotations: map[string]string{lockconfig.SourceRefAnnotation: "my.registry.io/app:v1"}})
+	require.NoError(t, imagesLock.WriteToPath(imagesLockPath))
+
+	kbldLockPath := filepath.Join(tempDir, "kbld.lock.yml")
+
+	t.Run("converts images lock to kbld lock by default", func(t *testing.T) {
+		lockConvert := LockConvertOptions{ui: confUI, LockFilePath: imagesLockPath, OutputPath: kbldLockPath}
+		require.NoError(t, lockConvert.Run())
+
+		kbldLock, err := lockconfig.NewKbldLockFromPath(kbldLockPath)
+		require.NoError(t, err)
+		assert.Equal(t, []lockconfig.KbldOverride{{Image: "my.registry.io/app:v1", NewImage: image, Preresolved: true}}, kbldLock.Overrides)
+	})
+
+	t.Run("converts kbld lock to images lock by default", func(t *testing.T) {
+		convertedPath := filepath.Join(tempDir, "converted-images.yml")
+
+		lockConvert := LockConvertOptions{ui: confUI, LockFilePath: kbldLockPath, OutputPath: convertedPath}
+		require.NoError(t, lockConvert.Run())
+
+		convertedLock, err := lockconfig.NewImagesLockFromPath(convertedPath)
+		require.NoError(t, err)
+		assert.Equal(t, []lockconfig.ImageRef{
+			{Image: image, Annotations: map[string]string{lockconfig.KbldIDAnnotation: "my.registry.io/app:v1"}},
+		}, convertedLock.Images)
+	})
+
+	t.Run("errors on unknown format", func(t *testing.T) {
+		lockConvert := LockConvertOptions{ui: confUI, LockFilePath: kbldLockPath, Format: "other"}
+		require.EqualError(t, lockConvert.Run(), "Unknown --format 'other' (known: imgpkg, kbld)")
+	})
+}
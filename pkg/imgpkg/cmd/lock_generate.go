@@ -0,0 +1,93 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	"github.com/k14s/imgpkg/pkg/imgpkg/lockgen"
+	"github.com/k14s/imgpkg/pkg/imgpkg/registry"
+	"github.com/spf13/cobra"
+)
+
+type LockGenerateOptions struct {
+	ui ui.UI
+
+	RegistryFlags RegistryFlags
+
+	Files         []string
+	ImageKeyPaths []string
+	OutputPath    string
+}
+
+func NewLockGenerateOptions(ui ui.UI) *LockGenerateOptions {
+	return &LockGenerateOptions{ui: ui}
+}
+
+func NewLockGenerateCmd(o *LockGenerateOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Generate images lock file from image references found in configuration",
+		RunE:  func(_ *cobra.Command, _ []string) error { return o.Run() },
+		Example: `
+  # Resolve images referenced in config/ and write them to the bundle images lock file
+  imgpkg lock generate -f config/ --output config/.imgpkg/images.yml
+
+  # Only consider images of containers
+  imgpkg lock generate -f config/ --image-key-path 'containers[].image' --image-key-path 'initContainers[].image'`,
+	}
+	o.RegistryFlags.Set(cmd)
+	cmd.Flags().StringSliceVarP(&o.Files, "file", "f", nil, "Set file or directory to search for image references (format: /tmp/foo) (can be specified multiple times)")
+	cmd.Flags().StringSliceVar(&o.ImageKeyPaths, "image-key-path", lockgen.DefaultKeyPaths,
+		"Key path whose values are image references, matched against the end of each value path (format: spec.containers[].image) (can be specified multiple times)")
+	cmd.Flags().StringVarP(&o.OutputPath, "output", "o", "", "Location to write the images lock file (prints to stdout if not set)")
+	cmd.MarkFlagRequired("file")
+	return cmd
+}
+
+func (o *LockGenerateOptions) Run() error {
+	if len(o.Files) == 0 {
+		return fmt.Errorf("Expected at least one --file (-f)")
+	}
+
+	finder, err := lockgen.NewImageRefsFinder(o.ImageKeyPaths)
+	if err != nil {
+		return err
+	}
+
+	refs, err := finder.FindInPaths(o.Files)
+	if err != nil {
+		return err
+	}
+
+	reg, err := registry.NewRegistry(o.RegistryFlags.AsRegistryOpts())
+	if err != nil {
+		return fmt.Errorf("Unable to create a registry with the options %v: %v", o.RegistryFlags.AsRegistryOpts(), err)
+	}
+
+	imagesLock, err := lockgen.NewGenerator(reg).Generate(refs)
+	if err != nil {
+		return err
+	}
+
+	if o.OutputPath == "" {
+		bs, err := imagesLock.AsBytes()
+		if err != nil {
+			return err
+		}
+
+		o.ui.PrintBlock(bs)
+		return nil
+	}
+
+	err = imagesLock.WriteToPath(o.OutputPath)
+	if err != nil {
+		return err
+	}
+
+	o.ui.BeginLinef("Wrote %d images to '%s'", len(imagesLock.Images), o.OutputPath)
+
+	return nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	goui "github.com/cppforlife/go-cli-ui/ui"
+	"github.com/k14s/imgpkg/pkg/imgpkg/lockconfig"
+	"github.com/k14s/imgpkg/pkg/imgpkg/lockgen"
+	"github.com/k14s/imgpkg/test/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockGenerate(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+
+	appImage := fakeRegistry.WithRandomImage("library/app:v1")
+	nestedBundle := fakeRegistry.WithRandomBundle("library/nested-bundle:v1")
+	fakeRegistry.Build()
+
+	configDir, err := os.MkdirTemp("", "imgpkg-lock-generate")
+	require.NoError(t, err)
+	defer Cleanup(configDir)
+
+	appRef := fakeRegistry.ReferenceOnTestServer("library/app:v1")
+	bundleRef := fakeRegistry.ReferenceOnTestServer("library/nested-bundle:v1")
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(configDir, "config.yml"), []byte(fmt.Sprintf(`
+containers:
+- image: %s
+- image: %s
+---
+package:
+  image: %s
+`, appRef, appRef, bundleRef)), 0600))
+
+	confUI := goui.NewConfUI(goui.NewNoopLogger())
+	defer confUI.Flush()
+
+	t.Run("writes resolved images with their original references", func(t *testing.T) {
+		outputPath := filepath.Join(configDir, "images.yml")
+
+		lockGenerate := LockGenerateOptions{ui: confUI, Files: []string{configDir}, OutputPath: outputPath}
+		require.NoError(t, lockGenerate.Run())
+
+		imagesLock, err := lockconfig.NewImagesLockFromPath(outputPath)
+		require.NoError(t, err)
+
+		assert.Equal(t, []lockconfig.ImageRef{
+			{
+				Image:       appImage.RefDigest,
+				Annotations: map[string]string{lockconfig.SourceRefAnnotation: appRef},
+			},
+			{
+				Image:       nestedBundle.RefDigest,
+				Annotations: map[string]string{lockconfig.SourceRefAnnotation: bundleRef, lockgen.BundleAnnotation: "true"},
+			},
+		}, imagesLock.Images)
+	})
+
+	t.Run("fails when an image cannot be resolved", func(t *testing.T) {
+		missingConfigPath := filepath.Join(configDir, "missing.yml")
+		missingRef := fakeRegistry.ReferenceOnTestServer("library/missing:v1")
+		require.NoError(t, ioutil.WriteFile(missingConfigPath, []byte("image: "+missingRef), 0600))
+		defer os.Remove(missingConfigPath)
+
+		lockGenerate := LockGenerateOptions{ui: confUI, Files: []string{missingConfigPath}}
+		err := lockGenerate.Run()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), fmt.Sprintf("Resolving image '%s' found in '%s'", missingRef, missingConfigPath))
+	})
+}
@@ -0,0 +1,111 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	uitable "github.com/cppforlife/go-cli-ui/ui/table"
+	"github.com/k14s/imgpkg/pkg/imgpkg/lockconfig"
+	"github.com/k14s/imgpkg/pkg/imgpkg/lockgen"
+	"github.com/k14s/imgpkg/pkg/imgpkg/registry"
+	"github.com/spf13/cobra"
+)
+
+type LockUpdateOptions struct {
+	ui ui.UI
+
+	RegistryFlags RegistryFlags
+
+	LockFilePath string
+	Check        bool
+}
+
+func NewLockUpdateOptions(ui ui.UI) *LockUpdateOptions {
+	return &LockUpdateOptions{ui: ui}
+}
+
+func NewLockUpdateCmd(o *LockUpdateOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "update",
+		Short: "Update images lock file by resolving source references again",
+		RunE:  func(_ *cobra.Command, _ []string) error { return o.Run() },
+		Example: `
+  # Update digests of images whose tags moved
+  imgpkg lock update --lock config/.imgpkg/images.yml
+
+  # Fail if any image is out of date (e.g. in CI)
+  imgpkg lock update --lock config/.imgpkg/images.yml --check`,
+	}
+	o.RegistryFlags.Set(cmd)
+	cmd.Flags().StringVar(&o.LockFilePath, "lock", "", "Images lock file to update (format: config/.imgpkg/images.yml)")
+	cmd.Flags().BoolVar(&o.Check, "check", false, fmt.Sprintf("Only check, without updating the file, that images resolved from '%s' annotations are up to date", lockconfig.SourceRefAnnotation))
+	cmd.MarkFlagRequired("lock")
+	return cmd
+}
+
+func (o *LockUpdateOptions) Run() error {
+	if o.LockFilePath == "" {
+		return fmt.Errorf("Expected --lock to be provided")
+	}
+
+	imagesLock, err := lockconfig.NewImagesLockFromPath(o.LockFilePath)
+	if err != nil {
+		return err
+	}
+
+	reg, err := registry.NewRegistry(o.RegistryFlags.AsRegistryOpts())
+	if err != nil {
+		return fmt.Errorf("Unable to create a registry with the options %v: %v", o.RegistryFlags.AsRegistryOpts(), err)
+	}
+
+	updatedLock, updates, err := lockgen.NewGenerator(reg).Update(imagesLock)
+	if err != nil {
+		return err
+	}
+
+	if len(updates) == 0 {
+		o.ui.BeginLinef("All images in '%s' are up to date", o.LockFilePath)
+		return nil
+	}
+
+	o.printUpdates(updates)
+
+	if o.Check {
+		return fmt.Errorf("Expected all images in '%s' to be up to date, but %d are stale", o.LockFilePath, len(updates))
+	}
+
+	err = updatedLock.WriteToPath(o.LockFilePath)
+	if err != nil {
+		return err
+	}
+
+	o.ui.BeginLinef("Updated %d images in '%s'", len(updates), o.LockFilePath)
+
+	return nil
+}
+
+func (o *LockUpdateOptions) printUpdates(updates []lockgen.ImageUpdate) {
+	table := uitable.Table{
+		Title:   "Stale images",
+		Content: "images",
+
+		Header: []uitable.Header{
+			uitable.NewHeader("Source"),
+			uitable.NewHeader("Previous image"),
+			uitable.NewHeader("Current image"),
+		},
+	}
+
+	for _, update := range updates {
+		table.Rows = append(table.Rows, []uitable.Value{
+			uitable.NewValueString(update.SourceRef),
+			uitable.NewValueString(update.PreviousImage),
+			uitable.NewValueString(update.Image),
+		})
+	}
+
+	o.ui.PrintTable(table)
+}
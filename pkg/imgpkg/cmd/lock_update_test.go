@@ -0,0 +1,78 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	goui "github.com/cppforlife/go-cli-ui/ui"
+	"github.com/k14s/imgpkg/pkg/imgpkg/lockconfig"
+	"github.com/k14s/imgpkg/test/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockUpdate(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+
+	pinnedImage := fakeRegistry.WithRandomImage("library/pinned")
+	oldAppImage := fakeRegistry.WithRandomImage("library/app:v1")
+	fakeRegistry.Build()
+
+	appRef := fakeRegistry.ReferenceOnTestServer("library/app:v1")
+
+	tempDir, err := os.MkdirTemp("", "imgpkg-lock-update")
+	require.NoError(t, err)
+	defer Cleanup(tempDir)
+
+	lockPath := filepath.Join(tempDir, "images.yml")
+	imagesLock := lockconfig.NewEmptyImagesLock()
+	imagesLock.AddImageRef(lockconfig.ImageRef{
+		Image:       oldAppImage.RefDigest,
+		Annotations: map[string]string{lockconfig.SourceRefAnnotation: appRef, "other": "value"},
+	})
+	imagesLock.AddImageRef(lockconfig.ImageRef{Image: pinnedImage.RefDigest})
+	require.NoError(t, imagesLock.WriteToPath(lockPath))
+
+	confUI := goui.NewConfUI(goui.NewNoopLogger())
+	defer confUI.Flush()
+
+	t.Run("succeeds when all images are up to date", func(t *testing.T) {
+		lockUpdate := LockUpdateOptions{ui: confUI, LockFilePath: lockPath, Check: true}
+		require.NoError(t, lockUpdate.Run())
+	})
+
+	newAppImage := fakeRegistry.WithRandomImage("library/app:v1")
+	fakeRegistry.Build()
+
+	t.Run("check fails without changing the file when images are stale", func(t *testing.T) {
+		lockUpdate := LockUpdateOptions{ui: confUI, LockFilePath: lockPath, Check: true}
+		err := lockUpdate.Run()
+		require.Error(t, err)
+		assert.Equal(t, fmt.Sprintf("Expected all images in '%s' to be up to date, but 1 are stale", lockPath), err.Error())
+
+		currentLock, err := lockconfig.NewImagesLockFromPath(lockPath)
+		require.NoError(t, err)
+		assert.Equal(t, imagesLock.Images, currentLock.Images)
+	})
+
+	t.Run("rewrites stale digests preserving order and annotations", func(t *testing.T) {
+		lockUpdate := LockUpdateOptions{ui: confUI, LockFilePath: lockPath}
+		require.NoError(t, lockUpdate.Run())
+
+		updatedLock, err := lockconfig.NewImagesLockFromPath(lockPath)
+		require.NoError(t, err)
+		assert.Equal(t, []lockconfig.ImageRef{
+			{
+				Image:       newAppImage.RefDigest,
+				Annotations: map[string]string{lockconfig.SourceRefAnnotation: appRef, "other": "value"},
+			},
+			{Image: pinnedImage.RefDigest},
+		}, updatedLock.Images)
+	})
+}
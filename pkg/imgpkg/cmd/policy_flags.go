@@ -0,0 +1,86 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/k14s/imgpkg/pkg/imgpkg/policy"
+	"github.com/k14s/imgpkg/pkg/imgpkg/registry"
+	"github.com/k14s/imgpkg/pkg/imgpkg/signature"
+	"github.com/spf13/cobra"
+)
+
+const (
+	policyModeEnforce = "enforce"
+	policyModeWarn    = "warn"
+)
+
+type PolicyEvaluator interface {
+	Evaluate(images []policy.Image) (policy.Violations, error)
+}
+
+type PolicyFlags struct {
+	Path       string
+	Mode       string
+	ReportPath string
+}
+
+func (p *PolicyFlags) Set(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&p.Path, "policy", "",
+		fmt.Sprintf("Location of a policy file (kind: %s) that images have to satisfy before anything is written", policy.PolicyKind))
+	cmd.Flags().StringVar(&p.Mode, "policy-mode", policyModeEnforce,
+		fmt.Sprintf("Fail on policy violations (%s) or only report them (%s)", policyModeEnforce, policyModeWarn))
+	cmd.Flags().StringVar(&p.ReportPath, "policy-report", "",
+		fmt.Sprintf("Location to write a YAML report (kind: %s) of policy violations, in both %s and %s modes", policy.ReportKind, policyModeEnforce, policyModeWarn))
+}
+
+func (p *PolicyFlags) IsSet() bool { return p.Path != "" }
+
+func (p *PolicyFlags) Validate() error {
+	if p.Mode != "" && p.Mode != policyModeEnforce && p.Mode != policyModeWarn {
+		return fmt.Errorf("Unknown --policy-mode '%s' (known: %s, %s)", p.Mode, policyModeEnforce, policyModeWarn)
+	}
+	if p.ReportPath != "" && !p.IsSet() {
+		return fmt.Errorf("Expected --policy-report to be used with --policy")
+	}
+	return nil
+}
+
+// Evaluator returns an evaluator of the provided policy
+func (p *PolicyFlags) Evaluator(reg registry.Registry, concurrency int) (PolicyEvaluator, error) {
+	pol, err := policy.NewPolicyFromPath(p.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	return policy.NewEvaluator(pol, reg, signature.NewCosign(reg), concurrency), nil
+}
+
+// enforcePolicy returns violations as an error when the policy is enforced, otherwise reports them with warn.
+// Violations are also written to reportPath (when provided) regardless of mode.
+func enforcePolicy(evaluator PolicyEvaluator, mode, reportPath string, images []policy.Image, warn func(string, ...interface{})) error {
+	violations, err := evaluator.Evaluate(images)
+	if err != nil {
+		return err
+	}
+	if mode == "" {
+		mode = policyModeEnforce
+	}
+	if reportPath != "" {
+		err = policy.NewReport(mode, violations).WriteToPath(reportPath)
+		if err != nil {
+			return err
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+
+	if mode == policyModeWarn {
+		warn("%s\n", violations.Error())
+		return nil
+	}
+	return violations
+}
@@ -11,7 +11,9 @@ import (
 	ctlimg "github.com/k14s/imgpkg/pkg/imgpkg/image"
 	"github.com/k14s/imgpkg/pkg/imgpkg/lockconfig"
 	"github.com/k14s/imgpkg/pkg/imgpkg/plainimage"
+	"github.com/k14s/imgpkg/pkg/imgpkg/policy"
 	"github.com/k14s/imgpkg/pkg/imgpkg/registry"
+	"github.com/k14s/imgpkg/pkg/imgpkg/util"
 	"github.com/spf13/cobra"
 )
 
@@ -23,11 +25,16 @@ type PullOptions struct {
 	BundleFlags          BundleFlags
 	LockInputFlags       LockInputFlags
 	BundleRecursiveFlags BundleRecursiveFlags
+	VerifyFlags          VerifyFlags
+	PolicyFlags          PolicyFlags
 	OutputPath           string
 }
 
 var _ ctlimg.ImagesMetadata = registry.Registry{}
 
+// pullVerifyConcurrency is the number of images whose signatures are verified concurrently
+const pullVerifyConcurrency = 5
+
 func NewPullOptions(ui ui.UI) *PullOptions {
 	return &PullOptions{ui: ui}
 }
@@ -42,13 +49,21 @@ func NewPullCmd(o *PullOptions) *cobra.Command {
   imgpkg pull -b repo/app1-bundle -o /tmp/app1-bundle
 
   # Pull image repo/app1-image and extract into /tmp/app1-image
-  imgpkg pull -i repo/app1-image -o /tmp/app1-image`,
+  imgpkg pull -i repo/app1-image -o /tmp/app1-image
+
+  # Pull bundle repo/app1-bundle only if it and its images are signed with the cosign key
+  imgpkg pull -b repo/app1-bundle -o /tmp/app1-bundle --verify-key cosign.pub
+
+  # Pull bundle repo/app1-bundle only if it and its images satisfy the policy
+  imgpkg pull -b repo/app1-bundle -o /tmp/app1-bundle --policy policy.yml`,
 	}
 	o.ImageFlags.Set(cmd)
 	o.RegistryFlags.Set(cmd)
 	o.BundleFlags.Set(cmd)
 	o.BundleRecursiveFlags.Set(cmd)
 	o.LockInputFlags.Set(cmd)
+	o.VerifyFlags.Set(cmd)
+	o.PolicyFlags.Set(cmd)
 	cmd.Flags().StringVarP(&o.OutputPath, "output", "o", "", "Output directory path")
 	cmd.MarkFlagRequired("output")
 
@@ -78,7 +93,23 @@ func (po *PullOptions) Run() error {
 			bundleRef = bundleLock.Bundle.Image
 		}
 
-		err := bundle.NewBundle(bundleRef, reg).Pull(po.OutputPath, po.ui, po.BundleRecursiveFlags.Recursive)
+		bundleToPull := bundle.NewBundle(bundleRef, reg)
+
+		// Images of the bundle are only listed once for both verification and policy evaluation
+		var nestedBundles []*bundle.Bundle
+		var imageRefs bundle.ImageRefs
+		if po.VerifyFlags.IsSet() || po.PolicyFlags.IsSet() {
+			nestedBundles, imageRefs, err = bundleToPull.AllImagesRefs(pullVerifyConcurrency, po.levelLogger())
+		}
+		if err == nil {
+			err = po.verifyBundle(bundleToPull, nestedBundles, imageRefs, reg)
+		}
+		if err == nil {
+			err = po.evaluateBundlePolicy(bundleToPull, imageRefs, reg)
+		}
+		if err == nil {
+			err = bundleToPull.Pull(po.OutputPath, po.ui, po.BundleRecursiveFlags.Recursive)
+		}
 		if err != nil {
 			if bundle.IsNotBundleError(err) {
 				return fmt.Errorf("Expected bundle image but found plain image (hint: Did you use -i instead of -b?)")
@@ -96,6 +127,17 @@ func (po *PullOptions) Run() error {
 		if ok {
 			return fmt.Errorf("Expected bundle flag when pulling a bundle (hint: Use -b instead of -i for bundles)")
 		}
+
+		err = po.verifyImage(plainImg, reg)
+		if err != nil {
+			return err
+		}
+
+		err = po.evaluatePolicy([]policy.Image{{DigestRef: plainImg.DigestRef()}}, reg)
+		if err != nil {
+			return err
+		}
+
 		return plainImg.Pull(po.OutputPath, po.ui)
 
 	default:
@@ -124,5 +166,93 @@ func (po *PullOptions) validate() error {
 	if presentInputParams == 0 {
 		return fmt.Errorf("Expected either image or bundle reference")
 	}
+	if err := po.VerifyFlags.Validate(); err != nil {
+		return err
+	}
+	if err := po.PolicyFlags.Validate(); err != nil {
+		return err
+	}
+	if po.VerifyFlags.Scope == verifyScopeBundles && len(po.ImageFlags.Image) > 0 {
+		return fmt.Errorf("Expected --verify-scope %s to be used when pulling a bundle", verifyScopeBundles)
+	}
 	return nil
 }
+
+// verifyBundle checks signatures of the bundle and, unless only bundles are verified, of its images.
+// Nested bundles are verified when they are pulled as well.
+// allBundles and imageRefs are the ones returned by AllImagesRefs of the bundle.
+func (po *PullOptions) verifyBundle(bundleToPull *bundle.Bundle, allBundles []*bundle.Bundle, imageRefs bundle.ImageRefs, reg registry.Registry) error {
+	if !po.VerifyFlags.IsSet() {
+		return nil
+	}
+
+	verifier, err := po.VerifyFlags.Verifier(reg, pullVerifyConcurrency)
+	if err != nil {
+		return err
+	}
+
+	var refsToVerify []string
+	switch {
+	case po.VerifyFlags.Scope == verifyScopeBundles && !po.BundleRecursiveFlags.Recursive:
+		refsToVerify = append(refsToVerify, bundleToPull.DigestRef())
+
+	case po.VerifyFlags.Scope == verifyScopeBundles:
+		for _, b := range allBundles {
+			refsToVerify = append(refsToVerify, b.DigestRef())
+		}
+
+	default:
+		refsToVerify = append(refsToVerify, bundleToPull.DigestRef())
+		for _, imageRef := range imageRefs.ImageRefs() {
+			refsToVerify = append(refsToVerify, imageRef.PrimaryLocation())
+		}
+	}
+
+	return verifier.Verify(refsToVerify)
+}
+
+func (po *PullOptions) verifyImage(plainImg *plainimage.PlainImage, reg registry.Registry) error {
+	if !po.VerifyFlags.IsSet() {
+		return nil
+	}
+
+	verifier, err := po.VerifyFlags.Verifier(reg, pullVerifyConcurrency)
+	if err != nil {
+		return err
+	}
+
+	return verifier.Verify([]string{plainImg.DigestRef()})
+}
+
+// evaluateBundlePolicy checks that the bundle and all of its images (including images of nested bundles) satisfy the policy
+func (po *PullOptions) evaluateBundlePolicy(bundleToPull *bundle.Bundle, imageRefs bundle.ImageRefs, reg registry.Registry) error {
+	if !po.PolicyFlags.IsSet() {
+		return nil
+	}
+
+	images := []policy.Image{{DigestRef: bundleToPull.DigestRef(), IsBundle: true}}
+	for _, imageRef := range imageRefs.ImageRefs() {
+		isBundle := imageRef.IsBundle != nil && *imageRef.IsBundle
+		images = append(images, policy.Image{DigestRef: imageRef.PrimaryLocation(), Annotations: imageRef.Annotations, IsBundle: isBundle})
+	}
+
+	return po.evaluatePolicy(images, reg)
+}
+
+func (po *PullOptions) evaluatePolicy(images []policy.Image, reg registry.Registry) error {
+	if !po.PolicyFlags.IsSet() {
+		return nil
+	}
+
+	evaluator, err := po.PolicyFlags.Evaluator(reg, pullVerifyConcurrency)
+	if err != nil {
+		return err
+	}
+
+	return enforcePolicy(evaluator, po.PolicyFlags.Mode, po.PolicyFlags.ReportPath, images, po.levelLogger().Warnf)
+}
+
+func (po *PullOptions) levelLogger() util.LoggerWithLevels {
+	logger := util.NewLogger(uiBlockWriter{po.ui})
+	return logger.NewLevelLogger(util.LogWarn, logger.NewPrefixedWriter("pull | "))
+}
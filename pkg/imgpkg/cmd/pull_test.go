@@ -4,10 +4,23 @@
 package cmd
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/cppforlife/go-cli-ui/ui"
+	"github.com/k14s/imgpkg/pkg/imgpkg/lockconfig"
+	"github.com/k14s/imgpkg/pkg/imgpkg/policy"
+	"github.com/k14s/imgpkg/test/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/yaml"
 )
 
 func TestNoImageOrBundleOrLockError(t *testing.T) {
@@ -50,3 +63,152 @@ func TestInvalidArgsPassed(t *testing.T) {
 		t.Fatalf("\nExpceted: %s\nGot: %s", expected, err.Error())
 	}
 }
+
+func TestPullBundleWithPolicy(t *testing.T) {
+	confUI := ui.NewConfUI(ui.NewNoopLogger())
+	defer confUI.Flush()
+
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	bundleInfo := fakeRegistry.WithBundleFromPath("library/bundle", "test_assets/bundle").
+		WithEveryImageFromPath("test_assets/image_with_config", map[string]string{})
+	fakeRegistry.Build()
+
+	policyPath := filepath.Join(t.TempDir(), "policy.yml")
+	require.NoError(t, ioutil.WriteFile(policyPath, []byte(`---
+apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: Policy
+requireBundleSignatures: true
+`), 0600))
+
+	t.Run("does not pull an unsigned bundle", func(t *testing.T) {
+		outputPath := filepath.Join(t.TempDir(), "bundle")
+
+		pull := PullOptions{
+			ui:          confUI,
+			BundleFlags: BundleFlags{bundleInfo.RefDigest},
+			PolicyFlags: PolicyFlags{Path: policyPath, Mode: policyModeEnforce},
+			OutputPath:  outputPath,
+		}
+		err := pull.Run()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), fmt.Sprintf("[requireBundleSignatures] %s: bundle is not signed", bundleInfo.RefDigest))
+
+		_, err = os.Stat(outputPath)
+		require.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("pulls an unsigned bundle when violations are only reported", func(t *testing.T) {
+		outputPath := filepath.Join(t.TempDir(), "bundle")
+
+		reportPath := filepath.Join(t.TempDir(), "policy-report.yml")
+
+		pull := PullOptions{
+			ui:          confUI,
+			BundleFlags: BundleFlags{bundleInfo.RefDigest},
+			PolicyFlags: PolicyFlags{Path: policyPath, Mode: policyModeWarn, ReportPath: reportPath},
+			OutputPath:  outputPath,
+		}
+		require.NoError(t, pull.Run())
+
+		_, err := os.Stat(filepath.Join(outputPath, ".imgpkg", "images.yml"))
+		require.NoError(t, err)
+
+		bs, err := ioutil.ReadFile(reportPath)
+		require.NoError(t, err)
+		var report policy.Report
+		require.NoError(t, yaml.Unmarshal(bs, &report))
+		assert.Equal(t, policyModeWarn, report.Mode)
+		assert.Equal(t, policy.Violations{{
+			Rule:    policy.RequireBundleSignaturesRule,
+			Image:   bundleInfo.RefDigest,
+			Message: "bundle is not signed",
+		}}, report.Violations)
+	})
+}
+
+func TestPullBundleVerifyingSignatures(t *testing.T) {
+	confUI := ui.NewConfUI(ui.NewNoopLogger())
+	defer confUI.Flush()
+
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	rootImage := fakeRegistry.WithRandomImage("library/root-image")
+	nestedImage := fakeRegistry.WithRandomImage("library/nested-image")
+	nestedBundle := fakeRegistry.WithBundleFromPath("library/nested-bundle", "test_assets/bundle_with_mult_images").
+		WithImageRefs([]lockconfig.ImageRef{{Image: nestedImage.RefDigest}})
+	rootBundle := fakeRegistry.WithBundleFromPath("library/bundle", "test_assets/bundle_with_mult_images").
+		WithImageRefs([]lockconfig.ImageRef{{Image: rootImage.RefDigest}, {Image: nestedBundle.RefDigest}})
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	// Nested image is the only one that is not signed
+	fakeRegistry.WithCosignSignature(rootImage.RefDigest, rootImage.Digest, key)
+	fakeRegistry.WithCosignSignature(rootBundle.RefDigest, rootBundle.Digest, key)
+	fakeRegistry.WithCosignSignature(nestedBundle.RefDigest, nestedBundle.Digest, key)
+	fakeRegistry.Build()
+
+	keyPath := filepath.Join(t.TempDir(), "cosign.pub")
+	helpers.WriteCosignPublicKey(t, keyPath, key.Public())
+
+	t.Run("does not pull a bundle when an image of a nested bundle is not signed", func(t *testing.T) {
+		outputPath := filepath.Join(t.TempDir(), "bundle")
+
+		pull := PullOptions{
+			ui:                   confUI,
+			BundleFlags:          BundleFlags{rootBundle.RefDigest},
+			BundleRecursiveFlags: BundleRecursiveFlags{Recursive: true},
+			VerifyFlags:          VerifyFlags{KeyPaths: []string{keyPath}},
+			OutputPath:           outputPath,
+		}
+		err := pull.Run()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Expected images to be signed with one of the verification keys, but 1 images could not be verified")
+		assert.Contains(t, err.Error(), fmt.Sprintf("- %s: no signature found", nestedImage.RefDigest))
+
+		_, err = os.Stat(outputPath)
+		require.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("pulls the bundle when only signatures of bundles are verified", func(t *testing.T) {
+		outputPath := filepath.Join(t.TempDir(), "bundle")
+
+		pull := PullOptions{
+			ui:                   confUI,
+			BundleFlags:          BundleFlags{rootBundle.RefDigest},
+			BundleRecursiveFlags: BundleRecursiveFlags{Recursive: true},
+			VerifyFlags:          VerifyFlags{KeyPaths: []string{keyPath}, Scope: verifyScopeBundles},
+			OutputPath:           outputPath,
+		}
+		require.NoError(t, pull.Run())
+
+		_, err := os.Stat(filepath.Join(outputPath, ".imgpkg", "images.yml"))
+		require.NoError(t, err)
+	})
+
+	t.Run("does not pull the bundle when a nested bundle is signed with another key", func(t *testing.T) {
+		otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		otherKeyPath := filepath.Join(t.TempDir(), "other-cosign.pub")
+		helpers.WriteCosignPublicKey(t, otherKeyPath, otherKey.Public())
+
+		outputPath := filepath.Join(t.TempDir(), "bundle")
+
+		pull := PullOptions{
+			ui:                   confUI,
+			BundleFlags:          BundleFlags{rootBundle.RefDigest},
+			BundleRecursiveFlags: BundleRecursiveFlags{Recursive: true},
+			VerifyFlags:          VerifyFlags{KeyPaths: []string{otherKeyPath}, Scope: verifyScopeBundles},
+			OutputPath:           outputPath,
+		}
+		err = pull.Run()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "2 images could not be verified")
+		assert.Contains(t, err.Error(), rootBundle.RefDigest)
+		assert.Contains(t, err.Error(), nestedBundle.RefDigest)
+		assert.NotContains(t, err.Error(), rootImage.RefDigest)
+
+		_, err = os.Stat(outputPath)
+		require.True(t, os.IsNotExist(err))
+	})
+}
@@ -5,28 +5,54 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
 
 	"github.com/cppforlife/go-cli-ui/ui"
 	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
 	"github.com/k14s/imgpkg/pkg/imgpkg/bundle"
+	ctlimg "github.com/k14s/imgpkg/pkg/imgpkg/image"
+	ctlimgset "github.com/k14s/imgpkg/pkg/imgpkg/imageset"
+	"github.com/k14s/imgpkg/pkg/imgpkg/imagetar"
 	"github.com/k14s/imgpkg/pkg/imgpkg/lockconfig"
 	"github.com/k14s/imgpkg/pkg/imgpkg/plainimage"
 	"github.com/k14s/imgpkg/pkg/imgpkg/registry"
+	"github.com/k14s/imgpkg/pkg/imgpkg/signature"
+	"github.com/k14s/imgpkg/pkg/imgpkg/util"
 	"github.com/spf13/cobra"
 )
 
 type PushOptions struct {
-	ui ui.UI
+	ui    ui.UI
+	stdin io.Reader
 
 	ImageFlags      ImageFlags
 	BundleFlags     BundleFlags
 	LockOutputFlags LockOutputFlags
 	FileFlags       FileFlags
+	LayerFlags      LayerFlags
 	RegistryFlags   RegistryFlags
+	SignFlags       SignFlags
+
+	TarDst       string
+	OCILayoutDst string
 }
 
 func NewPushOptions(ui ui.UI) *PushOptions {
-	return &PushOptions{ui: ui}
+	return &PushOptions{ui: ui, stdin: os.Stdin}
+}
+
+type bundleContents interface {
+	Push(regname.Tag, bundle.ImagesMetadataWriter, ui.UI) (string, error)
+	Build(ui.UI) (*ctlimg.FileImage, error)
+}
+
+type imageContents interface {
+	Push(regname.Tag, map[string]string, plainimage.ImagesWriter, ui.UI) (string, error)
+	Build(map[string]string, ui.UI) (*ctlimg.FileImage, error)
 }
 
 func NewPushCmd(o *PushOptions) *cobra.Command {
@@ -39,13 +65,30 @@ func NewPushCmd(o *PushOptions) *cobra.Command {
   imgpkg push -b repo/app1-config -f config/
 
   # Push image repo/app1-config with contents from multiple locations
-  imgpkg push -i repo/app1-config -f config/ -f additional-config.yml`,
+  imgpkg push -i repo/app1-config -f config/ -f additional-config.yml
+
+  # Push bundle repo/app1-config placing each top level directory in its own layer
+  imgpkg push -b repo/app1-config -f config/ --layer-per-dir
+
+  # Push bundle repo/app1-config using a tarball produced by another tool as its only layer
+  tar -cf - -C config/ . | imgpkg push -b repo/app1-config -f -
+
+  # Build bundle repo/app1-config into a tarball without accessing a registry
+  # (upload it later with: imgpkg copy --tar /tmp/app1-config.tar --to-repo repo/app1-config)
+  imgpkg push -b repo/app1-config -f config/ --to-tar /tmp/app1-config.tar
+
+  # Push bundle repo/app1-config and sign it with a private key, recording the commit it was built from
+  imgpkg push -b repo/app1-config -f config/ --sign-key key.pem --sign-annotation git-commit=$(git rev-parse HEAD)`,
 	}
 	o.ImageFlags.Set(cmd)
 	o.BundleFlags.Set(cmd)
 	o.LockOutputFlags.Set(cmd)
 	o.FileFlags.Set(cmd)
+	o.LayerFlags.Set(cmd)
 	o.RegistryFlags.Set(cmd)
+	o.SignFlags.Set(cmd)
+	cmd.Flags().StringVar(&o.TarDst, "to-tar", "", "Location to write a tar file containing the image instead of pushing it (can be uploaded via 'copy --tar')")
+	cmd.Flags().StringVar(&o.OCILayoutDst, "to-oci-layout", "", "Location of an OCI image layout directory to write the image to instead of pushing it (can be uploaded via 'copy --tar')")
 	return cmd
 }
 
@@ -67,6 +110,20 @@ func (po *PushOptions) Run() error {
 	case !isBundle && !isImage:
 		return fmt.Errorf("Expected either image or bundle")
 
+	case po.TarDst != "" && po.OCILayoutDst != "":
+		return fmt.Errorf("Expected only one of --to-tar or --to-oci-layout")
+
+	case po.SignFlags.IsSet() && po.isOfflineDst():
+		return fmt.Errorf("Expected --sign-key to be used when pushing to a registry")
+	}
+
+	// Load key before pushing so that an unusable key does not leave an unsigned image behind
+	signer, err := po.signer(reg)
+	if err != nil {
+		return err
+	}
+
+	switch {
 	case isBundle:
 		imageURL, err = po.pushBundle(reg)
 		if err != nil {
@@ -83,22 +140,86 @@ func (po *PushOptions) Run() error {
 		panic("Unreachable code")
 	}
 
+	if po.isOfflineDst() {
+		po.ui.BeginLinef("Wrote '%s' to '%s'", imageURL, po.offlineDst())
+		return nil
+	}
+
 	po.ui.BeginLinef("Pushed '%s'", imageURL)
 
+	if signer == nil {
+		return nil
+	}
+
+	annotations, err := po.SignFlags.AnnotationsMap()
+	if err != nil {
+		return err
+	}
+
+	sigRef, err := signer.Sign(imageURL, annotations)
+	if err != nil {
+		return err
+	}
+
+	po.ui.BeginLinef("\nSigned '%s' (signature '%s')", imageURL, sigRef)
+
 	return nil
 }
 
+// signer returns nil when pushed image should not be signed
+func (po *PushOptions) signer(registry registry.Registry) (*signature.Signer, error) {
+	err := po.SignFlags.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	if !po.SignFlags.IsSet() {
+		return nil, nil
+	}
+
+	return po.SignFlags.Signer(registry)
+}
+
 func (po *PushOptions) pushBundle(registry registry.Registry) (string, error) {
 	uploadRef, err := regname.NewTag(po.BundleFlags.Bundle, regname.WeakValidation)
 	if err != nil {
 		return "", fmt.Errorf("Parsing '%s': %s", po.BundleFlags.Bundle, err)
 	}
 
-	imageURL, err := bundle.NewContents(po.FileFlags.Files, po.FileFlags.ExcludedFilePaths).Push(uploadRef, registry, po.ui)
+	layerOpts, err := po.LayerFlags.LayerOpts()
+	if err != nil {
+		return "", err
+	}
+
+	tarStream, err := po.tarStream(layerOpts)
 	if err != nil {
 		return "", err
 	}
 
+	var contents bundleContents = bundle.NewContentsWithLayers(po.FileFlags.Files, po.FileFlags.ExcludedFilePaths, layerOpts)
+	if tarStream != nil {
+		defer tarStream.Close()
+		contents = bundle.NewTarContents(tarStream, layerOpts)
+	}
+
+	var imageURL string
+	if po.isOfflineDst() {
+		img, err := contents.Build(po.ui)
+		if err != nil {
+			return "", err
+		}
+
+		imageURL, err = po.writeOffline(uploadRef, img, true)
+		if err != nil {
+			return "", err
+		}
+	} else {
+		imageURL, err = contents.Push(uploadRef, registry, po.ui)
+		if err != nil {
+			return "", err
+		}
+	}
+
 	if po.LockOutputFlags.LockFilePath != "" {
 		bundleLock := lockconfig.BundleLock{
 			LockVersion: lockconfig.LockVersion{
@@ -130,13 +251,153 @@ func (po *PushOptions) pushImage(registry registry.Registry) (string, error) {
 		return "", fmt.Errorf("Parsing '%s': %s", po.ImageFlags.Image, err)
 	}
 
-	isBundle, err := bundle.NewContents(po.FileFlags.Files, po.FileFlags.ExcludedFilePaths).PresentsAsBundle()
+	layerOpts, err := po.LayerFlags.LayerOpts()
 	if err != nil {
 		return "", err
 	}
-	if isBundle {
-		return "", fmt.Errorf("Images cannot be pushed with '.imgpkg' directories, consider using --bundle (-b) option")
+
+	tarStream, err := po.tarStream(layerOpts)
+	if err != nil {
+		return "", err
+	}
+
+	var contents imageContents
+
+	if tarStream != nil {
+		defer tarStream.Close()
+
+		contents = plainimage.NewTarContentsWithValidation(tarStream, layerOpts, func(paths []string) error {
+			if bundle.TarPathsPresentAsBundle(paths) {
+				return fmt.Errorf("Images cannot be pushed with '.imgpkg' directories, consider using --bundle (-b) option")
+			}
+			return nil
+		})
+	} else {
+		isBundle, err := bundle.NewContents(po.FileFlags.Files, po.FileFlags.ExcludedFilePaths).PresentsAsBundle()
+		if err != nil {
+			return "", err
+		}
+		if isBundle {
+			return "", fmt.Errorf("Images cannot be pushed with '.imgpkg' directories, consider using --bundle (-b) option")
+		}
+
+		contents = plainimage.NewContentsWithLayers(po.FileFlags.Files, po.FileFlags.ExcludedFilePaths, layerOpts)
+	}
+
+	if po.isOfflineDst() {
+		img, err := contents.Build(nil, po.ui)
+		if err != nil {
+			return "", err
+		}
+
+		return po.writeOffline(uploadRef, img, false)
+	}
+
+	return contents.Push(uploadRef, nil, registry, po.ui)
+}
+
+// tarStream returns content provided as an existing tar stream (via --from-tar or '-f -'),
+// or nil if content is provided as files
+func (po *PushOptions) tarStream(layerOpts ctlimg.LayerOpts) (io.ReadCloser, error) {
+	fromStdin := false
+	for _, file := range po.FileFlags.Files {
+		if file == "-" {
+			fromStdin = true
+		}
+	}
+
+	switch {
+	case po.FileFlags.FromTar != "" && len(po.FileFlags.Files) > 0:
+		return nil, fmt.Errorf("Expected only one of --file (-f) or --from-tar")
+	case fromStdin && len(po.FileFlags.Files) > 1:
+		return nil, fmt.Errorf("Expected '-f -' to be the only file when reading a tar stream from stdin")
+	case po.FileFlags.FromTar == "" && !fromStdin:
+		return nil, nil
+	}
+
+	if _, isSingleLayer := layerOpts.Selector.(ctlimg.SingleLayer); !isSingleLayer {
+		return nil, fmt.Errorf("Expected --layer-per-file and --layer-per-dir to not be used with a tar stream since it becomes a single layer")
+	}
+
+	if fromStdin || po.FileFlags.FromTar == "-" {
+		stdin := po.stdin
+		if stdin == nil {
+			stdin = os.Stdin
+		}
+		return ioutil.NopCloser(stdin), nil
+	}
+
+	file, err := os.Open(po.FileFlags.FromTar)
+	if err != nil {
+		return nil, fmt.Errorf("Opening tar file: %s", err)
+	}
+
+	return file, nil
+}
+
+func (po *PushOptions) isOfflineDst() bool { return po.offlineDst() != "" }
+
+// offlineDst returns location of the tarball or OCI image layout the image is written to
+func (po *PushOptions) offlineDst() string {
+	if po.TarDst != "" {
+		return po.TarDst
+	}
+	return po.OCILayoutDst
+}
+
+// writeOffline writes the built image to a tarball or OCI image layout instead of a registry.
+// Returned digest reference is the one the image will have once uploaded to the requested repository.
+func (po *PushOptions) writeOffline(uploadRef regname.Tag, img *ctlimg.FileImage, isBundle bool) (string, error) {
+	defer img.Remove()
+
+	localImages := ctlimg.NewLocalImages()
+
+	digestRef, err := localImages.Add(uploadRef.Context(), img)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case po.TarDst != "":
+		labels := map[string]string{}
+		if isBundle {
+			// Lets copy (--tar --to-repo --lock-output) find the root bundle
+			labels[rootBundleLabelKey] = ""
+		}
+
+		imageRefs := ctlimgset.NewUnprocessedImageRefs()
+		imageRefs.Add(ctlimgset.UnprocessedImageRef{DigestRef: digestRef.Name(), Tag: uploadRef.TagStr(), Labels: labels})
+
+		logger := util.NewLogger(uiBlockWriter{po.ui}).NewPrefixedWriter("push | ")
+		imageSet := ctlimgset.NewImageSet(1, logger)
+
+		_, err = ctlimgset.NewTarImageSet(imageSet, 1, logger).Export(imageRefs, po.TarDst, localImages, imagetar.NewImageLayerWriterCheck(true))
+		if err != nil {
+			return "", fmt.Errorf("Writing '%s' to tar: %s", uploadRef.Name(), err)
+		}
+
+	case po.OCILayoutDst != "":
+		layoutPath, err := layout.FromPath(po.OCILayoutDst)
+		if err != nil {
+			layoutPath, err = layout.Write(po.OCILayoutDst, empty.Index)
+			if err != nil {
+				return "", fmt.Errorf("Creating OCI image layout: %s", err)
+			}
+		}
+
+		annotations := map[string]string{imagetar.OCIRefNameAnnotation: uploadRef.Name()}
+		if isBundle {
+			annotations[rootBundleLabelKey] = ""
+		}
+
+		err = layoutPath.AppendImage(img, layout.WithAnnotations(annotations))
+		if err != nil {
+			return "", fmt.Errorf("Writing '%s' to OCI image layout: %s", uploadRef.Name(), err)
+		}
+
+	default:
+		panic("Unreachable code")
 	}
 
-	return plainimage.NewContents(po.FileFlags.Files, po.FileFlags.ExcludedFilePaths).Push(uploadRef, nil, registry, po.ui)
+	return digestRef.Name(), nil
 }
@@ -4,12 +4,33 @@
 package cmd
 
 import (
+	"archive/tar"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	goui "github.com/cppforlife/go-cli-ui/ui"
+	"github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/k14s/imgpkg/pkg/imgpkg/bundle"
+	ctlimg "github.com/k14s/imgpkg/pkg/imgpkg/image"
+	"github.com/k14s/imgpkg/pkg/imgpkg/imagetar"
+	"github.com/k14s/imgpkg/pkg/imgpkg/lockconfig"
+	"github.com/k14s/imgpkg/pkg/imgpkg/signature"
+	"github.com/k14s/imgpkg/test/helpers"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -257,3 +278,578 @@ func createEmptyBundleDir(loc string) error {
 	bundleDir := filepath.Join(loc, ".imgpkg")
 	return os.Mkdir(bundleDir, 0700)
 }
+
+func TestPushBundleWithLayerPerDir(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	reg := fakeRegistry.Build()
+
+	bundleDir, err := os.MkdirTemp("", "imgpkg-push-layer-per-dir")
+	require.NoError(t, err)
+	defer Cleanup(bundleDir)
+
+	require.NoError(t, createBundleDir(bundleDir, ""))
+	require.NoError(t, os.MkdirAll(filepath.Join(bundleDir, "config"), 0700))
+	require.NoError(t, os.MkdirAll(filepath.Join(bundleDir, "static"), 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(bundleDir, "config", "config.yml"), []byte("foo: bar"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(bundleDir, "static", "asset.bin"), []byte("large content"), 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(bundleDir, "README.md"), []byte("readme"), 0600))
+
+	push := func() []regv1.Layer {
+		confUI := goui.NewConfUI(goui.NewNoopLogger())
+		defer confUI.Flush()
+
+		pushOpts := PushOptions{
+			ui:          confUI,
+			FileFlags:   FileFlags{Files: []string{bundleDir}},
+			BundleFlags: BundleFlags{Bundle: fakeRegistry.ReferenceOnTestServer("library/layered-bundle")},
+			LayerFlags:  LayerFlags{LayerPerDir: true},
+		}
+		require.NoError(t, pushOpts.Run())
+
+		ref, err := name.ParseReference(fakeRegistry.ReferenceOnTestServer("library/layered-bundle"))
+		require.NoError(t, err)
+		img, err := reg.Image(ref)
+		require.NoError(t, err)
+		layers, err := img.Layers()
+		require.NoError(t, err)
+		return layers
+	}
+
+	layerDigests := func(layers []regv1.Layer) []string {
+		var digests []string
+		for _, layer := range layers {
+			digest, err := layer.Digest()
+			require.NoError(t, err)
+			digests = append(digests, digest.String())
+		}
+		return digests
+	}
+
+	t.Run("each top level directory and .imgpkg are separate layers", func(t *testing.T) {
+		layers := push()
+		// root files, .imgpkg, config and static
+		require.Len(t, layers, 4)
+	})
+
+	t.Run("unchanged directories result in the same layers", func(t *testing.T) {
+		firstPush := layerDigests(push())
+
+		require.NoError(t, ioutil.WriteFile(filepath.Join(bundleDir, "config", "config.yml"), []byte("foo: baz"), 0600))
+		secondPush := layerDigests(push())
+
+		require.Len(t, secondPush, len(firstPush))
+		var changed int
+		for i := range firstPush {
+			if firstPush[i] != secondPush[i] {
+				changed++
+			}
+		}
+		assert.Equal(t, 1, changed)
+	})
+
+	t.Run("pulling the bundle extracts every layer", func(t *testing.T) {
+		outputDir, err := os.MkdirTemp("", "imgpkg-pull-layer-per-dir")
+		require.NoError(t, err)
+		defer Cleanup(outputDir)
+
+		confUI := goui.NewConfUI(goui.NewNoopLogger())
+		defer confUI.Flush()
+
+		pullOpts := PullOptions{
+			ui:          confUI,
+			OutputPath:  outputDir,
+			BundleFlags: BundleFlags{Bundle: fakeRegistry.ReferenceOnTestServer("library/layered-bundle")},
+		}
+		require.NoError(t, pullOpts.Run())
+
+		for _, path := range []string{".imgpkg/images.yml", "config/config.yml", "static/asset.bin", "README.md"} {
+			assert.FileExists(t, filepath.Join(outputDir, path))
+		}
+	})
+}
+
+func TestPushBundleToTar(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	fakeRegistry.Build()
+
+	tempDir, err := os.MkdirTemp("", "imgpkg-push-to-tar")
+	require.NoError(t, err)
+	defer Cleanup(tempDir)
+
+	bundleDir := filepath.Join(tempDir, "bundle")
+	require.NoError(t, os.MkdirAll(bundleDir, 0700))
+	require.NoError(t, createBundleDir(bundleDir, ""))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(bundleDir, "config.yml"), []byte("foo: bar"), 0600))
+
+	confUI := goui.NewConfUI(goui.NewNoopLogger())
+	defer confUI.Flush()
+
+	t.Run("copying the tarball to a repository results in the same bundle digest", func(t *testing.T) {
+		tarPath := filepath.Join(tempDir, "bundle.tar")
+		pushLockPath := filepath.Join(tempDir, "push-lock.yml")
+
+		pushOpts := PushOptions{
+			ui:              confUI,
+			FileFlags:       FileFlags{Files: []string{bundleDir}},
+			BundleFlags:     BundleFlags{Bundle: "some.registry.io/offline-bundle:v1"},
+			LockOutputFlags: LockOutputFlags{LockFilePath: pushLockPath},
+			TarDst:          tarPath,
+		}
+		require.NoError(t, pushOpts.Run())
+
+		pushLock, err := lockconfig.NewBundleLockFromPath(pushLockPath)
+		require.NoError(t, err)
+		require.Contains(t, pushLock.Bundle.Image, "some.registry.io/offline-bundle@sha256:")
+		pushedDigest := strings.Split(pushLock.Bundle.Image, "@")[1]
+
+		copyLockPath := filepath.Join(tempDir, "copy-lock.yml")
+		copyOpts := CopyOptions{
+			TarFlags:        TarFlags{TarSrc: tarPath},
+			RepoDsts:        []string{fakeRegistry.ReferenceOnTestServer("library/offline-bundle")},
+			LockOutputFlags: LockOutputFlags{LockFilePath: copyLockPath},
+			Concurrency:     1,
+		}
+		require.NoError(t, copyOpts.Run())
+
+		copyLock, err := lockconfig.NewBundleLockFromPath(copyLockPath)
+		require.NoError(t, err)
+		assert.Equal(t, fakeRegistry.ReferenceOnTestServer("library/offline-bundle")+"@"+pushedDigest, copyLock.Bundle.Image)
+		assert.Equal(t, "v1", copyLock.Bundle.Tag)
+	})
+
+	t.Run("writes the bundle to an OCI image layout", func(t *testing.T) {
+		layoutDir := filepath.Join(tempDir, "layout")
+
+		output := &bytes.Buffer{}
+		pushOpts := PushOptions{
+			ui:           goui.NewWriterUI(output, output, nil),
+			FileFlags:    FileFlags{Files: []string{bundleDir}},
+			BundleFlags:  BundleFlags{Bundle: "some.registry.io/offline-bundle:v1"},
+			OCILayoutDst: layoutDir,
+		}
+		require.NoError(t, pushOpts.Run())
+		assert.Contains(t, output.String(), "' to '"+layoutDir+"'")
+
+		layoutPath, err := layout.FromPath(layoutDir)
+		require.NoError(t, err)
+		idx, err := layoutPath.ImageIndex()
+		require.NoError(t, err)
+		idxManifest, err := idx.IndexManifest()
+		require.NoError(t, err)
+		require.Len(t, idxManifest.Manifests, 1)
+		assert.Equal(t, "some.registry.io/offline-bundle:v1", idxManifest.Manifests[0].Annotations[imagetar.OCIRefNameAnnotation])
+
+		img, err := idx.Image(idxManifest.Manifests[0].Digest)
+		require.NoError(t, err)
+		cfg, err := img.ConfigFile()
+		require.NoError(t, err)
+		assert.Contains(t, cfg.Config.Labels, bundle.BundleConfigLabel)
+	})
+
+	t.Run("copying the OCI image layout to a repository results in the same bundle digest", func(t *testing.T) {
+		layoutDir := filepath.Join(tempDir, "copied-layout")
+		pushLockPath := filepath.Join(tempDir, "layout-push-lock.yml")
+
+		pushOpts := PushOptions{
+			ui:              confUI,
+			FileFlags:       FileFlags{Files: []string{bundleDir}},
+			BundleFlags:     BundleFlags{Bundle: "some.registry.io/offline-bundle:v1"},
+			LockOutputFlags: LockOutputFlags{LockFilePath: pushLockPath},
+			OCILayoutDst:    layoutDir,
+		}
+		require.NoError(t, pushOpts.Run())
+
+		pushLock, err := lockconfig.NewBundleLockFromPath(pushLockPath)
+		require.NoError(t, err)
+		pushedDigest := strings.Split(pushLock.Bundle.Image, "@")[1]
+
+		copyLockPath := filepath.Join(tempDir, "layout-copy-lock.yml")
+		copyOpts := CopyOptions{
+			TarFlags:        TarFlags{TarSrc: layoutDir},
+			RepoDsts:        []string{fakeRegistry.ReferenceOnTestServer("library/offline-layout-bundle")},
+			LockOutputFlags: LockOutputFlags{LockFilePath: copyLockPath},
+			Concurrency:     1,
+		}
+		require.NoError(t, copyOpts.Run())
+
+		copyLock, err := lockconfig.NewBundleLockFromPath(copyLockPath)
+		require.NoError(t, err)
+		assert.Equal(t, fakeRegistry.ReferenceOnTestServer("library/offline-layout-bundle")+"@"+pushedDigest, copyLock.Bundle.Image)
+		assert.Equal(t, "v1", copyLock.Bundle.Tag)
+	})
+}
+
+func TestPushImageWithCompressionLevel(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	reg := fakeRegistry.Build()
+
+	imageDir, err := os.MkdirTemp("", "imgpkg-push-compression-level")
+	require.NoError(t, err)
+	defer Cleanup(imageDir)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(imageDir, "config.yml"), []byte(strings.Repeat("foo: bar\n", 1000)), 0600))
+
+	push := func(level int) regv1.Layer {
+		confUI := goui.NewConfUI(goui.NewNoopLogger())
+		defer confUI.Flush()
+
+		pushOpts := PushOptions{
+			ui:         confUI,
+			FileFlags:  FileFlags{Files: []string{imageDir}},
+			ImageFlags: ImageFlags{Image: fakeRegistry.ReferenceOnTestServer("library/compressed-image")},
+			LayerFlags: LayerFlags{CompressionLevel: level},
+		}
+		require.NoError(t, pushOpts.Run())
+
+		ref, err := name.ParseReference(fakeRegistry.ReferenceOnTestServer("library/compressed-image"))
+		require.NoError(t, err)
+		img, err := reg.Image(ref)
+		require.NoError(t, err)
+		layers, err := img.Layers()
+		require.NoError(t, err)
+		require.Len(t, layers, 1)
+		return layers[0]
+	}
+
+	t.Run("different levels change compressed layer but not its content", func(t *testing.T) {
+		fastest := push(1)
+		smallest := push(9)
+
+		fastestDigest, err := fastest.Digest()
+		require.NoError(t, err)
+		smallestDigest, err := smallest.Digest()
+		require.NoError(t, err)
+		assert.NotEqual(t, fastestDigest, smallestDigest)
+
+		fastestDiffID, err := fastest.DiffID()
+		require.NoError(t, err)
+		smallestDiffID, err := smallest.DiffID()
+		require.NoError(t, err)
+		assert.Equal(t, fastestDiffID, smallestDiffID)
+	})
+
+	t.Run("invalid level returns an error", func(t *testing.T) {
+		pushOpts := PushOptions{
+			FileFlags:  FileFlags{Files: []string{imageDir}},
+			ImageFlags: ImageFlags{Image: fakeRegistry.ReferenceOnTestServer("library/compressed-image")},
+			LayerFlags: LayerFlags{CompressionLevel: 10},
+		}
+		err := pushOpts.Run()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Expected --compression-level to be between 1 and 9 for gzip compression")
+	})
+}
+
+func TestPushBundleWithZstdCompression(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	reg := fakeRegistry.Build()
+
+	bundleDir, err := os.MkdirTemp("", "imgpkg-push-zstd")
+	require.NoError(t, err)
+	defer Cleanup(bundleDir)
+
+	require.NoError(t, createBundleDir(bundleDir, ""))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(bundleDir, "config.yml"), []byte("foo: bar"), 0600))
+
+	confUI := goui.NewConfUI(goui.NewNoopLogger())
+	defer confUI.Flush()
+
+	bundleRef := fakeRegistry.ReferenceOnTestServer("library/zstd-bundle")
+	pushOpts := PushOptions{
+		ui:          confUI,
+		FileFlags:   FileFlags{Files: []string{bundleDir}},
+		BundleFlags: BundleFlags{Bundle: bundleRef},
+		LayerFlags:  LayerFlags{Compression: "zstd"},
+	}
+	require.NoError(t, pushOpts.Run())
+
+	t.Run("layers use zstd media type", func(t *testing.T) {
+		ref, err := name.ParseReference(bundleRef)
+		require.NoError(t, err)
+		img, err := reg.Image(ref)
+		require.NoError(t, err)
+		layers, err := img.Layers()
+		require.NoError(t, err)
+		require.Len(t, layers, 1)
+
+		mediaType, err := layers[0].MediaType()
+		require.NoError(t, err)
+		assert.Equal(t, ctlimg.OCILayerZstd, mediaType)
+	})
+
+	t.Run("pulling the bundle extracts zstd layers", func(t *testing.T) {
+		outputDir, err := os.MkdirTemp("", "imgpkg-pull-zstd")
+		require.NoError(t, err)
+		defer Cleanup(outputDir)
+
+		pullOpts := PullOptions{
+			ui:          confUI,
+			BundleFlags: BundleFlags{Bundle: bundleRef},
+			OutputPath:  outputDir,
+		}
+		require.NoError(t, pullOpts.Run())
+
+		contents, err := ioutil.ReadFile(filepath.Join(outputDir, "config.yml"))
+		require.NoError(t, err)
+		assert.Equal(t, "foo: bar", string(contents))
+	})
+
+	t.Run("unknown compression returns an error", func(t *testing.T) {
+		pushOpts := pushOpts
+		pushOpts.LayerFlags = LayerFlags{Compression: "lz4"}
+		err := pushOpts.Run()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Unknown compression 'lz4' (known: gzip, zstd)")
+	})
+}
+
+func TestPushBundleFromTarStream(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "imgpkg-push-from-tar")
+	require.NoError(t, err)
+	defer Cleanup(tempDir)
+
+	confUI := goui.NewConfUI(goui.NewNoopLogger())
+	defer confUI.Flush()
+
+	writeTar := func(t *testing.T, headers []*tar.Header) []byte {
+		buf := &bytes.Buffer{}
+		tarWriter := tar.NewWriter(buf)
+		for _, header := range headers {
+			require.NoError(t, tarWriter.WriteHeader(header))
+			if header.Typeflag == tar.TypeReg {
+				_, err := tarWriter.Write(make([]byte, header.Size))
+				require.NoError(t, err)
+			}
+		}
+		require.NoError(t, tarWriter.Close())
+		return buf.Bytes()
+	}
+
+	bundleTar := writeTar(t, []*tar.Header{
+		{Name: "./", Typeflag: tar.TypeDir, Mode: 0700},
+		{Name: "./.imgpkg/", Typeflag: tar.TypeDir, Mode: 0700},
+		{Name: "./.imgpkg/images.yml", Typeflag: tar.TypeReg, Mode: 0600, Size: 10},
+		{Name: "./config.yml", Typeflag: tar.TypeReg, Mode: 0600, Size: 5},
+	})
+
+	t.Run("uses the stream from stdin as the only layer", func(t *testing.T) {
+		layoutDir := filepath.Join(tempDir, "layout")
+
+		pushOpts := PushOptions{
+			ui:           confUI,
+			stdin:        bytes.NewReader(bundleTar),
+			FileFlags:    FileFlags{Files: []string{"-"}},
+			BundleFlags:  BundleFlags{Bundle: "some.registry.io/tar-bundle:v1"},
+			OCILayoutDst: layoutDir,
+		}
+		require.NoError(t, pushOpts.Run())
+
+		layoutPath, err := layout.FromPath(layoutDir)
+		require.NoError(t, err)
+		idx, err := layoutPath.ImageIndex()
+		require.NoError(t, err)
+		idxManifest, err := idx.IndexManifest()
+		require.NoError(t, err)
+		require.Len(t, idxManifest.Manifests, 1)
+
+		img, err := idx.Image(idxManifest.Manifests[0].Digest)
+		require.NoError(t, err)
+		layers, err := img.Layers()
+		require.NoError(t, err)
+		require.Len(t, layers, 1)
+
+		uncompressed, err := layers[0].Uncompressed()
+		require.NoError(t, err)
+		defer uncompressed.Close()
+		layerContents, err := ioutil.ReadAll(uncompressed)
+		require.NoError(t, err)
+		assert.Equal(t, bundleTar, layerContents)
+	})
+
+	t.Run("reads the stream from --from-tar", func(t *testing.T) {
+		tarPath := filepath.Join(tempDir, "bundle.tar")
+		require.NoError(t, ioutil.WriteFile(tarPath, bundleTar, 0600))
+
+		pushOpts := PushOptions{
+			ui:           confUI,
+			FileFlags:    FileFlags{FromTar: tarPath},
+			BundleFlags:  BundleFlags{Bundle: "some.registry.io/tar-bundle:v1"},
+			OCILayoutDst: filepath.Join(tempDir, "from-tar-layout"),
+		}
+		require.NoError(t, pushOpts.Run())
+	})
+
+	t.Run("rejects invalid streams", func(t *testing.T) {
+		testCases := []struct {
+			name          string
+			headers       []*tar.Header
+			isImage       bool
+			expectedError string
+		}{
+			{
+				name: "symlink entry",
+				headers: []*tar.Header{
+					{Name: ".imgpkg/images.yml", Typeflag: tar.TypeReg, Mode: 0600},
+					{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"},
+				},
+				expectedError: "Expected tar entry 'link' to be a regular file or directory",
+			},
+			{
+				name:          "entry outside of archive",
+				headers:       []*tar.Header{{Name: "../config.yml", Typeflag: tar.TypeReg, Mode: 0600}},
+				expectedError: "Expected tar entry '../config.yml' to be a relative path within the archive",
+			},
+			{
+				name:          "missing images.yml",
+				headers:       []*tar.Header{{Name: ".imgpkg/", Typeflag: tar.TypeDir, Mode: 0700}},
+				expectedError: "The bundle expected .imgpkg/images.yml to exist, but it wasn't found in the tar",
+			},
+			{
+				name:          "nested .imgpkg directory",
+				headers:       []*tar.Header{{Name: "nested/.imgpkg/images.yml", Typeflag: tar.TypeReg, Mode: 0600}},
+				expectedError: "Expected '.imgpkg' directory, to be a direct child of the tar root; was nested/.imgpkg",
+			},
+			{
+				name:          "image with .imgpkg directory",
+				headers:       []*tar.Header{{Name: ".imgpkg/images.yml", Typeflag: tar.TypeReg, Mode: 0600}},
+				isImage:       true,
+				expectedError: "Images cannot be pushed with '.imgpkg' directories, consider using --bundle (-b) option",
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				pushOpts := PushOptions{
+					ui:           confUI,
+					stdin:        bytes.NewReader(writeTar(t, tc.headers)),
+					FileFlags:    FileFlags{Files: []string{"-"}},
+					OCILayoutDst: filepath.Join(tempDir, "invalid-layout"),
+				}
+				if tc.isImage {
+					pushOpts.ImageFlags = ImageFlags{Image: "some.registry.io/tar-image:v1"}
+				} else {
+					pushOpts.BundleFlags = BundleFlags{Bundle: "some.registry.io/tar-bundle:v1"}
+				}
+
+				err := pushOpts.Run()
+				require.Error(t, err)
+				assert.Equal(t, tc.expectedError, err.Error())
+			})
+		}
+	})
+
+	t.Run("rejects -f - combined with other files", func(t *testing.T) {
+		pushOpts := PushOptions{
+			ui:          confUI,
+			FileFlags:   FileFlags{Files: []string{"-", tempDir}},
+			BundleFlags: BundleFlags{Bundle: "some.registry.io/tar-bundle:v1"},
+		}
+		err := pushOpts.Run()
+		require.Error(t, err)
+		assert.Equal(t, "Expected '-f -' to be the only file when reading a tar stream from stdin", err.Error())
+	})
+}
+
+func TestPushBundleSignedWithKey(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{LogLevel: helpers.LogDebug})
+	defer fakeRegistry.CleanUp()
+	reg := fakeRegistry.Build()
+
+	bundleDir := t.TempDir()
+	require.NoError(t, createBundleDir(bundleDir, ""))
+
+	keysDir := t.TempDir()
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	confUI := goui.NewConfUI(goui.NewNoopLogger())
+	defer confUI.Flush()
+
+	for i, key := range []crypto.Signer{ecdsaKey, ed25519Key} {
+		t.Run(fmt.Sprintf("signs pushed bundle with %T", key), func(t *testing.T) {
+			keyPath := filepath.Join(keysDir, "key.pem")
+			der, err := x509.MarshalPKCS8PrivateKey(key)
+			require.NoError(t, err)
+			require.NoError(t, ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+
+			pubKeyPath := filepath.Join(keysDir, "key.pub")
+			helpers.WriteCosignPublicKey(t, pubKeyPath, key.Public())
+
+			bundleRef := fakeRegistry.ReferenceOnTestServer("library/signed-bundle")
+			pushOpts := PushOptions{
+				ui:          confUI,
+				FileFlags:   FileFlags{Files: []string{bundleDir}},
+				BundleFlags: BundleFlags{Bundle: bundleRef},
+				SignFlags:   SignFlags{KeyPath: keyPath, Annotations: []string{"git-commit=abc123"}},
+			}
+			require.NoError(t, pushOpts.Run())
+
+			ref, err := name.ParseReference(bundleRef)
+			require.NoError(t, err)
+			digest, err := reg.Digest(ref)
+			require.NoError(t, err)
+
+			pubKeys, err := signature.LoadPublicKeys([]string{pubKeyPath})
+			require.NoError(t, err)
+			err = signature.NewVerifier(reg, pubKeys, 1).Verify([]string{bundleRef + "@" + digest.String()})
+			require.NoError(t, err)
+
+			sigRef, err := name.ParseReference(bundleRef + ":" + strings.ReplaceAll(digest.String(), ":", "-") + ".sig")
+			require.NoError(t, err)
+			sigImg, err := reg.Image(sigRef)
+			require.NoError(t, err)
+			layers, err := sigImg.Layers()
+			require.NoError(t, err)
+			// Signatures made by previous runs are kept
+			require.Len(t, layers, i+1)
+			rc, err := layers[i].Compressed()
+			require.NoError(t, err)
+			defer rc.Close()
+			payload, err := ioutil.ReadAll(rc)
+			require.NoError(t, err)
+			assert.Contains(t, string(payload), `"optional":{"git-commit":"abc123"}`)
+		})
+	}
+
+	t.Run("does not push when key cannot be used", func(t *testing.T) {
+		keyPath := filepath.Join(keysDir, "rsa.pem")
+		rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}), 0600))
+
+		bundleRef := fakeRegistry.ReferenceOnTestServer("library/not-signed-bundle")
+		pushOpts := PushOptions{
+			ui:          confUI,
+			FileFlags:   FileFlags{Files: []string{bundleDir}},
+			BundleFlags: BundleFlags{Bundle: bundleRef},
+			SignFlags:   SignFlags{KeyPath: keyPath},
+		}
+		err = pushOpts.Run()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "to be a not encrypted 'EC PRIVATE KEY' or 'PRIVATE KEY' PEM block but was 'RSA PRIVATE KEY'")
+
+		ref, err := name.ParseReference(bundleRef)
+		require.NoError(t, err)
+		_, err = reg.Digest(ref)
+		require.Error(t, err)
+	})
+
+	t.Run("rejects signing when not pushing to a registry", func(t *testing.T) {
+		pushOpts := PushOptions{
+			ui:          confUI,
+			FileFlags:   FileFlags{Files: []string{bundleDir}},
+			BundleFlags: BundleFlags{Bundle: "some.registry.io/bundle:v1"},
+			SignFlags:   SignFlags{KeyPath: "key.pem"},
+			TarDst:      filepath.Join(t.TempDir(), "bundle.tar"),
+		}
+		err := pushOpts.Run()
+		require.Error(t, err)
+		assert.Equal(t, "Expected --sign-key to be used when pushing to a registry", err.Error())
+	})
+}
@@ -0,0 +1,56 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/k14s/imgpkg/pkg/imgpkg/signature"
+	"github.com/spf13/cobra"
+)
+
+type SignFlags struct {
+	KeyPath     string
+	Annotations []string
+}
+
+func (s *SignFlags) Set(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&s.KeyPath, "sign-key", "",
+		"Sign pushed image with private key, creating a cosign signature (ECDSA or ED25519, PEM encoded, not encrypted)")
+	cmd.Flags().StringSliceVar(&s.Annotations, "sign-annotation", nil,
+		"Include claim in the signed payload (format: key=value) (can be specified multiple times)")
+}
+
+func (s SignFlags) IsSet() bool { return s.KeyPath != "" }
+
+func (s SignFlags) Validate() error {
+	if !s.IsSet() && len(s.Annotations) > 0 {
+		return fmt.Errorf("Expected --sign-annotation to be used with --sign-key")
+	}
+	_, err := s.AnnotationsMap()
+	return err
+}
+
+// AnnotationsMap returns claims to include in the signed payload
+func (s SignFlags) AnnotationsMap() (map[string]string, error) {
+	annotations := map[string]string{}
+	for _, annotation := range s.Annotations {
+		pieces := strings.SplitN(annotation, "=", 2)
+		if len(pieces) != 2 || pieces[0] == "" {
+			return nil, fmt.Errorf("Expected --sign-annotation '%s' to be in format key=value", annotation)
+		}
+		annotations[pieces[0]] = pieces[1]
+	}
+	return annotations, nil
+}
+
+func (s SignFlags) Signer(registry signature.ImagesReaderWriter) (*signature.Signer, error) {
+	key, err := signature.LoadPrivateKey(s.KeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return signature.NewSigner(registry, key), nil
+}
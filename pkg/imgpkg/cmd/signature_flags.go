@@ -3,12 +3,34 @@
 
 package cmd
 
-import "github.com/spf13/cobra"
+import (
+	"github.com/k14s/imgpkg/pkg/imgpkg/signature"
+	"github.com/spf13/cobra"
+)
 
 type SignatureFlags struct {
-	CopyCosignSignatures bool
+	CopyCosignSignatures   bool
+	CopyCosignAttestations bool
+	CopyCosignSBOMs        bool
 }
 
 func (s *SignatureFlags) Set(cmd *cobra.Command) {
 	cmd.Flags().BoolVar(&s.CopyCosignSignatures, "cosign-signatures", false, "Find and copy cosign signatures for images")
+	cmd.Flags().BoolVar(&s.CopyCosignAttestations, "cosign-attestations", false, "Find and copy cosign attestations for images")
+	cmd.Flags().BoolVar(&s.CopyCosignSBOMs, "cosign-sboms", false, "Find and copy cosign SBOMs for images")
+}
+
+// Artifacts returns kinds of cosign artifacts to copy alongside images
+func (s *SignatureFlags) Artifacts() []signature.Artifact {
+	var artifacts []signature.Artifact
+	if s.CopyCosignSignatures {
+		artifacts = append(artifacts, signature.SignatureArtifact)
+	}
+	if s.CopyCosignAttestations {
+		artifacts = append(artifacts, signature.AttestationArtifact)
+	}
+	if s.CopyCosignSBOMs {
+		artifacts = append(artifacts, signature.SBOMArtifact)
+	}
+	return artifacts
 }
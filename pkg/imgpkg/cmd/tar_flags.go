@@ -14,5 +14,5 @@ type TarFlags struct {
 
 func (t *TarFlags) Set(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&t.TarDst, "to-tar", "", "Location to write a tar file containing assets")
-	cmd.Flags().StringVar(&t.TarSrc, "tar", "", "Path to tar file which contains assets to be copied to a registry")
+	cmd.Flags().StringVar(&t.TarSrc, "tar", "", "Path to tar file or OCI image layout directory which contains assets to be copied to a registry")
 }
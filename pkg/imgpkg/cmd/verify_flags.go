@@ -0,0 +1,59 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+
+	ctlimg "github.com/k14s/imgpkg/pkg/imgpkg/image"
+	"github.com/k14s/imgpkg/pkg/imgpkg/signature"
+	"github.com/spf13/cobra"
+)
+
+const (
+	verifyScopeAll     = "all"
+	verifyScopeBundles = "bundles"
+)
+
+type ImageVerifier interface {
+	Verify(imageRefs []string) error
+}
+
+type VerifyFlags struct {
+	KeyPaths []string
+	Scope    string
+}
+
+func (v *VerifyFlags) Set(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&v.KeyPaths, "verify-key", nil,
+		"Cosign public key that images must be signed with (ECDSA or ED25519) (can be specified multiple times)")
+	cmd.Flags().StringVar(&v.Scope, "verify-scope", verifyScopeAll,
+		fmt.Sprintf("Images whose signatures are verified with --verify-key (%s, %s)", verifyScopeAll, verifyScopeBundles))
+}
+
+func (v *VerifyFlags) IsSet() bool { return len(v.KeyPaths) > 0 }
+
+func (v *VerifyFlags) Validate() error {
+	if v.Scope != "" && v.Scope != verifyScopeAll && v.Scope != verifyScopeBundles {
+		return fmt.Errorf("Unknown --verify-scope '%s' (known: %s, %s)", v.Scope, verifyScopeAll, verifyScopeBundles)
+	}
+	if v.Scope == verifyScopeBundles && !v.IsSet() {
+		return fmt.Errorf("Expected --verify-scope to be used with --verify-key")
+	}
+	return nil
+}
+
+// Verifier returns a verifier checking signatures with provided keys, or one that does not verify anything when none were provided
+func (v *VerifyFlags) Verifier(registry ctlimg.ImagesMetadata, concurrency int) (ImageVerifier, error) {
+	if !v.IsSet() {
+		return signature.NewNoop(), nil
+	}
+
+	keys, err := signature.LoadPublicKeys(v.KeyPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	return signature.NewVerifier(registry, keys, concurrency), nil
+}
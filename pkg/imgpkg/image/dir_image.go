@@ -51,7 +51,7 @@ func (i *DirImage) AsDirectory() error {
 
 		i.ui.BeginLinef("Extracting layer '%s' (%d/%d)\n", digest, idx+1, len(layers))
 
-		layerStream, err := imgLayer.Uncompressed()
+		layerStream, err := UncompressedLayer(imgLayer)
 		if err != nil {
 			return err
 		}
@@ -72,6 +72,10 @@ func (i *DirImage) AsDirectory() error {
 func (i *DirImage) writeLayer(stream io.Reader) error {
 	tarReader := tar.NewReader(stream)
 
+	// Opaque whiteouts only apply to previous layers, hence entries of this layer
+	// are kept even when they come before the marker in the tar stream
+	writtenPaths := map[string]struct{}{}
+
 	for {
 		hdr, err := tarReader.Next()
 		if err != nil {
@@ -85,9 +89,18 @@ func (i *DirImage) writeLayer(stream io.Reader) error {
 		base := filepath.Base(path)
 
 		const (
-			whiteoutPrefix = ".wh."
+			whiteoutPrefix       = ".wh."
+			whiteoutOpaqueMarker = ".wh..wh..opq"
 		)
 
+		if base == whiteoutOpaqueMarker {
+			err := i.removeDirContents(filepath.Dir(path), writtenPaths)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
 		if strings.HasPrefix(base, whiteoutPrefix) {
 			dir := filepath.Dir(path)
 
@@ -113,6 +126,48 @@ func (i *DirImage) writeLayer(stream io.Reader) error {
 		if err != nil {
 			return err
 		}
+
+		i.recordWrittenPath(path, writtenPaths)
+	}
+
+	return nil
+}
+
+// recordWrittenPath records path and its parent directories (created when extracting it)
+func (i *DirImage) recordWrittenPath(path string, writtenPaths map[string]struct{}) {
+	for dirPath := filepath.Clean(i.dirPath); len(path) > len(dirPath); path = filepath.Dir(path) {
+		writtenPaths[path] = struct{}{}
+	}
+}
+
+// removeDirContents handles opaque whiteouts: content of the directory coming
+// from previous layers is removed while the directory itself is kept
+func (i *DirImage) removeDirContents(dir string, writtenPaths map[string]struct{}) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, entry := range entries {
+		entryPath := filepath.Join(dir, entry.Name())
+
+		if _, written := writtenPaths[entryPath]; written {
+			if entry.IsDir() {
+				err := i.removeDirContents(entryPath, writtenPaths)
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		err := os.RemoveAll(entryPath)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
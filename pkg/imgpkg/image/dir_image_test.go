@@ -0,0 +1,77 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package image_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	goui "github.com/cppforlife/go-cli-ui/ui"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	ctlimg "github.com/k14s/imgpkg/pkg/imgpkg/image"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirImageOpaqueWhiteout(t *testing.T) {
+	baseLayer := tarLayer(t, []tarEntry{
+		{"config/old.yml", "old"},
+		{"config/nested/old.yml", "old"},
+		{"other/kept.yml", "kept"},
+	})
+	topLayer := tarLayer(t, []tarEntry{
+		{"config/new.yml", "new"},
+		{"config/nested/new.yml", "new"},
+		// Marker placed after entries of the same layer
+		{"config/.wh..wh..opq", ""},
+		{"config/nested/after-marker.yml", "after marker"},
+		{"other/added.yml", "added"},
+	})
+
+	img, err := mutate.AppendLayers(empty.Image, baseLayer, topLayer)
+	require.NoError(t, err)
+
+	outputDir := filepath.Join(t.TempDir(), "output")
+	require.NoError(t, ctlimg.NewDirImage(outputDir, img, goui.NewNoopUI()).AsDirectory())
+
+	for _, path := range []string{"config/new.yml", "config/nested/new.yml", "config/nested/after-marker.yml", "other/kept.yml", "other/added.yml"} {
+		assert.FileExists(t, filepath.Join(outputDir, path))
+	}
+	for _, path := range []string{"config/old.yml", "config/nested/old.yml", "config/.wh..wh..opq"} {
+		_, err := os.Stat(filepath.Join(outputDir, path))
+		assert.True(t, os.IsNotExist(err), "Expected %s to be removed", path)
+	}
+}
+
+type tarEntry struct {
+	name     string
+	contents string
+}
+
+func tarLayer(t *testing.T, entries []tarEntry) regv1.Layer {
+	buf := &bytes.Buffer{}
+	tarWriter := tar.NewWriter(buf)
+	for _, entry := range entries {
+		require.NoError(t, tarWriter.WriteHeader(&tar.Header{
+			Name: entry.name, Typeflag: tar.TypeReg, Mode: 0600, Size: int64(len(entry.contents)),
+		}))
+		_, err := tarWriter.Write([]byte(entry.contents))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tarWriter.Close())
+
+	layer, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(buf.Bytes())), nil
+	})
+	require.NoError(t, err)
+	return layer
+}
@@ -4,49 +4,70 @@
 package image
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
 	"fmt"
-	"io"
 	"os"
 
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/empty"
 	"github.com/google/go-containerregistry/pkg/v1/mutate"
-	"github.com/google/go-containerregistry/pkg/v1/partial"
 	"github.com/google/go-containerregistry/pkg/v1/types"
 )
 
 type FileImage struct {
 	v1.Image
-	path string
+	paths []string
 }
 
+// NewFileImage builds a single layer image out of an uncompressed tarball
 func NewFileImage(path string, labels map[string]string) (*FileImage, error) {
-	sha256, err := sha256Path(path)
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	layer, err := NewCompressedFileLayer(file, GzipCompression, DefaultCompressionLevel)
 	if err != nil {
 		return nil, err
 	}
 
-	layer, err := partial.UncompressedToLayer(&UncompressedFileLayer{
-		diffID:    v1.Hash{Algorithm: "sha256", Hex: sha256},
-		mediaType: types.DockerLayer,
-		path:      path,
-	})
+	img, err := NewFileImageWithLayers([]*CompressedFileLayer{layer}, labels)
 	if err != nil {
+		_ = os.Remove(layer.Path())
 		return nil, err
 	}
 
-	add := mutate.Addendum{
-		Layer: layer,
-		History: v1.History{
-			Author:    "imgpkg",
-			CreatedBy: "imgpkg",
-			Created:   v1.Time{}, // static
-		},
+	img.paths = append(img.paths, path)
+
+	return img, nil
+}
+
+// NewFileImageWithLayers builds an image out of the provided layers (base layer first)
+func NewFileImageWithLayers(layers []*CompressedFileLayer, labels map[string]string) (*FileImage, error) {
+	var adds []mutate.Addendum
+	var paths []string
+
+	base := empty.Image
+
+	for _, layer := range layers {
+		// zstd compressed layers are only defined by OCI image spec
+		if IsZstdLayer(layer.mediaType) {
+			base = mutate.MediaType(empty.Image, types.OCIManifestSchema1)
+		}
+
+		adds = append(adds, mutate.Addendum{
+			Layer: layer,
+			History: v1.History{
+				Author:    "imgpkg",
+				CreatedBy: "imgpkg",
+				Created:   v1.Time{}, // static
+			},
+		})
+		paths = append(paths, layer.Path())
 	}
 
-	img, err := mutate.Append(empty.Image, add)
+	img, err := mutate.Append(base, adds...)
 	if err != nil {
 		return nil, err
 	}
@@ -65,27 +86,16 @@ func NewFileImage(path string, labels map[string]string) (*FileImage, error) {
 		}
 	}
 
-	return &FileImage{img, path}, nil
+	return &FileImage{img, paths}, nil
 }
 
 func (i *FileImage) Remove() error {
-	return os.Remove(i.path)
-}
-
-func sha256Path(path string) (string, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return "", err
-	}
-
-	defer file.Close()
-
-	hash := sha256.New()
-
-	_, err = io.Copy(hash, file)
-	if err != nil {
-		return "", err
+	var lastErr error
+	for _, path := range i.paths {
+		err := os.Remove(path)
+		if err != nil {
+			lastErr = err
+		}
 	}
-
-	return hex.EncodeToString(hash.Sum(nil)), nil
+	return lastErr
 }
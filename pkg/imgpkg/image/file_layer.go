@@ -4,31 +4,165 @@
 package image
 
 import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
 	"io"
+	"io/ioutil"
 	"os"
 
 	regv1 "github.com/google/go-containerregistry/pkg/v1"
-	regpartial "github.com/google/go-containerregistry/pkg/v1/partial"
 	regtypes "github.com/google/go-containerregistry/pkg/v1/types"
+	ctlgzip "github.com/k14s/imgpkg/pkg/imgpkg/imageutils/gzip"
+	ctlzstd "github.com/k14s/imgpkg/pkg/imgpkg/imageutils/zstd"
 )
 
-type UncompressedFileLayer struct {
+// CompressedFileLayer is a layer whose compressed blob is stored in a file.
+// Its digests and size are known upfront, so the file never needs to be re-read
+// to describe the layer.
+type CompressedFileLayer struct {
+	path      string
+	digest    regv1.Hash
 	diffID    regv1.Hash
+	size      int64
 	mediaType regtypes.MediaType
-	path      string
 }
 
-var _ regpartial.UncompressedLayer = (*UncompressedFileLayer)(nil)
+var _ regv1.Layer = (*CompressedFileLayer)(nil)
+
+// NewCompressedFileLayer compresses the provided uncompressed tar stream into a temporary file.
+// Zero compression level falls back to the default level of the compression algorithm.
+func NewCompressedFileLayer(uncompressed io.Reader, compression Compression, compressionLevel int) (*CompressedFileLayer, error) {
+	writer, err := newCompressingWriter(compression, compressionLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = io.Copy(writer, uncompressed)
+	if err != nil {
+		writer.Remove()
+		return nil, err
+	}
+
+	layer, err := writer.Close()
+	if err != nil {
+		writer.Remove()
+		return nil, err
+	}
+
+	return layer, nil
+}
+
+func (l *CompressedFileLayer) Digest() (regv1.Hash, error) { return l.digest, nil }
+
+func (l *CompressedFileLayer) DiffID() (regv1.Hash, error) { return l.diffID, nil }
+
+func (l *CompressedFileLayer) Size() (int64, error) { return l.size, nil }
+
+func (l *CompressedFileLayer) MediaType() (regtypes.MediaType, error) { return l.mediaType, nil }
+
+func (l *CompressedFileLayer) Compressed() (io.ReadCloser, error) {
+	return os.Open(l.path)
+}
+
+func (l *CompressedFileLayer) Uncompressed() (io.ReadCloser, error) {
+	file, err := os.Open(l.path)
+	if err != nil {
+		return nil, err
+	}
+
+	if IsZstdLayer(l.mediaType) {
+		return ctlzstd.UnzipReadCloser(file)
+	}
+	return ctlgzip.UnzipReadCloser(file)
+}
+
+// Path returns location of the compressed blob
+func (l *CompressedFileLayer) Path() string { return l.path }
+
+// compressingWriter compresses everything written to it into a temporary file
+// while hashing both the uncompressed and the compressed streams,
+// so that content is only read once
+type compressingWriter struct {
+	io.Writer
+
+	file             *os.File
+	fileWriter       *bufio.Writer
+	compressedWriter io.WriteCloser
+	mediaType        regtypes.MediaType
+	diffIDHash       hash.Hash
+	digestHash       hash.Hash
+	size             *countingWriter
+}
+
+func newCompressingWriter(compression Compression, compressionLevel int) (*compressingWriter, error) {
+	if compressionLevel == 0 {
+		compressionLevel = compression.defaultLevel()
+	}
+
+	file, err := ioutil.TempFile("", "imgpkg-layer")
+	if err != nil {
+		return nil, err
+	}
+
+	w := &compressingWriter{
+		file:       file,
+		fileWriter: bufio.NewWriterSize(file, 1024*1024),
+		mediaType:  compression.layerMediaType(),
+		diffIDHash: sha256.New(),
+		digestHash: sha256.New(),
+		size:       &countingWriter{},
+	}
+
+	w.compressedWriter, err = compression.newWriter(io.MultiWriter(w.fileWriter, w.digestHash, w.size), compressionLevel)
+	if err != nil {
+		w.Remove()
+		return nil, fmt.Errorf("Creating %s writer: %s", compression, err)
+	}
+
+	w.Writer = io.MultiWriter(w.diffIDHash, w.compressedWriter)
+
+	return w, nil
+}
+
+// Close flushes all compressed data to the file and returns the resulting layer
+func (w *compressingWriter) Close() (*CompressedFileLayer, error) {
+	err := w.compressedWriter.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	err = w.fileWriter.Flush()
+	if err != nil {
+		return nil, err
+	}
+
+	err = w.file.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return &CompressedFileLayer{
+		path:      w.file.Name(),
+		digest:    regv1.Hash{Algorithm: "sha256", Hex: hex.EncodeToString(w.digestHash.Sum(nil))},
+		diffID:    regv1.Hash{Algorithm: "sha256", Hex: hex.EncodeToString(w.diffIDHash.Sum(nil))},
+		size:      w.size.count,
+		mediaType: w.mediaType,
+	}, nil
+}
 
-func (ul *UncompressedFileLayer) DiffID() (regv1.Hash, error) {
-	return ul.diffID, nil
+func (w *compressingWriter) Remove() {
+	_ = w.file.Close()
+	_ = os.Remove(w.file.Name())
 }
 
-func (ul *UncompressedFileLayer) Uncompressed() (io.ReadCloser, error) {
-	file, err := os.Open(ul.path)
-	return file, err
+type countingWriter struct {
+	count int64
 }
 
-func (ul *UncompressedFileLayer) MediaType() (regtypes.MediaType, error) {
-	return ul.mediaType, nil
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.count += int64(len(p))
+	return len(p), nil
 }
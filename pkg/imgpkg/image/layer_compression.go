@@ -0,0 +1,102 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package image
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	regtypes "github.com/google/go-containerregistry/pkg/v1/types"
+	ctlzstd "github.com/k14s/imgpkg/pkg/imgpkg/imageutils/zstd"
+)
+
+// Compression is the algorithm used to compress layers
+type Compression string
+
+const (
+	GzipCompression Compression = "gzip"
+	ZstdCompression Compression = "zstd"
+)
+
+// Layer media types that are not (yet) known to go-containerregistry
+const (
+	OCILayerZstd           regtypes.MediaType = "application/vnd.oci.image.layer.v1.tar+zstd"
+	OCIRestrictedLayerZstd regtypes.MediaType = "application/vnd.oci.image.layer.nondistributable.v1.tar+zstd"
+)
+
+func NewCompression(name string) (Compression, error) {
+	switch Compression(name) {
+	case GzipCompression, ZstdCompression:
+		return Compression(name), nil
+	default:
+		return "", fmt.Errorf("Unknown compression '%s' (known: %s, %s)", name, GzipCompression, ZstdCompression)
+	}
+}
+
+// LevelRange returns the minimum and maximum compression levels
+func (c Compression) LevelRange() (int, int) {
+	if c == ZstdCompression {
+		return 1, 22
+	}
+	return gzip.BestSpeed, gzip.BestCompression
+}
+
+func (c Compression) defaultLevel() int {
+	if c == ZstdCompression {
+		return ctlzstd.DefaultLevel
+	}
+	return DefaultCompressionLevel
+}
+
+func (c Compression) layerMediaType() regtypes.MediaType {
+	if c == ZstdCompression {
+		return OCILayerZstd
+	}
+	return regtypes.DockerLayer
+}
+
+func (c Compression) newWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	if c == ZstdCompression {
+		return ctlzstd.NewWriterLevel(w, level)
+	}
+	return gzip.NewWriterLevel(w, level)
+}
+
+// IsZstdLayer checks if the layer media type indicates zstd compressed content
+func IsZstdLayer(mediaType regtypes.MediaType) bool {
+	return mediaType == OCILayerZstd || mediaType == OCIRestrictedLayerZstd
+}
+
+// IsGzipLayer checks if the layer media type indicates gzip compressed content
+func IsGzipLayer(mediaType regtypes.MediaType) bool {
+	switch mediaType {
+	case regtypes.DockerLayer, regtypes.DockerForeignLayer, regtypes.OCILayer, regtypes.OCIRestrictedLayer:
+		return true
+	default:
+		return false
+	}
+}
+
+// UncompressedLayer returns the layer contents uncompressed.
+// go-containerregistry always expects gzip compressed layers,
+// hence zstd compressed layers are decompressed here.
+func UncompressedLayer(layer regv1.Layer) (io.ReadCloser, error) {
+	mediaType, err := layer.MediaType()
+	if err != nil {
+		return nil, err
+	}
+
+	if !IsZstdLayer(mediaType) {
+		return layer.Uncompressed()
+	}
+
+	rc, err := layer.Compressed()
+	if err != nil {
+		return nil, err
+	}
+
+	return ctlzstd.UnzipReadCloser(rc)
+}
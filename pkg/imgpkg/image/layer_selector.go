@@ -0,0 +1,48 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package image
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// LayerSelector decides in which layer a file or directory is placed.
+// Entries that share the same key end up in the same layer and layers
+// are ordered by key, so pushing the same content always results
+// in the same layers.
+type LayerSelector interface {
+	LayerKey(fileFlagIdx int, relPath string, isDir bool) string
+}
+
+// SingleLayer places all the content in a single layer
+type SingleLayer struct{}
+
+var _ LayerSelector = SingleLayer{}
+
+func (SingleLayer) LayerKey(int, string, bool) string { return "" }
+
+// LayerPerFilePath places the content of each provided path in its own layer
+type LayerPerFilePath struct{}
+
+var _ LayerSelector = LayerPerFilePath{}
+
+func (LayerPerFilePath) LayerKey(fileFlagIdx int, _ string, _ bool) string {
+	return fmt.Sprintf("%04d", fileFlagIdx)
+}
+
+// LayerPerDir places each top level directory in its own layer.
+// Files that are not part of a top level directory share a single layer.
+type LayerPerDir struct{}
+
+var _ LayerSelector = LayerPerDir{}
+
+func (LayerPerDir) LayerKey(_ int, relPath string, isDir bool) string {
+	parts := strings.SplitN(filepath.ToSlash(filepath.Clean(relPath)), "/", 2)
+	if len(parts) == 1 && (!isDir || parts[0] == ".") {
+		return ""
+	}
+	return parts[0]
+}
@@ -0,0 +1,111 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package image
+
+import (
+	"fmt"
+	"sync"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	regremote "github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// LocalImages serves images that only exist locally (i.e. were never pushed to a registry)
+// so that they can be exported the same way as images retrieved from a registry
+type LocalImages struct {
+	images     map[string]regv1.Image
+	imagesLock sync.Mutex
+}
+
+var _ ImagesMetadata = &LocalImages{}
+
+func NewLocalImages() *LocalImages {
+	return &LocalImages{images: map[string]regv1.Image{}}
+}
+
+// Add makes the image available under the provided repository and returns its digest reference
+func (l *LocalImages) Add(repo regname.Repository, img regv1.Image) (regname.Digest, error) {
+	digest, err := img.Digest()
+	if err != nil {
+		return regname.Digest{}, err
+	}
+
+	l.imagesLock.Lock()
+	defer l.imagesLock.Unlock()
+
+	l.images[digest.String()] = img
+
+	return repo.Digest(digest.String()), nil
+}
+
+func (l *LocalImages) Get(ref regname.Reference) (*regremote.Descriptor, error) {
+	img, err := l.Image(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaType, err := img.MediaType()
+	if err != nil {
+		return nil, err
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := img.RawManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	return &regremote.Descriptor{
+		Descriptor: regv1.Descriptor{
+			MediaType: mediaType,
+			Digest:    digest,
+			Size:      int64(len(manifest)),
+		},
+		Manifest: manifest,
+	}, nil
+}
+
+func (l *LocalImages) Digest(ref regname.Reference) (regv1.Hash, error) {
+	img, err := l.Image(ref)
+	if err != nil {
+		return regv1.Hash{}, err
+	}
+	return img.Digest()
+}
+
+func (l *LocalImages) Index(ref regname.Reference) (regv1.ImageIndex, error) {
+	return nil, fmt.Errorf("Expected '%s' to be an image, but local image indexes are not supported", ref.Name())
+}
+
+func (l *LocalImages) Image(ref regname.Reference) (regv1.Image, error) {
+	digestRef, ok := ref.(regname.Digest)
+	if !ok {
+		return nil, fmt.Errorf("Expected '%s' to be a digest reference", ref.Name())
+	}
+
+	l.imagesLock.Lock()
+	defer l.imagesLock.Unlock()
+
+	img, found := l.images[digestRef.DigestStr()]
+	if !found {
+		return nil, fmt.Errorf("Expected to find local image '%s'", ref.Name())
+	}
+	return img, nil
+}
+
+func (l *LocalImages) FirstImageExists(digests []string) (string, error) {
+	for _, digest := range digests {
+		ref, err := regname.NewDigest(digest)
+		if err != nil {
+			return "", err
+		}
+		if _, err := l.Image(ref); err == nil {
+			return digest, nil
+		}
+	}
+	return "", fmt.Errorf("Checking image existence: none of the images were found locally")
+}
@@ -5,58 +5,94 @@ package image
 
 import (
 	"archive/tar"
+	"compress/gzip"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"time"
 )
 
+// DefaultCompressionLevel is used to compress gzip layers unless configured otherwise
+const DefaultCompressionLevel = gzip.BestSpeed
+
+// LayerOpts configures how content is split into layers and how those layers are compressed
+type LayerOpts struct {
+	// Selector defaults to SingleLayer
+	Selector LayerSelector
+	// Compression defaults to GzipCompression
+	Compression Compression
+	// CompressionLevel defaults to default level of the compression algorithm
+	CompressionLevel int
+}
+
+func (o LayerOpts) selector() LayerSelector {
+	if o.Selector == nil {
+		return SingleLayer{}
+	}
+	return o.Selector
+}
+
+func (o LayerOpts) compression() Compression {
+	if o.Compression == "" {
+		return GzipCompression
+	}
+	return o.Compression
+}
+
 type TarImage struct {
 	files        []string
 	excludePaths []string
+	layerOpts    LayerOpts
 	infoLog      io.Writer
 }
 
-func NewTarImage(files []string, excludePaths []string, infoLog io.Writer) *TarImage {
-	return &TarImage{files, excludePaths, infoLog}
+func NewTarImage(files []string, excludePaths []string, layerOpts LayerOpts, infoLog io.Writer) *TarImage {
+	return &TarImage{files, excludePaths, layerOpts, infoLog}
 }
 
+// AsFileImage tars, hashes and compresses content in a single pass
 func (i *TarImage) AsFileImage(labels map[string]string) (*FileImage, error) {
-	tmpFile, err := ioutil.TempFile("", "imgpkg-tar-image")
-	if err != nil {
-		return nil, err
+	tarballs := &layerTarballs{
+		tarballs:         map[string]*layerTarball{},
+		compression:      i.layerOpts.compression(),
+		compressionLevel: i.layerOpts.CompressionLevel,
 	}
 
-	err = i.createTarball(tmpFile, i.files)
+	err := i.createTarballs(tarballs, i.files)
 	if err != nil {
-		_ = tmpFile.Close()
-		_ = os.Remove(tmpFile.Name())
+		tarballs.Remove()
 		return nil, err
 	}
 
-	// Close file explicitly to make sure all data is flushed
-	err = tmpFile.Close()
+	// Always produce at least one (possibly empty) layer
+	if len(tarballs.tarballs) == 0 {
+		_, err = tarballs.Writer("")
+		if err != nil {
+			tarballs.Remove()
+			return nil, err
+		}
+	}
+
+	// Close files explicitly to make sure all data is flushed
+	layers, err := tarballs.Close()
 	if err != nil {
-		_ = os.Remove(tmpFile.Name())
+		tarballs.Remove()
 		return nil, err
 	}
 
-	fileImg, err := NewFileImage(tmpFile.Name(), labels)
+	fileImg, err := NewFileImageWithLayers(layers, labels)
 	if err != nil {
-		_ = os.Remove(tmpFile.Name())
+		tarballs.Remove()
 		return nil, err
 	}
 
 	return fileImg, nil
 }
 
-func (i *TarImage) createTarball(file *os.File, filePaths []string) error {
-	tarWriter := tar.NewWriter(file)
-	defer tarWriter.Close()
-
-	for _, path := range filePaths {
+func (i *TarImage) createTarballs(tarballs *layerTarballs, filePaths []string) error {
+	for fileFlagIdx, path := range filePaths {
 		info, err := os.Stat(path)
 		if err != nil {
 			return err
@@ -76,18 +112,37 @@ func (i *TarImage) createTarball(file *os.File, filePaths []string) error {
 					if i.isExcluded(relPath) {
 						return filepath.SkipDir
 					}
+					tarWriter, err := tarballs.Writer(i.layerOpts.selector().LayerKey(fileFlagIdx, relPath, true))
+					if err != nil {
+						return err
+					}
 					return i.addDirToTar(relPath, info, tarWriter)
 				}
 				if (info.Mode() & os.ModeType) != 0 {
 					return fmt.Errorf("Expected file '%s' to be a regular file", walkedPath)
 				}
+				if i.isExcluded(relPath) {
+					return nil
+				}
+				tarWriter, err := tarballs.Writer(i.layerOpts.selector().LayerKey(fileFlagIdx, relPath, false))
+				if err != nil {
+					return err
+				}
 				return i.addFileToTar(walkedPath, relPath, info, tarWriter)
 			})
 			if err != nil {
 				return fmt.Errorf("Adding file '%s' to tar: %s", path, err)
 			}
 		} else {
-			err := i.addFileToTar(path, filepath.Base(path), info, tarWriter)
+			relPath := filepath.Base(path)
+			if i.isExcluded(relPath) {
+				continue
+			}
+			tarWriter, err := tarballs.Writer(i.layerOpts.selector().LayerKey(fileFlagIdx, relPath, false))
+			if err != nil {
+				return err
+			}
+			err = i.addFileToTar(path, relPath, info, tarWriter)
 			if err != nil {
 				return err
 			}
@@ -99,7 +154,7 @@ func (i *TarImage) createTarball(file *os.File, filePaths []string) error {
 
 func (i *TarImage) addDirToTar(relPath string, info os.FileInfo, tarWriter *tar.Writer) error {
 	if i.isExcluded(relPath) {
-		panic("Unreachable") // directories excluded above
+		return nil
 	}
 
 	i.infoLog.Write([]byte(fmt.Sprintf("dir: %s\n", relPath)))
@@ -153,3 +208,65 @@ func (i *TarImage) isExcluded(relPath string) bool {
 	}
 	return false
 }
+
+type layerTarball struct {
+	compressed *compressingWriter
+	writer     *tar.Writer
+}
+
+// layerTarballs lazily creates a compressed temporary tarball for each layer key
+type layerTarballs struct {
+	tarballs         map[string]*layerTarball
+	compression      Compression
+	compressionLevel int
+}
+
+func (l *layerTarballs) Writer(key string) (*tar.Writer, error) {
+	if tarball, found := l.tarballs[key]; found {
+		return tarball.writer, nil
+	}
+
+	compressed, err := newCompressingWriter(l.compression, l.compressionLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	tarball := &layerTarball{compressed: compressed, writer: tar.NewWriter(compressed)}
+	l.tarballs[key] = tarball
+
+	return tarball.writer, nil
+}
+
+// Close flushes every tarball and returns resulting layers ordered by layer key
+func (l *layerTarballs) Close() ([]*CompressedFileLayer, error) {
+	var keys []string
+	for key := range l.tarballs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var layers []*CompressedFileLayer
+	for _, key := range keys {
+		tarball := l.tarballs[key]
+
+		err := tarball.writer.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		layer, err := tarball.compressed.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		layers = append(layers, layer)
+	}
+
+	return layers, nil
+}
+
+func (l *layerTarballs) Remove() {
+	for _, tarball := range l.tarballs {
+		tarball.compressed.Remove()
+	}
+}
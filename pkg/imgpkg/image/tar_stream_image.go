@@ -0,0 +1,117 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package image
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+)
+
+// TarStreamImage uses an existing tar stream, byte for byte, as the content of a single layer image.
+// Entries are validated while the stream is compressed so it never needs to be extracted.
+type TarStreamImage struct {
+	stream    io.Reader
+	layerOpts LayerOpts
+	infoLog   io.Writer
+
+	entryPaths []string
+}
+
+func NewTarStreamImage(stream io.Reader, layerOpts LayerOpts, infoLog io.Writer) *TarStreamImage {
+	return &TarStreamImage{stream: stream, layerOpts: layerOpts, infoLog: infoLog}
+}
+
+func (i *TarStreamImage) AsFileImage(labels map[string]string) (*FileImage, error) {
+	compressed, err := newCompressingWriter(i.layerOpts.compression(), i.layerOpts.CompressionLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	err = i.readEntries(io.TeeReader(i.stream, compressed))
+	if err != nil {
+		compressed.Remove()
+		return nil, err
+	}
+
+	layer, err := compressed.Close()
+	if err != nil {
+		compressed.Remove()
+		return nil, err
+	}
+
+	fileImg, err := NewFileImageWithLayers([]*CompressedFileLayer{layer}, labels)
+	if err != nil {
+		compressed.Remove()
+		return nil, err
+	}
+
+	return fileImg, nil
+}
+
+// EntryPaths returns slash separated paths of all entries (relative to the root of the stream)
+// including directories that are only implied by nested entries. Available once the image was built.
+func (i *TarStreamImage) EntryPaths() []string {
+	return append([]string{}, i.entryPaths...)
+}
+
+func (i *TarStreamImage) readEntries(stream io.Reader) error {
+	seenPaths := map[string]struct{}{}
+	addPath := func(entryPath string) {
+		if _, found := seenPaths[entryPath]; !found {
+			seenPaths[entryPath] = struct{}{}
+			i.entryPaths = append(i.entryPaths, entryPath)
+		}
+	}
+
+	tarReader := tar.NewReader(stream)
+
+	for {
+		header, err := tarReader.Next()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return fmt.Errorf("Reading tar stream: %s", err)
+		}
+
+		entryPath, err := i.entryPath(header.Name)
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			i.infoLog.Write([]byte(fmt.Sprintf("dir: %s\n", entryPath)))
+		case tar.TypeReg, tar.TypeRegA:
+			i.infoLog.Write([]byte(fmt.Sprintf("file: %s\n", entryPath)))
+		default:
+			return fmt.Errorf("Expected tar entry '%s' to be a regular file or directory", header.Name)
+		}
+
+		if entryPath == "." {
+			continue
+		}
+
+		for dir := path.Dir(entryPath); dir != "."; dir = path.Dir(dir) {
+			addPath(dir)
+		}
+		addPath(entryPath)
+	}
+
+	// Consume end of archive padding so that layer contains the whole stream
+	_, err := io.Copy(ioutil.Discard, stream)
+	return err
+}
+
+func (i *TarStreamImage) entryPath(name string) (string, error) {
+	cleanPath := path.Clean(strings.TrimPrefix(name, "./"))
+	if path.IsAbs(cleanPath) || cleanPath == ".." || strings.HasPrefix(cleanPath, "../") {
+		return "", fmt.Errorf("Expected tar entry '%s' to be a relative path within the archive", name)
+	}
+	return cleanPath, nil
+}
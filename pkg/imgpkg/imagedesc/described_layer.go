@@ -9,8 +9,10 @@ import (
 
 	regv1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/types"
+	ctlimg "github.com/k14s/imgpkg/pkg/imgpkg/image"
 	"github.com/k14s/imgpkg/pkg/imgpkg/imageutils/gzip"
 	"github.com/k14s/imgpkg/pkg/imgpkg/imageutils/verify"
+	"github.com/k14s/imgpkg/pkg/imgpkg/imageutils/zstd"
 )
 
 type DescribedLayer struct {
@@ -45,7 +47,15 @@ func (l DescribedLayer) Uncompressed() (io.ReadCloser, error) {
 		return nil, fmt.Errorf("Creating verified reader: %v", err)
 	}
 
-	return gzip.ReadCloser(rc), nil
+	mediaType, err := l.MediaType()
+	if err != nil {
+		return nil, err
+	}
+
+	if ctlimg.IsZstdLayer(mediaType) {
+		return zstd.UnzipReadCloser(rc)
+	}
+	return gzip.UnzipReadCloser(rc)
 }
 
 func (l DescribedLayer) Size() (int64, error) { return l.desc.Size, nil }
@@ -4,6 +4,7 @@
 package imagedesc
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -34,7 +35,8 @@ type Metadata struct {
 }
 
 type ImageRefDescriptors struct {
-	registry Registry
+	registry       Registry
+	platformFilter PlatformFilter
 
 	descs []ImageOrImageIndexDescriptor
 
@@ -54,11 +56,18 @@ func NewImageRefDescriptorsFromBytes(data []byte) (*ImageRefDescriptors, error)
 }
 
 func NewImageRefDescriptors(refs []Metadata, registry Registry) (*ImageRefDescriptors, error) {
+	return NewImageRefDescriptorsWithPlatformFilter(refs, registry, PlatformFilter{})
+}
+
+// NewImageRefDescriptorsWithPlatformFilter only keeps image index manifests for selected platforms.
+// Filtered image indexes are described with a new manifest, hence they get a new digest.
+func NewImageRefDescriptorsWithPlatformFilter(refs []Metadata, registry Registry, platformFilter PlatformFilter) (*ImageRefDescriptors, error) {
 	registry = errRegistry{registry}
 
 	imageRefDescs := &ImageRefDescriptors{
-		registry:    registry,
-		imageLayers: map[ImageLayerDescriptor]regv1.Layer{},
+		registry:       registry,
+		platformFilter: platformFilter,
+		imageLayers:    map[ImageLayerDescriptor]regv1.Layer{},
 	}
 
 	var imageRefDescsLock sync.Mutex
@@ -85,6 +94,9 @@ func NewImageRefDescriptors(refs []Metadata, registry Registry) (*ImageRefDescri
 				if err != nil {
 					return err
 				}
+				if len(imgIndexTd.Images) == 0 && len(imgIndexTd.Indexes) == 0 && !imageRefDescs.platformFilter.IsEmpty() {
+					return fmt.Errorf("Expected image index '%s' to contain images for platforms %s", ref.Ref.Name(), imageRefDescs.platformFilter)
+				}
 
 				td = ImageOrImageIndexDescriptor{ImageIndex: &imgIndexTd}
 			} else {
@@ -139,25 +151,144 @@ func (ids *ImageRefDescriptors) buildImageIndex(ref Metadata, regDesc regv1.Desc
 		return td, err
 	}
 
-	for _, manDesc := range imgIndexManifest.Manifests {
+	// keptManDescs are indexed by position in the image index manifest
+	keptManDescs := map[int]regv1.Descriptor{}
+	manifestChanged := false
+
+	for manIdx, manDesc := range imgIndexManifest.Manifests {
 		if ids.isImageIndex(manDesc) {
 			imgIndexTd, err := ids.buildImageIndex(Metadata{ids.buildRef(ref.Ref, manDesc.Digest.String()), ref.Tag, ref.Labels}, manDesc)
 			if err != nil {
 				return ImageIndexDescriptor{}, err
 			}
+			if len(imgIndexTd.Images) == 0 && len(imgIndexTd.Indexes) == 0 && !ids.platformFilter.IsEmpty() {
+				manifestChanged = true
+				continue
+			}
+			if imgIndexTd.Digest != manDesc.Digest.String() {
+				manDesc.Digest, err = regv1.NewHash(imgIndexTd.Digest)
+				if err != nil {
+					return ImageIndexDescriptor{}, err
+				}
+				manDesc.Size = int64(len(imgIndexTd.Raw))
+				manifestChanged = true
+			}
 			td.Indexes = append(td.Indexes, imgIndexTd)
 		} else {
+			if !ids.platformFilter.Includes(manDesc.Platform) {
+				manifestChanged = true
+				continue
+			}
 			imgTd, err := ids.buildImage(Metadata{ids.buildRef(ref.Ref, manDesc.Digest.String()), ref.Tag, ref.Labels})
 			if err != nil {
 				return ImageIndexDescriptor{}, err
 			}
 			td.Images = append(td.Images, imgTd)
 		}
+		keptManDescs[manIdx] = manDesc
+	}
+
+	if manifestChanged {
+		err = ids.rewriteImageIndexManifest(&td, keptManDescs)
+		if err != nil {
+			return ImageIndexDescriptor{}, fmt.Errorf("Filtering platforms of image index '%s': %s", ref.Ref.Name(), err)
+		}
 	}
 
 	return td, nil
 }
 
+// rewriteImageIndexManifest keeps only given manifests (by position) in the raw index manifest
+// while preserving the rest of its fields (including unknown fields of kept manifests),
+// and updates digest accordingly
+func (ids *ImageRefDescriptors) rewriteImageIndexManifest(td *ImageIndexDescriptor, manDescs map[int]regv1.Descriptor) error {
+	var rawFields map[string]json.RawMessage
+
+	err := json.Unmarshal([]byte(td.Raw), &rawFields)
+	if err != nil {
+		return err
+	}
+
+	var rawManDescs []json.RawMessage
+
+	err = json.Unmarshal(rawFields["manifests"], &rawManDescs)
+	if err != nil {
+		return err
+	}
+
+	keptRawManDescs := []json.RawMessage{}
+
+	for manIdx, rawManDesc := range rawManDescs {
+		manDesc, found := manDescs[manIdx]
+		if !found {
+			continue
+		}
+		rawManDesc, err = ids.rewriteManifestDescriptor(rawManDesc, manDesc)
+		if err != nil {
+			return err
+		}
+		keptRawManDescs = append(keptRawManDescs, rawManDesc)
+	}
+
+	if len(keptRawManDescs) != len(manDescs) {
+		return fmt.Errorf("Expected image index manifest to list %d kept manifests, but found %d", len(manDescs), len(keptRawManDescs))
+	}
+
+	rawFields["manifests"], err = json.Marshal(keptRawManDescs)
+	if err != nil {
+		return err
+	}
+
+	rawManifest, err := json.Marshal(rawFields)
+	if err != nil {
+		return err
+	}
+
+	digest, _, err := regv1.SHA256(bytes.NewReader(rawManifest))
+	if err != nil {
+		return err
+	}
+
+	td.Raw = string(rawManifest)
+	td.Digest = digest.String()
+
+	return nil
+}
+
+// rewriteManifestDescriptor updates digest and size of a raw manifest descriptor
+// (e.g. nested image index was filtered), leaving the rest of it untouched
+func (ids *ImageRefDescriptors) rewriteManifestDescriptor(rawManDesc json.RawMessage, manDesc regv1.Descriptor) (json.RawMessage, error) {
+	var rawDescFields map[string]json.RawMessage
+
+	err := json.Unmarshal(rawManDesc, &rawDescFields)
+	if err != nil {
+		return nil, err
+	}
+
+	var digest regv1.Hash
+
+	err = json.Unmarshal(rawDescFields["digest"], &digest)
+	if err != nil {
+		return nil, err
+	}
+
+	if digest == manDesc.Digest {
+		return rawManDesc, nil
+	}
+
+	rawDescFields["digest"], err = json.Marshal(manDesc.Digest)
+	if err != nil {
+		return nil, err
+	}
+
+	rawDescFields["size"], err = json.Marshal(manDesc.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(rawDescFields)
+}
+
 func (ids *ImageRefDescriptors) buildImage(ref Metadata) (ImageDescriptor, error) {
 	td := ImageDescriptor{}
 
@@ -221,7 +352,11 @@ func (ids *ImageRefDescriptors) buildImage(ref Metadata) (ImageDescriptor, error
 		}
 		layerDiffID, err := layer.DiffID()
 		if err != nil {
-			return td, err
+			if ids.configListsDiffIDs(cfgBlob) {
+				return td, err
+			}
+			// Configs of artifacts (e.g. attached files) do not list diff ids of their layers
+			layerDiffID = layerDigest
 		}
 		layerSize, err := layer.Size()
 		if err != nil {
@@ -245,6 +380,20 @@ func (ids *ImageRefDescriptors) buildImage(ref Metadata) (ImageDescriptor, error
 	return td, nil
 }
 
+// configListsDiffIDs returns true when the config has rootfs diff ids, hence every layer is expected to be listed
+func (*ImageRefDescriptors) configListsDiffIDs(cfgBlob []byte) bool {
+	var cfg struct {
+		RootFS struct {
+			DiffIDs []string `json:"diff_ids"`
+		} `json:"rootfs"`
+	}
+	// Configs of artifacts are not necessarily JSON objects
+	if json.Unmarshal(cfgBlob, &cfg) != nil {
+		return false
+	}
+	return len(cfg.RootFS.DiffIDs) > 0
+}
+
 func (*ImageRefDescriptors) isImageIndex(regDesc regv1.Descriptor) bool {
 	switch regDesc.MediaType {
 	case regtypes.OCIImageIndex, regtypes.DockerManifestList:
@@ -0,0 +1,58 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package imagedesc
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageRefDescriptors_rewriteImageIndexManifest(t *testing.T) {
+	amd64Digest := "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	arm64Digest := "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+	nestedIndexDigest := "sha256:3333333333333333333333333333333333333333333333333333333333333333"
+	filteredNestedIndexDigest := "sha256:4444444444444444444444444444444444444444444444444444444444444444"
+
+	td := ImageIndexDescriptor{
+		Raw: `{"schemaVersion":2,"mediaType":"application/vnd.oci.image.index.v1+json","manifests":[` +
+			`{"mediaType":"application/vnd.oci.image.manifest.v1+json","size":10,"digest":"` + amd64Digest + `","platform":{"os":"linux","architecture":"amd64"},"x-custom":{"kept":true}},` +
+			`{"mediaType":"application/vnd.oci.image.manifest.v1+json","size":20,"digest":"` + arm64Digest + `","platform":{"os":"linux","architecture":"arm64"}},` +
+			`{"mediaType":"application/vnd.oci.image.index.v1+json","size":30,"digest":"` + nestedIndexDigest + `","x-custom":"nested"}` +
+			`],"x-index-custom":"value"}`,
+	}
+
+	ids := &ImageRefDescriptors{}
+	err := ids.rewriteImageIndexManifest(&td, map[int]regv1.Descriptor{
+		0: {Digest: regv1.Hash{Algorithm: "sha256", Hex: amd64Digest[len("sha256:"):]}, Size: 10},
+		2: {Digest: regv1.Hash{Algorithm: "sha256", Hex: filteredNestedIndexDigest[len("sha256:"):]}, Size: 25},
+	})
+	require.NoError(t, err)
+
+	var rewritten struct {
+		Manifests     []map[string]interface{} `json:"manifests"`
+		IndexCustom   string                   `json:"x-index-custom"`
+		SchemaVersion int                      `json:"schemaVersion"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(td.Raw), &rewritten))
+
+	assert.Equal(t, "value", rewritten.IndexCustom)
+	assert.Equal(t, 2, rewritten.SchemaVersion)
+	require.Len(t, rewritten.Manifests, 2)
+
+	assert.Equal(t, amd64Digest, rewritten.Manifests[0]["digest"])
+	assert.Equal(t, map[string]interface{}{"kept": true}, rewritten.Manifests[0]["x-custom"])
+
+	assert.Equal(t, filteredNestedIndexDigest, rewritten.Manifests[1]["digest"])
+	assert.Equal(t, float64(25), rewritten.Manifests[1]["size"])
+	assert.Equal(t, "nested", rewritten.Manifests[1]["x-custom"])
+
+	digest, _, err := regv1.SHA256(strings.NewReader(td.Raw))
+	require.NoError(t, err)
+	assert.Equal(t, digest.String(), td.Digest)
+}
@@ -0,0 +1,76 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package imagedesc
+
+import (
+	"fmt"
+	"strings"
+
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// PlatformFilter selects which manifests of an image index are kept.
+// Zero value keeps all manifests.
+type PlatformFilter struct {
+	platforms []regv1.Platform
+}
+
+// NewPlatformFilter parses platforms in the form os/arch[/variant] (e.g. linux/arm64/v8)
+func NewPlatformFilter(platforms []string) (PlatformFilter, error) {
+	var filter PlatformFilter
+
+	for _, platform := range platforms {
+		pieces := strings.Split(platform, "/")
+		if len(pieces) < 2 || len(pieces) > 3 {
+			return PlatformFilter{}, fmt.Errorf("Expected platform '%s' to be in the form os/arch[/variant]", platform)
+		}
+		for _, piece := range pieces {
+			if piece == "" {
+				return PlatformFilter{}, fmt.Errorf("Expected platform '%s' to be in the form os/arch[/variant]", platform)
+			}
+		}
+
+		parsed := regv1.Platform{OS: pieces[0], Architecture: pieces[1]}
+		if len(pieces) == 3 {
+			parsed.Variant = pieces[2]
+		}
+		filter.platforms = append(filter.platforms, parsed)
+	}
+
+	return filter, nil
+}
+
+func (f PlatformFilter) IsEmpty() bool { return len(f.platforms) == 0 }
+
+// Includes returns true if manifest with given platform should be kept.
+// Manifests without a platform cannot be matched, hence are always kept.
+func (f PlatformFilter) Includes(platform *regv1.Platform) bool {
+	if f.IsEmpty() || platform == nil {
+		return true
+	}
+
+	for _, selected := range f.platforms {
+		if selected.OS != platform.OS || selected.Architecture != platform.Architecture {
+			continue
+		}
+		if selected.Variant != "" && selected.Variant != platform.Variant {
+			continue
+		}
+		return true
+	}
+
+	return false
+}
+
+func (f PlatformFilter) String() string {
+	var result []string
+	for _, platform := range f.platforms {
+		pieces := []string{platform.OS, platform.Architecture}
+		if platform.Variant != "" {
+			pieces = append(pieces, platform.Variant)
+		}
+		result = append(result, strings.Join(pieces, "/"))
+	}
+	return strings.Join(result, ", ")
+}
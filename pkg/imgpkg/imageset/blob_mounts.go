@@ -0,0 +1,223 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package imageset
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	regremote "github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/k14s/imgpkg/pkg/imgpkg/util"
+)
+
+// blobMounter finds blobs that are already present in other repositories of the import registry,
+// so that they are mounted into the import repository instead of being uploaded.
+// To read more about mounting across a repo: https://github.com/opencontainers/distribution-spec/blob/master/spec.md#mounting-a-blob-from-another-repository
+type blobMounter struct {
+	importRepo     regname.Repository
+	mountFromRepos []regname.Repository
+	registry       ImagesReaderWriter
+
+	blobs     map[regv1.Hash]*blobMountSource
+	blobsLock sync.Mutex
+}
+
+type blobMountSource struct {
+	once sync.Once
+	// repo is nil when blob does not need to be or cannot be mounted
+	repo *regname.Repository
+	size int64
+}
+
+// newBlobMounter returns nil when none of the repositories blobs can be mounted from
+// live in the same registry as the import repository
+func newBlobMounter(mountFromRepos []regname.Repository, importRepo regname.Repository, registry ImagesReaderWriter) *blobMounter {
+	var repos []regname.Repository
+	for _, repo := range mountFromRepos {
+		if imageBlobsCanBeMounted(repo.Digest(""), importRepo.Digest("")) && repo.Name() != importRepo.Name() {
+			repos = append(repos, repo)
+		}
+	}
+	if len(repos) == 0 {
+		return nil
+	}
+
+	return &blobMounter{
+		importRepo:     importRepo,
+		mountFromRepos: repos,
+		registry:       registry,
+		blobs:          map[regv1.Hash]*blobMountSource{},
+	}
+}
+
+// Taggable wraps image or image index so that its blobs are mounted when found in other repositories
+func (m *blobMounter) Taggable(taggable regremote.Taggable) regremote.Taggable {
+	switch typedTaggable := taggable.(type) {
+	case regv1.Image:
+		return mountFromImage{typedTaggable, m}
+	case regv1.ImageIndex:
+		return mountFromIndex{typedTaggable, m}
+	default:
+		return taggable
+	}
+}
+
+// MountedBlobs returns number of blobs (and their size) mounted from other repositories
+func (m *blobMounter) MountedBlobs() (int, int64, []string) {
+	m.blobsLock.Lock()
+	defer m.blobsLock.Unlock()
+
+	var count int
+	var size int64
+	repos := map[string]struct{}{}
+
+	for _, blob := range m.blobs {
+		if blob.repo != nil {
+			count++
+			size += blob.size
+			repos[blob.repo.Name()] = struct{}{}
+		}
+	}
+
+	var repoNames []string
+	for repo := range repos {
+		repoNames = append(repoNames, repo)
+	}
+	sort.Strings(repoNames)
+
+	return count, size, repoNames
+}
+
+// Report logs number of bytes that did not need to be uploaded thanks to mounting
+func (m *blobMounter) Report(logger Logger) {
+	count, size, repos := m.MountedBlobs()
+	if count == 0 {
+		return
+	}
+	logger.WriteStr("mounted %d blobs (%s) into %s from %s\n",
+		count, util.FormatBytes(size), m.importRepo.Name(), strings.Join(repos, ", "))
+}
+
+func (m *blobMounter) layer(layer regv1.Layer) (regv1.Layer, error) {
+	// Layers of images from the same registry are already mounted from their source repository
+	if _, ok := layer.(*regremote.MountableLayer); ok {
+		return layer, nil
+	}
+
+	digest, err := layer.Digest()
+	if err != nil {
+		return nil, err
+	}
+
+	m.blobsLock.Lock()
+	blob, found := m.blobs[digest]
+	if !found {
+		blob = &blobMountSource{}
+		m.blobs[digest] = blob
+	}
+	m.blobsLock.Unlock()
+
+	blob.once.Do(func() {
+		blob.repo, blob.size = m.findBlob(layer, digest)
+	})
+
+	if blob.repo == nil {
+		return layer, nil
+	}
+	return &regremote.MountableLayer{Layer: layer, Reference: blob.repo.Digest(digest.String())}, nil
+}
+
+// findBlob returns first repository containing the blob, unless it is already present in the import repository.
+// Any failure to find the blob results in the blob being uploaded.
+func (m *blobMounter) findBlob(layer regv1.Layer, digest regv1.Hash) (*regname.Repository, int64) {
+	exists, err := m.registry.BlobExists(m.importRepo.Digest(digest.String()))
+	if err != nil || exists {
+		return nil, 0
+	}
+
+	for _, repo := range m.mountFromRepos {
+		repo := repo // copy
+
+		exists, err := m.registry.BlobExists(repo.Digest(digest.String()))
+		if err != nil || !exists {
+			continue
+		}
+
+		size, err := layer.Size()
+		if err != nil {
+			return nil, 0
+		}
+		return &repo, size
+	}
+
+	return nil, 0
+}
+
+// mountFromImage mounts layers found in other repositories (config blob is always uploaded)
+type mountFromImage struct {
+	regv1.Image
+	mounter *blobMounter
+}
+
+func (i mountFromImage) Layers() ([]regv1.Layer, error) {
+	layers, err := i.Image.Layers()
+	if err != nil {
+		return nil, err
+	}
+
+	var mountableLayers []regv1.Layer
+	for _, layer := range layers {
+		mountableLayer, err := i.mounter.layer(layer)
+		if err != nil {
+			return nil, err
+		}
+		mountableLayers = append(mountableLayers, mountableLayer)
+	}
+	return mountableLayers, nil
+}
+
+func (i mountFromImage) LayerByDigest(digest regv1.Hash) (regv1.Layer, error) {
+	layer, err := i.Image.LayerByDigest(digest)
+	if err != nil {
+		return nil, err
+	}
+	return i.mounter.layer(layer)
+}
+
+// mountFromIndex mounts layers of images referenced by the index
+type mountFromIndex struct {
+	index   regv1.ImageIndex
+	mounter *blobMounter
+}
+
+var _ regv1.ImageIndex = mountFromIndex{}
+
+func (i mountFromIndex) MediaType() (types.MediaType, error) { return i.index.MediaType() }
+func (i mountFromIndex) Digest() (regv1.Hash, error)         { return i.index.Digest() }
+func (i mountFromIndex) Size() (int64, error)                { return i.index.Size() }
+func (i mountFromIndex) RawManifest() ([]byte, error)        { return i.index.RawManifest() }
+
+func (i mountFromIndex) IndexManifest() (*regv1.IndexManifest, error) {
+	return i.index.IndexManifest()
+}
+
+func (i mountFromIndex) Image(digest regv1.Hash) (regv1.Image, error) {
+	img, err := i.index.Image(digest)
+	if err != nil {
+		return nil, err
+	}
+	return mountFromImage{img, i.mounter}, nil
+}
+
+func (i mountFromIndex) ImageIndex(digest regv1.Hash) (regv1.ImageIndex, error) {
+	idx, err := i.index.ImageIndex(digest)
+	if err != nil {
+		return nil, err
+	}
+	return mountFromIndex{idx, i.mounter}, nil
+}
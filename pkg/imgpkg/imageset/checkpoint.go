@@ -0,0 +1,223 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package imageset
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/k14s/imgpkg/pkg/imgpkg/imagetar"
+)
+
+// Checkpoint records progress of a copy in a file, so that an interrupted copy
+// skips images (and tarball layers) that were already copied when it is rerun with the same file
+type Checkpoint struct {
+	path string
+
+	state     checkpointState
+	stateLock sync.Mutex
+}
+
+type checkpointState struct {
+	// ImportedImages were imported and verified in their import repository
+	ImportedImages []CheckpointImage  `json:"importedImages,omitempty"`
+	Tarball        *checkpointTarball `json:"tarball,omitempty"`
+}
+
+// CheckpointImage is an image imported to an import repository
+type CheckpointImage struct {
+	ImportRepo string            `json:"importRepo"`
+	SourceRef  string            `json:"sourceRef"`
+	Tag        string            `json:"tag,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	DigestRef  string            `json:"digestRef"`
+}
+
+type checkpointTarball struct {
+	Path string `json:"path"`
+	imagetar.TarLayersCheckpoint
+}
+
+// NewCheckpoint reads checkpoint file when it exists (it is created when progress is first recorded)
+func NewCheckpoint(path string) (*Checkpoint, error) {
+	checkpoint := &Checkpoint{path: path}
+
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return checkpoint, nil
+		}
+		return nil, fmt.Errorf("Reading checkpoint: %s", err)
+	}
+
+	err = json.Unmarshal(bs, &checkpoint.state)
+	if err != nil {
+		return nil, fmt.Errorf("Unmarshaling checkpoint '%s': %s", path, err)
+	}
+
+	return checkpoint, nil
+}
+
+// ImportedImage returns image previously imported to the import repository
+func (c *Checkpoint) ImportedImage(ref UnprocessedImageRef, importRepo regname.Repository) (CheckpointImage, bool) {
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	for _, img := range c.state.ImportedImages {
+		if img.ImportRepo == importRepo.Name() && img.SourceRef == normalizedDigestRef(ref.DigestRef) && img.Tag == ref.Tag {
+			return img, true
+		}
+	}
+	return CheckpointImage{}, false
+}
+
+// RecordImportedImage records image that was imported and verified in the import repository
+func (c *Checkpoint) RecordImportedImage(img ProcessedImage, importRepo regname.Repository) error {
+	c.stateLock.Lock()
+	defer c.stateLock.Unlock()
+
+	c.state.ImportedImages = append(c.state.ImportedImages, CheckpointImage{
+		ImportRepo: importRepo.Name(),
+		SourceRef:  normalizedDigestRef(img.UnprocessedImageRef.DigestRef),
+		Tag:        img.Tag,
+		Labels:     img.Labels,
+		DigestRef:  img.DigestRef,
+	})
+
+	return c.write()
+}
+
+// TarCheckpoint returns checkpoint of layers written to the tarball at given path
+func (c *Checkpoint) TarCheckpoint(path string) imagetar.TarCheckpoint {
+	return tarCheckpoint{c, path}
+}
+
+// write replaces checkpoint file so that it is never left partially written
+func (c *Checkpoint) write() error {
+	bs, err := json.MarshalIndent(c.state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := c.path + ".tmp"
+
+	err = ioutil.WriteFile(tmpPath, bs, 0600)
+	if err != nil {
+		return fmt.Errorf("Writing checkpoint: %s", err)
+	}
+
+	err = os.Rename(tmpPath, c.path)
+	if err != nil {
+		return fmt.Errorf("Writing checkpoint: %s", err)
+	}
+
+	return nil
+}
+
+// normalizedDigestRef allows to find images referenced in different ways (e.g. nginx and index.docker.io/library/nginx)
+func normalizedDigestRef(digestRef string) string {
+	ref, err := regname.NewDigest(digestRef)
+	if err != nil {
+		return digestRef
+	}
+	return ref.Name()
+}
+
+type tarCheckpoint struct {
+	checkpoint *Checkpoint
+	path       string
+}
+
+var _ imagetar.TarCheckpoint = tarCheckpoint{}
+
+func (c tarCheckpoint) WrittenTarLayers() imagetar.TarLayersCheckpoint {
+	c.checkpoint.stateLock.Lock()
+	defer c.checkpoint.stateLock.Unlock()
+
+	tarball := c.checkpoint.state.Tarball
+	if tarball == nil || tarball.Path != c.path {
+		return imagetar.TarLayersCheckpoint{}
+	}
+	return tarball.TarLayersCheckpoint
+}
+
+func (c tarCheckpoint) RecordTarLayers(layers imagetar.TarLayersCheckpoint) error {
+	c.checkpoint.stateLock.Lock()
+	defer c.checkpoint.stateLock.Unlock()
+
+	c.checkpoint.state.Tarball = &checkpointTarball{Path: c.path, TarLayersCheckpoint: layers}
+
+	return c.checkpoint.write()
+}
+
+// checkpointedImage returns image imported during a previous copy according to the checkpoint.
+// Images that cannot be found in the import repository anymore are copied again.
+func (i ImageSet) checkpointedImage(ref UnprocessedImageRef, importRepo regname.Repository, registry ImagesReaderWriter) (ProcessedImage, bool) {
+	if i.checkpoint == nil {
+		return ProcessedImage{}, false
+	}
+
+	img, found := i.checkpoint.ImportedImage(ref, importRepo)
+	if !found {
+		return ProcessedImage{}, false
+	}
+
+	importDigestRef, err := regname.NewDigest(img.DigestRef)
+	if err != nil {
+		return ProcessedImage{}, false
+	}
+
+	descriptor, err := registry.Get(importDigestRef)
+	if err != nil {
+		return ProcessedImage{}, false
+	}
+
+	processedImage := ProcessedImage{
+		UnprocessedImageRef: ref,
+		DigestRef:           importDigestRef.Name(),
+		AlreadyPresent:      true,
+	}
+
+	if descriptor.MediaType.IsIndex() {
+		processedImage.ImageIndex, err = descriptor.ImageIndex()
+	} else {
+		processedImage.Image, err = descriptor.Image()
+	}
+	if err != nil {
+		return ProcessedImage{}, false
+	}
+
+	return processedImage, true
+}
+
+// findCheckpointedImages splits found images into the ones that need to be copied
+// and the ones that were imported during a previous copy according to the checkpoint
+func (i ImageSet) findCheckpointedImages(foundImages *UnprocessedImageRefs,
+	importRepo regname.Repository, registry ImagesReaderWriter) (*UnprocessedImageRefs, []ProcessedImage) {
+
+	imagesToCopy := NewUnprocessedImageRefs()
+	var checkpointedImages []ProcessedImage
+
+	for _, img := range foundImages.All() {
+		if processedImage, found := i.checkpointedImage(img, importRepo, registry); found {
+			checkpointedImages = append(checkpointedImages, processedImage)
+		} else {
+			imagesToCopy.Add(img)
+		}
+	}
+
+	return imagesToCopy, checkpointedImages
+}
+
+// recordInCheckpoint records image imported and verified in the import repository
+func (i ImageSet) recordInCheckpoint(img ProcessedImage, importRepo regname.Repository) error {
+	if i.checkpoint == nil {
+		return nil
+	}
+	return i.checkpoint.RecordImportedImage(img, importRepo)
+}
@@ -0,0 +1,173 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package imageset
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+const (
+	ImgpkgDigestTagStrategy = "imgpkg-digest"
+	NoneTagStrategy         = "none"
+	OriginalTagStrategy     = "original"
+	TemplateTagStrategy     = "template"
+)
+
+var destinationTagStrategies = []string{ImgpkgDigestTagStrategy, NoneTagStrategy, OriginalTagStrategy, TemplateTagStrategy}
+
+// DestinationTagStrategy decides which reference is used to upload images to the import repository.
+// Zero value tags images with imgpkg digest tags (e.g. sha256-<hex>.imgpkg)
+type DestinationTagStrategy struct {
+	name        string
+	tagTemplate *template.Template
+}
+
+// DestinationTagTemplateData is available to tag templates (e.g. {{.Tag}}-{{.Hex}})
+type DestinationTagTemplateData struct {
+	Repo      string
+	Digest    string
+	Algorithm string
+	Hex       string
+	Tag       string
+}
+
+func NewDestinationTagStrategy(name string, tagTemplate string) (DestinationTagStrategy, error) {
+	strategy := DestinationTagStrategy{name: name}
+
+	switch name {
+	case "", ImgpkgDigestTagStrategy, NoneTagStrategy, OriginalTagStrategy:
+		if tagTemplate != "" {
+			return DestinationTagStrategy{}, fmt.Errorf("Expected tag template to only be used with '%s' strategy", TemplateTagStrategy)
+		}
+
+	case TemplateTagStrategy:
+		if tagTemplate == "" {
+			return DestinationTagStrategy{}, fmt.Errorf("Expected tag template to be provided with '%s' strategy", TemplateTagStrategy)
+		}
+
+		tmpl, err := template.New("tag").Option("missingkey=error").Parse(tagTemplate)
+		if err != nil {
+			return DestinationTagStrategy{}, fmt.Errorf("Parsing tag template: %s", err)
+		}
+		strategy.tagTemplate = tmpl
+
+	default:
+		return DestinationTagStrategy{}, fmt.Errorf("Unknown destination tag strategy '%s' (known: %s)",
+			name, strings.Join(destinationTagStrategies, ", "))
+	}
+
+	return strategy, nil
+}
+
+// UploadRef returns reference used to upload image with given digest and original tag
+func (s DestinationTagStrategy) UploadRef(importRepo regname.Repository, digest regv1.Hash, tag string) (regname.Reference, error) {
+	switch s.name {
+	case "", ImgpkgDigestTagStrategy:
+		return buildUploadTagRefFromDigest(digest, importRepo)
+
+	case NoneTagStrategy:
+		return s.digestRef(importRepo, digest)
+
+	case OriginalTagStrategy:
+		if tag == "" {
+			return s.digestRef(importRepo, digest)
+		}
+		uploadTagRef, err := regname.NewTag(fmt.Sprintf("%s:%s", importRepo.Name(), tag))
+		if err != nil {
+			return nil, fmt.Errorf("Building upload tag image ref: %s", err)
+		}
+		return uploadTagRef, nil
+
+	case TemplateTagStrategy:
+		var buf bytes.Buffer
+		err := s.tagTemplate.Execute(&buf, DestinationTagTemplateData{
+			Repo:      importRepo.Name(),
+			Digest:    digest.String(),
+			Algorithm: digest.Algorithm,
+			Hex:       digest.Hex,
+			Tag:       tag,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Executing tag template: %s", err)
+		}
+
+		uploadTagRef, err := regname.NewTag(fmt.Sprintf("%s:%s", importRepo.Name(), buf.String()))
+		if err != nil {
+			return nil, fmt.Errorf("Expected tag template to produce a valid tag, got '%s': %s", buf.String(), err)
+		}
+		return uploadTagRef, nil
+
+	default:
+		panic(fmt.Sprintf("Unknown destination tag strategy '%s'", s.name))
+	}
+}
+
+// Tags returns tags written to the import repository for image with given digest and original tag
+func (s DestinationTagStrategy) Tags(importRepo regname.Repository, digest regv1.Hash, tag string) ([]string, error) {
+	uploadRef, err := s.UploadRef(importRepo, digest, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := []string{}
+	if uploadTagRef, ok := uploadRef.(regname.Tag); ok {
+		tags = append(tags, uploadTagRef.TagStr())
+	}
+	if tag != "" && (len(tags) == 0 || tags[0] != tag) {
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// digestRef uploads by digest, except for registries that do not accept manifests uploaded by digest
+func (s DestinationTagStrategy) digestRef(importRepo regname.Repository, digest regv1.Hash) (regname.Reference, error) {
+	// AWS ECR doesnt like using digests for manifest uploads
+	registryHost := importRepo.RegistryStr()
+	if strings.Contains(registryHost, ".dkr.ecr.") || registryHost == "public.ecr.aws" {
+		return buildUploadTagRefFromDigest(digest, importRepo)
+	}
+
+	importDigestRef, err := regname.NewDigest(fmt.Sprintf("%s@%s", importRepo.Name(), digest))
+	if err != nil {
+		return nil, fmt.Errorf("Building new digest image ref: %s", err)
+	}
+	return importDigestRef, nil
+}
+
+// DestinationTags keeps track of tags written to import repositories
+// to detect different images that would overwrite each other's tag
+type DestinationTags struct {
+	claimed map[string]destinationTagClaim
+}
+
+type destinationTagClaim struct {
+	digest regv1.Hash
+	source string
+}
+
+func NewDestinationTags() *DestinationTags {
+	return &DestinationTags{claimed: map[string]destinationTagClaim{}}
+}
+
+// Claim records tags written for image copied from source,
+// failing when a different image is written with one of these tags
+func (t *DestinationTags) Claim(importRepo regname.Repository, tags []string, digest regv1.Hash, source string) error {
+	for _, tag := range tags {
+		tagRef := importRepo.Name() + ":" + tag
+
+		if existing, found := t.claimed[tagRef]; found && existing.digest != digest {
+			return fmt.Errorf("Expected images to be written with distinct tags, but '%s' and '%s' would both be tagged '%s' "+
+				"(hint: use a destination tag strategy producing a tag for each image, e.g. template including {{.Hex}})",
+				existing.source, source, tagRef)
+		}
+		t.claimed[tagRef] = destinationTagClaim{digest: digest, source: source}
+	}
+	return nil
+}
@@ -0,0 +1,324 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package imageset
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	regremote "github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/k14s/imgpkg/pkg/imgpkg/imagedesc"
+	"github.com/k14s/imgpkg/pkg/imgpkg/util"
+)
+
+// existingImage is an image that is already present in the import repository
+type existingImage struct {
+	UnprocessedImageRef
+	importDigestRef regname.Digest
+	descriptor      *regremote.Descriptor
+}
+
+// skipsExistingImages returns true when images present in the import repository do not need to be copied.
+// Recompression and platform filtering give images new digests, hence they cannot be found by their original digest.
+func (i ImageSet) skipsExistingImages() bool {
+	return !i.force && i.recompression == "" && i.platformFilter.IsEmpty()
+}
+
+// ExistingImages returns images that are not copied since they are already present in the import repository
+func (i ImageSet) ExistingImages(foundImages *UnprocessedImageRefs,
+	importRepo regname.Repository, registry ImagesReaderWriter) ([]UnprocessedImageRef, error) {
+
+	if !i.skipsExistingImages() {
+		return nil, nil
+	}
+
+	_, existingImages, err := i.findExistingImages(foundImages, importRepo, registry)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []UnprocessedImageRef
+	for _, img := range existingImages {
+		result = append(result, img.UnprocessedImageRef)
+	}
+	return result, nil
+}
+
+// findExistingImages splits found images into the ones that need to be copied
+// and the ones that are already present in the import repository
+func (i ImageSet) findExistingImages(foundImages *UnprocessedImageRefs,
+	importRepo regname.Repository, registry ImagesReaderWriter) (*UnprocessedImageRefs, []existingImage, error) {
+
+	imagesToCopy := NewUnprocessedImageRefs()
+	var existingImages []existingImage
+	var existingImagesLock sync.Mutex
+
+	allImages := foundImages.All()
+	throttle := util.NewThrottle(i.concurrency)
+	errCh := make(chan error, len(allImages))
+
+	for _, img := range allImages {
+		img := img // copy
+
+		go func() {
+			throttle.Take()
+			defer throttle.Done()
+
+			ref, err := regname.NewDigest(img.DigestRef)
+			if err != nil {
+				errCh <- err
+				return
+			}
+
+			importDigestRef, err := regname.NewDigest(fmt.Sprintf("%s@%s", importRepo.Name(), ref.DigestStr()))
+			if err != nil {
+				errCh <- fmt.Errorf("Building new digest image ref: %s", err)
+				return
+			}
+
+			// Any failure to find the image results in the image being copied,
+			// which reports a meaningful error if the import repository is not reachable
+			_, err = registry.Digest(importDigestRef)
+			if err != nil {
+				imagesToCopy.Add(img)
+				errCh <- nil
+				return
+			}
+
+			descriptor, err := registry.Get(importDigestRef)
+			if err != nil {
+				imagesToCopy.Add(img)
+				errCh <- nil
+				return
+			}
+
+			existingImagesLock.Lock()
+			existingImages = append(existingImages, existingImage{img, importDigestRef, descriptor})
+			existingImagesLock.Unlock()
+			errCh <- nil
+		}()
+	}
+
+	for range allImages {
+		if err := <-errCh; err != nil {
+			return nil, nil, err
+		}
+	}
+
+	sort.Slice(existingImages, func(i, j int) bool {
+		return existingImages[i].Key() < existingImages[j].Key()
+	})
+
+	return imagesToCopy, existingImages, nil
+}
+
+// importExistingImages only tags and verifies images that are already present in the import repository
+func (i ImageSet) importExistingImages(existingImages []existingImage, importRepo regname.Repository,
+	registry ImagesReaderWriter, processedImages *ProcessedImages) error {
+
+	throttle := util.NewThrottle(i.concurrency)
+	errCh := make(chan error, len(existingImages))
+
+	for _, img := range existingImages {
+		img := img // copy
+
+		go func() {
+			throttle.Take()
+			defer throttle.Done()
+
+			processedImage, err := i.importExistingImage(img, importRepo, registry)
+			if err != nil {
+				if i.continueOnError {
+					processedImages.AddFailure(FailedImage{UnprocessedImageRef: img.UnprocessedImageRef, Err: err})
+					err = nil
+				}
+				errCh <- err
+				return
+			}
+
+			processedImages.Add(processedImage)
+			errCh <- i.recordInCheckpoint(processedImage, importRepo)
+		}()
+	}
+
+	for range existingImages {
+		if err := <-errCh; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (i ImageSet) importExistingImage(img existingImage, importRepo regname.Repository, registry ImagesReaderWriter) (ProcessedImage, error) {
+	uploadRef, err := i.tagStrategy.UploadRef(importRepo, img.descriptor.Digest, img.Tag)
+	if err != nil {
+		return ProcessedImage{}, err
+	}
+
+	// Images uploaded by digest do not need to be tagged
+	if uploadTagRef, ok := uploadRef.(regname.Tag); ok {
+		err = registry.WriteTag(uploadTagRef, img.descriptor)
+		if err != nil {
+			return ProcessedImage{}, fmt.Errorf("Tagging existing image %s: %s", img.importDigestRef.Name(), err)
+		}
+	}
+
+	if img.Tag != "" {
+		uploadOriginalTagRef, err := regname.NewTag(fmt.Sprintf("%s:%s", importRepo.Name(), img.Tag))
+		if err != nil {
+			return ProcessedImage{}, fmt.Errorf("Building upload tag image ref: %s", err)
+		}
+
+		err = registry.WriteTag(uploadOriginalTagRef, img.descriptor)
+		if err != nil {
+			return ProcessedImage{}, fmt.Errorf("Tagging existing image %s: %s", img.importDigestRef.Name(), err)
+		}
+	}
+
+	err = i.verifyTagDigest(uploadRef, img.importDigestRef, registry)
+	if err != nil {
+		return ProcessedImage{}, err
+	}
+
+	processedImage := ProcessedImage{
+		UnprocessedImageRef: img.UnprocessedImageRef,
+		DigestRef:           img.importDigestRef.Name(),
+		AlreadyPresent:      true,
+	}
+
+	if img.descriptor.MediaType.IsIndex() {
+		processedImage.ImageIndex, err = img.descriptor.ImageIndex()
+	} else {
+		processedImage.Image, err = img.descriptor.Image()
+	}
+	if err != nil {
+		return ProcessedImage{}, fmt.Errorf("Reading existing image %s: %s", img.importDigestRef.Name(), err)
+	}
+
+	return processedImage, nil
+}
+
+// existingImagesSize returns number of bytes of manifests, configs and layers of existing images
+func existingImagesSize(existingImages []existingImage) (int64, error) {
+	var total int64
+	for _, img := range existingImages {
+		var size int64
+		var err error
+
+		if img.descriptor.MediaType.IsIndex() {
+			var idx regv1.ImageIndex
+			idx, err = img.descriptor.ImageIndex()
+			if err == nil {
+				size, err = imageIndexSize(idx)
+			}
+		} else {
+			var regImg regv1.Image
+			regImg, err = img.descriptor.Image()
+			if err == nil {
+				size, err = imageSize(regImg)
+			}
+		}
+		if err != nil {
+			return 0, fmt.Errorf("Calculating size of existing image %s: %s", img.importDigestRef.Name(), err)
+		}
+		total += size
+	}
+	return total, nil
+}
+
+func imageIndexSize(idx regv1.ImageIndex) (int64, error) {
+	size, err := idx.Size()
+	if err != nil {
+		return 0, err
+	}
+
+	indexManifest, err := idx.IndexManifest()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, manDesc := range indexManifest.Manifests {
+		var childSize int64
+		if manDesc.MediaType.IsIndex() {
+			childIdx, err := idx.ImageIndex(manDesc.Digest)
+			if err != nil {
+				return 0, err
+			}
+			childSize, err = imageIndexSize(childIdx)
+			if err != nil {
+				return 0, err
+			}
+		} else {
+			childImg, err := idx.Image(manDesc.Digest)
+			if err != nil {
+				return 0, err
+			}
+			childSize, err = imageSize(childImg)
+			if err != nil {
+				return 0, err
+			}
+		}
+		size += childSize
+	}
+
+	return size, nil
+}
+
+func imageSize(img regv1.Image) (int64, error) {
+	size, err := img.Size()
+	if err != nil {
+		return 0, err
+	}
+
+	manifest, err := img.Manifest()
+	if err != nil {
+		return 0, err
+	}
+
+	size += manifest.Config.Size
+	for _, layer := range manifest.Layers {
+		size += layer.Size
+	}
+	return size, nil
+}
+
+// descriptorsSize returns number of bytes of manifests, configs and layers of described images with given refs
+func descriptorsSize(ids *imagedesc.ImageRefDescriptors, refs map[string]struct{}) int64 {
+	var total int64
+	for _, desc := range ids.Descriptors() {
+		switch {
+		case desc.Image != nil:
+			if _, found := refs[desc.Image.Refs[0]]; found {
+				total += imageDescriptorSize(*desc.Image)
+			}
+		case desc.ImageIndex != nil:
+			if _, found := refs[desc.ImageIndex.Refs[0]]; found {
+				total += imageIndexDescriptorSize(*desc.ImageIndex)
+			}
+		}
+	}
+	return total
+}
+
+func imageIndexDescriptorSize(desc imagedesc.ImageIndexDescriptor) int64 {
+	size := int64(len(desc.Raw))
+	for _, img := range desc.Images {
+		size += imageDescriptorSize(img)
+	}
+	for _, idx := range desc.Indexes {
+		size += imageIndexDescriptorSize(idx)
+	}
+	return size
+}
+
+func imageDescriptorSize(desc imagedesc.ImageDescriptor) int64 {
+	size := int64(len(desc.Manifest.Raw) + len(desc.Config.Raw))
+	for _, layer := range desc.Layers {
+		size += layer.Size
+	}
+	return size
+}
@@ -5,13 +5,16 @@ package imageset
 
 import (
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 
 	regname "github.com/google/go-containerregistry/pkg/name"
 	regv1 "github.com/google/go-containerregistry/pkg/v1"
 	regremote "github.com/google/go-containerregistry/pkg/v1/remote"
 	ctlimg "github.com/k14s/imgpkg/pkg/imgpkg/image"
 	"github.com/k14s/imgpkg/pkg/imgpkg/imagedesc"
+	ctlreg "github.com/k14s/imgpkg/pkg/imgpkg/registry"
 	"github.com/k14s/imgpkg/pkg/imgpkg/util"
 )
 
@@ -26,27 +29,248 @@ type ImagesReaderWriter interface {
 	WriteImage(regname.Reference, regv1.Image) error
 	WriteIndex(regname.Reference, regv1.ImageIndex) error
 	WriteTag(regname.Tag, regremote.Taggable) error
+	BlobExists(regname.Digest) (bool, error)
+	Referrers(regname.Digest) ([]ctlreg.ReferrerDescriptor, error)
+	ReferrersAPISupported(regname.Repository) (bool, error)
 }
 
 type ImageSet struct {
 	concurrency int
 	logger      Logger
+
+	// recompression is empty when layers are copied as is
+	recompression ctlimg.Compression
+	// platformFilter is empty when all manifests of image indexes are copied
+	platformFilter imagedesc.PlatformFilter
+	// force copies images even when they are already present in the import repository
+	force bool
+	// tagStrategy decides which references are used to upload images to the import repository
+	tagStrategy DestinationTagStrategy
+	// mountFromRepos are repositories of the import registry that blobs are mounted from instead of being uploaded
+	mountFromRepos []regname.Repository
+	// checkpoint is nil when copy progress is not recorded
+	checkpoint *Checkpoint
+	// continueOnError records images that failed to be copied instead of stopping at the first failure
+	continueOnError bool
 }
 
 func NewImageSet(concurrency int, logger Logger) ImageSet {
-	return ImageSet{concurrency, logger}
+	return ImageSet{concurrency: concurrency, logger: logger}
+}
+
+// NewImageSetWithRecompression creates an ImageSet that converts gzip compressed layers
+// while importing images, which results in images with different digests
+func NewImageSetWithRecompression(concurrency int, logger Logger, compression ctlimg.Compression) ImageSet {
+	return ImageSet{concurrency: concurrency, logger: logger, recompression: compression}
+}
+
+// WithPlatformFilter returns a copy of the ImageSet that only exports image index manifests
+// for selected platforms, which results in image indexes with different digests
+func (i ImageSet) WithPlatformFilter(platformFilter imagedesc.PlatformFilter) ImageSet {
+	i.platformFilter = platformFilter
+	return i
+}
+
+// WithForce returns a copy of the ImageSet that copies images during relocation
+// even when they are already present in the import repository
+func (i ImageSet) WithForce(force bool) ImageSet {
+	i.force = force
+	return i
+}
+
+// WithDestinationTagStrategy returns a copy of the ImageSet that uploads images
+// to the import repository using references built by the strategy
+func (i ImageSet) WithDestinationTagStrategy(tagStrategy DestinationTagStrategy) ImageSet {
+	i.tagStrategy = tagStrategy
+	return i
+}
+
+// WithMountFromRepos returns a copy of the ImageSet that mounts blobs found in given repositories
+// into the import repository (only repositories in the same registry as the import repository are used)
+func (i ImageSet) WithMountFromRepos(mountFromRepos []regname.Repository) ImageSet {
+	i.mountFromRepos = mountFromRepos
+	return i
+}
+
+// WithCheckpoint returns a copy of the ImageSet that records imported images in the checkpoint
+// and skips images that were imported according to it
+func (i ImageSet) WithCheckpoint(checkpoint *Checkpoint) ImageSet {
+	i.checkpoint = checkpoint
+	return i
+}
+
+// WithContinueOnError returns a copy of the ImageSet that copies every image it can,
+// recording the ones that failed in processed images
+func (i ImageSet) WithContinueOnError(continueOnError bool) ImageSet {
+	i.continueOnError = continueOnError
+	return i
+}
+
+// DestinationTags returns tags written to the import repository for image with given digest and original tag
+func (i ImageSet) DestinationTags(importRepo regname.Repository, digest regv1.Hash, tag string) ([]string, error) {
+	return i.tagStrategy.Tags(importRepo, digest, tag)
 }
 
 func (i ImageSet) Relocate(foundImages *UnprocessedImageRefs,
 	importRepo regname.Repository, registry ImagesReaderWriter) (*ProcessedImages, *imagedesc.ImageRefDescriptors, error) {
 
-	ids, err := i.Export(foundImages, registry)
+	processedImages, ids, err := i.RelocateToRepos(foundImages, []regname.Repository{importRepo}, registry)
 	if err != nil {
 		return nil, nil, err
 	}
+	return processedImages[0], ids, nil
+}
+
+// RelocateToRepos relocates images to every import repository concurrently.
+// Images are only exported once and, when there are multiple import repositories,
+// layers are spooled to a temporary directory so that each of them is only fetched once.
+// Returned processed images are in the same order as import repositories.
+func (i ImageSet) RelocateToRepos(foundImages *UnprocessedImageRefs,
+	importRepos []regname.Repository, registry ImagesReaderWriter) ([]*ProcessedImages, *imagedesc.ImageRefDescriptors, error) {
+
+	destinations := make([]relocationDestination, len(importRepos))
+	imagesToExport := NewUnprocessedImageRefs()
+
+	for idx, importRepo := range importRepos {
+		dest := relocationDestination{importRepo: importRepo, imagesToCopy: foundImages}
+
+		if i.checkpoint != nil {
+			dest.imagesToCopy, dest.checkpointedImages = i.findCheckpointedImages(foundImages, importRepo, registry)
+		}
+
+		if i.skipsExistingImages() {
+			var err error
+			dest.imagesToCopy, dest.existingImages, err = i.findExistingImages(dest.imagesToCopy, importRepo, registry)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		for _, img := range dest.imagesToCopy.All() {
+			imagesToExport.Add(img)
+		}
+		destinations[idx] = dest
+	}
+
+	ids, failedImages, err := i.exportAvailable(imagesToExport, registry)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var layerProvider imagedesc.LayerProvider = ids
+	if len(importRepos) > 1 {
+		spooledLayers, err := newSpooledLayerProvider(ids)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer spooledLayers.Remove()
+
+		layerProvider = spooledLayers
+	}
+
+	imgOrIndexes := imagedesc.NewDescribedReader(ids, layerProvider).Read()
+
+	processedImages := make([]*ProcessedImages, len(destinations))
+	errCh := make(chan error, len(destinations))
+
+	for idx, dest := range destinations {
+		idx, dest := idx, dest // copy
+
+		go func() {
+			destProcessedImages, err := i.relocateToRepo(dest, imgOrIndexes, ids, registry)
+			if err != nil {
+				errCh <- fmt.Errorf("Relocating images to %s: %w", dest.importRepo.Name(), err)
+				return
+			}
+			for _, failedImage := range failedImages {
+				destProcessedImages.AddFailure(failedImage)
+			}
+			processedImages[idx] = destProcessedImages
+			errCh <- nil
+		}()
+	}
+
+	// Every destination has to finish before spooled layers are removed
+	var relocateErr error
+	for range destinations {
+		if err := <-errCh; err != nil && relocateErr == nil {
+			relocateErr = err
+		}
+	}
+	if relocateErr != nil {
+		return nil, nil, relocateErr
+	}
+
+	return processedImages, ids, nil
+}
+
+// relocationDestination describes which images need to be copied to an import repository
+type relocationDestination struct {
+	importRepo     regname.Repository
+	imagesToCopy   *UnprocessedImageRefs
+	existingImages []existingImage
+	// checkpointedImages were imported during a previous copy
+	checkpointedImages []ProcessedImage
+}
+
+func (i ImageSet) relocateToRepo(dest relocationDestination, imgOrIndexes []imagedesc.ImageOrIndex,
+	ids *imagedesc.ImageRefDescriptors, registry ImagesReaderWriter) (*ProcessedImages, error) {
+
+	refsToCopy := map[string]struct{}{}
+	for _, img := range dest.imagesToCopy.All() {
+		ref, err := regname.NewDigest(img.DigestRef)
+		if err != nil {
+			return nil, err
+		}
+		refsToCopy[ref.Name()] = struct{}{}
+	}
+
+	var itemsToCopy []imagedesc.ImageOrIndex
+	for _, item := range imgOrIndexes {
+		if _, found := refsToCopy[item.Ref()]; found {
+			itemsToCopy = append(itemsToCopy, item)
+		}
+	}
+
+	images := NewProcessedImages()
+	if len(itemsToCopy) > 0 {
+		var err error
+		images, err = i.Import(itemsToCopy, dest.importRepo, registry)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(dest.checkpointedImages) > 0 {
+		for _, processedImage := range dest.checkpointedImages {
+			images.Add(processedImage)
+		}
+		i.logger.WriteStr("skipped %d images already copied to %s according to checkpoint\n",
+			len(dest.checkpointedImages), dest.importRepo.Name())
+	}
+
+	if i.skipsExistingImages() {
+		err := i.importExistingImages(dest.existingImages, dest.importRepo, registry, images)
+		if err != nil {
+			return nil, err
+		}
 
-	images, err := i.Import(imagedesc.NewDescribedReader(ids, ids).Read(), importRepo, registry)
-	return images, ids, err
+		existingSize, err := existingImagesSize(dest.existingImages)
+		if err != nil {
+			return nil, err
+		}
+
+		i.logger.WriteStr("copied %d images (%s), skipped %d images already present in %s (%s)\n",
+			len(itemsToCopy), util.FormatBytes(descriptorsSize(ids, refsToCopy)), len(dest.existingImages),
+			dest.importRepo.Name(), util.FormatBytes(existingSize))
+	}
+
+	err := i.writeReferrersFallbackIndexes(images, dest.importRepo, registry)
+	if err != nil {
+		return nil, err
+	}
+
+	return images, nil
 }
 
 func (i ImageSet) Export(foundImages *UnprocessedImageRefs,
@@ -67,7 +291,7 @@ func (i ImageSet) Export(foundImages *UnprocessedImageRefs,
 		refs = append(refs, imagedesc.Metadata{Ref: ref, Tag: img.Tag, Labels: img.Labels})
 	}
 
-	ids, err := imagedesc.NewImageRefDescriptors(refs, imagesMetadata)
+	ids, err := imagedesc.NewImageRefDescriptorsWithPlatformFilter(refs, imagesMetadata, i.platformFilter)
 	if err != nil {
 		return nil, fmt.Errorf("Collecting packaging metadata: %s", err)
 	}
@@ -75,60 +299,149 @@ func (i ImageSet) Export(foundImages *UnprocessedImageRefs,
 	return ids, nil
 }
 
+// exportAvailable exports images, skipping the ones that fail to be exported when copy continues on errors.
+// Images are only exported one by one after exporting all of them at once failed.
+func (i ImageSet) exportAvailable(foundImages *UnprocessedImageRefs,
+	imagesMetadata ctlimg.ImagesMetadata) (*imagedesc.ImageRefDescriptors, []FailedImage, error) {
+
+	ids, err := i.Export(foundImages, imagesMetadata)
+	if err == nil || !i.continueOnError {
+		return ids, nil, err
+	}
+
+	i.logger.WriteStr("exporting images one by one to find the ones that failed: %s\n", err)
+
+	availableImages := NewUnprocessedImageRefs()
+	var failedImages []FailedImage
+
+	for _, img := range foundImages.All() {
+		ref, err := regname.NewDigest(img.DigestRef)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		metadata := []imagedesc.Metadata{{Ref: ref, Tag: img.Tag, Labels: img.Labels}}
+
+		_, err = imagedesc.NewImageRefDescriptorsWithPlatformFilter(metadata, imagesMetadata, i.platformFilter)
+		if err != nil {
+			i.logger.WriteStr("failed to export %s: %s\n", img.DigestRef, err)
+			failedImages = append(failedImages, FailedImage{UnprocessedImageRef: img, Err: err})
+			continue
+		}
+		availableImages.Add(img)
+	}
+
+	ids, err = i.Export(availableImages, imagesMetadata)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ids, failedImages, nil
+}
+
 func (i *ImageSet) Import(imgOrIndexes []imagedesc.ImageOrIndex,
 	importRepo regname.Repository, registry ImagesReaderWriter) (*ProcessedImages, error) {
 
 	importedImages := NewProcessedImages()
 
-	i.logger.WriteStr("importing %d images...\n", len(imgOrIndexes))
+	if i.checkpoint != nil {
+		imgOrIndexes = i.skipCheckpointedItems(imgOrIndexes, importRepo, registry, importedImages)
+		if len(imgOrIndexes) == 0 {
+			return importedImages, nil
+		}
+	}
 
 	importThrottle := util.NewThrottle(i.concurrency)
 
+	if i.recompression != "" {
+		recompressor := newRecompressor(i.recompression)
+		defer recompressor.Remove()
+
+		var err error
+		imgOrIndexes, err = i.recompress(imgOrIndexes, recompressor, importThrottle)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	i.logger.WriteStr("importing %d images...\n", len(imgOrIndexes))
+	importStartedAt := time.Now()
+
+	mounter := newBlobMounter(i.mountFromRepos, importRepo, registry)
+
+	uploadRefs, err := i.uploadRefs(imgOrIndexes, importRepo)
+	if err != nil {
+		return nil, err
+	}
+
 	imageOrIndexesToWrite := map[regname.Reference]regremote.Taggable{}
+	// failedItems are only set when copy continues on errors
+	failedItems := make([]bool, len(imgOrIndexes))
 	var imageOrIndexesToWriteLock = &sync.Mutex{}
 	errCh := make(chan error, len(imgOrIndexes))
-	for _, item := range imgOrIndexes {
-		item := item // copy
+	for idx, item := range imgOrIndexes {
+		idx, item := idx, item // copy
 
 		go func() {
 			importThrottle.Take()
 			defer importThrottle.Done()
-			tag, taggable, err := i.getImageOrImageIndexForMultiWrite(item, importRepo, registry)
+			taggable, err := i.getImageOrImageIndexForMultiWrite(item, uploadRefs[idx], registry)
 			if err != nil {
-				errCh <- err
+				failedItems[idx] = true
+				errCh <- i.failItem(item, err, importedImages)
 				return
 			}
+			if mounter != nil {
+				taggable = mounter.Taggable(taggable)
+			}
 			imageOrIndexesToWriteLock.Lock()
 			defer imageOrIndexesToWriteLock.Unlock()
 
-			imageOrIndexesToWrite[tag] = taggable
+			imageOrIndexesToWrite[uploadRefs[idx]] = taggable
 			errCh <- nil
 		}()
 	}
 
-	err := checkForAnyAsyncErrors(imgOrIndexes, errCh)
+	err = checkForAnyAsyncErrors(imgOrIndexes, errCh)
 	if err != nil {
 		return nil, err
 	}
 
-	err = registry.MultiWrite(imageOrIndexesToWrite, i.concurrency, nil)
-	if err != nil {
-		return nil, err
+	if len(imageOrIndexesToWrite) > 0 {
+		err = registry.MultiWrite(imageOrIndexesToWrite, i.concurrency, nil)
+		if err != nil {
+			if !i.continueOnError {
+				return nil, err
+			}
+			i.writeItemsOneByOne(imgOrIndexes, uploadRefs, imageOrIndexesToWrite, failedItems, registry, importedImages, err)
+		}
+	}
+
+	if mounter != nil {
+		mounter.Report(i.logger)
 	}
 
 	errChVerifyImages := make(chan error, len(imgOrIndexes))
-	for _, item := range imgOrIndexes {
-		item := item // copy
+	for idx, item := range imgOrIndexes {
+		idx, item := idx, item // copy
 
 		go func() {
 			importThrottle.Take()
 			defer importThrottle.Done()
 
-			processedImage, err := i.tagAndVerifyItem(item, importRepo, registry)
-			if err == nil {
-				importedImages.Add(processedImage)
+			if failedItems[idx] {
+				errChVerifyImages <- nil
+				return
 			}
-			errChVerifyImages <- err
+
+			processedImage, err := i.tagAndVerifyItem(item, uploadRefs[idx], importRepo, registry)
+			if err != nil {
+				errChVerifyImages <- i.failItem(item, err, importedImages)
+				return
+			}
+			processedImage.Duration = time.Since(importStartedAt)
+
+			importedImages.Add(processedImage)
+			errChVerifyImages <- i.recordInCheckpoint(processedImage, importRepo)
 		}()
 	}
 
@@ -140,46 +453,129 @@ func (i *ImageSet) Import(imgOrIndexes []imagedesc.ImageOrIndex,
 	return importedImages, nil
 }
 
-func checkForAnyAsyncErrors(imgOrIndexes []imagedesc.ImageOrIndex, errCh chan error) error {
-	for i := 0; i < len(imgOrIndexes); i++ {
-		err := <-errCh
+// writeItemsOneByOne writes each item on its own after writing all of them at once failed,
+// since a failed write does not tell which of the items could not be written
+func (i *ImageSet) writeItemsOneByOne(imgOrIndexes []imagedesc.ImageOrIndex, uploadRefs []regname.Reference,
+	imageOrIndexesToWrite map[regname.Reference]regremote.Taggable, failedItems []bool,
+	registry ImagesReaderWriter, processedImages *ProcessedImages, multiWriteErr error) {
+
+	i.logger.WriteStr("importing images one by one to find the ones that failed: %s\n", multiWriteErr)
+
+	for idx, item := range imgOrIndexes {
+		if failedItems[idx] {
+			continue
+		}
+
+		toWrite := map[regname.Reference]regremote.Taggable{uploadRefs[idx]: imageOrIndexesToWrite[uploadRefs[idx]]}
+
+		err := registry.MultiWrite(toWrite, i.concurrency, nil)
 		if err != nil {
-			return err
+			failedItems[idx] = true
+			_ = i.failItem(item, err, processedImages)
 		}
 	}
+}
+
+// failItem records item as failed when copy continues on errors, otherwise it returns the error
+func (i *ImageSet) failItem(item imagedesc.ImageOrIndex, err error, processedImages *ProcessedImages) error {
+	if !i.continueOnError {
+		return err
+	}
+
+	i.logger.WriteStr("failed to import %s: %s\n", item.Ref(), err)
+	processedImages.AddFailure(FailedImage{
+		UnprocessedImageRef: UnprocessedImageRef{DigestRef: normalizedDigestRef(item.Ref()), Tag: item.Tag(), Labels: item.Labels},
+		Err:                 err,
+	})
 	return nil
 }
 
-func (i ImageSet) getImageOrImageIndexForMultiWrite(item imagedesc.ImageOrIndex, importRepo regname.Repository, registry ImagesReaderWriter) (regname.Tag, regremote.Taggable, error) {
-	uploadTagRef, err := buildUploadTagRef(item, importRepo)
+// skipCheckpointedItems adds items imported during a previous copy to processed images
+// and returns items that still need to be imported
+func (i *ImageSet) skipCheckpointedItems(imgOrIndexes []imagedesc.ImageOrIndex, importRepo regname.Repository,
+	registry ImagesReaderWriter, processedImages *ProcessedImages) []imagedesc.ImageOrIndex {
+
+	var itemsToImport []imagedesc.ImageOrIndex
+	for _, item := range imgOrIndexes {
+		ref := UnprocessedImageRef{DigestRef: item.Ref(), Tag: item.Tag(), Labels: item.Labels}
+
+		if processedImage, found := i.checkpointedImage(ref, importRepo, registry); found {
+			processedImages.Add(processedImage)
+		} else {
+			itemsToImport = append(itemsToImport, item)
+		}
+	}
+
+	if skipped := len(imgOrIndexes) - len(itemsToImport); skipped > 0 {
+		i.logger.WriteStr("skipped %d images already copied to %s according to checkpoint\n", skipped, importRepo.Name())
+	}
+
+	return itemsToImport
+}
+
+func (i *ImageSet) recompress(imgOrIndexes []imagedesc.ImageOrIndex, recompressor *recompressor, throttle util.Throttle) ([]imagedesc.ImageOrIndex, error) {
+	i.logger.WriteStr("recompressing %d images with %s...\n", len(imgOrIndexes), i.recompression)
+
+	result := make([]imagedesc.ImageOrIndex, len(imgOrIndexes))
+	errCh := make(chan error, len(imgOrIndexes))
+
+	for idx, item := range imgOrIndexes {
+		idx, item := idx, item // copy
+
+		go func() {
+			throttle.Take()
+			defer throttle.Done()
+
+			recompressedItem, err := recompressor.Item(item)
+			if err == nil {
+				result[idx] = recompressedItem
+			}
+			errCh <- err
+		}()
+	}
+
+	err := checkForAnyAsyncErrors(imgOrIndexes, errCh)
 	if err != nil {
-		return regname.Tag{}, nil, err
+		return nil, err
 	}
 
-	var artifactToWrite regremote.Taggable
-	switch {
-	case item.Image != nil:
-		artifactToWrite, err = i.mountableImage(*item.Image, uploadTagRef, registry)
+	return result, nil
+}
+
+func checkForAnyAsyncErrors(imgOrIndexes []imagedesc.ImageOrIndex, errCh chan error) error {
+	for i := 0; i < len(imgOrIndexes); i++ {
+		err := <-errCh
 		if err != nil {
-			return regname.Tag{}, nil, err
+			return err
 		}
+	}
+	return nil
+}
 
+func (i ImageSet) getImageOrImageIndexForMultiWrite(item imagedesc.ImageOrIndex, uploadRef regname.Reference, registry ImagesReaderWriter) (regremote.Taggable, error) {
+	switch {
+	case item.Image != nil:
+		return i.mountableImage(*item.Image, uploadRef, registry)
 	case item.Index != nil:
-		artifactToWrite = *item.Index
+		return *item.Index, nil
 	default:
 		panic("Unknown item")
 	}
-
-	return uploadTagRef, artifactToWrite, nil
 }
 
-func (ImageSet) mountableImage(imageWithRef imagedesc.ImageWithRef, uploadTagRef regname.Tag, registry ImagesReaderWriter) (regremote.Taggable, error) {
+func (ImageSet) mountableImage(imageWithRef imagedesc.ImageWithRef, uploadRef regname.Reference, registry ImagesReaderWriter) (regremote.Taggable, error) {
 	itemRef, err := regname.NewDigest(imageWithRef.Ref())
 	if err != nil {
 		return nil, fmt.Errorf("Unable to parse reference: %s: %s", imageWithRef.Ref(), err)
 	}
 
-	if imageBlobsCanBeMounted(itemRef, uploadTagRef) {
+	digest, err := imageWithRef.Digest()
+	if err != nil {
+		return nil, err
+	}
+
+	// Recompressed images are not present in the source registry
+	if imageBlobsCanBeMounted(itemRef, uploadRef) && itemRef.DigestStr() == digest.String() {
 		descriptor, err := registry.Get(itemRef)
 		if err != nil {
 			// If a performance improvement cannot be done, fallback to the 'non-performant' way
@@ -195,12 +591,48 @@ func (ImageSet) mountableImage(imageWithRef imagedesc.ImageWithRef, uploadTagRef
 	return regv1.Image(imageWithRef), nil
 }
 
-func buildUploadTagRef(item imagedesc.ImageOrIndex, importRepo regname.Repository) (regname.Tag, error) {
-	itemDigest, err := item.Digest()
-	if err != nil {
-		return regname.Tag{}, err
+// uploadRefs returns references used to upload items, in the same order as items.
+// Different images written with the same tag (e.g. when reusing original tags) would overwrite each other,
+// so they are rejected before anything is written.
+func (i ImageSet) uploadRefs(imgOrIndexes []imagedesc.ImageOrIndex, importRepo regname.Repository) ([]regname.Reference, error) {
+	uploadRefs := make([]regname.Reference, len(imgOrIndexes))
+	tags := NewDestinationTags()
+
+	// Items are checked in a stable order so that the same collision is reported each time
+	order := make([]int, len(imgOrIndexes))
+	for idx := range order {
+		order[idx] = idx
+	}
+	sort.SliceStable(order, func(a, b int) bool { return imgOrIndexes[order[a]].Ref() < imgOrIndexes[order[b]].Ref() })
+
+	for _, idx := range order {
+		item := imgOrIndexes[idx]
+
+		itemDigest, err := item.Digest()
+		if err != nil {
+			return nil, err
+		}
+
+		uploadRefs[idx], err = i.tagStrategy.UploadRef(importRepo, itemDigest, item.Tag())
+		if err != nil {
+			return nil, err
+		}
+
+		itemTags, err := i.tagStrategy.Tags(importRepo, itemDigest, item.Tag())
+		if err != nil {
+			return nil, err
+		}
+
+		err = tags.Claim(importRepo, itemTags, itemDigest, item.Ref())
+		if err != nil {
+			return nil, err
+		}
 	}
 
+	return uploadRefs, nil
+}
+
+func buildUploadTagRefFromDigest(itemDigest regv1.Hash, importRepo regname.Repository) (regname.Tag, error) {
 	tag := fmt.Sprintf("%s-%s.imgpkg", itemDigest.Algorithm, itemDigest.Hex)
 	uploadTagRef, err := regname.NewTag(fmt.Sprintf("%s:%s", importRepo.Name(), tag))
 	if err != nil {
@@ -209,20 +641,22 @@ func buildUploadTagRef(item imagedesc.ImageOrIndex, importRepo regname.Repositor
 	return uploadTagRef, nil
 }
 
-func (i *ImageSet) tagAndVerifyItem(item imagedesc.ImageOrIndex, importRepo regname.Repository, registry ImagesReaderWriter) (ProcessedImage, error) {
+func (i *ImageSet) tagAndVerifyItem(item imagedesc.ImageOrIndex, uploadRef regname.Reference,
+	importRepo regname.Repository, registry ImagesReaderWriter) (ProcessedImage, error) {
+
 	existingRef, err := regname.NewDigest(item.Ref())
 	if err != nil {
 		return ProcessedImage{}, err
 	}
 
-	importDigestRef, err := i.verifyItemCopied(item, importRepo, registry)
+	importDigestRef, err := i.verifyItemCopied(item, uploadRef, importRepo, registry)
 	if err != nil {
 		return ProcessedImage{}, err
 	}
 
-	err = i.tagItemCopied(item, importRepo, registry, importDigestRef)
+	err = i.tagItemCopied(item, uploadRef, importRepo, registry, importDigestRef)
 	if err != nil {
-		return ProcessedImage{}, fmt.Errorf("Importing image %s: %s", existingRef.Name(), err)
+		return ProcessedImage{}, fmt.Errorf("Importing image %s: %w", existingRef.Name(), err)
 	}
 
 	var regImage regv1.Image
@@ -241,24 +675,31 @@ func (i *ImageSet) tagAndVerifyItem(item imagedesc.ImageOrIndex, importRepo regn
 	}, nil
 }
 
-func (i *ImageSet) tagItemCopied(item imagedesc.ImageOrIndex, importRepo regname.Repository, registry ImagesReaderWriter, importDigestRef regname.Digest) error {
+func (i *ImageSet) tagItemCopied(item imagedesc.ImageOrIndex, uploadRef regname.Reference,
+	importRepo regname.Repository, registry ImagesReaderWriter, importDigestRef regname.Digest) error {
+
 	if item.Tag() != "" {
 		uploadOriginalTagRef, err := regname.NewTag(fmt.Sprintf("%s:%s", importRepo.Name(), item.Tag()))
 		if err != nil {
 			return fmt.Errorf("Building upload tag image ref: %s", err)
 		}
 
+		// Image was already uploaded using its original tag
+		if uploadOriginalTagRef == uploadRef {
+			return nil
+		}
+
 		switch {
 		case item.Image != nil:
 			err = registry.WriteTag(uploadOriginalTagRef, *item.Image)
 			if err != nil {
-				return fmt.Errorf("Importing image as %s: %s", importDigestRef.Name(), err)
+				return fmt.Errorf("Importing image as %s: %w", importDigestRef.Name(), err)
 			}
 
 		case item.Index != nil:
 			err = registry.WriteTag(uploadOriginalTagRef, *item.Index)
 			if err != nil {
-				return fmt.Errorf("Importing image index as %s: %s", importDigestRef.Name(), err)
+				return fmt.Errorf("Importing image index as %s: %w", importDigestRef.Name(), err)
 			}
 
 		default:
@@ -268,7 +709,9 @@ func (i *ImageSet) tagItemCopied(item imagedesc.ImageOrIndex, importRepo regname
 	return nil
 }
 
-func (i *ImageSet) verifyItemCopied(item imagedesc.ImageOrIndex, importRepo regname.Repository, registry ImagesReaderWriter) (regname.Digest, error) {
+func (i *ImageSet) verifyItemCopied(item imagedesc.ImageOrIndex, uploadRef regname.Reference,
+	importRepo regname.Repository, registry ImagesReaderWriter) (regname.Digest, error) {
+
 	itemDigest, err := item.Digest()
 	if err != nil {
 		return regname.Digest{}, err
@@ -279,17 +722,11 @@ func (i *ImageSet) verifyItemCopied(item imagedesc.ImageOrIndex, importRepo regn
 		return regname.Digest{}, fmt.Errorf("Building new digest image ref: %s", err)
 	}
 
-	// AWS ECR doesnt like using digests for manifest uploads
-	uploadTagRef, err := buildUploadTagRef(item, importRepo)
-	if err != nil {
-		return regname.Digest{}, err
-	}
-
 	// Verify that imported image still has the same digest as we expect.
-	// Being a little bit paranoid here because tag ref is used for import
-	// instead of plain digest ref, because AWS ECR doesnt like digests
-	// during manifest upload.
-	err = i.verifyTagDigest(uploadTagRef, importDigestRef, registry)
+	// Being a little bit paranoid here because, depending on the destination
+	// tag strategy, tag ref is used for import instead of plain digest ref
+	// (e.g. AWS ECR doesnt like digests during manifest upload).
+	err = i.verifyTagDigest(uploadRef, importDigestRef, registry)
 	if err != nil {
 		return regname.Digest{}, err
 	}
@@ -297,16 +734,16 @@ func (i *ImageSet) verifyItemCopied(item imagedesc.ImageOrIndex, importRepo regn
 }
 
 func (i *ImageSet) verifyTagDigest(
-	uploadTagRef regname.Reference, importDigestRef regname.Digest, registry ImagesReaderWriter) error {
+	uploadRef regname.Reference, importDigestRef regname.Digest, registry ImagesReaderWriter) error {
 
-	resultURL, err := getResolvedImageURL(uploadTagRef.Name(), registry)
+	resultURL, err := getResolvedImageURL(uploadRef, registry)
 	if err != nil {
-		return fmt.Errorf("Verifying imported image %s: %s", uploadTagRef.Name(), err)
+		return fmt.Errorf("Verifying imported image %s: %w", uploadRef.Name(), err)
 	}
 
 	resultRef, err := regname.NewDigest(resultURL)
 	if err != nil {
-		return fmt.Errorf("Verifying imported image %s: %s", resultURL, err)
+		return fmt.Errorf("Verifying imported image %s: %w", resultURL, err)
 	}
 
 	if resultRef.DigestStr() != importDigestRef.DigestStr() {
@@ -317,18 +754,13 @@ func (i *ImageSet) verifyTagDigest(
 	return nil
 }
 
-func getResolvedImageURL(tagRef string, registry ctlimg.ImagesMetadata) (string, error) {
-	tag, err := regname.NewTag(tagRef, regname.WeakValidation)
-	if err != nil {
-		return "", err
-	}
-
-	hash, err := registry.Digest(tag)
+func getResolvedImageURL(ref regname.Reference, registry ctlimg.ImagesMetadata) (string, error) {
+	hash, err := registry.Digest(ref)
 	if err != nil {
 		return "", err
 	}
 
-	digest, err := regname.NewDigest(tag.Repository.String() + "@" + hash.String())
+	digest, err := regname.NewDigest(ref.Context().String() + "@" + hash.String())
 	if err != nil {
 		return "", err
 	}
@@ -339,6 +771,6 @@ func getResolvedImageURL(tagRef string, registry ctlimg.ImagesMetadata) (string,
 // This is a constraint on how registries are able to mount 'objects' across repos.
 // When mounting an object from repo A to repo B, the object in repo A needs to live in the same registry as repo B.
 // To read more about mounting across a repo: https://github.com/opencontainers/distribution-spec/blob/master/spec.md#mounting-a-blob-from-another-repository
-func imageBlobsCanBeMounted(ref regname.Reference, uploadTagRef regname.Tag) bool {
-	return ref.Context().RegistryStr() == uploadTagRef.Context().RegistryStr()
+func imageBlobsCanBeMounted(ref regname.Reference, uploadRef regname.Reference) bool {
+	return ref.Context().RegistryStr() == uploadRef.Context().RegistryStr()
 }
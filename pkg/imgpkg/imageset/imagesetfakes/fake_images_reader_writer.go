@@ -8,9 +8,23 @@ import (
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/k14s/imgpkg/pkg/imgpkg/imageset"
+	"github.com/k14s/imgpkg/pkg/imgpkg/registry"
 )
 
 type FakeImagesReaderWriter struct {
+	BlobExistsStub        func(name.Digest) (bool, error)
+	blobExistsMutex       sync.RWMutex
+	blobExistsArgsForCall []struct {
+		arg1 name.Digest
+	}
+	blobExistsReturns struct {
+		result1 bool
+		result2 error
+	}
+	blobExistsReturnsOnCall map[int]struct {
+		result1 bool
+		result2 error
+	}
 	DigestStub        func(name.Reference) (v1.Hash, error)
 	digestMutex       sync.RWMutex
 	digestArgsForCall []struct {
@@ -89,6 +103,32 @@ type FakeImagesReaderWriter struct {
 	multiWriteReturnsOnCall map[int]struct {
 		result1 error
 	}
+	ReferrersStub        func(name.Digest) ([]registry.ReferrerDescriptor, error)
+	referrersMutex       sync.RWMutex
+	referrersArgsForCall []struct {
+		arg1 name.Digest
+	}
+	referrersReturns struct {
+		result1 []registry.ReferrerDescriptor
+		result2 error
+	}
+	referrersReturnsOnCall map[int]struct {
+		result1 []registry.ReferrerDescriptor
+		result2 error
+	}
+	ReferrersAPISupportedStub        func(name.Repository) (bool, error)
+	referrersAPISupportedMutex       sync.RWMutex
+	referrersAPISupportedArgsForCall []struct {
+		arg1 name.Repository
+	}
+	referrersAPISupportedReturns struct {
+		result1 bool
+		result2 error
+	}
+	referrersAPISupportedReturnsOnCall map[int]struct {
+		result1 bool
+		result2 error
+	}
 	WriteImageStub        func(name.Reference, v1.Image) error
 	writeImageMutex       sync.RWMutex
 	writeImageArgsForCall []struct {
@@ -129,6 +169,70 @@ type FakeImagesReaderWriter struct {
 	invocationsMutex sync.RWMutex
 }
 
+func (fake *FakeImagesReaderWriter) BlobExists(arg1 name.Digest) (bool, error) {
+	fake.blobExistsMutex.Lock()
+	ret, specificReturn := fake.blobExistsReturnsOnCall[len(fake.blobExistsArgsForCall)]
+	fake.blobExistsArgsForCall = append(fake.blobExistsArgsForCall, struct {
+		arg1 name.Digest
+	}{arg1})
+	stub := fake.BlobExistsStub
+	fakeReturns := fake.blobExistsReturns
+	fake.recordInvocation("BlobExists", []interface{}{arg1})
+	fake.blobExistsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeImagesReaderWriter) BlobExistsCallCount() int {
+	fake.blobExistsMutex.RLock()
+	defer fake.blobExistsMutex.RUnlock()
+	return len(fake.blobExistsArgsForCall)
+}
+
+func (fake *FakeImagesReaderWriter) BlobExistsCalls(stub func(name.Digest) (bool, error)) {
+	fake.blobExistsMutex.Lock()
+	defer fake.blobExistsMutex.Unlock()
+	fake.BlobExistsStub = stub
+}
+
+func (fake *FakeImagesReaderWriter) BlobExistsArgsForCall(i int) name.Digest {
+	fake.blobExistsMutex.RLock()
+	defer fake.blobExistsMutex.RUnlock()
+	argsForCall := fake.blobExistsArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeImagesReaderWriter) BlobExistsReturns(result1 bool, result2 error) {
+	fake.blobExistsMutex.Lock()
+	defer fake.blobExistsMutex.Unlock()
+	fake.BlobExistsStub = nil
+	fake.blobExistsReturns = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeImagesReaderWriter) BlobExistsReturnsOnCall(i int, result1 bool, result2 error) {
+	fake.blobExistsMutex.Lock()
+	defer fake.blobExistsMutex.Unlock()
+	fake.BlobExistsStub = nil
+	if fake.blobExistsReturnsOnCall == nil {
+		fake.blobExistsReturnsOnCall = make(map[int]struct {
+			result1 bool
+			result2 error
+		})
+	}
+	fake.blobExistsReturnsOnCall[i] = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeImagesReaderWriter) Digest(arg1 name.Reference) (v1.Hash, error) {
 	fake.digestMutex.Lock()
 	ret, specificReturn := fake.digestReturnsOnCall[len(fake.digestArgsForCall)]
@@ -517,6 +621,134 @@ func (fake *FakeImagesReaderWriter) MultiWriteReturnsOnCall(i int, result1 error
 	}{result1}
 }
 
+func (fake *FakeImagesReaderWriter) Referrers(arg1 name.Digest) ([]registry.ReferrerDescriptor, error) {
+	fake.referrersMutex.Lock()
+	ret, specificReturn := fake.referrersReturnsOnCall[len(fake.referrersArgsForCall)]
+	fake.referrersArgsForCall = append(fake.referrersArgsForCall, struct {
+		arg1 name.Digest
+	}{arg1})
+	stub := fake.ReferrersStub
+	fakeReturns := fake.referrersReturns
+	fake.recordInvocation("Referrers", []interface{}{arg1})
+	fake.referrersMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeImagesReaderWriter) ReferrersCallCount() int {
+	fake.referrersMutex.RLock()
+	defer fake.referrersMutex.RUnlock()
+	return len(fake.referrersArgsForCall)
+}
+
+func (fake *FakeImagesReaderWriter) ReferrersCalls(stub func(name.Digest) ([]registry.ReferrerDescriptor, error)) {
+	fake.referrersMutex.Lock()
+	defer fake.referrersMutex.Unlock()
+	fake.ReferrersStub = stub
+}
+
+func (fake *FakeImagesReaderWriter) ReferrersArgsForCall(i int) name.Digest {
+	fake.referrersMutex.RLock()
+	defer fake.referrersMutex.RUnlock()
+	argsForCall := fake.referrersArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeImagesReaderWriter) ReferrersReturns(result1 []registry.ReferrerDescriptor, result2 error) {
+	fake.referrersMutex.Lock()
+	defer fake.referrersMutex.Unlock()
+	fake.ReferrersStub = nil
+	fake.referrersReturns = struct {
+		result1 []registry.ReferrerDescriptor
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeImagesReaderWriter) ReferrersReturnsOnCall(i int, result1 []registry.ReferrerDescriptor, result2 error) {
+	fake.referrersMutex.Lock()
+	defer fake.referrersMutex.Unlock()
+	fake.ReferrersStub = nil
+	if fake.referrersReturnsOnCall == nil {
+		fake.referrersReturnsOnCall = make(map[int]struct {
+			result1 []registry.ReferrerDescriptor
+			result2 error
+		})
+	}
+	fake.referrersReturnsOnCall[i] = struct {
+		result1 []registry.ReferrerDescriptor
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeImagesReaderWriter) ReferrersAPISupported(arg1 name.Repository) (bool, error) {
+	fake.referrersAPISupportedMutex.Lock()
+	ret, specificReturn := fake.referrersAPISupportedReturnsOnCall[len(fake.referrersAPISupportedArgsForCall)]
+	fake.referrersAPISupportedArgsForCall = append(fake.referrersAPISupportedArgsForCall, struct {
+		arg1 name.Repository
+	}{arg1})
+	stub := fake.ReferrersAPISupportedStub
+	fakeReturns := fake.referrersAPISupportedReturns
+	fake.recordInvocation("ReferrersAPISupported", []interface{}{arg1})
+	fake.referrersAPISupportedMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeImagesReaderWriter) ReferrersAPISupportedCallCount() int {
+	fake.referrersAPISupportedMutex.RLock()
+	defer fake.referrersAPISupportedMutex.RUnlock()
+	return len(fake.referrersAPISupportedArgsForCall)
+}
+
+func (fake *FakeImagesReaderWriter) ReferrersAPISupportedCalls(stub func(name.Repository) (bool, error)) {
+	fake.referrersAPISupportedMutex.Lock()
+	defer fake.referrersAPISupportedMutex.Unlock()
+	fake.ReferrersAPISupportedStub = stub
+}
+
+func (fake *FakeImagesReaderWriter) ReferrersAPISupportedArgsForCall(i int) name.Repository {
+	fake.referrersAPISupportedMutex.RLock()
+	defer fake.referrersAPISupportedMutex.RUnlock()
+	argsForCall := fake.referrersAPISupportedArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeImagesReaderWriter) ReferrersAPISupportedReturns(result1 bool, result2 error) {
+	fake.referrersAPISupportedMutex.Lock()
+	defer fake.referrersAPISupportedMutex.Unlock()
+	fake.ReferrersAPISupportedStub = nil
+	fake.referrersAPISupportedReturns = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeImagesReaderWriter) ReferrersAPISupportedReturnsOnCall(i int, result1 bool, result2 error) {
+	fake.referrersAPISupportedMutex.Lock()
+	defer fake.referrersAPISupportedMutex.Unlock()
+	fake.ReferrersAPISupportedStub = nil
+	if fake.referrersAPISupportedReturnsOnCall == nil {
+		fake.referrersAPISupportedReturnsOnCall = make(map[int]struct {
+			result1 bool
+			result2 error
+		})
+	}
+	fake.referrersAPISupportedReturnsOnCall[i] = struct {
+		result1 bool
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeImagesReaderWriter) WriteImage(arg1 name.Reference, arg2 v1.Image) error {
 	fake.writeImageMutex.Lock()
 	ret, specificReturn := fake.writeImageReturnsOnCall[len(fake.writeImageArgsForCall)]
@@ -538,6 +770,10 @@ func (fake *FakeImagesReaderWriter) WriteImage(arg1 name.Reference, arg2 v1.Imag
 }
 
 func (fake *FakeImagesReaderWriter) WriteImageCallCount() int {
+	fake.referrersMutex.RLock()
+	defer fake.referrersMutex.RUnlock()
+	fake.referrersAPISupportedMutex.RLock()
+	defer fake.referrersAPISupportedMutex.RUnlock()
 	fake.writeImageMutex.RLock()
 	defer fake.writeImageMutex.RUnlock()
 	return len(fake.writeImageArgsForCall)
@@ -550,6 +786,10 @@ func (fake *FakeImagesReaderWriter) WriteImageCalls(stub func(name.Reference, v1
 }
 
 func (fake *FakeImagesReaderWriter) WriteImageArgsForCall(i int) (name.Reference, v1.Image) {
+	fake.referrersMutex.RLock()
+	defer fake.referrersMutex.RUnlock()
+	fake.referrersAPISupportedMutex.RLock()
+	defer fake.referrersAPISupportedMutex.RUnlock()
 	fake.writeImageMutex.RLock()
 	defer fake.writeImageMutex.RUnlock()
 	argsForCall := fake.writeImageArgsForCall[i]
@@ -706,6 +946,8 @@ func (fake *FakeImagesReaderWriter) WriteTagReturnsOnCall(i int, result1 error)
 func (fake *FakeImagesReaderWriter) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
+	fake.blobExistsMutex.RLock()
+	defer fake.blobExistsMutex.RUnlock()
 	fake.digestMutex.RLock()
 	defer fake.digestMutex.RUnlock()
 	fake.firstImageExistsMutex.RLock()
@@ -718,6 +960,10 @@ func (fake *FakeImagesReaderWriter) Invocations() map[string][][]interface{} {
 	defer fake.indexMutex.RUnlock()
 	fake.multiWriteMutex.RLock()
 	defer fake.multiWriteMutex.RUnlock()
+	fake.referrersMutex.RLock()
+	defer fake.referrersMutex.RUnlock()
+	fake.referrersAPISupportedMutex.RLock()
+	defer fake.referrersAPISupportedMutex.RUnlock()
 	fake.writeImageMutex.RLock()
 	defer fake.writeImageMutex.RUnlock()
 	fake.writeIndexMutex.RLock()
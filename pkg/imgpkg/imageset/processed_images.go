@@ -4,12 +4,15 @@
 package imageset
 
 import (
+	"errors"
 	"fmt"
 	"sort"
 	"sync"
+	"time"
 
 	regname "github.com/google/go-containerregistry/pkg/name"
 	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
 )
 
 type ProcessedImage struct {
@@ -18,6 +21,14 @@ type ProcessedImage struct {
 
 	Image      regv1.Image
 	ImageIndex regv1.ImageIndex
+
+	// AlreadyPresent is set when the image was found in the import repository, hence it was not uploaded
+	AlreadyPresent bool
+	// MissingImages is set for bundles whose images failed to be copied
+	MissingImages []string
+	// Duration is the time from the start of the import until the image was written, tagged and verified.
+	// Images are written concurrently, hence durations of images overlap.
+	Duration time.Duration
 }
 
 func (p ProcessedImage) Key() string {
@@ -37,13 +48,43 @@ func (p ProcessedImage) Validate() {
 	p.UnprocessedImageRef.Validate()
 }
 
+// FailedImage is an image that failed to be copied when copy continues on errors
+type FailedImage struct {
+	UnprocessedImageRef
+	Err error
+}
+
+// StatusCode returns HTTP status code returned by the registry, or 0 when failure did not come from a registry response
+func (f FailedImage) StatusCode() int {
+	var transportErr *transport.Error
+	if errors.As(f.Err, &transportErr) {
+		return transportErr.StatusCode
+	}
+	return 0
+}
+
+// ErrorCodes returns error codes returned by the registry (e.g. MANIFEST_UNKNOWN)
+func (f FailedImage) ErrorCodes() []string {
+	var transportErr *transport.Error
+	if !errors.As(f.Err, &transportErr) {
+		return nil
+	}
+
+	var codes []string
+	for _, diagnostic := range transportErr.Errors {
+		codes = append(codes, string(diagnostic.Code))
+	}
+	return codes
+}
+
 type ProcessedImages struct {
 	imgs     map[string]ProcessedImage
+	failed   map[string]FailedImage
 	imgsLock sync.Mutex
 }
 
 func NewProcessedImages() *ProcessedImages {
-	return &ProcessedImages{imgs: map[string]ProcessedImage{}}
+	return &ProcessedImages{imgs: map[string]ProcessedImage{}, failed: map[string]FailedImage{}}
 }
 
 func (i *ProcessedImages) Add(img ProcessedImage) {
@@ -55,6 +96,54 @@ func (i *ProcessedImages) Add(img ProcessedImage) {
 	i.imgs[img.UnprocessedImageRef.Key()] = img
 }
 
+// AddFailure records image that failed to be copied
+func (i *ProcessedImages) AddFailure(img FailedImage) {
+	i.imgsLock.Lock()
+	defer i.imgsLock.Unlock()
+
+	img.Validate()
+
+	i.failed[img.UnprocessedImageRef.Key()] = img
+}
+
+// AddAll adds processed and failed images of other ProcessedImages
+func (i *ProcessedImages) AddAll(other *ProcessedImages) {
+	for _, img := range other.All() {
+		i.Add(img)
+	}
+	for _, img := range other.Failures() {
+		i.AddFailure(img)
+	}
+}
+
+// Failures returns images that failed to be copied
+func (i *ProcessedImages) Failures() []FailedImage {
+	i.imgsLock.Lock()
+	defer i.imgsLock.Unlock()
+
+	var result []FailedImage
+	for _, img := range i.failed {
+		result = append(result, img)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].UnprocessedImageRef.DigestRef < result[j].UnprocessedImageRef.DigestRef
+	})
+	return result
+}
+
+// MarkIncomplete records images of a processed bundle that failed to be copied
+func (i *ProcessedImages) MarkIncomplete(digestRef string, missingImages []string) {
+	i.imgsLock.Lock()
+	defer i.imgsLock.Unlock()
+
+	for key, img := range i.imgs {
+		if img.UnprocessedImageRef.DigestRef == digestRef {
+			img.MissingImages = missingImages
+			i.imgs[key] = img
+		}
+	}
+}
+
 func (i *ProcessedImages) FindByURL(unprocessedImageURL UnprocessedImageRef) (ProcessedImage, bool) {
 	i.imgsLock.Lock()
 	defer i.imgsLock.Unlock()
@@ -0,0 +1,229 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package imageset
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	ctlimg "github.com/k14s/imgpkg/pkg/imgpkg/image"
+	"github.com/k14s/imgpkg/pkg/imgpkg/imagedesc"
+)
+
+// recompressor converts gzip compressed layers to a different compression.
+// Since layer digests change, images (and indexes) that contain them get new digests.
+// Images without gzip compressed layers are left untouched.
+type recompressor struct {
+	compression ctlimg.Compression
+
+	layers     map[regv1.Hash]*recompressedLayer
+	layersLock sync.Mutex
+}
+
+type recompressedLayer struct {
+	once  sync.Once
+	layer *ctlimg.CompressedFileLayer
+	err   error
+}
+
+func newRecompressor(compression ctlimg.Compression) *recompressor {
+	return &recompressor{compression: compression, layers: map[regv1.Hash]*recompressedLayer{}}
+}
+
+func (r *recompressor) Item(item imagedesc.ImageOrIndex) (imagedesc.ImageOrIndex, error) {
+	switch {
+	case item.Image != nil:
+		img, _, err := r.image(*item.Image)
+		if err != nil {
+			return imagedesc.ImageOrIndex{}, fmt.Errorf("Recompressing image %s: %s", item.Ref(), err)
+		}
+		var imgWithRef imagedesc.ImageWithRef = recompressedImage{img, item.Ref(), item.Tag()}
+		return imagedesc.ImageOrIndex{Image: &imgWithRef, Labels: item.Labels}, nil
+
+	case item.Index != nil:
+		idx, _, err := r.index(*item.Index)
+		if err != nil {
+			return imagedesc.ImageOrIndex{}, fmt.Errorf("Recompressing image index %s: %s", item.Ref(), err)
+		}
+		var idxWithRef imagedesc.ImageIndexWithRef = recompressedIndex{idx, item.Ref(), item.Tag()}
+		return imagedesc.ImageOrIndex{Index: &idxWithRef, Labels: item.Labels}, nil
+
+	default:
+		panic("Unknown item")
+	}
+}
+
+// Remove deletes temporary files holding recompressed layers
+func (r *recompressor) Remove() {
+	r.layersLock.Lock()
+	defer r.layersLock.Unlock()
+
+	for _, recompressed := range r.layers {
+		if recompressed.layer != nil {
+			_ = os.Remove(recompressed.layer.Path())
+		}
+	}
+}
+
+func (r *recompressor) image(img regv1.Image) (regv1.Image, bool, error) {
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, false, err
+	}
+
+	var adds []mutate.Addendum
+	var changed bool
+
+	for _, desc := range manifest.Layers {
+		layer, err := img.LayerByDigest(desc.Digest)
+		if err != nil {
+			return nil, false, err
+		}
+
+		// Non-distributable layers are referenced by digest from their URLs, so they are kept as is
+		if desc.MediaType == types.DockerLayer || desc.MediaType == types.OCILayer {
+			layer, err = r.layer(desc.Digest, layer)
+			if err != nil {
+				return nil, false, err
+			}
+			changed = true
+		}
+
+		adds = append(adds, mutate.Addendum{Layer: layer, Annotations: desc.Annotations, URLs: desc.URLs})
+	}
+
+	if !changed {
+		return img, false, nil
+	}
+
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Layers compressed with anything other than gzip are only defined by OCI image spec
+	result, err := mutate.Append(mutate.MediaType(empty.Image, types.OCIManifestSchema1), adds...)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Uncompressed layer contents are the same hence original config (including diff IDs) is still valid
+	result, err = mutate.ConfigFile(result, cfg)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return result, true, nil
+}
+
+func (r *recompressor) index(idx regv1.ImageIndex) (regv1.ImageIndex, bool, error) {
+	manifest, err := idx.IndexManifest()
+	if err != nil {
+		return nil, false, err
+	}
+
+	var adds []mutate.IndexAddendum
+	var changed bool
+
+	for _, desc := range manifest.Manifests {
+		var add mutate.Appendable
+		var childChanged bool
+
+		switch {
+		case desc.MediaType.IsIndex():
+			childIdx, err := idx.ImageIndex(desc.Digest)
+			if err != nil {
+				return nil, false, err
+			}
+			add, childChanged, err = r.index(childIdx)
+			if err != nil {
+				return nil, false, err
+			}
+
+		case desc.MediaType.IsImage():
+			childImg, err := idx.Image(desc.Digest)
+			if err != nil {
+				return nil, false, err
+			}
+			add, childChanged, err = r.image(childImg)
+			if err != nil {
+				return nil, false, err
+			}
+
+		default:
+			return nil, false, fmt.Errorf("Unsupported media type '%s' of manifest '%s'", desc.MediaType, desc.Digest)
+		}
+
+		changed = changed || childChanged
+
+		adds = append(adds, mutate.IndexAddendum{
+			Add: add,
+			Descriptor: regv1.Descriptor{
+				Platform:    desc.Platform,
+				Annotations: desc.Annotations,
+				URLs:        desc.URLs,
+			},
+		})
+	}
+
+	if !changed {
+		return idx, false, nil
+	}
+
+	return mutate.AppendManifests(mutate.IndexMediaType(empty.Index, types.OCIImageIndex), adds...), true, nil
+}
+
+// layer recompresses each layer only once even if it is shared by multiple images
+func (r *recompressor) layer(digest regv1.Hash, layer regv1.Layer) (regv1.Layer, error) {
+	r.layersLock.Lock()
+	recompressed, found := r.layers[digest]
+	if !found {
+		recompressed = &recompressedLayer{}
+		r.layers[digest] = recompressed
+	}
+	r.layersLock.Unlock()
+
+	recompressed.once.Do(func() {
+		uncompressed, err := layer.Uncompressed()
+		if err != nil {
+			recompressed.err = err
+			return
+		}
+
+		defer uncompressed.Close()
+
+		recompressed.layer, recompressed.err = ctlimg.NewCompressedFileLayer(uncompressed, r.compression, 0)
+	})
+
+	if recompressed.err != nil {
+		return nil, fmt.Errorf("Recompressing layer %s: %s", digest, recompressed.err)
+	}
+	return recompressed.layer, nil
+}
+
+type recompressedImage struct {
+	regv1.Image
+	ref string
+	tag string
+}
+
+func (i recompressedImage) Ref() string { return i.ref }
+func (i recompressedImage) Tag() string { return i.tag }
+
+// imageIndex allows embedding since regv1.ImageIndex has a method called ImageIndex
+type imageIndex = regv1.ImageIndex
+
+type recompressedIndex struct {
+	imageIndex
+	ref string
+	tag string
+}
+
+func (i recompressedIndex) Ref() string { return i.ref }
+func (i recompressedIndex) Tag() string { return i.tag }
@@ -0,0 +1,178 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package imageset
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	ctlreg "github.com/k14s/imgpkg/pkg/imgpkg/registry"
+)
+
+// ReferrerLabelKey labels images that refer to another image via their subject field.
+// Its value is the digest of the subject, so that referrers can be listed again after being copied.
+const ReferrerLabelKey = "dev.carvel.imgpkg.copy.referrer"
+
+// writeReferrersFallbackIndexes writes, for every subject of processed referrers, the index listing its referrers
+// using the fallback tag schema, since registries without referrers API do not list them on their own
+func (i ImageSet) writeReferrersFallbackIndexes(processedImages *ProcessedImages,
+	importRepo regname.Repository, registry ImagesReaderWriter) error {
+
+	referrersBySubject := map[string][]ProcessedImage{}
+	for _, img := range processedImages.All() {
+		if subject, found := img.Labels[ReferrerLabelKey]; found {
+			referrersBySubject[subject] = append(referrersBySubject[subject], img)
+		}
+	}
+	if len(referrersBySubject) == 0 {
+		return nil
+	}
+
+	// Support does not depend on subjects, hence it is only checked once for the import repository
+	supported, err := registry.ReferrersAPISupported(importRepo)
+	if err != nil {
+		return err
+	}
+	if supported {
+		return nil
+	}
+
+	var subjects []string
+	for subject := range referrersBySubject {
+		subjects = append(subjects, subject)
+	}
+	sort.Strings(subjects)
+
+	for _, subject := range subjects {
+		err := i.writeReferrersFallbackIndex(subject, referrersBySubject[subject], importRepo, registry)
+		if err != nil {
+			return fmt.Errorf("Writing referrers fallback index of '%s': %s", subject, err)
+		}
+	}
+
+	return nil
+}
+
+func (i ImageSet) writeReferrersFallbackIndex(subject string, referrers []ProcessedImage,
+	importRepo regname.Repository, registry ImagesReaderWriter) error {
+
+	subjectDigest, err := regv1.NewHash(subject)
+	if err != nil {
+		return err
+	}
+
+	// Keep referrers listed by the index already present in the import repository
+	descriptors, err := registry.Referrers(importRepo.Digest(subject))
+	if err != nil {
+		return err
+	}
+
+	listed := map[regv1.Hash]struct{}{}
+	for _, desc := range descriptors {
+		listed[desc.Digest] = struct{}{}
+	}
+
+	added := 0
+	for _, referrer := range referrers {
+		desc, err := referrerDescriptor(referrer)
+		if err != nil {
+			return err
+		}
+		if _, found := listed[desc.Digest]; found {
+			continue
+		}
+		listed[desc.Digest] = struct{}{}
+		descriptors = append(descriptors, desc)
+		added++
+	}
+
+	if added == 0 {
+		return nil
+	}
+
+	index, err := ctlreg.NewReferrersIndex(descriptors)
+	if err != nil {
+		return err
+	}
+
+	tag := importRepo.Tag(ctlreg.ReferrersFallbackTag(subjectDigest))
+
+	err = registry.WriteIndex(tag, index)
+	if err != nil {
+		return err
+	}
+
+	i.logger.WriteStr("wrote referrers fallback index %s listing %d referrers\n", tag.Name(), len(descriptors))
+	return nil
+}
+
+func referrerDescriptor(img ProcessedImage) (ctlreg.ReferrerDescriptor, error) {
+	type manifestOrIndex interface {
+		MediaType() (types.MediaType, error)
+		Size() (int64, error)
+		RawManifest() ([]byte, error)
+	}
+
+	var item manifestOrIndex = img.ImageIndex
+	if img.Image != nil {
+		item = img.Image
+	}
+
+	digestRef, err := regname.NewDigest(img.DigestRef)
+	if err != nil {
+		return ctlreg.ReferrerDescriptor{}, err
+	}
+
+	digest, err := regv1.NewHash(digestRef.DigestStr())
+	if err != nil {
+		return ctlreg.ReferrerDescriptor{}, err
+	}
+
+	mediaType, err := item.MediaType()
+	if err != nil {
+		return ctlreg.ReferrerDescriptor{}, err
+	}
+
+	size, err := item.Size()
+	if err != nil {
+		return ctlreg.ReferrerDescriptor{}, err
+	}
+
+	rawManifest, err := item.RawManifest()
+	if err != nil {
+		return ctlreg.ReferrerDescriptor{}, err
+	}
+
+	var manifest struct {
+		ArtifactType string `json:"artifactType"`
+		Config       struct {
+			MediaType string `json:"mediaType"`
+		} `json:"config"`
+		Annotations map[string]string `json:"annotations"`
+	}
+
+	err = json.Unmarshal(rawManifest, &manifest)
+	if err != nil {
+		return ctlreg.ReferrerDescriptor{}, fmt.Errorf("Parsing manifest of referrer '%s': %s", img.DigestRef, err)
+	}
+
+	// Artifact type of manifests without one is the media type of their config
+	// (https://github.com/opencontainers/distribution-spec/blob/main/spec.md#listing-referrers)
+	artifactType := manifest.ArtifactType
+	if artifactType == "" && img.Image != nil {
+		artifactType = manifest.Config.MediaType
+	}
+
+	return ctlreg.ReferrerDescriptor{
+		MediaType:    mediaType,
+		Size:         size,
+		Digest:       digest,
+		ArtifactType: artifactType,
+		Annotations:  manifest.Annotations,
+	}, nil
+}
@@ -0,0 +1,138 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package imageset
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/k14s/imgpkg/pkg/imgpkg/imagedesc"
+	"github.com/k14s/imgpkg/pkg/imgpkg/imagetar"
+)
+
+// RegistryPrefix places images in repositories that keep their source repository structure
+// under a common prefix (e.g. internal.corp/mirror/index.docker.io/library/nginx)
+type RegistryPrefix struct {
+	prefix    regname.Repository
+	stripHost bool
+}
+
+// NewRegistryPrefix creates a RegistryPrefix; when stripHost is set
+// source registry hosts are not included in destination repositories
+func NewRegistryPrefix(prefix string, stripHost bool) (RegistryPrefix, error) {
+	prefixRepo, err := regname.NewRepository(strings.TrimSuffix(prefix, "/"))
+	if err != nil {
+		return RegistryPrefix{}, fmt.Errorf("Building registry prefix: %s", err)
+	}
+	return RegistryPrefix{prefix: prefixRepo, stripHost: stripHost}, nil
+}
+
+func (p RegistryPrefix) String() string { return p.prefix.Name() }
+
+// ImportRepo returns repository under the prefix that image with given source reference is copied to
+func (p RegistryPrefix) ImportRepo(sourceRef string) (regname.Repository, error) {
+	ref, err := regname.ParseReference(sourceRef)
+	if err != nil {
+		return regname.Repository{}, err
+	}
+
+	repoPath := ref.Context().RepositoryStr()
+	if !p.stripHost {
+		// Registry ports are not allowed in repository paths (e.g. localhost:5000 becomes localhost-5000)
+		repoPath = strings.ReplaceAll(ref.Context().RegistryStr(), ":", "-") + "/" + repoPath
+	}
+
+	importRepo, err := regname.NewRepository(p.prefix.Name() + "/" + repoPath)
+	if err != nil {
+		return regname.Repository{}, fmt.Errorf("Building import repository ref for %s: %s", sourceRef, err)
+	}
+	return importRepo, nil
+}
+
+// RelocateToRegistryPrefix relocates every image to its repository under the prefix.
+// Images are relocated one import repository at a time since uploads are done per repository.
+func (i ImageSet) RelocateToRegistryPrefix(foundImages *UnprocessedImageRefs, prefix RegistryPrefix,
+	registry ImagesReaderWriter) (*ProcessedImages, []*imagedesc.ImageRefDescriptors, error) {
+
+	imagesByRepo := map[string]*UnprocessedImageRefs{}
+	importRepos := map[string]regname.Repository{}
+
+	for _, img := range foundImages.All() {
+		importRepo, err := prefix.ImportRepo(img.DigestRef)
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, found := imagesByRepo[importRepo.Name()]; !found {
+			imagesByRepo[importRepo.Name()] = NewUnprocessedImageRefs()
+			importRepos[importRepo.Name()] = importRepo
+		}
+		imagesByRepo[importRepo.Name()].Add(img)
+	}
+
+	processedImages := NewProcessedImages()
+	var allIds []*imagedesc.ImageRefDescriptors
+
+	for _, repoName := range sortedKeys(importRepos) {
+		i.logger.WriteStr("relocating %d images to %s...\n", imagesByRepo[repoName].Length(), repoName)
+
+		repoProcessedImages, ids, err := i.Relocate(imagesByRepo[repoName], importRepos[repoName], registry)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		processedImages.AddAll(repoProcessedImages)
+		allIds = append(allIds, ids)
+	}
+
+	return processedImages, allIds, nil
+}
+
+// ImportToRegistryPrefix imports every image found in the tarball to its repository under the prefix
+func (i *TarImageSet) ImportToRegistryPrefix(path string, prefix RegistryPrefix, registry ImagesReaderWriter) (*ProcessedImages, error) {
+	imgOrIndexes, err := imagetar.NewTarReader(path).Read()
+	if err != nil {
+		return nil, err
+	}
+
+	imagesByRepo := map[string][]imagedesc.ImageOrIndex{}
+	importRepos := map[string]regname.Repository{}
+
+	for _, item := range imgOrIndexes {
+		importRepo, err := prefix.ImportRepo(item.Ref())
+		if err != nil {
+			return nil, err
+		}
+		importRepos[importRepo.Name()] = importRepo
+		imagesByRepo[importRepo.Name()] = append(imagesByRepo[importRepo.Name()], item)
+	}
+
+	processedImages := NewProcessedImages()
+
+	for _, repoName := range sortedKeys(importRepos) {
+		repoProcessedImages, err := i.imageSet.Import(imagesByRepo[repoName], importRepos[repoName], registry)
+		if err != nil {
+			return nil, err
+		}
+
+		err = i.imageSet.writeReferrersFallbackIndexes(repoProcessedImages, importRepos[repoName], registry)
+		if err != nil {
+			return nil, err
+		}
+
+		processedImages.AddAll(repoProcessedImages)
+	}
+
+	return processedImages, nil
+}
+
+func sortedKeys(repos map[string]regname.Repository) []string {
+	var keys []string
+	for key := range repos {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
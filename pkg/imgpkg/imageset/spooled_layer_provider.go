@@ -0,0 +1,119 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package imageset
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/k14s/imgpkg/pkg/imgpkg/imagedesc"
+)
+
+// spooledLayerProvider writes layers to a temporary directory the first time they are read,
+// so that layers uploaded to multiple import repositories are only fetched once
+type spooledLayerProvider struct {
+	layerProvider imagedesc.LayerProvider
+	dir           string
+
+	layers     map[string]*spooledLayer
+	layersLock sync.Mutex
+}
+
+type spooledLayer struct {
+	lock    sync.Mutex
+	spooled bool
+	path    string
+}
+
+var _ imagedesc.LayerProvider = &spooledLayerProvider{}
+
+func newSpooledLayerProvider(layerProvider imagedesc.LayerProvider) (*spooledLayerProvider, error) {
+	dir, err := ioutil.TempDir("", "imgpkg-spooled-layers")
+	if err != nil {
+		return nil, fmt.Errorf("Creating directory for spooled layers: %s", err)
+	}
+
+	return &spooledLayerProvider{
+		layerProvider: layerProvider,
+		dir:           dir,
+		layers:        map[string]*spooledLayer{},
+	}, nil
+}
+
+func (p *spooledLayerProvider) FindLayer(layerTD imagedesc.ImageLayerDescriptor) (imagedesc.LayerContents, error) {
+	p.layersLock.Lock()
+	defer p.layersLock.Unlock()
+
+	layer, found := p.layers[layerTD.Digest]
+	if !found {
+		layer = &spooledLayer{path: filepath.Join(p.dir, strings.ReplaceAll(layerTD.Digest, ":", "-"))}
+		p.layers[layerTD.Digest] = layer
+	}
+
+	return spooledLayerContents{layer, layerTD, p.layerProvider}, nil
+}
+
+// Remove deletes temporary files holding spooled layers
+func (p *spooledLayerProvider) Remove() {
+	_ = os.RemoveAll(p.dir)
+}
+
+type spooledLayerContents struct {
+	layer         *spooledLayer
+	layerTD       imagedesc.ImageLayerDescriptor
+	layerProvider imagedesc.LayerProvider
+}
+
+func (c spooledLayerContents) Open() (io.ReadCloser, error) {
+	c.layer.lock.Lock()
+	defer c.layer.lock.Unlock()
+
+	// Failed attempts are not remembered so that other destinations retry fetching the layer
+	if !c.layer.spooled {
+		err := c.spool()
+		if err != nil {
+			return nil, err
+		}
+		c.layer.spooled = true
+	}
+
+	return os.Open(c.layer.path)
+}
+
+func (c spooledLayerContents) spool() error {
+	contents, err := c.layerProvider.FindLayer(c.layerTD)
+	if err != nil {
+		return err
+	}
+
+	rc, err := contents.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	file, err := os.Create(c.layer.path)
+	if err != nil {
+		return fmt.Errorf("Spooling layer %s: %s", c.layerTD.Digest, err)
+	}
+
+	_, err = io.Copy(file, rc)
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("Spooling layer %s: %s", c.layerTD.Digest, err)
+	}
+
+	// Spool is only marked complete once its contents are known to be flushed
+	err = file.Close()
+	if err != nil {
+		return fmt.Errorf("Spooling layer %s: %s", c.layerTD.Digest, err)
+	}
+
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"os"
 
 	regname "github.com/google/go-containerregistry/pkg/name"
+	ctlimg "github.com/k14s/imgpkg/pkg/imgpkg/image"
 	"github.com/k14s/imgpkg/pkg/imgpkg/imagedesc"
 	"github.com/k14s/imgpkg/pkg/imgpkg/imagetar"
 )
@@ -23,13 +24,22 @@ func NewTarImageSet(imageSet ImageSet, concurrency int, logger Logger) TarImageS
 	return TarImageSet{imageSet, concurrency, logger}
 }
 
-func (i TarImageSet) Export(foundImages *UnprocessedImageRefs, outputPath string, registry ImagesReaderWriter, imageLayerWriterCheck imagetar.ImageLayerWriterFilter) (*imagedesc.ImageRefDescriptors, error) {
+func (i TarImageSet) Export(foundImages *UnprocessedImageRefs, outputPath string, registry ctlimg.ImagesMetadata, imageLayerWriterCheck imagetar.ImageLayerWriterFilter) (*imagedesc.ImageRefDescriptors, error) {
 	ids, err := i.imageSet.Export(foundImages, registry)
 	if err != nil {
 		return nil, err
 	}
 
-	outputFile, err := os.Create(outputPath)
+	opts := imagetar.TarWriterOpts{Concurrency: i.concurrency}
+	createFlags := os.O_RDWR | os.O_CREATE | os.O_TRUNC
+
+	// Tarball is kept when resuming from a checkpoint since tar writer continues where it stopped
+	if i.imageSet.checkpoint != nil {
+		opts.Checkpoint = i.imageSet.checkpoint.TarCheckpoint(outputPath)
+		createFlags = os.O_RDWR | os.O_CREATE
+	}
+
+	outputFile, err := os.OpenFile(outputPath, createFlags, 0666)
 	if err != nil {
 		return nil, fmt.Errorf("Creating file '%s': %s", outputPath, err)
 	}
@@ -45,8 +55,6 @@ func (i TarImageSet) Export(foundImages *UnprocessedImageRefs, outputPath string
 
 	i.logger.WriteStr("writing layers...\n")
 
-	opts := imagetar.TarWriterOpts{Concurrency: i.concurrency}
-
 	return ids, imagetar.NewTarWriter(ids, outputFileOpener, opts, i.logger, imageLayerWriterCheck).Write()
 }
 
@@ -62,5 +70,10 @@ func (i *TarImageSet) Import(path string, importRepo regname.Repository, registr
 		return nil, err
 	}
 
-	return processedImages, err
+	err = i.imageSet.writeReferrersFallbackIndexes(processedImages, importRepo, registry)
+	if err != nil {
+		return nil, err
+	}
+
+	return processedImages, nil
 }
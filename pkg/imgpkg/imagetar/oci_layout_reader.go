@@ -0,0 +1,112 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package imagetar
+
+import (
+	"fmt"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/k14s/imgpkg/pkg/imgpkg/imagedesc"
+)
+
+// OCIRefNameAnnotation holds reference (including tag) of an image in an OCI image layout
+const OCIRefNameAnnotation = "org.opencontainers.image.ref.name"
+
+type ociLayoutImage struct {
+	regv1.Image
+	ref string
+	tag string
+}
+
+var _ imagedesc.ImageWithRef = ociLayoutImage{}
+
+func (i ociLayoutImage) Ref() string { return i.ref }
+func (i ociLayoutImage) Tag() string { return i.tag }
+
+// imageIndex is embedded under a different name since regv1.ImageIndex has an ImageIndex method
+type imageIndex = regv1.ImageIndex
+
+type ociLayoutImageIndex struct {
+	imageIndex
+	ref string
+	tag string
+}
+
+var _ imagedesc.ImageIndexWithRef = ociLayoutImageIndex{}
+
+func (i ociLayoutImageIndex) Ref() string { return i.ref }
+func (i ociLayoutImageIndex) Tag() string { return i.tag }
+
+// ociLayoutReader reads images of an OCI image layout directory.
+// Each manifest must be annotated with its reference; remaining annotations become labels.
+type ociLayoutReader struct {
+	path string
+}
+
+func (r ociLayoutReader) Read() ([]imagedesc.ImageOrIndex, error) {
+	layoutPath, err := layout.FromPath(r.path)
+	if err != nil {
+		return nil, fmt.Errorf("Reading OCI image layout: %s", err)
+	}
+
+	layoutIndex, err := layoutPath.ImageIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	indexManifest, err := layoutIndex.IndexManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []imagedesc.ImageOrIndex
+
+	for _, manDesc := range indexManifest.Manifests {
+		refName, found := manDesc.Annotations[OCIRefNameAnnotation]
+		if !found {
+			return nil, fmt.Errorf("Expected OCI image layout manifest '%s' to have annotation '%s'",
+				manDesc.Digest, OCIRefNameAnnotation)
+		}
+
+		tagRef, err := regname.NewTag(refName, regname.WeakValidation)
+		if err != nil {
+			return nil, fmt.Errorf("Parsing annotation '%s' of manifest '%s': %s", OCIRefNameAnnotation, manDesc.Digest, err)
+		}
+
+		ref := tagRef.Context().Digest(manDesc.Digest.String()).Name()
+
+		labels := map[string]string{}
+		for key, val := range manDesc.Annotations {
+			if key != OCIRefNameAnnotation {
+				labels[key] = val
+			}
+		}
+
+		switch {
+		case manDesc.MediaType.IsImage():
+			img, err := layoutIndex.Image(manDesc.Digest)
+			if err != nil {
+				return nil, err
+			}
+			var imgWithRef imagedesc.ImageWithRef = ociLayoutImage{img, ref, tagRef.TagStr()}
+			result = append(result, imagedesc.ImageOrIndex{Image: &imgWithRef, Labels: labels})
+
+		case manDesc.MediaType.IsIndex():
+			idx, err := layoutIndex.ImageIndex(manDesc.Digest)
+			if err != nil {
+				return nil, err
+			}
+			var idxWithRef imagedesc.ImageIndexWithRef = ociLayoutImageIndex{idx, ref, tagRef.TagStr()}
+			result = append(result, imagedesc.ImageOrIndex{Index: &idxWithRef, Labels: labels})
+
+		default:
+			return nil, fmt.Errorf("Expected manifest '%s' to be an image or an image index, but was '%s'",
+				manDesc.Digest, manDesc.MediaType)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,37 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package imagetar
+
+// TarCheckpoint records layers written to a tarball, so that an interrupted write
+// resumes after the last written layer instead of starting from zero
+type TarCheckpoint interface {
+	WrittenTarLayers() TarLayersCheckpoint
+	RecordTarLayers(TarLayersCheckpoint) error
+}
+
+// TarLayersCheckpoint describes layers written to a tarball with a particular manifest
+type TarLayersCheckpoint struct {
+	// ManifestDigest identifies tarball contents; layers are only reused when it matches
+	ManifestDigest string `json:"manifestDigest"`
+	// End is the offset right after the last tar entry (manifest or layer)
+	End    int64                `json:"end"`
+	Layers []TarLayerCheckpoint `json:"layers,omitempty"`
+}
+
+// TarLayerCheckpoint is a layer tar entry. Entries of layers written concurrently
+// are first filled with zeros, in which case Written is set once actual contents are written.
+type TarLayerCheckpoint struct {
+	Name    string `json:"name"`
+	Offset  int64  `json:"offset"`
+	Written bool   `json:"written"`
+}
+
+func (c TarLayersCheckpoint) layer(name string) (TarLayerCheckpoint, bool) {
+	for _, layer := range c.Layers {
+		if layer.Name == name {
+			return layer, true
+		}
+	}
+	return TarLayerCheckpoint{}, false
+}
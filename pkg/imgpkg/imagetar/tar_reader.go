@@ -5,6 +5,7 @@ package imagetar
 
 import (
 	"io/ioutil"
+	"os"
 
 	"github.com/k14s/imgpkg/pkg/imgpkg/imagedesc"
 )
@@ -17,7 +18,16 @@ func NewTarReader(path string) TarReader {
 	return TarReader{path}
 }
 
+// Read returns images of the tarball, or of the OCI image layout when path is a directory
 func (r TarReader) Read() ([]imagedesc.ImageOrIndex, error) {
+	fileInfo, err := os.Stat(r.path)
+	if err != nil {
+		return nil, err
+	}
+	if fileInfo.IsDir() {
+		return ociLayoutReader{r.path}.Read()
+	}
+
 	file := tarFile{r.path}
 
 	ids, err := r.getIdsFromManifest(file)
@@ -6,10 +6,12 @@ package imagetar
 import (
 	"archive/tar"
 	"bytes"
+	"crypto/sha256"
 	"fmt"
 	"io"
 	"os"
 	"sort"
+	"sync"
 	"time"
 
 	regv1 "github.com/google/go-containerregistry/pkg/v1"
@@ -23,6 +25,8 @@ type Logger interface {
 
 type TarWriterOpts struct {
 	Concurrency int
+	// Checkpoint is optional; when provided, writing resumes after layers recorded in it
+	Checkpoint TarCheckpoint
 }
 
 type TarWriter struct {
@@ -36,6 +40,9 @@ type TarWriter struct {
 	opts                  TarWriterOpts
 	logger                Logger
 	imageLayerWriterCheck ImageLayerWriterFilter
+
+	checkpoint     TarLayersCheckpoint
+	checkpointLock sync.Mutex
 }
 
 func NewTarWriter(ids *imagedesc.ImageRefDescriptors, dstOpener func() (io.WriteCloser, error), opts TarWriterOpts, logger Logger, imageLayerWriterCheck ImageLayerWriterFilter) *TarWriter {
@@ -52,19 +59,31 @@ func (w *TarWriter) Write() error {
 
 	defer w.dst.Close()
 
-	w.tf = tar.NewWriter(w.dst)
-	defer w.tf.Close()
-
 	idsBytes, err := w.ids.AsBytes()
 	if err != nil {
 		return err
 	}
 
-	err = w.writeTarEntry(w.tf, "manifest.json", bytes.NewReader(idsBytes), int64(len(idsBytes)))
+	err = w.resumeFromCheckpoint(idsBytes)
 	if err != nil {
 		return err
 	}
 
+	w.tf = tar.NewWriter(w.dst)
+	defer w.tf.Close()
+
+	if w.checkpoint.End == 0 {
+		err = w.writeTarEntry(w.tf, "manifest.json", bytes.NewReader(idsBytes), int64(len(idsBytes)))
+		if err != nil {
+			return err
+		}
+
+		err = w.recordEntry(nil)
+		if err != nil {
+			return err
+		}
+	}
+
 	for _, td := range w.ids.Descriptors() {
 		switch {
 		case td.Image != nil:
@@ -122,6 +141,8 @@ type writtenLayer struct {
 	Name   string
 	Offset int64
 	Layer  imagedesc.ImageLayerDescriptor
+	// Written is not set when tar entry was only filled with zeros
+	Written bool
 }
 
 func (w *TarWriter) writeLayers() error {
@@ -148,6 +169,17 @@ func (w *TarWriter) writeLayers() error {
 			continue
 		}
 
+		// Layers recorded in the checkpoint are already present in the tarball
+		if layerCheckpoint, found := w.checkpoint.layer(name); found {
+			writtenLayers[name] = writtenLayer{
+				Name:    name,
+				Layer:   imgLayer,
+				Offset:  layerCheckpoint.Offset,
+				Written: layerCheckpoint.Written,
+			}
+			continue
+		}
+
 		err = w.tf.Flush()
 		if err != nil {
 			return err
@@ -183,9 +215,15 @@ func (w *TarWriter) writeLayers() error {
 		}
 
 		writtenLayers[name] = writtenLayer{
-			Name:   name,
-			Layer:  imgLayer,
-			Offset: currPos,
+			Name:    name,
+			Layer:   imgLayer,
+			Offset:  currPos,
+			Written: !isInflatable,
+		}
+
+		err = w.recordEntry(&TarLayerCheckpoint{Name: name, Offset: currPos, Written: !isInflatable})
+		if err != nil {
+			return err
 		}
 	}
 
@@ -194,18 +232,17 @@ func (w *TarWriter) writeLayers() error {
 		return err
 	}
 
-	if isInflatable {
-		return w.fillInLayers(writtenLayers)
-	}
-
-	return nil
+	// Layers filled with zeros might also come from a previous (concurrent) write
+	return w.fillInLayers(writtenLayers)
 }
 
 func (w *TarWriter) fillInLayers(writtenLayers map[string]writtenLayer) error {
 	var sortedWrittenLayers []writtenLayer
 
 	for _, writtenLayer := range writtenLayers {
-		sortedWrittenLayers = append(sortedWrittenLayers, writtenLayer)
+		if !writtenLayer.Written {
+			sortedWrittenLayers = append(sortedWrittenLayers, writtenLayer)
+		}
 	}
 
 	// Prefer larger sizes first
@@ -213,7 +250,7 @@ func (w *TarWriter) fillInLayers(writtenLayers map[string]writtenLayer) error {
 		return sortedWrittenLayers[i].Layer.Size >= sortedWrittenLayers[j].Layer.Size
 	})
 
-	errCh := make(chan error, len(writtenLayers))
+	errCh := make(chan error, len(sortedWrittenLayers))
 	writeThrottle := util.NewThrottle(w.opts.Concurrency)
 
 	// Fill in actual data
@@ -230,7 +267,7 @@ func (w *TarWriter) fillInLayers(writtenLayers map[string]writtenLayer) error {
 		}()
 	}
 
-	for i := 0; i < len(writtenLayers); i++ {
+	for i := 0; i < len(sortedWrittenLayers); i++ {
 		err := <-errCh
 		if err != nil {
 			return fmt.Errorf("Filling in a layer: %s", err)
@@ -271,7 +308,104 @@ func (w *TarWriter) fillInLayer(wl writtenLayer) error {
 		return fmt.Errorf("Rewriting tar entry (%s): %s", wl.Name, err)
 	}
 
-	return tw.Flush()
+	err = tw.Flush()
+	if err != nil {
+		return err
+	}
+
+	return w.recordCheckpoint(func(checkpoint *TarLayersCheckpoint) {
+		for i, layer := range checkpoint.Layers {
+			if layer.Name == wl.Name {
+				checkpoint.Layers[i].Written = true
+			}
+		}
+	})
+}
+
+// resumeFromCheckpoint positions destination right after the last entry recorded in the checkpoint,
+// or at the beginning when nothing was recorded for a tarball with the same manifest
+func (w *TarWriter) resumeFromCheckpoint(idsBytes []byte) error {
+	if w.opts.Checkpoint == nil {
+		return nil
+	}
+
+	seekableDst, isSeekable := w.dst.(*os.File)
+	if !isSeekable {
+		// Offsets of tar entries are only known when writing to a file
+		w.opts.Checkpoint = nil
+		return nil
+	}
+
+	manifestDigest := fmt.Sprintf("sha256:%x", sha256.Sum256(idsBytes))
+	w.checkpoint = w.opts.Checkpoint.WrittenTarLayers()
+
+	dstInfo, err := seekableDst.Stat()
+	if err != nil {
+		return err
+	}
+
+	if w.checkpoint.ManifestDigest != manifestDigest || dstInfo.Size() < w.checkpoint.End {
+		w.checkpoint = TarLayersCheckpoint{ManifestDigest: manifestDigest}
+	} else if w.checkpoint.End > 0 {
+		w.logger.WriteStr("resuming from checkpoint (%d layers already written)\n", len(w.checkpoint.Layers))
+	}
+
+	// Drops partially written tar entry and tar footer
+	err = seekableDst.Truncate(w.checkpoint.End)
+	if err != nil {
+		return fmt.Errorf("Truncating tarball: %s", err)
+	}
+
+	_, err = seekableDst.Seek(w.checkpoint.End, io.SeekStart)
+	if err != nil {
+		return fmt.Errorf("Seeking to offset: %s", err)
+	}
+
+	return nil
+}
+
+// recordEntry records tar entry that was just written (manifest when layer is nil)
+func (w *TarWriter) recordEntry(layer *TarLayerCheckpoint) error {
+	if w.opts.Checkpoint == nil {
+		return nil
+	}
+
+	err := w.tf.Flush()
+	if err != nil {
+		return err
+	}
+
+	end, err := w.dst.(*os.File).Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("Find current pos: %s", err)
+	}
+
+	return w.recordCheckpoint(func(checkpoint *TarLayersCheckpoint) {
+		checkpoint.End = end
+		if layer != nil {
+			checkpoint.Layers = append(checkpoint.Layers, *layer)
+		}
+	})
+}
+
+func (w *TarWriter) recordCheckpoint(update func(*TarLayersCheckpoint)) error {
+	if w.opts.Checkpoint == nil {
+		return nil
+	}
+
+	w.checkpointLock.Lock()
+	defer w.checkpointLock.Unlock()
+
+	update(&w.checkpoint)
+
+	checkpoint := w.checkpoint
+	checkpoint.Layers = append([]TarLayerCheckpoint{}, w.checkpoint.Layers...)
+
+	err := w.opts.Checkpoint.RecordTarLayers(checkpoint)
+	if err != nil {
+		return fmt.Errorf("Recording checkpoint: %s", err)
+	}
+	return nil
 }
 
 func (w *TarWriter) writeTarEntry(tw *tar.Writer, path string, r io.Reader, size int64) error {
@@ -0,0 +1,37 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package zstd
+
+import (
+	"io"
+
+	"github.com/k14s/imgpkg/pkg/imgpkg/imageutils/and"
+	"github.com/klauspost/compress/zstd"
+)
+
+// DefaultLevel is the compression level used by the zstd command line tool
+const DefaultLevel = 3
+
+// NewWriterLevel returns an io.WriteCloser that compresses everything written to it into w.
+// Refer to https://github.com/facebook/zstd for the level (1 to 22)
+func NewWriterLevel(w io.Writer, level int) (io.WriteCloser, error) {
+	// Single goroutine keeps memory usage low since layers are compressed concurrently already
+	return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)), zstd.WithEncoderConcurrency(1))
+}
+
+// UnzipReadCloser reads compressed input data from the io.ReadCloser and
+// returns an io.ReadCloser from which uncompressed data may be read.
+func UnzipReadCloser(r io.ReadCloser) (io.ReadCloser, error) {
+	decoder, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return &and.ReadCloser{
+		Reader: decoder,
+		CloseFunc: func() error {
+			decoder.Close()
+			return r.Close()
+		},
+	}, nil
+}
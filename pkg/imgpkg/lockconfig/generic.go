@@ -12,6 +12,8 @@ type LockVersion struct {
 	Kind       string `json:"kind"`       // This generated yaml, but due to lib we need to use `json`
 }
 
+// NewLockFromPath reads a bundle or images lock file.
+// kbld lock files are converted to an images lock.
 func NewLockFromPath(path string) (*BundleLock, *ImagesLock, error) {
 	bundleLock, err := NewBundleLockFromPath(path)
 	if err == nil {
@@ -21,5 +23,13 @@ func NewLockFromPath(path string) (*BundleLock, *ImagesLock, error) {
 	if err == nil {
 		return nil, &imagesLock, nil
 	}
-	return nil, nil, fmt.Errorf("Trying to read bundle or images lock file: %s", err)
+	kbldLock, kbldErr := NewKbldLockFromPath(path)
+	if kbldErr == nil {
+		imagesLock = kbldLock.AsImagesLock()
+		return nil, &imagesLock, nil
+	}
+	if kbldLock.APIVersion == KbldLockAPIVersion {
+		err = kbldErr
+	}
+	return nil, nil, fmt.Errorf("Trying to read bundle, images or kbld lock file: %s", err)
 }
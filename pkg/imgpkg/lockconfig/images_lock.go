@@ -14,6 +14,8 @@ import (
 const (
 	ImagesLockKind       = "ImagesLock"
 	ImagesLockAPIVersion = "imgpkg.carvel.dev/v1alpha1"
+	// SourceRefAnnotation records the reference (typically a tag) an image was resolved from
+	SourceRefAnnotation = "imgpkg.carvel.dev/source-ref"
 )
 
 type ImagesLock struct {
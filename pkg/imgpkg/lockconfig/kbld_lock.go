@@ -0,0 +1,153 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package lockconfig
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	KbldLockKind       = "Config"
+	KbldLockAPIVersion = "kbld.k14s.io/v1alpha1"
+
+	// KbldIDAnnotation is used by kbld to record the original image reference
+	KbldIDAnnotation = "kbld.carvel.dev/id"
+)
+
+// KbldLock is a kbld configuration whose overrides pin images to digests
+// (as produced by 'kbld --lock-output'). Other kbld configuration sections are ignored.
+type KbldLock struct {
+	LockVersion
+	MinimumRequiredVersion string         `json:"minimumRequiredVersion,omitempty"` // This generated yaml, but due to lib we need to use `json`
+	Overrides              []KbldOverride `json:"overrides,omitempty"`              // This generated yaml, but due to lib we need to use `json`
+}
+
+type KbldOverride struct {
+	Image       string `json:"image"`                 // This generated yaml, but due to lib we need to use `json`
+	NewImage    string `json:"newImage"`              // This generated yaml, but due to lib we need to use `json`
+	Preresolved bool   `json:"preresolved,omitempty"` // This generated yaml, but due to lib we need to use `json`
+}
+
+// NewKbldLockFromImagesLock converts images lock entries to kbld overrides.
+// Original references are taken from kbld or imgpkg annotations;
+// images without them override their own digest reference.
+func NewKbldLockFromImagesLock(imagesLock ImagesLock) KbldLock {
+	kbldLock := KbldLock{
+		LockVersion: LockVersion{
+			APIVersion: KbldLockAPIVersion,
+			Kind:       KbldLockKind,
+		},
+	}
+
+	for _, imageRef := range imagesLock.Images {
+		image := imageRef.PrimaryLocation()
+
+		originalImage := imageRef.Annotations[KbldIDAnnotation]
+		if originalImage == "" {
+			originalImage = imageRef.Annotations[SourceRefAnnotation]
+		}
+		if originalImage == "" {
+			originalImage = image
+		}
+
+		kbldLock.Overrides = append(kbldLock.Overrides, KbldOverride{Image: originalImage, NewImage: image, Preresolved: true})
+	}
+
+	return kbldLock
+}
+
+func NewKbldLockFromPath(path string) (KbldLock, error) {
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		return KbldLock{}, fmt.Errorf("Reading path %s: %s", path, err)
+	}
+
+	return NewKbldLockFromBytes(bs)
+}
+
+func NewKbldLockFromBytes(data []byte) (KbldLock, error) {
+	var lock KbldLock
+
+	// Not strict since kbld configuration may contain other sections (e.g. searchRules)
+	err := yaml.Unmarshal(data, &lock)
+	if err != nil {
+		return lock, fmt.Errorf("Unmarshaling kbld lock: %s", err)
+	}
+
+	err = lock.Validate()
+	if err != nil {
+		return lock, fmt.Errorf("Validating kbld lock: %s", err)
+	}
+
+	return lock, nil
+}
+
+func (k KbldLock) Validate() error {
+	if k.APIVersion != KbldLockAPIVersion {
+		return fmt.Errorf("Validating apiVersion: Unknown version (known: %s)", KbldLockAPIVersion)
+	}
+	if k.Kind != KbldLockKind {
+		return fmt.Errorf("Validating kind: Unknown kind (known: %s)", KbldLockKind)
+	}
+	for _, override := range k.Overrides {
+		if _, err := regname.NewDigest(override.NewImage); err != nil {
+			return fmt.Errorf("Expected override newImage to be in digest form, got '%s'", override.NewImage)
+		}
+	}
+	return nil
+}
+
+// AsImagesLock converts overrides to images lock entries
+// keeping original references in kbld annotations
+func (k KbldLock) AsImagesLock() ImagesLock {
+	imagesLock := NewEmptyImagesLock()
+
+	for _, override := range k.Overrides {
+		digestRef, err := regname.NewDigest(override.NewImage)
+		if err != nil {
+			panic(fmt.Sprintf("Image reference (%s) is in an invalid format: %s", override.NewImage, err.Error()))
+		}
+
+		imageRef := ImageRef{Image: digestRef.Name()}
+		if override.Image != "" && override.Image != override.NewImage {
+			imageRef.Annotations = map[string]string{KbldIDAnnotation: override.Image}
+		}
+
+		imagesLock.AddImageRef(imageRef)
+	}
+
+	return imagesLock
+}
+
+func (k KbldLock) AsBytes() ([]byte, error) {
+	err := k.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("Validating kbld lock: %s", err)
+	}
+
+	bs, err := yaml.Marshal(k)
+	if err != nil {
+		return nil, fmt.Errorf("Marshaling config: %s", err)
+	}
+
+	return []byte(fmt.Sprintf("---\n%s", bs)), nil
+}
+
+func (k KbldLock) WriteToPath(path string) error {
+	bs, err := k.AsBytes()
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(path, bs, 0600)
+	if err != nil {
+		return fmt.Errorf("Writing kbld config: %s", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package lockconfig_test
+
+import (
+	"testing"
+
+	"github.com/k14s/imgpkg/pkg/imgpkg/lockconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKbldLock(t *testing.T) {
+	t.Run("When newImage is not resolved, it errors", func(t *testing.T) {
+		data := `
+apiVersion: kbld.k14s.io/v1alpha1
+kind: Config
+overrides:
+- image: nginx
+  newImage: nginx:v1
+`
+
+		_, err := lockconfig.NewKbldLockFromBytes([]byte(data))
+		require.EqualError(t, err, "Validating kbld lock: Expected override newImage to be in digest form, got 'nginx:v1'")
+	})
+
+	t.Run("Converts overrides to images lock entries and back", func(t *testing.T) {
+		data := `
+apiVersion: kbld.k14s.io/v1alpha1
+kind: Config
+overrides:
+- image: nginx:1.21
+  newImage: nginx@sha256:f5e4e03ee1b6f7e5fbc1b4ab6f0e5cfeffe8a0d2fa0df1c4ba5b69d0a1e4fc3d
+  preresolved: true
+- image: my.registry.io/app@sha256:a7e8c1d3a2cbbc4f9d5b1cbdd0fdd1e0c7c1a4df0a1a4f7f7c8b1e0b0c8d1a2b
+  newImage: my.registry.io/app@sha256:a7e8c1d3a2cbbc4f9d5b1cbdd0fdd1e0c7c1a4df0a1a4f7f7c8b1e0b0c8d1a2b
+`
+
+		kbldLock, err := lockconfig.NewKbldLockFromBytes([]byte(data))
+		require.NoError(t, err)
+
+		imagesLock := kbldLock.AsImagesLock()
+		assert.Equal(t, []lockconfig.ImageRef{
+			{
+				Image:       "index.docker.io/library/nginx@sha256:f5e4e03ee1b6f7e5fbc1b4ab6f0e5cfeffe8a0d2fa0df1c4ba5b69d0a1e4fc3d",
+				Annotations: map[string]string{lockconfig.KbldIDAnnotation: "nginx:1.21"},
+			},
+			{Image: "my.registry.io/app@sha256:a7e8c1d3a2cbbc4f9d5b1cbdd0fdd1e0c7c1a4df0a1a4f7f7c8b1e0b0c8d1a2b"},
+		}, imagesLock.Images)
+
+		assert.Equal(t, []lockconfig.KbldOverride{
+			{
+				Image:       "nginx:1.21",
+				NewImage:    "index.docker.io/library/nginx@sha256:f5e4e03ee1b6f7e5fbc1b4ab6f0e5cfeffe8a0d2fa0df1c4ba5b69d0a1e4fc3d",
+				Preresolved: true,
+			},
+			{
+				Image:       "my.registry.io/app@sha256:a7e8c1d3a2cbbc4f9d5b1cbdd0fdd1e0c7c1a4df0a1a4f7f7c8b1e0b0c8d1a2b",
+				NewImage:    "my.registry.io/app@sha256:a7e8c1d3a2cbbc4f9d5b1cbdd0fdd1e0c7c1a4df0a1a4f7f7c8b1e0b0c8d1a2b",
+				Preresolved: true,
+			},
+		}, lockconfig.NewKbldLockFromImagesLock(imagesLock).Overrides)
+	})
+}
@@ -0,0 +1,76 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package lockgen
+
+import (
+	"fmt"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/k14s/imgpkg/pkg/imgpkg/bundle"
+	ctlimg "github.com/k14s/imgpkg/pkg/imgpkg/image"
+	"github.com/k14s/imgpkg/pkg/imgpkg/lockconfig"
+)
+
+const (
+	// BundleAnnotation marks images that are bundles
+	BundleAnnotation = "imgpkg.carvel.dev/bundle"
+)
+
+// Generator resolves image references to digests to produce an images lock
+type Generator struct {
+	imagesMetadata ctlimg.ImagesMetadata
+}
+
+func NewGenerator(imagesMetadata ctlimg.ImagesMetadata) Generator {
+	return Generator{imagesMetadata}
+}
+
+func (g Generator) Generate(refs []FoundImageRef) (lockconfig.ImagesLock, error) {
+	imagesLock := lockconfig.NewEmptyImagesLock()
+	resolvedRefs := map[string]struct{}{}
+
+	for _, foundRef := range refs {
+		if _, found := resolvedRefs[foundRef.Ref]; found {
+			continue
+		}
+		resolvedRefs[foundRef.Ref] = struct{}{}
+
+		imageRef, err := g.Resolve(foundRef.Ref)
+		if err != nil {
+			return lockconfig.ImagesLock{}, fmt.Errorf("Resolving image '%s' found in '%s': %s", foundRef.Ref, foundRef.Path, err)
+		}
+
+		imagesLock.AddImageRef(imageRef)
+	}
+
+	return imagesLock, nil
+}
+
+// Resolve looks up the current digest of a reference and annotates
+// the resulting lock entry with the original reference and whether it is a bundle
+func (g Generator) Resolve(ref string) (lockconfig.ImageRef, error) {
+	parsedRef, err := regname.ParseReference(ref, regname.WeakValidation)
+	if err != nil {
+		return lockconfig.ImageRef{}, err
+	}
+
+	digest, err := g.imagesMetadata.Digest(parsedRef)
+	if err != nil {
+		return lockconfig.ImageRef{}, err
+	}
+
+	digestRef := parsedRef.Context().Digest(digest.String()).Name()
+
+	isBundle, err := bundle.NewBundle(digestRef, g.imagesMetadata).IsBundle()
+	if err != nil {
+		return lockconfig.ImageRef{}, fmt.Errorf("Checking if image is a bundle: %s", err)
+	}
+
+	annotations := map[string]string{lockconfig.SourceRefAnnotation: ref}
+	if isBundle {
+		annotations[BundleAnnotation] = "true"
+	}
+
+	return lockconfig.ImageRef{Image: digestRef, Annotations: annotations}, nil
+}
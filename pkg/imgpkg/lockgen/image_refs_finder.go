@@ -0,0 +1,192 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package lockgen
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultKeyPaths matches any key called image, which covers
+// Kubernetes containers as well as most Helm values and CRDs
+var DefaultKeyPaths = []string{"image"}
+
+// KeyPath identifies values that contain image references (format: spec.containers[].image).
+// It is matched against the end of each value's path, so it does not need to start at the document root.
+type KeyPath struct {
+	segments []string
+}
+
+func NewKeyPath(path string) (KeyPath, error) {
+	segments := strings.Split(path, ".")
+	for _, segment := range segments {
+		if strings.TrimSuffix(segment, "[]") == "" {
+			return KeyPath{}, fmt.Errorf("Expected key path '%s' to contain only non-empty keys (format: spec.containers[].image)", path)
+		}
+	}
+	return KeyPath{segments}, nil
+}
+
+func (p KeyPath) matches(valuePath []string) bool {
+	if len(valuePath) < len(p.segments) {
+		return false
+	}
+	valuePath = valuePath[len(valuePath)-len(p.segments):]
+	for i, segment := range p.segments {
+		if segment != valuePath[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// FoundImageRef is an image reference found in a file
+type FoundImageRef struct {
+	Ref  string
+	Path string
+}
+
+type ImageRefsFinder struct {
+	keyPaths []KeyPath
+}
+
+func NewImageRefsFinder(keyPaths []string) (ImageRefsFinder, error) {
+	if len(keyPaths) == 0 {
+		keyPaths = DefaultKeyPaths
+	}
+
+	var parsedKeyPaths []KeyPath
+	for _, keyPath := range keyPaths {
+		parsedKeyPath, err := NewKeyPath(keyPath)
+		if err != nil {
+			return ImageRefsFinder{}, err
+		}
+		parsedKeyPaths = append(parsedKeyPaths, parsedKeyPath)
+	}
+
+	return ImageRefsFinder{parsedKeyPaths}, nil
+}
+
+// FindInPaths returns image references found in YAML and JSON files in order of appearance.
+// Directories are searched recursively skipping .imgpkg directories, since they contain
+// already resolved references. Explicitly provided files are searched regardless of their extension.
+func (f ImageRefsFinder) FindInPaths(paths []string) ([]FoundImageRef, error) {
+	var result []FoundImageRef
+
+	for _, path := range paths {
+		err := filepath.Walk(path, func(currPath string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if info.IsDir() {
+				if filepath.Base(currPath) == ".imgpkg" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if currPath != path && !f.isConfigFile(currPath) {
+				return nil
+			}
+
+			refs, err := f.findInFile(currPath)
+			if err != nil {
+				return err
+			}
+
+			result = append(result, refs...)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+func (f ImageRefsFinder) isConfigFile(path string) bool {
+	switch filepath.Ext(path) {
+	case ".yml", ".yaml", ".json":
+		return true
+	default:
+		return false
+	}
+}
+
+func (f ImageRefsFinder) findInFile(path string) ([]FoundImageRef, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Reading file '%s': %s", path, err)
+	}
+
+	var result []FoundImageRef
+
+	// JSON is a subset of YAML, so both can be decoded the same way
+	decoder := yaml.NewDecoder(bytes.NewReader(contents))
+
+	for {
+		var doc yaml.Node
+
+		err := decoder.Decode(&doc)
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("Parsing file '%s': %s", path, err)
+		}
+
+		f.findInNode(&doc, nil, func(ref string) {
+			result = append(result, FoundImageRef{Ref: ref, Path: path})
+		})
+	}
+
+	return result, nil
+}
+
+func (f ImageRefsFinder) findInNode(node *yaml.Node, nodePath []string, foundFunc func(string)) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			f.findInNode(child, nodePath, foundFunc)
+		}
+
+	case yaml.AliasNode:
+		f.findInNode(node.Alias, nodePath, foundFunc)
+
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			childPath := append(append([]string{}, nodePath...), node.Content[i].Value)
+			f.findInNode(node.Content[i+1], childPath, foundFunc)
+		}
+
+	case yaml.SequenceNode:
+		var itemsPath []string
+		if len(nodePath) > 0 {
+			itemsPath = append(append([]string{}, nodePath[:len(nodePath)-1]...), nodePath[len(nodePath)-1]+"[]")
+		}
+		for _, child := range node.Content {
+			f.findInNode(child, itemsPath, foundFunc)
+		}
+
+	case yaml.ScalarNode:
+		if node.Tag != "!!str" || node.Value == "" {
+			return
+		}
+		for _, keyPath := range f.keyPaths {
+			if keyPath.matches(nodePath) {
+				foundFunc(node.Value)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package lockgen_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/k14s/imgpkg/pkg/imgpkg/lockgen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageRefsFinder(t *testing.T) {
+	configDir, err := os.MkdirTemp("", "imgpkg-lockgen")
+	require.NoError(t, err)
+	defer os.RemoveAll(configDir)
+
+	deploymentPath := filepath.Join(configDir, "deployment.yml")
+	require.NoError(t, ioutil.WriteFile(deploymentPath, []byte(`
+apiVersion: apps/v1
+kind: Deployment
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+        image: busybox:1.33
+      containers:
+      - name: app
+        image: my.registry.io/app:v1
+        env:
+        - name: image
+          value: not-an-image-key
+---
+kind: ConfigMap
+data:
+  image: nginx:1.21
+`), 0600))
+
+	valuesPath := filepath.Join(configDir, "values.json")
+	require.NoError(t, ioutil.WriteFile(valuesPath, []byte(`{"sidecar": {"image": "envoy:v1"}, "replicas": 1}`), 0600))
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(configDir, "README.md"), []byte("image: ignored:v1"), 0600))
+
+	require.NoError(t, os.MkdirAll(filepath.Join(configDir, ".imgpkg"), 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(configDir, ".imgpkg", "images.yml"), []byte("images:\n- image: ignored@sha256:abc\n"), 0600))
+
+	t.Run("finds values of any image key by default", func(t *testing.T) {
+		finder, err := lockgen.NewImageRefsFinder(nil)
+		require.NoError(t, err)
+
+		refs, err := finder.FindInPaths([]string{configDir})
+		require.NoError(t, err)
+
+		assert.Equal(t, []lockgen.FoundImageRef{
+			{Ref: "busybox:1.33", Path: deploymentPath},
+			{Ref: "my.registry.io/app:v1", Path: deploymentPath},
+			{Ref: "nginx:1.21", Path: deploymentPath},
+			{Ref: "envoy:v1", Path: valuesPath},
+		}, refs)
+	})
+
+	t.Run("only finds values matching provided key paths", func(t *testing.T) {
+		finder, err := lockgen.NewImageRefsFinder([]string{"spec.containers[].image", "sidecar.image"})
+		require.NoError(t, err)
+
+		refs, err := finder.FindInPaths([]string{configDir})
+		require.NoError(t, err)
+
+		assert.Equal(t, []lockgen.FoundImageRef{
+			{Ref: "my.registry.io/app:v1", Path: deploymentPath},
+			{Ref: "envoy:v1", Path: valuesPath},
+		}, refs)
+	})
+
+	t.Run("errors on invalid key paths", func(t *testing.T) {
+		_, err := lockgen.NewImageRefsFinder([]string{"spec..image"})
+		require.EqualError(t, err, "Expected key path 'spec..image' to contain only non-empty keys (format: spec.containers[].image)")
+	})
+}
@@ -0,0 +1,76 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package lockgen
+
+import (
+	"fmt"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/k14s/imgpkg/pkg/imgpkg/lockconfig"
+)
+
+// ImageUpdate describes an images lock entry whose source reference resolves to a different image
+type ImageUpdate struct {
+	SourceRef     string
+	PreviousImage string
+	Image         string
+}
+
+// Update re-resolves every entry that has a source reference annotation.
+// Order of entries and their other annotations are preserved;
+// entries without a source reference are left untouched.
+func (g Generator) Update(imagesLock lockconfig.ImagesLock) (lockconfig.ImagesLock, []ImageUpdate, error) {
+	var updates []ImageUpdate
+
+	updatedLock := imagesLock
+	updatedLock.Images = nil
+
+	for _, imageRef := range imagesLock.Images {
+		imageRef = imageRef.DeepCopy()
+
+		sourceRef, found := imageRef.Annotations[lockconfig.SourceRefAnnotation]
+		if !found {
+			updatedLock.Images = append(updatedLock.Images, imageRef)
+			continue
+		}
+
+		resolvedRef, err := g.Resolve(sourceRef)
+		if err != nil {
+			return lockconfig.ImagesLock{}, nil, fmt.Errorf("Resolving image '%s' of '%s': %s", sourceRef, imageRef.Image, err)
+		}
+
+		changed, err := g.isDifferentImage(imageRef.Image, resolvedRef.Image)
+		if err != nil {
+			return lockconfig.ImagesLock{}, nil, err
+		}
+
+		if changed {
+			updates = append(updates, ImageUpdate{SourceRef: sourceRef, PreviousImage: imageRef.Image, Image: resolvedRef.Image})
+
+			imageRef.Image = resolvedRef.Image
+			delete(imageRef.Annotations, BundleAnnotation)
+			for key, val := range resolvedRef.Annotations {
+				imageRef.Annotations[key] = val
+			}
+		}
+
+		updatedLock.Images = append(updatedLock.Images, imageRef)
+	}
+
+	return updatedLock, updates, nil
+}
+
+func (g Generator) isDifferentImage(image, resolvedImage string) (bool, error) {
+	digestRef, err := regname.NewDigest(image, regname.WeakValidation)
+	if err != nil {
+		return false, fmt.Errorf("Parsing '%s': %s", image, err)
+	}
+
+	resolvedDigestRef, err := regname.NewDigest(resolvedImage, regname.WeakValidation)
+	if err != nil {
+		return false, fmt.Errorf("Parsing '%s': %s", resolvedImage, err)
+	}
+
+	return digestRef.Name() != resolvedDigestRef.Name(), nil
+}
@@ -18,6 +18,7 @@ import (
 type Contents struct {
 	paths         []string
 	excludedPaths []string
+	layerOpts     ctlimg.LayerOpts
 }
 
 type ImagesWriter interface {
@@ -25,25 +26,26 @@ type ImagesWriter interface {
 }
 
 func NewContents(paths []string, excludedPaths []string) Contents {
-	return Contents{paths: paths, excludedPaths: excludedPaths}
+	return NewContentsWithLayers(paths, excludedPaths, ctlimg.LayerOpts{})
+}
+
+func NewContentsWithLayers(paths []string, excludedPaths []string, layerOpts ctlimg.LayerOpts) Contents {
+	return Contents{paths: paths, excludedPaths: excludedPaths, layerOpts: layerOpts}
 }
 
 func (i Contents) Push(uploadRef regname.Tag, labels map[string]string, writer ImagesWriter, ui ui.UI) (string, error) {
-	err := i.validate()
+	img, err := i.Build(labels, ui)
 	if err != nil {
 		return "", err
 	}
 
-	tarImg := ctlimg.NewTarImage(i.paths, i.excludedPaths, InfoLog{ui})
-
-	img, err := tarImg.AsFileImage(labels)
-	if err != nil {
-		return "", err
-	}
+	return pushFileImage(uploadRef, img, writer)
+}
 
+func pushFileImage(uploadRef regname.Tag, img *ctlimg.FileImage, writer ImagesWriter) (string, error) {
 	defer img.Remove()
 
-	err = writer.WriteImage(uploadRef, img)
+	err := writer.WriteImage(uploadRef, img)
 	if err != nil {
 		return "", fmt.Errorf("Writing '%s': %s", uploadRef.Name(), err)
 	}
@@ -56,6 +58,19 @@ func (i Contents) Push(uploadRef regname.Tag, labels map[string]string, writer I
 	return fmt.Sprintf("%s@%s", uploadRef.Context(), digest), nil
 }
 
+// Build creates the image that Push would upload.
+// Caller is responsible for removing the image once it is no longer needed.
+func (i Contents) Build(labels map[string]string, ui ui.UI) (*ctlimg.FileImage, error) {
+	err := i.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	tarImg := ctlimg.NewTarImage(i.paths, i.excludedPaths, i.layerOpts, InfoLog{ui})
+
+	return tarImg.AsFileImage(labels)
+}
+
 func (i Contents) validate() error {
 	return i.checkRepeatedPaths()
 }
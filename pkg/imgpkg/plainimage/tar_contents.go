@@ -0,0 +1,60 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package plainimage
+
+import (
+	"io"
+
+	"github.com/cppforlife/go-cli-ui/ui"
+	regname "github.com/google/go-containerregistry/pkg/name"
+	ctlimg "github.com/k14s/imgpkg/pkg/imgpkg/image"
+)
+
+// TarContents uses an existing tar stream as the single layer of the image
+type TarContents struct {
+	stream        io.Reader
+	layerOpts     ctlimg.LayerOpts
+	validatePaths func(paths []string) error
+}
+
+func NewTarContents(stream io.Reader, layerOpts ctlimg.LayerOpts) TarContents {
+	return NewTarContentsWithValidation(stream, layerOpts, nil)
+}
+
+// NewTarContentsWithValidation checks paths of all tar entries
+// (see ctlimg.TarStreamImage.EntryPaths) before the image is used
+func NewTarContentsWithValidation(stream io.Reader, layerOpts ctlimg.LayerOpts, validatePaths func(paths []string) error) TarContents {
+	return TarContents{stream: stream, layerOpts: layerOpts, validatePaths: validatePaths}
+}
+
+func (i TarContents) Push(uploadRef regname.Tag, labels map[string]string, writer ImagesWriter, ui ui.UI) (string, error) {
+	img, err := i.Build(labels, ui)
+	if err != nil {
+		return "", err
+	}
+
+	return pushFileImage(uploadRef, img, writer)
+}
+
+// Build creates the image that Push would upload.
+// Stream is consumed, hence Build can only be called once.
+// Caller is responsible for removing the image once it is no longer needed.
+func (i TarContents) Build(labels map[string]string, ui ui.UI) (*ctlimg.FileImage, error) {
+	tarImg := ctlimg.NewTarStreamImage(i.stream, i.layerOpts, InfoLog{ui})
+
+	img, err := tarImg.AsFileImage(labels)
+	if err != nil {
+		return nil, err
+	}
+
+	if i.validatePaths != nil {
+		err = i.validatePaths(tarImg.EntryPaths())
+		if err != nil {
+			img.Remove()
+			return nil, err
+		}
+	}
+
+	return img, nil
+}
@@ -0,0 +1,351 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	ctlimg "github.com/k14s/imgpkg/pkg/imgpkg/image"
+	"github.com/k14s/imgpkg/pkg/imgpkg/imageset"
+	"github.com/k14s/imgpkg/pkg/imgpkg/lockconfig"
+	"github.com/k14s/imgpkg/pkg/imgpkg/signature"
+	"github.com/k14s/imgpkg/pkg/imgpkg/util"
+	"golang.org/x/sync/errgroup"
+)
+
+// Rules reported by violations
+const (
+	AllowedRegistriesRule       = "allowedRegistries"
+	RejectMutableTagsRule       = "rejectMutableTags"
+	RequireBundleSignaturesRule = "requireBundleSignatures"
+	MaxTotalSizeRule            = "maxTotalSizeBytes"
+	NonDistributableLayersRule  = "forbidNonDistributableLayers"
+)
+
+type SignatureFinder interface {
+	Artifact(reference regname.Digest, artifact signature.Artifact) (imageset.UnprocessedImageRef, error)
+}
+
+// Image is an image evaluated against the policy
+type Image struct {
+	DigestRef string
+	// Annotations recorded by the images lock of the bundle referencing the image
+	Annotations map[string]string
+	IsBundle    bool
+}
+
+type Violation struct {
+	Rule    string `json:"rule"`
+	Image   string `json:"image,omitempty"`
+	Message string `json:"message"`
+}
+
+// Violations lists every policy violation found; it is used as an error when the policy is enforced
+type Violations []Violation
+
+func (v Violations) Error() string {
+	var lines []string
+	for _, violation := range v {
+		lines = append(lines, "- "+violation.String())
+	}
+	return fmt.Sprintf("Expected images to satisfy policy, but found %d violations:\n%s", len(v), strings.Join(lines, "\n"))
+}
+
+func (v Violation) String() string {
+	if v.Image == "" {
+		return fmt.Sprintf("[%s] %s", v.Rule, v.Message)
+	}
+	return fmt.Sprintf("[%s] %s: %s", v.Rule, v.Image, v.Message)
+}
+
+type Evaluator struct {
+	policy          Policy
+	registry        ctlimg.ImagesMetadata
+	signatureFinder SignatureFinder
+	concurrency     int
+}
+
+func NewEvaluator(policy Policy, registry ctlimg.ImagesMetadata, signatureFinder SignatureFinder, concurrency int) *Evaluator {
+	return &Evaluator{policy: policy, registry: registry, signatureFinder: signatureFinder, concurrency: concurrency}
+}
+
+// Evaluate returns violations of the policy, sorted by rule and image.
+// Registries are only reached for rules that need manifests or signatures.
+func (e Evaluator) Evaluate(images []Image) (Violations, error) {
+	var violations Violations
+
+	for _, img := range images {
+		imgRef, err := regname.NewDigest(img.DigestRef)
+		if err != nil {
+			return nil, fmt.Errorf("Evaluating policy for '%s': %s", img.DigestRef, err)
+		}
+
+		violations = append(violations, e.registryViolations(imgRef)...)
+		violations = append(violations, e.mutableTagViolations(imgRef, img.Annotations)...)
+	}
+
+	signatureViolations, err := e.signatureViolations(images)
+	if err != nil {
+		return nil, err
+	}
+	violations = append(violations, signatureViolations...)
+
+	layersViolations, err := e.layersViolations(images)
+	if err != nil {
+		return nil, err
+	}
+	violations = append(violations, layersViolations...)
+
+	sort.SliceStable(violations, func(i, j int) bool {
+		if violations[i].Rule != violations[j].Rule {
+			return violations[i].Rule < violations[j].Rule
+		}
+		return violations[i].Image < violations[j].Image
+	})
+
+	return violations, nil
+}
+
+func (e Evaluator) registryViolations(imgRef regname.Digest) []Violation {
+	if len(e.policy.AllowedRegistries) == 0 {
+		return nil
+	}
+
+	for _, allowed := range e.policy.AllowedRegistries {
+		allowedRegistry, err := regname.NewRegistry(allowed, regname.WeakValidation)
+		if err == nil && allowedRegistry.RegistryStr() == imgRef.Context().RegistryStr() {
+			return nil
+		}
+	}
+
+	return []Violation{{
+		Rule:    AllowedRegistriesRule,
+		Image:   imgRef.Name(),
+		Message: fmt.Sprintf("registry '%s' is not allowed", imgRef.Context().RegistryStr()),
+	}}
+}
+
+func (e Evaluator) mutableTagViolations(imgRef regname.Digest, annotations map[string]string) []Violation {
+	if !e.policy.RejectMutableTags {
+		return nil
+	}
+
+	var violations []Violation
+
+	for _, annotation := range []string{lockconfig.KbldIDAnnotation, lockconfig.SourceRefAnnotation} {
+		sourceRef, found := annotations[annotation]
+		if !found {
+			continue
+		}
+
+		// References that are not image references (or are digests) are not mutable
+		tagRef, err := regname.NewTag(sourceRef, regname.WeakValidation)
+		if err != nil || !e.isMutableTag(tagRef.TagStr()) {
+			continue
+		}
+
+		violations = append(violations, Violation{
+			Rule:    RejectMutableTagsRule,
+			Image:   imgRef.Name(),
+			Message: fmt.Sprintf("referenced by mutable tag '%s' (annotation '%s')", sourceRef, annotation),
+		})
+	}
+
+	return violations
+}
+
+func (e Evaluator) isMutableTag(tag string) bool {
+	if len(e.policy.MutableTags) == 0 {
+		return true
+	}
+	for _, mutableTag := range e.policy.MutableTags {
+		if mutableTag == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func (e Evaluator) signatureViolations(images []Image) ([]Violation, error) {
+	if !e.policy.RequireBundleSignatures {
+		return nil, nil
+	}
+
+	var violations []Violation
+	violationsLock := &sync.Mutex{}
+
+	throttle := util.NewThrottle(e.concurrency)
+	var wg errgroup.Group
+
+	for _, img := range images {
+		if !img.IsBundle {
+			continue
+		}
+
+		img := img //copy
+		wg.Go(func() error {
+			imgRef, err := regname.NewDigest(img.DigestRef)
+			if err != nil {
+				return err
+			}
+
+			throttle.Take()
+			defer throttle.Done()
+
+			_, err = e.signatureFinder.Artifact(imgRef, signature.SignatureArtifact)
+			if err != nil {
+				if _, ok := err.(signature.NotFound); !ok {
+					return fmt.Errorf("Fetching signature of '%s': %s", img.DigestRef, err)
+				}
+
+				violationsLock.Lock()
+				violations = append(violations, Violation{Rule: RequireBundleSignaturesRule, Image: imgRef.Name(), Message: "bundle is not signed"})
+				violationsLock.Unlock()
+			}
+			return nil
+		})
+	}
+
+	err := wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	return violations, nil
+}
+
+// layersViolations checks total size of images and their layers media types
+func (e Evaluator) layersViolations(images []Image) ([]Violation, error) {
+	if e.policy.MaxTotalSizeBytes == 0 && !e.policy.ForbidNonDistributableLayers {
+		return nil, nil
+	}
+
+	blobSizes := map[regv1.Hash]int64{}
+	nonDistributable := map[string][]string{}
+	evaluated := map[string]struct{}{}
+	lock := &sync.Mutex{}
+
+	throttle := util.NewThrottle(e.concurrency)
+	var wg errgroup.Group
+
+	for _, img := range images {
+		img := img //copy
+		wg.Go(func() error {
+			imgRef, err := regname.NewDigest(img.DigestRef)
+			if err != nil {
+				return err
+			}
+
+			throttle.Take()
+			defer throttle.Done()
+
+			manifests, err := e.manifests(imgRef)
+			if err != nil {
+				return fmt.Errorf("Evaluating policy for '%s': %s", img.DigestRef, err)
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+
+			// Same image may be evaluated multiple times when it has multiple tags
+			if _, found := evaluated[imgRef.Name()]; found {
+				return nil
+			}
+			evaluated[imgRef.Name()] = struct{}{}
+
+			for _, manifest := range manifests {
+				blobSizes[manifest.Config.Digest] = manifest.Config.Size
+				for _, layer := range manifest.Layers {
+					blobSizes[layer.Digest] = layer.Size
+					if !layer.MediaType.IsDistributable() {
+						nonDistributable[imgRef.Name()] = append(nonDistributable[imgRef.Name()], layer.Digest.String())
+					}
+				}
+			}
+			return nil
+		})
+	}
+
+	err := wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []Violation
+
+	if e.policy.ForbidNonDistributableLayers {
+		for imgRef, layers := range nonDistributable {
+			violations = append(violations, Violation{
+				Rule:    NonDistributableLayersRule,
+				Image:   imgRef,
+				Message: fmt.Sprintf("contains non-distributable layers: %s", strings.Join(layers, ", ")),
+			})
+		}
+	}
+
+	if e.policy.MaxTotalSizeBytes > 0 {
+		var totalSize int64
+		for _, size := range blobSizes {
+			totalSize += size
+		}
+
+		if totalSize > e.policy.MaxTotalSizeBytes {
+			violations = append(violations, Violation{
+				Rule:    MaxTotalSizeRule,
+				Message: fmt.Sprintf("total size of images is %d bytes, exceeding %d bytes", totalSize, e.policy.MaxTotalSizeBytes),
+			})
+		}
+	}
+
+	return violations, nil
+}
+
+// manifests returns manifest of the image, or manifests of every image of the image index
+func (e Evaluator) manifests(imgRef regname.Digest) ([]*regv1.Manifest, error) {
+	desc, err := e.registry.Get(imgRef)
+	if err != nil {
+		return nil, err
+	}
+
+	switch desc.MediaType {
+	case types.OCIImageIndex, types.DockerManifestList:
+		index, err := e.registry.Index(imgRef)
+		if err != nil {
+			return nil, err
+		}
+
+		indexManifest, err := index.IndexManifest()
+		if err != nil {
+			return nil, err
+		}
+
+		var manifests []*regv1.Manifest
+		for _, child := range indexManifest.Manifests {
+			childManifests, err := e.manifests(imgRef.Context().Digest(child.Digest.String()))
+			if err != nil {
+				return nil, err
+			}
+			manifests = append(manifests, childManifests...)
+		}
+		return manifests, nil
+
+	default:
+		img, err := e.registry.Image(imgRef)
+		if err != nil {
+			return nil, err
+		}
+
+		manifest, err := img.Manifest()
+		if err != nil {
+			return nil, err
+		}
+		return []*regv1.Manifest{manifest}, nil
+	}
+}
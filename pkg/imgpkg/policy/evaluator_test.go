@@ -0,0 +1,143 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package policy_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"testing"
+
+	"github.com/k14s/imgpkg/pkg/imgpkg/lockconfig"
+	"github.com/k14s/imgpkg/pkg/imgpkg/policy"
+	"github.com/k14s/imgpkg/pkg/imgpkg/signature"
+	"github.com/k14s/imgpkg/test/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluator_Evaluate(t *testing.T) {
+	fakeRegistry := helpers.NewFakeRegistry(t, &helpers.Logger{})
+	defer fakeRegistry.CleanUp()
+	img := fakeRegistry.WithRandomImage("library/image")
+	nonDistributableImg := fakeRegistry.WithRandomImage("library/non-dist-image").WithNonDistributableLayer()
+	unsignedBundle := fakeRegistry.WithRandomImage("library/unsigned-bundle")
+	signedBundle := fakeRegistry.WithRandomImage("library/signed-bundle")
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	fakeRegistry.WithCosignSignature(signedBundle.RefDigest, signedBundle.Digest, key)
+
+	reg := fakeRegistry.Build()
+
+	evaluate := func(t *testing.T, pol policy.Policy, images ...policy.Image) policy.Violations {
+		violations, err := policy.NewEvaluator(pol, reg, signature.NewCosign(reg), 2).Evaluate(images)
+		require.NoError(t, err)
+		return violations
+	}
+
+	t.Run("empty policy does not find any violation", func(t *testing.T) {
+		violations := evaluate(t, policy.Policy{},
+			policy.Image{DigestRef: img.RefDigest}, policy.Image{DigestRef: unsignedBundle.RefDigest, IsBundle: true})
+		assert.Empty(t, violations)
+	})
+
+	t.Run("allowedRegistries", func(t *testing.T) {
+		violations := evaluate(t, policy.Policy{AllowedRegistries: []string{"gcr.io"}}, policy.Image{DigestRef: img.RefDigest})
+		assert.Equal(t, policy.Violations{{
+			Rule:    policy.AllowedRegistriesRule,
+			Image:   img.RefDigest,
+			Message: fmt.Sprintf("registry '%s' is not allowed", fakeRegistry.Host()),
+		}}, violations)
+
+		violations = evaluate(t, policy.Policy{AllowedRegistries: []string{"gcr.io", fakeRegistry.Host()}}, policy.Image{DigestRef: img.RefDigest})
+		assert.Empty(t, violations)
+	})
+
+	t.Run("rejectMutableTags", func(t *testing.T) {
+		image := policy.Image{DigestRef: img.RefDigest, Annotations: map[string]string{
+			lockconfig.KbldIDAnnotation:    "index.docker.io/library/image:latest",
+			lockconfig.SourceRefAnnotation: "index.docker.io/library/image:1.0.0",
+		}}
+
+		violations := evaluate(t, policy.Policy{RejectMutableTags: true}, image)
+		require.Len(t, violations, 2)
+		for _, violation := range violations {
+			assert.Equal(t, policy.RejectMutableTagsRule, violation.Rule)
+			assert.Equal(t, img.RefDigest, violation.Image)
+		}
+
+		violations = evaluate(t, policy.Policy{RejectMutableTags: true, MutableTags: []string{"latest"}}, image)
+		assert.Equal(t, policy.Violations{{
+			Rule:    policy.RejectMutableTagsRule,
+			Image:   img.RefDigest,
+			Message: fmt.Sprintf("referenced by mutable tag 'index.docker.io/library/image:latest' (annotation '%s')", lockconfig.KbldIDAnnotation),
+		}}, violations)
+
+		violations = evaluate(t, policy.Policy{RejectMutableTags: true}, policy.Image{DigestRef: img.RefDigest, Annotations: map[string]string{
+			lockconfig.SourceRefAnnotation: img.RefDigest,
+		}})
+		assert.Empty(t, violations, "digest references are not mutable")
+	})
+
+	t.Run("requireBundleSignatures", func(t *testing.T) {
+		violations := evaluate(t, policy.Policy{RequireBundleSignatures: true},
+			policy.Image{DigestRef: img.RefDigest},
+			policy.Image{DigestRef: unsignedBundle.RefDigest, IsBundle: true},
+			policy.Image{DigestRef: signedBundle.RefDigest, IsBundle: true})
+		assert.Equal(t, policy.Violations{{
+			Rule:    policy.RequireBundleSignaturesRule,
+			Image:   unsignedBundle.RefDigest,
+			Message: "bundle is not signed",
+		}}, violations)
+	})
+
+	t.Run("maxTotalSizeBytes", func(t *testing.T) {
+		violations := evaluate(t, policy.Policy{MaxTotalSizeBytes: 1}, policy.Image{DigestRef: img.RefDigest})
+		require.Len(t, violations, 1)
+		assert.Equal(t, policy.MaxTotalSizeRule, violations[0].Rule)
+		assert.Empty(t, violations[0].Image)
+		assert.Contains(t, violations[0].Message, "exceeding 1 bytes")
+
+		violations = evaluate(t, policy.Policy{MaxTotalSizeBytes: 1024 * 1024 * 1024}, policy.Image{DigestRef: img.RefDigest})
+		assert.Empty(t, violations)
+	})
+
+	t.Run("forbidNonDistributableLayers", func(t *testing.T) {
+		violations := evaluate(t, policy.Policy{ForbidNonDistributableLayers: true},
+			policy.Image{DigestRef: img.RefDigest}, policy.Image{DigestRef: nonDistributableImg.RefDigest})
+		require.Len(t, violations, 1)
+		assert.Equal(t, policy.NonDistributableLayersRule, violations[0].Rule)
+		assert.Equal(t, nonDistributableImg.RefDigest, violations[0].Image)
+		assert.Contains(t, violations[0].Message, "contains non-distributable layers: sha256:")
+	})
+
+	t.Run("violations are sorted by rule and image", func(t *testing.T) {
+		violations := evaluate(t, policy.Policy{AllowedRegistries: []string{"gcr.io"}, RequireBundleSignatures: true},
+			policy.Image{DigestRef: unsignedBundle.RefDigest, IsBundle: true},
+			policy.Image{DigestRef: img.RefDigest})
+
+		var rules []string
+		for _, violation := range violations {
+			rules = append(rules, violation.Rule+" "+violation.Image)
+		}
+		assert.Equal(t, []string{
+			policy.AllowedRegistriesRule + " " + img.RefDigest,
+			policy.AllowedRegistriesRule + " " + unsignedBundle.RefDigest,
+			policy.RequireBundleSignaturesRule + " " + unsignedBundle.RefDigest,
+		}, rules)
+	})
+}
+
+func TestViolations_Error(t *testing.T) {
+	err := policy.Violations{
+		{Rule: policy.MaxTotalSizeRule, Message: "total size of images is 10 bytes, exceeding 1 bytes"},
+		{Rule: policy.AllowedRegistriesRule, Image: "gcr.io/image@sha256:abc", Message: "registry 'gcr.io' is not allowed"},
+	}.Error()
+
+	assert.Equal(t, `Expected images to satisfy policy, but found 2 violations:
+- [maxTotalSizeBytes] total size of images is 10 bytes, exceeding 1 bytes
+- [allowedRegistries] gcr.io/image@sha256:abc: registry 'gcr.io' is not allowed`, err)
+}
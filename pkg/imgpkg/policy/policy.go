@@ -0,0 +1,82 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	PolicyKind       = "Policy"
+	PolicyAPIVersion = "imgpkg.carvel.dev/v1alpha1"
+)
+
+// Policy describes guardrails images have to satisfy to be copied or pulled
+type Policy struct {
+	APIVersion string `json:"apiVersion"` // This generated yaml, but due to lib we need to use `json`
+	Kind       string `json:"kind"`       // This generated yaml, but due to lib we need to use `json`
+
+	// AllowedRegistries are the only registries images can come from (e.g. index.docker.io, gcr.io)
+	AllowedRegistries []string `json:"allowedRegistries,omitempty"` // This generated yaml, but due to lib we need to use `json`
+	// RejectMutableTags rejects images that images lock annotations (kbld or imgpkg) record as resolved from a tag
+	RejectMutableTags bool `json:"rejectMutableTags,omitempty"` // This generated yaml, but due to lib we need to use `json`
+	// MutableTags are tags considered mutable (e.g. latest); every tag is when empty
+	MutableTags []string `json:"mutableTags,omitempty"` // This generated yaml, but due to lib we need to use `json`
+	// RequireBundleSignatures rejects bundles without a cosign signature
+	RequireBundleSignatures bool `json:"requireBundleSignatures,omitempty"` // This generated yaml, but due to lib we need to use `json`
+	// MaxTotalSizeBytes caps the total size of distinct configs and layers of all images
+	MaxTotalSizeBytes int64 `json:"maxTotalSizeBytes,omitempty"` // This generated yaml, but due to lib we need to use `json`
+	// ForbidNonDistributableLayers rejects images containing non-distributable (foreign) layers
+	ForbidNonDistributableLayers bool `json:"forbidNonDistributableLayers,omitempty"` // This generated yaml, but due to lib we need to use `json`
+}
+
+func NewPolicyFromPath(path string) (Policy, error) {
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Policy{}, fmt.Errorf("Reading path %s: %s", path, err)
+	}
+
+	return NewPolicyFromBytes(bs)
+}
+
+func NewPolicyFromBytes(data []byte) (Policy, error) {
+	var policy Policy
+
+	err := yaml.UnmarshalStrict(data, &policy)
+	if err != nil {
+		return policy, fmt.Errorf("Unmarshaling policy: %s", err)
+	}
+
+	err = policy.Validate()
+	if err != nil {
+		return policy, fmt.Errorf("Validating policy: %s", err)
+	}
+
+	return policy, nil
+}
+
+func (p Policy) Validate() error {
+	if p.APIVersion != PolicyAPIVersion {
+		return fmt.Errorf("Validating apiVersion: Unknown version (known: %s)", PolicyAPIVersion)
+	}
+	if p.Kind != PolicyKind {
+		return fmt.Errorf("Validating kind: Unknown kind (known: %s)", PolicyKind)
+	}
+	for _, registry := range p.AllowedRegistries {
+		if _, err := regname.NewRegistry(registry, regname.WeakValidation); err != nil {
+			return fmt.Errorf("Expected allowed registry '%s' to be a registry host: %s", registry, err)
+		}
+	}
+	if len(p.MutableTags) > 0 && !p.RejectMutableTags {
+		return fmt.Errorf("Expected mutableTags to be used with rejectMutableTags")
+	}
+	if p.MaxTotalSizeBytes < 0 {
+		return fmt.Errorf("Expected maxTotalSizeBytes to be positive")
+	}
+	return nil
+}
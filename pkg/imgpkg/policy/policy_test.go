@@ -0,0 +1,107 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package policy_test
+
+import (
+	"testing"
+
+	"github.com/k14s/imgpkg/pkg/imgpkg/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPolicyFromBytes(t *testing.T) {
+	t.Run("it reads every rule", func(t *testing.T) {
+		pol, err := policy.NewPolicyFromBytes([]byte(`---
+apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: Policy
+allowedRegistries: [gcr.io, "localhost:5000"]
+rejectMutableTags: true
+mutableTags: [latest]
+requireBundleSignatures: true
+maxTotalSizeBytes: 1024
+forbidNonDistributableLayers: true
+`))
+		require.NoError(t, err)
+		assert.Equal(t, policy.Policy{
+			APIVersion:                   policy.PolicyAPIVersion,
+			Kind:                         policy.PolicyKind,
+			AllowedRegistries:            []string{"gcr.io", "localhost:5000"},
+			RejectMutableTags:            true,
+			MutableTags:                  []string{"latest"},
+			RequireBundleSignatures:      true,
+			MaxTotalSizeBytes:            1024,
+			ForbidNonDistributableLayers: true,
+		}, pol)
+	})
+
+	t.Run("it rejects unknown fields", func(t *testing.T) {
+		_, err := policy.NewPolicyFromBytes([]byte(`---
+apiVersion: imgpkg.carvel.dev/v1alpha1
+kind: Policy
+allowedRegistry: gcr.io
+`))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Unmarshaling policy")
+	})
+}
+
+func TestPolicy_Validate(t *testing.T) {
+	validPolicy := policy.Policy{APIVersion: policy.PolicyAPIVersion, Kind: policy.PolicyKind}
+
+	testCases := []struct {
+		name          string
+		mutate        func(*policy.Policy)
+		expectedError string
+	}{
+		{name: "empty policy", mutate: func(*policy.Policy) {}},
+		{
+			name:          "unknown apiVersion",
+			mutate:        func(p *policy.Policy) { p.APIVersion = "imgpkg.carvel.dev/v2" },
+			expectedError: "Validating apiVersion: Unknown version",
+		},
+		{
+			name:          "unknown kind",
+			mutate:        func(p *policy.Policy) { p.Kind = "ImagesLock" },
+			expectedError: "Validating kind: Unknown kind",
+		},
+		{
+			name:          "allowed registry that is not a registry host",
+			mutate:        func(p *policy.Policy) { p.AllowedRegistries = []string{"gcr.io", "not a registry"} },
+			expectedError: "Expected allowed registry 'not a registry' to be a registry host",
+		},
+		{
+			name:          "mutable tags without rejecting mutable tags",
+			mutate:        func(p *policy.Policy) { p.MutableTags = []string{"latest"} },
+			expectedError: "Expected mutableTags to be used with rejectMutableTags",
+		},
+		{
+			name: "mutable tags rejected",
+			mutate: func(p *policy.Policy) {
+				p.RejectMutableTags = true
+				p.MutableTags = []string{"latest"}
+			},
+		},
+		{
+			name:          "negative max total size",
+			mutate:        func(p *policy.Policy) { p.MaxTotalSizeBytes = -1 },
+			expectedError: "Expected maxTotalSizeBytes to be positive",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pol := validPolicy
+			tc.mutate(&pol)
+
+			err := pol.Validate()
+			if tc.expectedError == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.expectedError)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	ReportKind = "PolicyReport"
+)
+
+// Report lists violations found when evaluating images against a policy
+type Report struct {
+	APIVersion string `json:"apiVersion"` // This generated yaml, but due to lib we need to use `json`
+	Kind       string `json:"kind"`       // This generated yaml, but due to lib we need to use `json`
+
+	// Mode describes whether violations failed the command (enforce) or were only reported (warn)
+	Mode       string     `json:"mode"`       // This generated yaml, but due to lib we need to use `json`
+	Violations Violations `json:"violations"` // This generated yaml, but due to lib we need to use `json`
+}
+
+func NewReport(mode string, violations Violations) Report {
+	if violations == nil {
+		violations = Violations{}
+	}
+	return Report{APIVersion: PolicyAPIVersion, Kind: ReportKind, Mode: mode, Violations: violations}
+}
+
+func (r Report) AsBytes() ([]byte, error) {
+	bs, err := yaml.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("Marshaling policy report: %s", err)
+	}
+
+	return bs, nil
+}
+
+func (r Report) WriteToPath(path string) error {
+	bs, err := r.AsBytes()
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(path, bs, 0600)
+	if err != nil {
+		return fmt.Errorf("Writing policy report: %s", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package referrers
+
+import (
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/k14s/imgpkg/pkg/imgpkg/imageset"
+	"github.com/k14s/imgpkg/pkg/imgpkg/registry"
+	"github.com/k14s/imgpkg/pkg/imgpkg/util"
+	"golang.org/x/sync/errgroup"
+)
+
+type Finder interface {
+	Referrers(subject name.Digest) ([]registry.ReferrerDescriptor, error)
+}
+
+type Referrers struct {
+	finder      Finder
+	concurrency int
+	logger      util.LoggerWithLevels
+}
+
+func NewReferrers(finder Finder, concurrency int, logger util.LoggerWithLevels) *Referrers {
+	return &Referrers{finder: finder, concurrency: concurrency, logger: logger}
+}
+
+// Fetch returns manifests that refer to the images via their subject field
+// as well as manifests that refer to these referrers (e.g. signatures of SBOMs).
+// Referrers are labeled with the digest of their subject.
+func (r *Referrers) Fetch(images *imageset.UnprocessedImageRefs) (*imageset.UnprocessedImageRefs, error) {
+	referrers := imageset.NewUnprocessedImageRefs()
+
+	seen := map[string]struct{}{}
+	for _, ref := range images.All() {
+		seen[ref.DigestRef] = struct{}{}
+	}
+
+	refs := images.All()
+	for len(refs) > 0 {
+		found, err := r.fetch(refs)
+		if err != nil {
+			return referrers, err
+		}
+
+		refs = nil
+		for _, referrer := range found {
+			if _, ok := seen[referrer.DigestRef]; ok {
+				continue
+			}
+			seen[referrer.DigestRef] = struct{}{}
+
+			referrers.Add(referrer)
+			refs = append(refs, referrer)
+		}
+	}
+
+	return referrers, nil
+}
+
+func (r *Referrers) fetch(refs []imageset.UnprocessedImageRef) ([]imageset.UnprocessedImageRef, error) {
+	referrers := imageset.NewUnprocessedImageRefs()
+
+	throttle := util.NewThrottle(r.concurrency)
+	var wg errgroup.Group
+	for _, ref := range refs {
+		ref := ref //copy
+		wg.Go(func() error {
+			subject, err := name.NewDigest(ref.DigestRef)
+			if err != nil {
+				return fmt.Errorf("referrers for: %s: %s", ref.DigestRef, err)
+			}
+
+			throttle.Take()
+			defer throttle.Done()
+
+			descriptors, err := r.finder.Referrers(subject)
+			if err != nil {
+				return err
+			}
+
+			for _, desc := range descriptors {
+				if desc.MediaType != types.OCIManifestSchema1 && desc.MediaType != types.OCIImageIndex {
+					r.logger.Warnf("skipping referrer %s of %s since its media type '%s' is not supported\n",
+						desc.Digest, subject.Name(), desc.MediaType)
+					continue
+				}
+
+				referrers.Add(imageset.UnprocessedImageRef{
+					DigestRef: subject.Context().Digest(desc.Digest.String()).Name(),
+					Labels:    map[string]string{imageset.ReferrerLabelKey: subject.DigestStr()},
+				})
+			}
+			return nil
+		})
+	}
+
+	err := wg.Wait()
+
+	return referrers.All(), err
+}
@@ -0,0 +1,278 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package registry
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	regremote "github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/k14s/imgpkg/pkg/imgpkg/util"
+)
+
+// ReferrerDescriptor describes a manifest that refers to another manifest via its subject field
+// (https://github.com/opencontainers/distribution-spec/blob/main/spec.md#listing-referrers)
+type ReferrerDescriptor struct {
+	MediaType    types.MediaType   `json:"mediaType"`
+	Size         int64             `json:"size"`
+	Digest       regv1.Hash        `json:"digest"`
+	ArtifactType string            `json:"artifactType,omitempty"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+}
+
+type referrersIndexManifest struct {
+	SchemaVersion int64                `json:"schemaVersion"`
+	MediaType     types.MediaType      `json:"mediaType"`
+	Manifests     []ReferrerDescriptor `json:"manifests"`
+}
+
+// ReferrersFallbackTag returns tag of the index listing referrers of the subject
+// in registries that do not support the referrers API (e.g. sha256-<hex>)
+func ReferrersFallbackTag(subject regv1.Hash) string {
+	return strings.ReplaceAll(subject.String(), ":", "-")
+}
+
+// Referrers returns manifests that refer to the subject.
+// When the registry does not support the referrers API, the fallback tag schema is used instead
+func (r Registry) Referrers(subject regname.Digest) ([]ReferrerDescriptor, error) {
+	overriddenRef, err := regname.NewDigest(subject.String(), r.refOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	referrers, supported, err := r.referrersFromAPI(overriddenRef)
+	if err != nil {
+		return nil, fmt.Errorf("Fetching referrers of '%s': %s", subject.String(), err)
+	}
+	if supported {
+		return referrers, nil
+	}
+
+	referrers, err = r.referrersFromFallbackTag(overriddenRef)
+	if err != nil {
+		return nil, fmt.Errorf("Fetching referrers of '%s' using fallback tag: %s", subject.String(), err)
+	}
+	return referrers, nil
+}
+
+// referrersProbeDigest is listed to check whether a repository supports the referrers API.
+// Registries implementing the API list referrers of any digest, even of manifests they do not have.
+const referrersProbeDigest = "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// ReferrersAPISupported returns true when the registry implements the referrers API for the repository
+func (r Registry) ReferrersAPISupported(repo regname.Repository) (bool, error) {
+	probeRef, err := regname.NewDigest(repo.Name()+"@"+referrersProbeDigest, r.refOpts...)
+	if err != nil {
+		return false, err
+	}
+
+	_, supported, err := r.referrersFromAPI(probeRef)
+	if err != nil {
+		return false, fmt.Errorf("Checking referrers API support of '%s': %s", repo.Name(), err)
+	}
+	return supported, nil
+}
+
+// referrersFromAPI lists referrers page by page, following Link headers.
+// Registries without referrers API respond with various errors (e.g. 404, 400, 405)
+// so every response other than a success or an authentication failure means it is not supported.
+func (r Registry) referrersFromAPI(subject regname.Digest) ([]ReferrerDescriptor, bool, error) {
+	rt, err := r.authenticatedTransport(subject.Context(), transport.PullScope)
+	if err != nil {
+		return nil, false, err
+	}
+	client := &http.Client{Transport: rt}
+
+	repo := subject.Context()
+	nextURL := &url.URL{
+		Scheme: repo.Registry.Scheme(),
+		Host:   repo.RegistryStr(),
+		Path:   fmt.Sprintf("/v2/%s/referrers/%s", repo.RepositoryStr(), subject.DigestStr()),
+	}
+
+	var referrers []ReferrerDescriptor
+	visitedURLs := map[string]struct{}{}
+
+	for nextURL != nil {
+		if _, found := visitedURLs[nextURL.String()]; found {
+			return nil, false, fmt.Errorf("Expected referrers pages to not repeat, but '%s' was already listed", nextURL)
+		}
+		visitedURLs[nextURL.String()] = struct{}{}
+
+		var page referrersPage
+		err := util.Retry(func() error {
+			var err error
+			page, err = fetchReferrersPage(client, nextURL)
+			return err
+		})
+		if err != nil {
+			return nil, false, err
+		}
+
+		if !page.supported {
+			if len(visitedURLs) > 1 {
+				return nil, false, fmt.Errorf("Expected referrers page '%s' to be listed, but registry responded with status %d", nextURL, page.statusCode)
+			}
+			return nil, false, nil
+		}
+
+		referrers = append(referrers, page.index.Manifests...)
+		nextURL = page.nextURL
+	}
+
+	return referrers, true, nil
+}
+
+type referrersPage struct {
+	index      referrersIndexManifest
+	nextURL    *url.URL
+	supported  bool
+	statusCode int
+}
+
+func fetchReferrersPage(client *http.Client, pageURL *url.URL) (referrersPage, error) {
+	req, err := http.NewRequest(http.MethodGet, pageURL.String(), nil)
+	if err != nil {
+		return referrersPage{}, util.NonRetryableError{Message: err.Error()}
+	}
+	req.Header.Set("Accept", string(types.OCIImageIndex))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return referrersPage{}, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return referrersPage{}, util.NonRetryableError{Message: transport.CheckError(resp, http.StatusOK).Error()}
+
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return referrersPage{statusCode: resp.StatusCode}, nil
+	}
+
+	page := referrersPage{supported: true, statusCode: resp.StatusCode}
+
+	err = json.NewDecoder(resp.Body).Decode(&page.index)
+	if err != nil {
+		return referrersPage{}, util.NonRetryableError{Message: fmt.Sprintf("Parsing referrers response: %s", err)}
+	}
+
+	page.nextURL, err = nextPageURL(resp)
+	if err != nil {
+		return referrersPage{}, util.NonRetryableError{Message: err.Error()}
+	}
+
+	return page, nil
+}
+
+// nextPageURL returns URL of the next page listed in Link header (e.g. </v2/...?n=10&last=...>; rel="next")
+func nextPageURL(resp *http.Response) (*url.URL, error) {
+	link := resp.Header.Get("Link")
+	if link == "" {
+		return nil, nil
+	}
+
+	for _, linkValue := range strings.Split(link, ",") {
+		linkParts := strings.Split(linkValue, ";")
+
+		var isNext bool
+		for _, param := range linkParts[1:] {
+			param = strings.ReplaceAll(strings.TrimSpace(param), " ", "")
+			if param == `rel="next"` || param == "rel=next" {
+				isNext = true
+			}
+		}
+		if !isNext {
+			continue
+		}
+
+		target, err := url.Parse(strings.Trim(strings.TrimSpace(linkParts[0]), "<>"))
+		if err != nil {
+			return nil, fmt.Errorf("Parsing Link header '%s': %s", link, err)
+		}
+		return resp.Request.URL.ResolveReference(target), nil
+	}
+
+	return nil, nil
+}
+
+func (r Registry) referrersFromFallbackTag(subject regname.Digest) ([]ReferrerDescriptor, error) {
+	subjectDigest, err := regv1.NewHash(subject.DigestStr())
+	if err != nil {
+		return nil, err
+	}
+
+	desc, err := regremote.Get(subject.Context().Tag(ReferrersFallbackTag(subjectDigest)), r.opts...)
+	if err != nil {
+		var transportErr *transport.Error
+		if errors.As(err, &transportErr) && transportErr.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var index referrersIndexManifest
+	err = json.Unmarshal(desc.Manifest, &index)
+	if err != nil {
+		return nil, fmt.Errorf("Parsing referrers index: %s", err)
+	}
+
+	return index.Manifests, nil
+}
+
+// NewReferrersIndex returns index listing provided referrers, used as fallback in registries without referrers API.
+// Referrers are expected to already be present in the repository the index is written to
+func NewReferrersIndex(referrers []ReferrerDescriptor) (regv1.ImageIndex, error) {
+	raw, err := json.Marshal(referrersIndexManifest{
+		SchemaVersion: 2,
+		MediaType:     types.OCIImageIndex,
+		Manifests:     referrers,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return referrersIndex{raw: raw}, nil
+}
+
+type referrersIndex struct {
+	raw []byte
+}
+
+var _ regv1.ImageIndex = referrersIndex{}
+
+func (i referrersIndex) MediaType() (types.MediaType, error) { return types.OCIImageIndex, nil }
+
+func (i referrersIndex) Digest() (regv1.Hash, error) {
+	sum := sha256.Sum256(i.raw)
+	return regv1.Hash{Algorithm: "sha256", Hex: hex.EncodeToString(sum[:])}, nil
+}
+
+func (i referrersIndex) Size() (int64, error) { return int64(len(i.raw)), nil }
+
+func (i referrersIndex) IndexManifest() (*regv1.IndexManifest, error) {
+	return regv1.ParseIndexManifest(bytes.NewReader(i.raw))
+}
+
+func (i referrersIndex) RawManifest() ([]byte, error) { return i.raw, nil }
+
+func (i referrersIndex) Image(digest regv1.Hash) (regv1.Image, error) {
+	return nil, fmt.Errorf("Expected referrer '%s' to be present in the repository", digest)
+}
+
+func (i referrersIndex) ImageIndex(digest regv1.Hash) (regv1.ImageIndex, error) {
+	return nil, fmt.Errorf("Expected referrer '%s' to be present in the repository", digest)
+}
@@ -12,9 +12,12 @@ import (
 	"os"
 	"time"
 
+	regauthn "github.com/google/go-containerregistry/pkg/authn"
 	regname "github.com/google/go-containerregistry/pkg/name"
 	regv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
 	regremote "github.com/google/go-containerregistry/pkg/v1/remote"
+	regtransport "github.com/google/go-containerregistry/pkg/v1/remote/transport"
 	"github.com/k14s/imgpkg/pkg/imgpkg/util"
 )
 
@@ -36,6 +39,9 @@ type Opts struct {
 type Registry struct {
 	opts    []regremote.Option
 	refOpts []regname.Option
+
+	transport http.RoundTripper
+	keychain  regauthn.Keychain
 }
 
 func NewRegistry(opts Opts, regOpts ...regremote.Option) (Registry, error) {
@@ -49,17 +55,19 @@ func NewRegistry(opts Opts, regOpts ...regremote.Option) (Registry, error) {
 		refOpts = append(refOpts, regname.Insecure)
 	}
 
+	keychain := Keychain(
+		KeychainOpts{
+			Username: opts.Username,
+			Password: opts.Password,
+			Token:    opts.Token,
+			Anon:     opts.Anon,
+		},
+		os.Environ,
+	)
+
 	regRemoteOptions := []regremote.Option{
 		regremote.WithTransport(httpTran),
-		regremote.WithAuthFromKeychain(Keychain(
-			KeychainOpts{
-				Username: opts.Username,
-				Password: opts.Password,
-				Token:    opts.Token,
-				Anon:     opts.Anon,
-			},
-			os.Environ),
-		),
+		regremote.WithAuthFromKeychain(keychain),
 	}
 	if opts.IncludeNonDistributableLayers {
 		regRemoteOptions = append(regRemoteOptions, regremote.WithNondistributable)
@@ -71,9 +79,23 @@ func NewRegistry(opts Opts, regOpts ...regremote.Option) (Registry, error) {
 	return Registry{
 		opts:    regRemoteOptions,
 		refOpts: refOpts,
+
+		transport: httpTran,
+		keychain:  keychain,
 	}, nil
 }
 
+// authenticatedTransport returns a transport authenticated with the registry keychain,
+// wrapping the same HTTP transport and user agent as requests made by go-containerregistry
+func (r Registry) authenticatedTransport(repo regname.Repository, scope string) (http.RoundTripper, error) {
+	auth, err := r.keychain.Resolve(repo.Registry)
+	if err != nil {
+		return nil, err
+	}
+
+	return regtransport.New(repo.Registry, auth, regtransport.NewUserAgent(r.transport, ""), []string{repo.Scope(scope)})
+}
+
 func (r Registry) Get(ref regname.Reference) (*regremote.Descriptor, error) {
 	overriddenRef, err := regname.ParseReference(ref.String(), r.refOpts...)
 	if err != nil {
@@ -149,7 +171,7 @@ func (r Registry) WriteImage(ref regname.Reference, img regv1.Image) error {
 		return regremote.Write(overriddenRef, img, r.opts...)
 	})
 	if err != nil {
-		return fmt.Errorf("Writing image: %s", err)
+		return fmt.Errorf("Writing image: %w", err)
 	}
 
 	return nil
@@ -173,7 +195,7 @@ func (r Registry) WriteIndex(ref regname.Reference, idx regv1.ImageIndex) error
 		return regremote.WriteIndex(overriddenRef, idx, r.opts...)
 	})
 	if err != nil {
-		return fmt.Errorf("Writing image index: %s", err)
+		return fmt.Errorf("Writing image index: %w", err)
 	}
 
 	return nil
@@ -189,7 +211,7 @@ func (r Registry) WriteTag(ref regname.Tag, taggagle regremote.Taggable) error {
 		return regremote.Tag(overriddenRef, taggagle, r.opts...)
 	})
 	if err != nil {
-		return fmt.Errorf("Tagging image: %s", err)
+		return fmt.Errorf("Tagging image: %w", err)
 	}
 
 	return nil
@@ -203,6 +225,20 @@ func (r Registry) ListTags(repo regname.Repository) ([]string, error) {
 	return regremote.List(overriddenRepo, r.opts...)
 }
 
+// BlobExists returns true when blob with given digest is present in the repository
+func (r Registry) BlobExists(ref regname.Digest) (bool, error) {
+	overriddenRef, err := regname.NewDigest(ref.String(), r.refOpts...)
+	if err != nil {
+		return false, err
+	}
+
+	layer, err := regremote.Layer(overriddenRef, r.opts...)
+	if err != nil {
+		return false, err
+	}
+	return partial.Exists(layer)
+}
+
 func (r Registry) FirstImageExists(digests []string) (string, error) {
 	var err error
 	for _, img := range digests {
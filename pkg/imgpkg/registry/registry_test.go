@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/google/go-containerregistry/pkg/name"
@@ -73,3 +74,113 @@ func createServer(handler func(w http.ResponseWriter, r *http.Request)) *httptes
 		w.Write(response)
 	}))
 }
+
+func TestRegistry_Referrers(t *testing.T) {
+	subjectDigest := "sha256:477c34d98f9e090a4441cf82d2f1f03e64c8eb730e8c1ef39a8595e685d4df65"
+	referrer1 := `{"mediaType":"application/vnd.oci.image.manifest.v1+json","size":10,"digest":"sha256:1111111111111111111111111111111111111111111111111111111111111111","artifactType":"application/spdx+json"}`
+	referrer2 := `{"mediaType":"application/vnd.oci.image.manifest.v1+json","size":20,"digest":"sha256:2222222222222222222222222222222222222222222222222222222222222222","artifactType":"text/markdown"}`
+
+	referrersServer := func(handler func(w http.ResponseWriter, r *http.Request)) (*httptest.Server, name.Digest) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/v2/" {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+			handler(w, r)
+		}))
+
+		u, err := url.Parse(server.URL)
+		require.NoError(t, err)
+		subjectRef, err := name.NewDigest(fmt.Sprintf("%s/repo@%s", u.Host, subjectDigest))
+		require.NoError(t, err)
+		return server, subjectRef
+	}
+
+	for _, statusCode := range []int{http.StatusNotFound, http.StatusBadRequest, http.StatusMethodNotAllowed} {
+		t.Run(fmt.Sprintf("falls back to tag schema when referrers API responds with %d", statusCode), func(t *testing.T) {
+			var fallbackTagRequested bool
+			server, subjectRef := referrersServer(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case strings.HasPrefix(r.URL.Path, "/v2/repo/referrers/"):
+					w.WriteHeader(statusCode)
+				case r.URL.Path == "/v2/repo/manifests/sha256-477c34d98f9e090a4441cf82d2f1f03e64c8eb730e8c1ef39a8595e685d4df65":
+					fallbackTagRequested = true
+					w.WriteHeader(http.StatusNotFound)
+				default:
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+			})
+			defer server.Close()
+
+			subject, err := registry.NewRegistry(registry.Opts{})
+			require.NoError(t, err)
+
+			supported, err := subject.ReferrersAPISupported(subjectRef.Context())
+			require.NoError(t, err)
+			require.False(t, supported)
+
+			referrers, err := subject.Referrers(subjectRef)
+			require.NoError(t, err)
+			require.Empty(t, referrers)
+			require.True(t, fallbackTagRequested)
+		})
+	}
+
+	t.Run("follows Link header to list every page of referrers", func(t *testing.T) {
+		server, subjectRef := referrersServer(func(w http.ResponseWriter, r *http.Request) {
+			require.True(t, strings.HasPrefix(r.UserAgent(), "go-containerregistry"), "user agent: %s", r.UserAgent())
+
+			w.Header().Set("Content-Type", string(types.OCIImageIndex))
+			if r.URL.Query().Get("last") == "" {
+				w.Header().Set("Link", fmt.Sprintf(`<%s?n=1&last=1>; rel="next"`, r.URL.Path))
+				fmt.Fprintf(w, `{"schemaVersion":2,"mediaType":"%s","manifests":[%s]}`, types.OCIImageIndex, referrer1)
+				return
+			}
+			fmt.Fprintf(w, `{"schemaVersion":2,"mediaType":"%s","manifests":[%s]}`, types.OCIImageIndex, referrer2)
+		})
+		defer server.Close()
+
+		subject, err := registry.NewRegistry(registry.Opts{})
+		require.NoError(t, err)
+
+		referrers, err := subject.Referrers(subjectRef)
+		require.NoError(t, err)
+		require.Len(t, referrers, 2)
+		require.Equal(t, "application/spdx+json", referrers[0].ArtifactType)
+		require.Equal(t, "text/markdown", referrers[1].ArtifactType)
+	})
+
+	t.Run("checks referrers API support of a repository without listing referrers of its manifests", func(t *testing.T) {
+		var requestedPaths []string
+		server, subjectRef := referrersServer(func(w http.ResponseWriter, r *http.Request) {
+			requestedPaths = append(requestedPaths, r.URL.Path)
+			w.Header().Set("Content-Type", string(types.OCIImageIndex))
+			fmt.Fprintf(w, `{"schemaVersion":2,"mediaType":"%s","manifests":[]}`, types.OCIImageIndex)
+		})
+		defer server.Close()
+
+		subject, err := registry.NewRegistry(registry.Opts{})
+		require.NoError(t, err)
+
+		supported, err := subject.ReferrersAPISupported(subjectRef.Context())
+		require.NoError(t, err)
+		require.True(t, supported)
+		require.Len(t, requestedPaths, 1)
+		require.True(t, strings.HasPrefix(requestedPaths[0], "/v2/repo/referrers/"), "path: %s", requestedPaths[0])
+		require.NotContains(t, requestedPaths[0], subjectDigest)
+	})
+
+	t.Run("fails when registry rejects credentials", func(t *testing.T) {
+		server, subjectRef := referrersServer(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		})
+		defer server.Close()
+
+		subject, err := registry.NewRegistry(registry.Opts{})
+		require.NoError(t, err)
+
+		_, err = subject.Referrers(subjectRef)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "401")
+	})
+}
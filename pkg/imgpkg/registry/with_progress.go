@@ -10,13 +10,15 @@ import (
 	"github.com/k14s/imgpkg/pkg/imgpkg/util"
 )
 
-func NewRegistryWithProgress(reg Registry, logger util.ProgressLogger) *WithProgress {
-	return &WithProgress{delegate: reg, logger: logger}
+// NewRegistryWithProgress returns a registry reporting progress of each write with a new logger,
+// so that writes happening concurrently (e.g. to multiple repositories) do not end each other's progress
+func NewRegistryWithProgress(reg Registry, newLogger func() util.ProgressLogger) *WithProgress {
+	return &WithProgress{delegate: reg, newLogger: newLogger}
 }
 
 type WithProgress struct {
-	delegate Registry
-	logger   util.ProgressLogger
+	delegate  Registry
+	newLogger func() util.ProgressLogger
 }
 
 func (w WithProgress) Get(reference regname.Reference) (*remote.Descriptor, error) {
@@ -39,10 +41,15 @@ func (w WithProgress) FirstImageExists(digests []string) (string, error) {
 	return w.delegate.FirstImageExists(digests)
 }
 
+func (w WithProgress) BlobExists(reference regname.Digest) (bool, error) {
+	return w.delegate.BlobExists(reference)
+}
+
 func (w *WithProgress) MultiWrite(imageOrIndexesToUpload map[regname.Reference]remote.Taggable, concurrency int, _ chan regv1.Update) error {
 	uploadProgress := make(chan regv1.Update)
-	w.logger.Start(uploadProgress)
-	defer w.logger.End()
+	logger := w.newLogger()
+	logger.Start(uploadProgress)
+	defer logger.End()
 
 	return w.delegate.MultiWrite(imageOrIndexesToUpload, concurrency, uploadProgress)
 }
@@ -58,3 +65,11 @@ func (w WithProgress) WriteIndex(reference regname.Reference, index regv1.ImageI
 func (w WithProgress) WriteTag(tag regname.Tag, taggable remote.Taggable) error {
 	return w.delegate.WriteTag(tag, taggable)
 }
+
+func (w WithProgress) Referrers(subject regname.Digest) ([]ReferrerDescriptor, error) {
+	return w.delegate.Referrers(subject)
+}
+
+func (w WithProgress) ReferrersAPISupported(repo regname.Repository) (bool, error) {
+	return w.delegate.ReferrersAPISupported(repo)
+}
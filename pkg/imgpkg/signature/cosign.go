@@ -15,6 +15,41 @@ import (
 	"github.com/k14s/imgpkg/pkg/imgpkg/signature/cosign"
 )
 
+// Labels below are persisted in tarballs together with other labels of images (e.g. root bundle label),
+// so that copying a tarball to a repository still identifies these images in copy reports
+// and in annotations of the lock output. Labels are ignored by versions of imgpkg that do not know them.
+const (
+	// SignatureLabelKey marks images that are signatures of other copied images
+	SignatureLabelKey = "dev.carvel.imgpkg.copy.signature"
+	// AttestationLabelKey marks images that are attestations of other copied images
+	AttestationLabelKey = "dev.carvel.imgpkg.copy.attestation"
+	// SBOMLabelKey marks images that are SBOMs of other copied images
+	SBOMLabelKey = "dev.carvel.imgpkg.copy.sbom"
+)
+
+// Artifact is a kind of image cosign attaches to other images using a tag derived from their digest
+type Artifact struct {
+	Name      string
+	TagSuffix string
+	LabelKey  string
+}
+
+var (
+	SignatureArtifact   = Artifact{Name: "signature", TagSuffix: cosign.SignatureTagSuffix, LabelKey: SignatureLabelKey}
+	AttestationArtifact = Artifact{Name: "attestation", TagSuffix: cosign.AttestationTagSuffix, LabelKey: AttestationLabelKey}
+	SBOMArtifact        = Artifact{Name: "sbom", TagSuffix: cosign.SBOMTagSuffix, LabelKey: SBOMLabelKey}
+)
+
+// ArtifactFromLabels returns the kind of artifact an image was copied as
+func ArtifactFromLabels(labels map[string]string) (Artifact, bool) {
+	for _, artifact := range []Artifact{SignatureArtifact, AttestationArtifact, SBOMArtifact} {
+		if _, found := labels[artifact.LabelKey]; found {
+			return artifact, true
+		}
+	}
+	return Artifact{}, false
+}
+
 func NewCosign(reg registry.Registry) *Cosign {
 	return &Cosign{registry: reg}
 }
@@ -24,12 +59,17 @@ type Cosign struct {
 }
 
 func (c Cosign) Signature(imageRef regname.Digest) (imageset.UnprocessedImageRef, error) {
-	sigTagRef, err := c.signatureTag(imageRef)
+	return c.Artifact(imageRef, SignatureArtifact)
+}
+
+// Artifact returns artifact attached to the image, or NotFound when the image does not have one
+func (c Cosign) Artifact(imageRef regname.Digest, artifact Artifact) (imageset.UnprocessedImageRef, error) {
+	artifactTagRef, err := c.artifactTag(imageRef, artifact)
 	if err != nil {
 		return imageset.UnprocessedImageRef{}, err
 	}
 
-	sigDigest, err := c.registry.Digest(sigTagRef)
+	artifactDigest, err := c.registry.Digest(artifactTagRef)
 	if err != nil {
 		if transportErr, ok := err.(*transport.Error); ok {
 			if transportErr.StatusCode == http.StatusNotFound {
@@ -39,14 +79,18 @@ func (c Cosign) Signature(imageRef regname.Digest) (imageset.UnprocessedImageRef
 		return imageset.UnprocessedImageRef{}, err
 	}
 
-	sigDigestRef := imageRef.Digest(sigDigest.String())
-	return imageset.UnprocessedImageRef{DigestRef: sigDigestRef.Name(), Tag: sigTagRef.TagStr()}, nil
+	artifactDigestRef := imageRef.Digest(artifactDigest.String())
+	return imageset.UnprocessedImageRef{
+		DigestRef: artifactDigestRef.Name(),
+		Tag:       artifactTagRef.TagStr(),
+		Labels:    map[string]string{artifact.LabelKey: ""},
+	}, nil
 }
 
-func (c Cosign) signatureTag(reference regname.Digest) (regname.Tag, error) {
+func (c Cosign) artifactTag(reference regname.Digest, artifact Artifact) (regname.Tag, error) {
 	digest, err := v1.NewHash(reference.DigestStr())
 	if err != nil {
 		return regname.Tag{}, fmt.Errorf("Converting to hash: %s", err)
 	}
-	return regname.NewTag(reference.Repository.Name() + ":" + cosign.Munge(v1.Descriptor{Digest: digest}))
+	return regname.NewTag(reference.Repository.Name() + ":" + cosign.AttachedImageTag(v1.Descriptor{Digest: digest}, artifact.TagSuffix))
 }
@@ -0,0 +1,23 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package cosign
+
+import (
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// Suffixes of tags cosign uses to attach artifacts to an image in the image repository
+// (https://github.com/sigstore/cosign/blob/main/specs/SIGNATURE_SPEC.md)
+const (
+	SignatureTagSuffix   = "sig"
+	AttestationTagSuffix = "att"
+	SBOMTagSuffix        = "sbom"
+)
+
+// AttachedImageTag returns tag of the artifact attached to the image (e.g. sha256-<hex>.att)
+func AttachedImageTag(desc v1.Descriptor, suffix string) string {
+	return strings.ReplaceAll(desc.Digest.String(), ":", "-") + "." + suffix
+}
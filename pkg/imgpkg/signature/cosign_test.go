@@ -51,4 +51,28 @@ func TestCosign_Signature(t *testing.T) {
 		_, ok := err.(signature.NotFound)
 		require.True(t, ok)
 	})
+
+	t.Run("it returns artifacts using their tag suffix", func(t *testing.T) {
+		logger := &helpers.Logger{}
+		regBuilder := helpers.NewFakeRegistry(t, logger)
+		attImg := regBuilder.WithRandomImage("some-image")
+		attestationTag := fmt.Sprintf("sha256-%s.att", strings.Split(attImg.Digest, ":")[1])
+		attImg.Tag = attestationTag
+		reg := regBuilder.Build()
+		defer regBuilder.CleanUp()
+
+		subject := signature.NewCosign(reg)
+		imgDigest, err := name.NewDigest(attImg.RefDigest)
+		require.NoError(t, err)
+
+		attestation, err := subject.Artifact(imgDigest, signature.AttestationArtifact)
+		require.NoError(t, err)
+		assert.Equal(t, attImg.RefDigest, attestation.DigestRef)
+		assert.Equal(t, attestationTag, attestation.Tag)
+		assert.Equal(t, map[string]string{signature.AttestationLabelKey: ""}, attestation.Labels)
+
+		_, err = subject.Artifact(imgDigest, signature.SBOMArtifact)
+		_, ok := err.(signature.NotFound)
+		require.True(t, ok)
+	})
 }
@@ -14,7 +14,7 @@ import (
 
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . Finder
 type Finder interface {
-	Signature(reference name.Digest) (imageset.UnprocessedImageRef, error)
+	Artifact(reference name.Digest, artifact Artifact) (imageset.UnprocessedImageRef, error)
 }
 
 type NotFound struct {
@@ -35,48 +35,95 @@ func (n Noop) Fetch(*imageset.UnprocessedImageRefs) (*imageset.UnprocessedImageR
 	return imageset.NewUnprocessedImageRefs(), nil
 }
 
+func (n Noop) Verify([]string) error {
+	return nil
+}
+
 type Signatures struct {
 	signatureFinder Finder
 	concurrency     int
+	artifacts       []Artifact
 }
 
 func NewSignatures(finder Finder, concurrency int) *Signatures {
 	return &Signatures{
 		signatureFinder: finder,
 		concurrency:     concurrency,
+		artifacts:       []Artifact{SignatureArtifact},
 	}
 }
 
+// WithArtifacts fetches given kinds of artifacts instead of only signatures
+func (s Signatures) WithArtifacts(artifacts ...Artifact) *Signatures {
+	s.artifacts = artifacts
+	return &s
+}
+
+// Fetch returns artifacts attached to the images as well as artifacts attached to these artifacts
+// (e.g. signatures of attestations)
 func (s *Signatures) Fetch(images *imageset.UnprocessedImageRefs) (*imageset.UnprocessedImageRefs, error) {
 	signatures := imageset.NewUnprocessedImageRefs()
 
+	seen := map[string]struct{}{}
+	for _, ref := range images.All() {
+		seen[ref.DigestRef] = struct{}{}
+	}
+
+	refs := images.All()
+	for len(refs) > 0 {
+		found, err := s.fetch(refs)
+		if err != nil {
+			return signatures, err
+		}
+
+		refs = nil
+		for _, signature := range found {
+			if _, ok := seen[signature.DigestRef]; ok {
+				continue
+			}
+			seen[signature.DigestRef] = struct{}{}
+
+			signatures.Add(signature)
+			refs = append(refs, signature)
+		}
+	}
+
+	return signatures, nil
+}
+
+func (s *Signatures) fetch(refs []imageset.UnprocessedImageRef) ([]imageset.UnprocessedImageRef, error) {
+	signatures := imageset.NewUnprocessedImageRefs()
+
 	throttle := util.NewThrottle(s.concurrency)
 	var wg errgroup.Group
-	for _, ref := range images.All() {
+	for _, ref := range refs {
 		ref := ref //copy
-		wg.Go(func() error {
-			imgDigest, err := name.NewDigest(ref.DigestRef)
-			if err != nil {
-				return fmt.Errorf("signatures for: %s: %s", ref.DigestRef, err)
-			}
+		for _, artifact := range s.artifacts {
+			artifact := artifact //copy
+			wg.Go(func() error {
+				imgDigest, err := name.NewDigest(ref.DigestRef)
+				if err != nil {
+					return fmt.Errorf("%ss for: %s: %s", artifact.Name, ref.DigestRef, err)
+				}
 
-			throttle.Take()
-			defer throttle.Done()
+				throttle.Take()
+				defer throttle.Done()
 
-			signature, err := s.signatureFinder.Signature(imgDigest)
-			if err != nil {
-				if _, ok := err.(NotFound); !ok {
-					return fmt.Errorf("unable to get signature for image '%s': %s", imgDigest.Name(), err)
+				signature, err := s.signatureFinder.Artifact(imgDigest, artifact)
+				if err != nil {
+					if _, ok := err.(NotFound); !ok {
+						return fmt.Errorf("unable to get %s for image '%s': %s", artifact.Name, imgDigest.Name(), err)
+					}
+					return nil
 				}
-				return nil
-			}
 
-			signatures.Add(signature)
-			return nil
-		})
+				signatures.Add(signature)
+				return nil
+			})
+		}
 	}
 
 	err := wg.Wait()
 
-	return signatures, err
+	return signatures.All(), err
 }
@@ -19,7 +19,7 @@ func TestSignatureRetriever_Signatures(t *testing.T) {
 	t.Run("it does not add signatures that cannot be found", func(t *testing.T) {
 		fakeSignatureFinder := &signaturefakes.FakeFinder{}
 		subject := signature.NewSignatures(fakeSignatureFinder, 2)
-		fakeSignatureFinder.SignatureCalls(func(digest regname.Digest) (imageset.UnprocessedImageRef, error) {
+		fakeSignatureFinder.ArtifactCalls(func(digest regname.Digest, _ signature.Artifact) (imageset.UnprocessedImageRef, error) {
 			availableResults := map[string]imageset.UnprocessedImageRef{
 				"sha256:4c8b96d4fffdfae29258d94a22ae4ad1fe36139d47288b8960d9958d1e63a9d0": {DigestRef: "registry.io/img@sha256:cf31af331f38d1d7158470e095b132acd126a7180a54f263d386da88eb681d93", Tag: "some-tag"},
 				"sha256:56cb33b3b4bc45509c5ff7513ddc6ed78764f9ad5165cc32826e04da49d5462b": {DigestRef: "registry.io/img2@sha256:be154cc2b1211a9f98f4d708f4266650c9129784d0485d4507d9b0fa05d928b6", Tag: "some-other-tag"},
@@ -47,8 +47,8 @@ func TestSignatureRetriever_Signatures(t *testing.T) {
 	t.Run("it returns error when returned error is not sign.NotFound", func(t *testing.T) {
 		fakeSignatureFinder := &signaturefakes.FakeFinder{}
 		subject := signature.NewSignatures(fakeSignatureFinder, 2)
-		fakeSignatureFinder.SignatureCalls(func(digest regname.Digest) (imageset.UnprocessedImageRef, error) {
-			if fakeSignatureFinder.SignatureCallCount() == 1 {
+		fakeSignatureFinder.ArtifactCalls(func(digest regname.Digest, _ signature.Artifact) (imageset.UnprocessedImageRef, error) {
+			if fakeSignatureFinder.ArtifactCallCount() == 1 {
 				return imageset.UnprocessedImageRef{DigestRef: "registry.io/img@sha256:cf31af331f38d1d7158470e095b132acd126a7180a54f263d386da88eb681d93", Tag: "some-tag"}, nil
 			}
 			return imageset.UnprocessedImageRef{}, fmt.Errorf("should make function fail")
@@ -61,4 +61,37 @@ func TestSignatureRetriever_Signatures(t *testing.T) {
 		_, err := subject.Fetch(args)
 		require.Error(t, err)
 	})
+
+	t.Run("it fetches every kind of artifact and artifacts of found artifacts", func(t *testing.T) {
+		fakeSignatureFinder := &signaturefakes.FakeFinder{}
+		subject := signature.NewSignatures(fakeSignatureFinder, 2).
+			WithArtifacts(signature.SignatureArtifact, signature.AttestationArtifact, signature.SBOMArtifact)
+
+		imgRef := "registry.io/img@sha256:4c8b96d4fffdfae29258d94a22ae4ad1fe36139d47288b8960d9958d1e63a9d0"
+		attRef := "registry.io/img@sha256:cf31af331f38d1d7158470e095b132acd126a7180a54f263d386da88eb681d93"
+		attSigRef := "registry.io/img@sha256:be154cc2b1211a9f98f4d708f4266650c9129784d0485d4507d9b0fa05d928b6"
+
+		fakeSignatureFinder.ArtifactCalls(func(digest regname.Digest, artifact signature.Artifact) (imageset.UnprocessedImageRef, error) {
+			switch {
+			case digest.Name() == imgRef && artifact == signature.AttestationArtifact:
+				return imageset.UnprocessedImageRef{DigestRef: attRef, Tag: "sha256-4c8b96d4fffdfae29258d94a22ae4ad1fe36139d47288b8960d9958d1e63a9d0.att"}, nil
+			case digest.Name() == attRef && artifact == signature.SignatureArtifact:
+				return imageset.UnprocessedImageRef{DigestRef: attSigRef, Tag: "sha256-cf31af331f38d1d7158470e095b132acd126a7180a54f263d386da88eb681d93.sig"}, nil
+			}
+			return imageset.UnprocessedImageRef{}, signature.NotFound{}
+		})
+
+		args := imageset.NewUnprocessedImageRefs()
+		args.Add(imageset.UnprocessedImageRef{DigestRef: imgRef})
+		signatures, err := subject.Fetch(args)
+		require.NoError(t, err)
+
+		var signatureRefs []string
+		for _, sig := range signatures.All() {
+			signatureRefs = append(signatureRefs, sig.DigestRef)
+		}
+		assert.ElementsMatch(t, []string{attRef, attSigRef}, signatureRefs)
+		// image, attestation and signature of the attestation are each checked for the 3 kinds of artifacts
+		assert.Equal(t, 9, fakeSignatureFinder.ArtifactCallCount())
+	})
 }
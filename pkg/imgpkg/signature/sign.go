@@ -0,0 +1,193 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package signature
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/k14s/imgpkg/pkg/imgpkg/signature/cosign"
+)
+
+const (
+	simpleSigningMediaType = "application/vnd.dev.cosign.simplesigning.v1+json"
+	simpleSigningType      = "cosign container image signature"
+)
+
+type ImagesReaderWriter interface {
+	Image(regname.Reference) (v1.Image, error)
+	WriteImage(regname.Reference, v1.Image) error
+}
+
+// LoadPrivateKey reads a PEM encoded, not encrypted, ECDSA or ED25519 private key
+func LoadPrivateKey(path string) (crypto.Signer, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Reading private key '%s': %s", path, err)
+	}
+
+	block, _ := pem.Decode(contents)
+	if block == nil {
+		return nil, fmt.Errorf("Expected private key '%s' to be PEM encoded", path)
+	}
+
+	var key interface{}
+
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("Expected private key '%s' to be a not encrypted 'EC PRIVATE KEY' or 'PRIVATE KEY' PEM block but was '%s'", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Parsing private key '%s': %s", path, err)
+	}
+
+	switch key := key.(type) {
+	case *ecdsa.PrivateKey:
+		return key, nil
+	case ed25519.PrivateKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("Expected private key '%s' to be an ECDSA or ED25519 key but was %T", path, key)
+	}
+}
+
+// Signer creates cosign compatible signatures of images using a private key
+type Signer struct {
+	registry ImagesReaderWriter
+	key      crypto.Signer
+}
+
+func NewSigner(registry ImagesReaderWriter, key crypto.Signer) *Signer {
+	return &Signer{registry: registry, key: key}
+}
+
+// Sign uploads a signature of the image (digest reference) next to it, using cosign tag schema (sha256-<hex>.sig).
+// Annotations are included as optional claims of the signed payload. Signatures already present in
+// the signature image (e.g. made with other keys) are kept. Returns the signature reference.
+func (s *Signer) Sign(imageRef string, annotations map[string]string) (string, error) {
+	imgDigest, err := regname.NewDigest(imageRef)
+	if err != nil {
+		return "", fmt.Errorf("Signing '%s': %s", imageRef, err)
+	}
+
+	digest, err := v1.NewHash(imgDigest.DigestStr())
+	if err != nil {
+		return "", err
+	}
+
+	var payload simpleSigningPayload
+	payload.Critical.Identity.DockerReference = imgDigest.Context().Name()
+	payload.Critical.Image.DockerManifestDigest = digest.String()
+	payload.Critical.Type = simpleSigningType
+	if len(annotations) > 0 {
+		payload.Optional = map[string]interface{}{}
+		for k, v := range annotations {
+			payload.Optional[k] = v
+		}
+	}
+
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	sig, err := s.sign(payloadBytes)
+	if err != nil {
+		return "", fmt.Errorf("Signing '%s': %s", imageRef, err)
+	}
+
+	sigTag, err := regname.NewTag(imgDigest.Context().Name() + ":" + cosign.Munge(v1.Descriptor{Digest: digest}))
+	if err != nil {
+		return "", err
+	}
+
+	existingSigImg, err := s.existingSignatureImage(sigTag)
+	if err != nil {
+		return "", err
+	}
+
+	sigImg, err := mutate.Append(existingSigImg, mutate.Addendum{
+		Layer:       payloadLayer{contents: payloadBytes},
+		Annotations: map[string]string{cosignSignatureAnnotation: base64.StdEncoding.EncodeToString(sig)},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	err = s.registry.WriteImage(sigTag, sigImg)
+	if err != nil {
+		return "", fmt.Errorf("Writing signature '%s': %s", sigTag.Name(), err)
+	}
+
+	return sigTag.Name(), nil
+}
+
+// existingSignatureImage returns the signature image already uploaded for the image,
+// or an empty image when there is none yet
+func (s *Signer) existingSignatureImage(sigTag regname.Tag) (v1.Image, error) {
+	sigImg, err := s.registry.Image(sigTag)
+	if err != nil {
+		if transportErr, ok := err.(*transport.Error); ok && transportErr.StatusCode == http.StatusNotFound {
+			return empty.Image, nil
+		}
+		return nil, fmt.Errorf("Fetching existing signature '%s': %s", sigTag.Name(), err)
+	}
+	return sigImg, nil
+}
+
+func (s *Signer) sign(payload []byte) ([]byte, error) {
+	if _, ok := s.key.Public().(ed25519.PublicKey); ok {
+		return s.key.Sign(rand.Reader, payload, crypto.Hash(0))
+	}
+
+	payloadHash := sha256.Sum256(payload)
+	return s.key.Sign(rand.Reader, payloadHash[:], crypto.SHA256)
+}
+
+// payloadLayer stores the signed payload as is (without compression), like cosign does
+type payloadLayer struct {
+	contents []byte
+}
+
+func (l payloadLayer) Digest() (v1.Hash, error) {
+	sum := sha256.Sum256(l.contents)
+	return v1.NewHash("sha256:" + hex.EncodeToString(sum[:]))
+}
+
+func (l payloadLayer) DiffID() (v1.Hash, error) { return l.Digest() }
+
+func (l payloadLayer) Compressed() (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(l.contents)), nil
+}
+
+func (l payloadLayer) Uncompressed() (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(l.contents)), nil
+}
+
+func (l payloadLayer) Size() (int64, error) { return int64(len(l.contents)), nil }
+
+func (l payloadLayer) MediaType() (types.MediaType, error) { return simpleSigningMediaType, nil }
@@ -0,0 +1,41 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package signature_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/k14s/imgpkg/pkg/imgpkg/signature"
+	"github.com/k14s/imgpkg/test/helpers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigner_Sign(t *testing.T) {
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	t.Run("it keeps signatures made with other keys", func(t *testing.T) {
+		regBuilder := helpers.NewFakeRegistry(t, &helpers.Logger{})
+		defer regBuilder.CleanUp()
+		img := regBuilder.WithRandomImage("some-image")
+		reg := regBuilder.Build()
+
+		firstSigRef, err := signature.NewSigner(reg, ecdsaKey).Sign(img.RefDigest, nil)
+		require.NoError(t, err)
+		secondSigRef, err := signature.NewSigner(reg, ed25519Key).Sign(img.RefDigest, nil)
+		require.NoError(t, err)
+		require.Equal(t, firstSigRef, secondSigRef)
+
+		err = signature.NewVerifier(reg, loadPublicKeys(t, ecdsaKey.Public()), 1).Verify([]string{img.RefDigest})
+		require.NoError(t, err)
+		err = signature.NewVerifier(reg, loadPublicKeys(t, ed25519Key.Public()), 1).Verify([]string{img.RefDigest})
+		require.NoError(t, err)
+	})
+}
@@ -10,16 +10,17 @@ import (
 )
 
 type FakeFinder struct {
-	SignatureStub        func(name.Digest) (imageset.UnprocessedImageRef, error)
-	signatureMutex       sync.RWMutex
-	signatureArgsForCall []struct {
+	ArtifactStub        func(name.Digest, signature.Artifact) (imageset.UnprocessedImageRef, error)
+	artifactMutex       sync.RWMutex
+	artifactArgsForCall []struct {
 		arg1 name.Digest
+		arg2 signature.Artifact
 	}
-	signatureReturns struct {
+	artifactReturns struct {
 		result1 imageset.UnprocessedImageRef
 		result2 error
 	}
-	signatureReturnsOnCall map[int]struct {
+	artifactReturnsOnCall map[int]struct {
 		result1 imageset.UnprocessedImageRef
 		result2 error
 	}
@@ -27,18 +28,19 @@ type FakeFinder struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeFinder) Signature(arg1 name.Digest) (imageset.UnprocessedImageRef, error) {
-	fake.signatureMutex.Lock()
-	ret, specificReturn := fake.signatureReturnsOnCall[len(fake.signatureArgsForCall)]
-	fake.signatureArgsForCall = append(fake.signatureArgsForCall, struct {
+func (fake *FakeFinder) Artifact(arg1 name.Digest, arg2 signature.Artifact) (imageset.UnprocessedImageRef, error) {
+	fake.artifactMutex.Lock()
+	ret, specificReturn := fake.artifactReturnsOnCall[len(fake.artifactArgsForCall)]
+	fake.artifactArgsForCall = append(fake.artifactArgsForCall, struct {
 		arg1 name.Digest
-	}{arg1})
-	stub := fake.SignatureStub
-	fakeReturns := fake.signatureReturns
-	fake.recordInvocation("Signature", []interface{}{arg1})
-	fake.signatureMutex.Unlock()
+		arg2 signature.Artifact
+	}{arg1, arg2})
+	stub := fake.ArtifactStub
+	fakeReturns := fake.artifactReturns
+	fake.recordInvocation("Artifact", []interface{}{arg1, arg2})
+	fake.artifactMutex.Unlock()
 	if stub != nil {
-		return stub(arg1)
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -46,46 +48,46 @@ func (fake *FakeFinder) Signature(arg1 name.Digest) (imageset.UnprocessedImageRe
 	return fakeReturns.result1, fakeReturns.result2
 }
 
-func (fake *FakeFinder) SignatureCallCount() int {
-	fake.signatureMutex.RLock()
-	defer fake.signatureMutex.RUnlock()
-	return len(fake.signatureArgsForCall)
+func (fake *FakeFinder) ArtifactCallCount() int {
+	fake.artifactMutex.RLock()
+	defer fake.artifactMutex.RUnlock()
+	return len(fake.artifactArgsForCall)
 }
 
-func (fake *FakeFinder) SignatureCalls(stub func(name.Digest) (imageset.UnprocessedImageRef, error)) {
-	fake.signatureMutex.Lock()
-	defer fake.signatureMutex.Unlock()
-	fake.SignatureStub = stub
+func (fake *FakeFinder) ArtifactCalls(stub func(name.Digest, signature.Artifact) (imageset.UnprocessedImageRef, error)) {
+	fake.artifactMutex.Lock()
+	defer fake.artifactMutex.Unlock()
+	fake.ArtifactStub = stub
 }
 
-func (fake *FakeFinder) SignatureArgsForCall(i int) name.Digest {
-	fake.signatureMutex.RLock()
-	defer fake.signatureMutex.RUnlock()
-	argsForCall := fake.signatureArgsForCall[i]
-	return argsForCall.arg1
+func (fake *FakeFinder) ArtifactArgsForCall(i int) (name.Digest, signature.Artifact) {
+	fake.artifactMutex.RLock()
+	defer fake.artifactMutex.RUnlock()
+	argsForCall := fake.artifactArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
 }
 
-func (fake *FakeFinder) SignatureReturns(result1 imageset.UnprocessedImageRef, result2 error) {
-	fake.signatureMutex.Lock()
-	defer fake.signatureMutex.Unlock()
-	fake.SignatureStub = nil
-	fake.signatureReturns = struct {
+func (fake *FakeFinder) ArtifactReturns(result1 imageset.UnprocessedImageRef, result2 error) {
+	fake.artifactMutex.Lock()
+	defer fake.artifactMutex.Unlock()
+	fake.ArtifactStub = nil
+	fake.artifactReturns = struct {
 		result1 imageset.UnprocessedImageRef
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *FakeFinder) SignatureReturnsOnCall(i int, result1 imageset.UnprocessedImageRef, result2 error) {
-	fake.signatureMutex.Lock()
-	defer fake.signatureMutex.Unlock()
-	fake.SignatureStub = nil
-	if fake.signatureReturnsOnCall == nil {
-		fake.signatureReturnsOnCall = make(map[int]struct {
+func (fake *FakeFinder) ArtifactReturnsOnCall(i int, result1 imageset.UnprocessedImageRef, result2 error) {
+	fake.artifactMutex.Lock()
+	defer fake.artifactMutex.Unlock()
+	fake.ArtifactStub = nil
+	if fake.artifactReturnsOnCall == nil {
+		fake.artifactReturnsOnCall = make(map[int]struct {
 			result1 imageset.UnprocessedImageRef
 			result2 error
 		})
 	}
-	fake.signatureReturnsOnCall[i] = struct {
+	fake.artifactReturnsOnCall[i] = struct {
 		result1 imageset.UnprocessedImageRef
 		result2 error
 	}{result1, result2}
@@ -94,8 +96,8 @@ func (fake *FakeFinder) SignatureReturnsOnCall(i int, result1 imageset.Unprocess
 func (fake *FakeFinder) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
-	fake.signatureMutex.RLock()
-	defer fake.signatureMutex.RUnlock()
+	fake.artifactMutex.RLock()
+	defer fake.artifactMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
@@ -0,0 +1,235 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package signature
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	regname "github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	ctlimg "github.com/k14s/imgpkg/pkg/imgpkg/image"
+	"github.com/k14s/imgpkg/pkg/imgpkg/signature/cosign"
+	"github.com/k14s/imgpkg/pkg/imgpkg/util"
+	"golang.org/x/sync/errgroup"
+)
+
+// cosignSignatureAnnotation holds the base64 encoded signature of the payload stored in the layer
+const cosignSignatureAnnotation = "dev.cosignproject.cosign/signature"
+
+// simpleSigningPayload is the payload cosign signs
+// (https://github.com/containers/image/blob/main/docs/containers-signature.5.md)
+type simpleSigningPayload struct {
+	Critical struct {
+		Identity struct {
+			DockerReference string `json:"docker-reference"`
+		} `json:"identity"`
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+	Optional map[string]interface{} `json:"optional"`
+}
+
+// LoadPublicKeys reads PEM encoded ECDSA or ED25519 public keys (e.g. cosign.pub)
+func LoadPublicKeys(paths []string) ([]crypto.PublicKey, error) {
+	var keys []crypto.PublicKey
+
+	for _, path := range paths {
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("Reading public key '%s': %s", path, err)
+		}
+
+		block, _ := pem.Decode(contents)
+		if block == nil {
+			return nil, fmt.Errorf("Expected public key '%s' to be PEM encoded", path)
+		}
+
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("Parsing public key '%s': %s", path, err)
+		}
+
+		switch key.(type) {
+		case *ecdsa.PublicKey, ed25519.PublicKey:
+			keys = append(keys, key)
+		default:
+			return nil, fmt.Errorf("Expected public key '%s' to be an ECDSA or ED25519 key but was %T", path, key)
+		}
+	}
+
+	return keys, nil
+}
+
+// Verifier checks cosign signatures of images using public keys, without reaching any transparency log
+type Verifier struct {
+	registry    ctlimg.ImagesMetadata
+	keys        []crypto.PublicKey
+	concurrency int
+}
+
+func NewVerifier(registry ctlimg.ImagesMetadata, keys []crypto.PublicKey, concurrency int) *Verifier {
+	return &Verifier{registry: registry, keys: keys, concurrency: concurrency}
+}
+
+// Verify checks that every image has a signature made with one of the keys,
+// returning an error listing every image that could not be verified
+func (v *Verifier) Verify(imageRefs []string) error {
+	failures := map[string]string{}
+	failuresLock := &sync.Mutex{}
+
+	throttle := util.NewThrottle(v.concurrency)
+	var wg errgroup.Group
+	for _, imageRef := range imageRefs {
+		imageRef := imageRef //copy
+		wg.Go(func() error {
+			imgDigest, err := regname.NewDigest(imageRef)
+			if err != nil {
+				return fmt.Errorf("Verifying signature of '%s': %s", imageRef, err)
+			}
+
+			throttle.Take()
+			defer throttle.Done()
+
+			err = v.verify(imgDigest)
+			if err != nil {
+				failuresLock.Lock()
+				failures[imgDigest.Name()] = err.Error()
+				failuresLock.Unlock()
+			}
+			return nil
+		})
+	}
+
+	err := wg.Wait()
+	if err != nil {
+		return err
+	}
+
+	if len(failures) == 0 {
+		return nil
+	}
+
+	var unverifiedImages []string
+	for imageRef, reason := range failures {
+		unverifiedImages = append(unverifiedImages, fmt.Sprintf("- %s: %s", imageRef, reason))
+	}
+	sort.Strings(unverifiedImages)
+
+	return fmt.Errorf("Expected images to be signed with one of the verification keys, but %d images could not be verified:\n%s",
+		len(unverifiedImages), strings.Join(unverifiedImages, "\n"))
+}
+
+func (v *Verifier) verify(imgDigest regname.Digest) error {
+	digest, err := v1.NewHash(imgDigest.DigestStr())
+	if err != nil {
+		return err
+	}
+
+	sigTag, err := regname.NewTag(imgDigest.Context().Name() + ":" + cosign.AttachedImageTag(v1.Descriptor{Digest: digest}, cosign.SignatureTagSuffix))
+	if err != nil {
+		return err
+	}
+
+	sigImg, err := v.registry.Image(sigTag)
+	if err != nil {
+		if transportErr, ok := err.(*transport.Error); ok && transportErr.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("no signature found (expected %s)", sigTag.Name())
+		}
+		return fmt.Errorf("fetching signature %s: %s", sigTag.Name(), err)
+	}
+
+	manifest, err := sigImg.Manifest()
+	if err != nil {
+		return fmt.Errorf("reading signature %s: %s", sigTag.Name(), err)
+	}
+
+	reason := "signature image does not contain any signature"
+
+	for _, layerDesc := range manifest.Layers {
+		encodedSig, found := layerDesc.Annotations[cosignSignatureAnnotation]
+		if !found {
+			continue
+		}
+
+		payload, err := v.layerContents(sigImg, layerDesc.Digest)
+		if err != nil {
+			return fmt.Errorf("reading signature %s: %s", sigTag.Name(), err)
+		}
+
+		var signedPayload simpleSigningPayload
+		err = json.Unmarshal(payload, &signedPayload)
+		if err != nil {
+			reason = fmt.Sprintf("parsing signed payload: %s", err)
+			continue
+		}
+
+		if signedPayload.Critical.Image.DockerManifestDigest != digest.String() {
+			reason = fmt.Sprintf("signed payload refers to digest '%s'", signedPayload.Critical.Image.DockerManifestDigest)
+			continue
+		}
+
+		sig, err := base64.StdEncoding.DecodeString(encodedSig)
+		if err != nil {
+			reason = fmt.Sprintf("decoding signature: %s", err)
+			continue
+		}
+
+		if v.verifiedByAnyKey(payload, sig) {
+			return nil
+		}
+		reason = "signature does not match any verification key"
+	}
+
+	return fmt.Errorf("%s", reason)
+}
+
+func (v *Verifier) layerContents(img v1.Image, digest v1.Hash) ([]byte, error) {
+	layer, err := img.LayerByDigest(digest)
+	if err != nil {
+		return nil, err
+	}
+
+	// Payloads are stored as is, hence compressed contents are the blob contents
+	rc, err := layer.Compressed()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return ioutil.ReadAll(rc)
+}
+
+func (v *Verifier) verifiedByAnyKey(payload, sig []byte) bool {
+	payloadHash := sha256.Sum256(payload)
+
+	for _, key := range v.keys {
+		switch key := key.(type) {
+		case *ecdsa.PublicKey:
+			if ecdsa.VerifyASN1(key, payloadHash[:], sig) {
+				return true
+			}
+		case ed25519.PublicKey:
+			if ed25519.Verify(key, payload, sig) {
+				return true
+			}
+		}
+	}
+	return false
+}
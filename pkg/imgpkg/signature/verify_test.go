@@ -0,0 +1,97 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package signature_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/k14s/imgpkg/pkg/imgpkg/signature"
+	"github.com/k14s/imgpkg/test/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifier_Verify(t *testing.T) {
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	keys := loadPublicKeys(t, ecdsaKey.Public(), ed25519Key.Public())
+
+	t.Run("it verifies images signed with any of the keys", func(t *testing.T) {
+		regBuilder := helpers.NewFakeRegistry(t, &helpers.Logger{})
+		defer regBuilder.CleanUp()
+		img1 := regBuilder.WithRandomImage("some-image")
+		regBuilder.WithCosignSignature(img1.RefDigest, img1.Digest, ecdsaKey)
+		img2 := regBuilder.WithRandomImage("other-image")
+		regBuilder.WithCosignSignature(img2.RefDigest, img2.Digest, ed25519Key)
+		reg := regBuilder.Build()
+
+		err := signature.NewVerifier(reg, keys, 1).Verify([]string{img1.RefDigest, img2.RefDigest})
+		require.NoError(t, err)
+	})
+
+	t.Run("it lists every image that cannot be verified", func(t *testing.T) {
+		regBuilder := helpers.NewFakeRegistry(t, &helpers.Logger{})
+		defer regBuilder.CleanUp()
+		signedImg := regBuilder.WithRandomImage("signed-image")
+		regBuilder.WithCosignSignature(signedImg.RefDigest, signedImg.Digest, ecdsaKey)
+		unsignedImg := regBuilder.WithRandomImage("unsigned-image")
+		otherKeyImg := regBuilder.WithRandomImage("other-key-image")
+		regBuilder.WithCosignSignature(otherKeyImg.RefDigest, otherKeyImg.Digest, otherKey)
+		tamperedImg := regBuilder.WithRandomImage("tampered-image")
+		regBuilder.WithCosignSignature(tamperedImg.RefDigest, signedImg.Digest, ecdsaKey)
+		reg := regBuilder.Build()
+
+		err := signature.NewVerifier(reg, keys, 2).Verify([]string{
+			signedImg.RefDigest, unsignedImg.RefDigest, otherKeyImg.RefDigest, tamperedImg.RefDigest})
+		require.Error(t, err)
+
+		assert.Contains(t, err.Error(), "3 images could not be verified")
+		assert.NotContains(t, err.Error(), signedImg.RefDigest)
+		assert.Contains(t, err.Error(), unsignedImg.RefDigest+": no signature found")
+		assert.Contains(t, err.Error(), otherKeyImg.RefDigest+": signature does not match any verification key")
+		assert.Contains(t, err.Error(), tamperedImg.RefDigest+": signed payload refers to digest '"+signedImg.Digest+"'")
+	})
+}
+
+func TestLoadPublicKeys(t *testing.T) {
+	t.Run("it rejects keys that are neither ECDSA nor ED25519 keys", func(t *testing.T) {
+		rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+		require.NoError(t, err)
+
+		path := filepath.Join(t.TempDir(), "rsa.pub")
+		helpers.WriteCosignPublicKey(t, path, rsaKey.Public())
+
+		_, err = signature.LoadPublicKeys([]string{path})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "to be an ECDSA or ED25519 key")
+	})
+}
+
+func loadPublicKeys(t *testing.T, keys ...crypto.PublicKey) []crypto.PublicKey {
+	dir := t.TempDir()
+
+	var paths []string
+	for i, key := range keys {
+		path := filepath.Join(dir, fmt.Sprintf("cosign-%d.pub", i))
+		helpers.WriteCosignPublicKey(t, path, key)
+		paths = append(paths, path)
+	}
+
+	loadedKeys, err := signature.LoadPublicKeys(paths)
+	require.NoError(t, err)
+	return loadedKeys
+}
@@ -18,6 +18,8 @@ type ProgressLogger interface {
 	End()
 }
 
+// NewProgressBar returns a logger reporting progress of a single write,
+// concurrent or subsequent writes need a logger each
 func (l ImgpkgLogger) NewProgressBar(logger LoggerWithLevels, finalMessage, errorMessagePrefix string) ProgressLogger {
 	ctx, cancel := context.WithCancel(context.Background())
 	if isatty.IsTerminal(os.Stdout.Fd()) {
@@ -46,7 +48,10 @@ func (l *ProgressBarLogger) Start(progressChan <-chan regv1.Update) {
 			select {
 			case <-l.ctx.Done():
 				return
-			case update := <-progressChan:
+			case update, ok := <-progressChan:
+				if !ok {
+					return
+				}
 				if update.Error != nil {
 					l.logger.Errorf("%s: %s\n", l.errorMessagePrefix, update.Error)
 					continue
@@ -85,7 +90,10 @@ func (l *ProgressBarNoTTYLogger) Start(progressChan <-chan regv1.Update) {
 			select {
 			case <-l.ctx.Done():
 				return
-			case <-progressChan:
+			case _, ok := <-progressChan:
+				if !ok {
+					return
+				}
 			}
 		}
 	}()
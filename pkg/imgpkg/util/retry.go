@@ -30,7 +30,7 @@ func Retry(doFunc func() error) error {
 		if tranErr, ok := lastErr.(*transport.Error); ok {
 			if len(tranErr.Errors) > 0 {
 				if tranErr.Errors[0].Code == transport.UnauthorizedErrorCode {
-					return fmt.Errorf("Non-retryable error: %s", lastErr)
+					return fmt.Errorf("Non-retryable error: %w", lastErr)
 				}
 			}
 		}
@@ -40,5 +40,5 @@ func Retry(doFunc func() error) error {
 
 		time.Sleep(1 * time.Second)
 	}
-	return fmt.Errorf("Retried 5 times: %s", lastErr)
+	return fmt.Errorf("Retried 5 times: %w", lastErr)
 }
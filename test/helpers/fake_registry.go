@@ -15,12 +15,14 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	regregistry "github.com/google/go-containerregistry/pkg/registry"
 	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
 	"github.com/google/go-containerregistry/pkg/v1/mutate"
 	"github.com/google/go-containerregistry/pkg/v1/random"
 	regremote "github.com/google/go-containerregistry/pkg/v1/remote"
@@ -394,6 +396,25 @@ func (r *FakeTestRegistryBuilder) WithImageIndex(imageIndexName string, images .
 	return r.updateState(imageIndexName, nil, index, "", "")
 }
 
+// WithMultiPlatformImageIndex creates an image index with a random image for each platform
+func (r *FakeTestRegistryBuilder) WithMultiPlatformImageIndex(imageIndexName string, platforms ...v1.Platform) *ImageOrImageIndexWithTarPath {
+	index := v1.ImageIndex(empty.Index)
+
+	for _, platform := range platforms {
+		platform := platform // copy
+
+		image, err := random.Image(500, 1)
+		require.NoError(r.t, err)
+
+		index = mutate.AppendManifests(index, mutate.IndexAddendum{
+			Add:        image,
+			Descriptor: v1.Descriptor{Platform: &platform},
+		})
+	}
+
+	return r.updateState(imageIndexName, nil, index, "", "")
+}
+
 func (r *FakeTestRegistryBuilder) RemoveImage(imageRef string) {
 	u, err := url.Parse(r.server.URL)
 	assert.NoError(r.t, err)
@@ -434,6 +455,67 @@ func (r *FakeTestRegistryBuilder) WithImageStatusCodeRemap(img string, originalS
 	return r
 }
 
+// WithManifestUploadError rejects uploads of manifests whose reference contains given string
+// with a registry error (e.g. 403 DENIED)
+func (r *FakeTestRegistryBuilder) WithManifestUploadError(ref string, statusCode int, errorCode string) *FakeTestRegistryBuilder {
+	originalHandler := r.server.Config.Handler
+	r.originalHandler = originalHandler
+
+	r.server.Config.Handler = http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		if request.Method == http.MethodPut && strings.Contains(request.URL.Path, "/manifests/") && strings.Contains(request.URL.Path, ref) {
+			responseWriter.Header().Set("Content-Type", "application/json")
+			responseWriter.WriteHeader(statusCode)
+			fmt.Fprintf(responseWriter, `{"errors":[{"code":"%s","message":"manifest upload rejected"}]}`, errorCode)
+			return
+		}
+		originalHandler.ServeHTTP(responseWriter, request)
+	})
+	return r
+}
+
+// WithRepositoryScopedBlobs only serves blobs from repositories they were uploaded or mounted to
+// (the fake registry shares blobs between all repositories) and mounts blobs across repositories
+func (r *FakeTestRegistryBuilder) WithRepositoryScopedBlobs() *FakeTestRegistryBuilder {
+	parentHandler := r.server.Config.Handler
+	repoBlobs := map[string]bool{}
+	repoBlobsLock := &sync.Mutex{}
+
+	r.server.Config.Handler = http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		pathParts := strings.SplitN(strings.TrimPrefix(request.URL.Path, "/v2/"), "/blobs/", 2)
+		if len(pathParts) != 2 {
+			parentHandler.ServeHTTP(writer, request)
+			return
+		}
+		repo, blobPath := pathParts[0], pathParts[1]
+
+		repoBlobsLock.Lock()
+		defer repoBlobsLock.Unlock()
+
+		switch {
+		case (request.Method == http.MethodHead || request.Method == http.MethodGet) && !strings.HasPrefix(blobPath, "uploads/"):
+			if !repoBlobs[repo+"@"+blobPath] {
+				writer.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+		case request.Method == http.MethodPost && request.URL.Query().Get("mount") != "":
+			mountDigest := request.URL.Query().Get("mount")
+			if repoBlobs[request.URL.Query().Get("from")+"@"+mountDigest] {
+				repoBlobs[repo+"@"+mountDigest] = true
+				writer.Header().Set("Location", fmt.Sprintf("/v2/%s/blobs/%s", repo, mountDigest))
+				writer.WriteHeader(http.StatusCreated)
+				return
+			}
+
+		case request.URL.Query().Get("digest") != "":
+			repoBlobs[repo+"@"+request.URL.Query().Get("digest")] = true
+		}
+
+		parentHandler.ServeHTTP(writer, request)
+	})
+	return r
+}
+
 func (r *FakeTestRegistryBuilder) ResetHandler() *FakeTestRegistryBuilder {
 	if r.originalHandler != nil {
 		r.server.Config.Handler = r.originalHandler
@@ -0,0 +1,90 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package helpers
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/stretchr/testify/require"
+)
+
+// WithCosignSignature signs the payload cosign creates for signedDigest (usually the digest of the image)
+// and attaches the signature to the image (digest reference) using cosign tag schema (sha256-<hex>.sig)
+func (r *FakeTestRegistryBuilder) WithCosignSignature(imageRef string, signedDigest string, key crypto.Signer) *ImageOrImageIndexWithTarPath {
+	imgRef, err := name.NewDigest(imageRef)
+	require.NoError(r.t, err)
+
+	payload := []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"%s"},"image":{"docker-manifest-digest":"%s"},"type":"cosign container image signature"},"optional":null}`,
+		imgRef.Context().Name(), signedDigest))
+
+	var sig []byte
+	if _, ok := key.Public().(ed25519.PublicKey); ok {
+		sig, err = key.Sign(rand.Reader, payload, crypto.Hash(0))
+	} else {
+		payloadHash := sha256.Sum256(payload)
+		sig, err = key.Sign(rand.Reader, payloadHash[:], crypto.SHA256)
+	}
+	require.NoError(r.t, err)
+
+	sigImg, err := mutate.Append(empty.Image, mutate.Addendum{
+		Layer:       rawLayer{contents: payload, mediaType: "application/vnd.dev.cosign.simplesigning.v1+json"},
+		Annotations: map[string]string{"dev.cosignproject.cosign/signature": base64.StdEncoding.EncodeToString(sig)},
+	})
+	require.NoError(r.t, err)
+
+	sigTag := strings.ReplaceAll(imgRef.DigestStr(), ":", "-") + ".sig"
+	return r.updateState(imgRef.Context().RepositoryStr()+":"+sigTag, sigImg, nil, "", "")
+}
+
+// WriteCosignPublicKey writes the public key to path PEM encoded the way cosign does (e.g. cosign.pub)
+func WriteCosignPublicKey(t *testing.T, path string, key crypto.PublicKey) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	require.NoError(t, err)
+
+	err = ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600)
+	require.NoError(t, err)
+}
+
+// rawLayer is a layer stored as is (without compression), like cosign signature payloads
+type rawLayer struct {
+	contents  []byte
+	mediaType types.MediaType
+}
+
+func (l rawLayer) Digest() (v1.Hash, error) {
+	sum := sha256.Sum256(l.contents)
+	return v1.NewHash("sha256:" + hex.EncodeToString(sum[:]))
+}
+
+func (l rawLayer) DiffID() (v1.Hash, error) { return l.Digest() }
+
+func (l rawLayer) Compressed() (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(l.contents)), nil
+}
+
+func (l rawLayer) Uncompressed() (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(l.contents)), nil
+}
+
+func (l rawLayer) Size() (int64, error) { return int64(len(l.contents)), nil }
+
+func (l rawLayer) MediaType() (types.MediaType, error) { return l.mediaType, nil }
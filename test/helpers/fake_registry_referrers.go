@@ -0,0 +1,147 @@
+// Copyright 2020 VMware, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package helpers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/stretchr/testify/require"
+)
+
+// WithReferrer creates a random image in the repository of the subject that refers to it via its subject field
+func (r *FakeTestRegistryBuilder) WithReferrer(subject *ImageOrImageIndexWithTarPath, artifactType string) *ImageOrImageIndexWithTarPath {
+	img, err := random.Image(100, 1)
+	require.NoError(r.t, err)
+
+	rawManifest, err := img.RawManifest()
+	require.NoError(r.t, err)
+
+	manifest := map[string]interface{}{}
+	require.NoError(r.t, json.Unmarshal(rawManifest, &manifest))
+
+	subjectMediaType := types.OCIManifestSchema1
+	subjectRawManifest, err := subject.rawManifest()
+	require.NoError(r.t, err)
+	if subject.ImageIndex != nil {
+		subjectMediaType = types.OCIImageIndex
+	}
+
+	manifest["mediaType"] = types.OCIManifestSchema1
+	manifest["artifactType"] = artifactType
+	manifest["subject"] = map[string]interface{}{
+		"mediaType": subjectMediaType,
+		"digest":    subject.Digest,
+		"size":      len(subjectRawManifest),
+	}
+
+	rawManifest, err = json.Marshal(manifest)
+	require.NoError(r.t, err)
+
+	subjectRef, err := name.NewDigest(subject.RefDigest)
+	require.NoError(r.t, err)
+
+	return r.updateState(subjectRef.Context().RepositoryStr(), imageWithRawManifest{Image: img, raw: rawManifest}, nil, "", "")
+}
+
+// WithReferrersAPI serves the referrers API (/v2/<repo>/referrers/<digest>) listing manifests uploaded with a subject
+func (r *FakeTestRegistryBuilder) WithReferrersAPI() *FakeTestRegistryBuilder {
+	parentHandler := r.server.Config.Handler
+	referrers := map[string][]map[string]interface{}{}
+	referrersLock := &sync.Mutex{}
+
+	r.server.Config.Handler = http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		path := strings.TrimPrefix(request.URL.Path, "/v2/")
+
+		if pathParts := strings.SplitN(path, "/referrers/", 2); len(pathParts) == 2 && request.Method == http.MethodGet {
+			referrersLock.Lock()
+			manifests := referrers[pathParts[0]+"@"+pathParts[1]]
+			referrersLock.Unlock()
+
+			if manifests == nil {
+				manifests = []map[string]interface{}{}
+			}
+
+			writer.Header().Set("Content-Type", string(types.OCIImageIndex))
+			json.NewEncoder(writer).Encode(map[string]interface{}{
+				"schemaVersion": 2,
+				"mediaType":     types.OCIImageIndex,
+				"manifests":     manifests,
+			})
+			return
+		}
+
+		if pathParts := strings.SplitN(path, "/manifests/", 2); len(pathParts) == 2 && request.Method == http.MethodPut {
+			body, err := ioutil.ReadAll(request.Body)
+			require.NoError(r.t, err)
+			request.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+			var manifest struct {
+				MediaType    string `json:"mediaType"`
+				ArtifactType string `json:"artifactType"`
+				Subject      *struct {
+					Digest string `json:"digest"`
+				} `json:"subject"`
+			}
+			if json.Unmarshal(body, &manifest) == nil && manifest.Subject != nil {
+				sum := sha256.Sum256(body)
+				digest := "sha256:" + hex.EncodeToString(sum[:])
+				key := pathParts[0] + "@" + manifest.Subject.Digest
+
+				referrersLock.Lock()
+				referrers[key] = append(referrers[key], map[string]interface{}{
+					"mediaType":    manifest.MediaType,
+					"digest":       digest,
+					"size":         len(body),
+					"artifactType": manifest.ArtifactType,
+				})
+				referrersLock.Unlock()
+			}
+		}
+
+		parentHandler.ServeHTTP(writer, request)
+	})
+	return r
+}
+
+func (r *ImageOrImageIndexWithTarPath) rawManifest() ([]byte, error) {
+	if r.Image != nil {
+		return r.Image.RawManifest()
+	}
+	return r.ImageIndex.RawManifest()
+}
+
+// imageWithRawManifest is an image whose manifest was modified (e.g. to add a subject)
+type imageWithRawManifest struct {
+	v1.Image
+	raw []byte
+}
+
+func (i imageWithRawManifest) MediaType() (types.MediaType, error) {
+	return types.OCIManifestSchema1, nil
+}
+
+func (i imageWithRawManifest) RawManifest() ([]byte, error) { return i.raw, nil }
+
+func (i imageWithRawManifest) Size() (int64, error) { return int64(len(i.raw)), nil }
+
+func (i imageWithRawManifest) Digest() (v1.Hash, error) {
+	sum := sha256.Sum256(i.raw)
+	return v1.NewHash(fmt.Sprintf("sha256:%s", hex.EncodeToString(sum[:])))
+}
+
+func (i imageWithRawManifest) Manifest() (*v1.Manifest, error) {
+	return v1.ParseManifest(bytes.NewReader(i.raw))
+}